@@ -1,16 +1,22 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"go-ai-copilot/internal/assistant"
 	"go-ai-copilot/internal/cache"
 	"go-ai-copilot/internal/config"
 	"go-ai-copilot/internal/database"
+	"go-ai-copilot/internal/events"
 	"go-ai-copilot/internal/handler"
+	"go-ai-copilot/internal/prompt"
 	"go-ai-copilot/internal/router"
+	"go-ai-copilot/internal/storage"
+	"go-ai-copilot/internal/store"
 	"go-ai-copilot/pkg/jwt"
 )
 
@@ -52,27 +58,67 @@ func main() {
 		log.Fatalf("配置加载失败: %v", err)
 	}
 
-	// 2. 初始化数据库
-	dbCfg := database.Config{
-		Host:     cfg.Database.Host,
-		Port:     cfg.Database.Port,
-		User:     cfg.Database.User,
-		Password: cfg.Database.Password,
-		DBName:   cfg.Database.DBName,
-		SSLMode:  cfg.Database.SSLMode,
+	// 数据库迁移子命令：server migrate up|down|status
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(cfg, os.Args[2:]))
 	}
-	if err := database.Init(dbCfg); err != nil {
+
+	// 重建向量子命令：server reindex start|resume|status|cancel
+	if len(os.Args) > 1 && os.Args[1] == "reindex" {
+		os.Exit(runReindex(cfg, os.Args[2:]))
+	}
+
+	// 2. 初始化数据库（执行未执行的迁移）
+	if err := database.Init(databaseConfig(cfg)); err != nil {
 		log.Fatalf("数据库初始化失败: %v", err)
 	}
+	st := store.New(database.DB)
 
-	// 3. 初始化Redis
-	redisCfg := cache.Config{
-		Addr:     cfg.Redis.Addr,
-		Password: cfg.Redis.Password,
-		DB:       cfg.Redis.DB,
+	// 写入内置提示词模板
+	if err := prompt.SeedDefaults(context.Background(), st.Prompts); err != nil {
+		log.Printf("警告: 内置提示词模板写入失败: %v", err)
 	}
-	if err := cache.Init(redisCfg); err != nil {
-		log.Printf("警告: Redis初始化失败，将使用内存缓存: %v", err)
+
+	// 写入内置助手
+	if err := assistant.SeedBuiltins(context.Background(), st.Assistants); err != nil {
+		log.Printf("警告: 内置助手写入失败: %v", err)
+	}
+
+	// 初始化聊天记录搜索（全文检索配置不合法或索引创建失败时停止启动）
+	searcher, err := handler.NewSearcher(cfg.Search, cfg.AI, st)
+	if err != nil {
+		log.Fatalf("聊天记录搜索初始化失败: %v", err)
+	}
+
+	// 3. 初始化缓存
+	appCache, err := cache.New(cache.Config{
+		Driver:              cfg.Cache.Driver,
+		Addr:                cfg.Redis.Addr,
+		Password:            cfg.Redis.Password,
+		DB:                  cfg.Redis.DB,
+		MaxEntries:          cfg.Cache.MaxEntries,
+		HealthCheckInterval: cfg.Cache.HealthCheckInterval,
+	})
+	if err != nil {
+		log.Fatal("缓存初始化失败: ", err)
+	}
+
+	// 初始化上传文件存储
+	blobs, err := storage.New(storage.Config{
+		Driver: cfg.Storage.Driver,
+		Dir:    cfg.Storage.Dir,
+		S3: storage.S3Config{
+			Endpoint:        cfg.Storage.S3.Endpoint,
+			Region:          cfg.Storage.S3.Region,
+			Bucket:          cfg.Storage.S3.Bucket,
+			Prefix:          cfg.Storage.S3.Prefix,
+			PathStyle:       cfg.Storage.S3.PathStyle,
+			AccessKeyID:     cfg.Storage.S3.AccessKeyID,
+			SecretAccessKey: cfg.Storage.S3.SecretAccessKey,
+		},
+	})
+	if err != nil {
+		log.Fatal("文件存储初始化失败: ", err)
 	}
 
 	// 4. 初始化JWT
@@ -82,21 +128,27 @@ func main() {
 	gin.SetMode(cfg.Server.Mode)
 
 	// 6. 初始化处理器
-	chatHandler, err := handler.NewChatHandler()
+	scheduler := handler.NewScheduler(cfg.AI.Concurrency)
+	hub := events.NewHub()
+	chatHandler, err := handler.NewChatHandler(scheduler, hub, appCache, st, searcher)
 	if err != nil {
 		log.Printf("警告: AI客户端初始化失败: %v", err)
 		// 创建一个空的处理器以避免空指针
 		chatHandler = &handler.ChatHandler{}
 	}
-	userHandler := handler.NewUserHandler(jwtTool)
-	sessionHandler := handler.NewSessionHandler()
-	ragHandler, err := handler.NewRAGHandler()
+	userHandler := handler.NewUserHandler(jwtTool, st)
+	sessionHandler := handler.NewSessionHandler(appCache, st, searcher)
+	ragHandler, err := handler.NewRAGHandler(chatHandler, st, blobs)
 	if err != nil {
 		log.Printf("警告: RAG处理器初始化失败: %v", err)
 	}
+	promptHandler := handler.NewPromptHandler(st)
+	assistantHandler := handler.NewAssistantHandler(st)
+	shareHandler := handler.NewShareHandler(sessionHandler)
+	eventsHandler := handler.NewEventsHandler(hub)
 
 	// 7. 设置路由
-	r := router.Setup(jwtTool, chatHandler, userHandler, sessionHandler, ragHandler)
+	r := router.Setup(jwtTool, cfg.Admin.Usernames, st.Users, chatHandler, userHandler, sessionHandler, ragHandler, promptHandler, assistantHandler, shareHandler, eventsHandler)
 
 	// 8. 启动服务
 	port := cfg.Server.Port
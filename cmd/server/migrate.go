@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"go-ai-copilot/internal/config"
+	"go-ai-copilot/internal/database"
+	"gorm.io/gorm/logger"
+)
+
+const migrateUsage = `用法: server migrate <命令>
+
+命令:
+  up          执行全部未执行的迁移
+  down [N]    回滚最近执行的N个迁移（默认1个）
+  status      查看迁移状态`
+
+// databaseConfig 数据库连接配置
+func databaseConfig(cfg *config.Config) database.Config {
+	return database.Config{
+		Driver:   cfg.Database.Driver,
+		Path:     cfg.Database.Path,
+		Host:     cfg.Database.Host,
+		Port:     cfg.Database.Port,
+		User:     cfg.Database.User,
+		Password: cfg.Database.Password,
+		DBName:   cfg.Database.DBName,
+		SSLMode:  cfg.Database.SSLMode,
+	}
+}
+
+// runMigrate 执行迁移子命令，返回进程退出码
+func runMigrate(cfg *config.Config, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	if err := database.Open(databaseConfig(cfg)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	// 迁移文件内容较长，只输出警告和错误
+	database.DB.Logger = database.DB.Logger.LogMode(logger.Warn)
+
+	switch args[0] {
+	case "up":
+		count, err := database.MigrateUp(database.DB)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Printf("执行了 %d 个迁移\n", count)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 {
+				fmt.Fprintln(os.Stderr, "回滚数量必须是正整数")
+				return 2
+			}
+			steps = n
+		}
+		count, err := database.MigrateDown(database.DB, steps)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Printf("回滚了 %d 个迁移\n", count)
+	case "status":
+		statuses, err := database.MigrationStatuses(database.DB)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		for _, s := range statuses {
+			state := "未执行"
+			if s.AppliedAt != nil {
+				state = "已执行 " + s.AppliedAt.Local().Format("2006-01-02 15:04:05")
+			}
+			if s.Missing {
+				state += "（缺少迁移文件）"
+			}
+			fmt.Printf("%04d  %-40s %s\n", s.Version, s.Name, state)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"go-ai-copilot/internal/config"
+	"go-ai-copilot/internal/database"
+	"go-ai-copilot/internal/handler"
+	"go-ai-copilot/internal/model"
+	"go-ai-copilot/internal/reindex"
+	"go-ai-copilot/internal/store"
+	"gorm.io/gorm/logger"
+)
+
+const reindexUsage = `用法: server reindex <命令>
+
+使用当前配置的向量化模型重新生成分块的向量，新向量全部生成后一次性替换
+
+命令:
+  start [知识库ID]   创建任务并在前台执行（不指定知识库时重建全部分块），Ctrl+C 中断后可继续
+  resume <任务ID>    继续执行已中断或失败的任务
+  status [任务ID]    查看任务进度（不指定时列出最近的任务）
+  cancel <任务ID>    取消任务，丢弃已生成的向量`
+
+// runReindex 执行重建向量子命令，返回进程退出码
+func runReindex(cfg *config.Config, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, reindexUsage)
+		return 2
+	}
+
+	var id uint
+	if len(args) > 1 {
+		n, err := strconv.ParseUint(args[1], 10, 32)
+		if err != nil || n == 0 {
+			fmt.Fprintln(os.Stderr, "ID必须是正整数")
+			return 2
+		}
+		id = uint(n)
+	}
+	if id == 0 && (args[0] == "resume" || args[0] == "cancel") {
+		fmt.Fprintln(os.Stderr, reindexUsage)
+		return 2
+	}
+
+	if err := database.Open(databaseConfig(cfg)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	database.DB.Logger = database.DB.Logger.LogMode(logger.Warn)
+	if _, err := database.MigrateUp(database.DB); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	st := store.New(database.DB)
+
+	// Ctrl+C 时中断任务，已生成的向量会保留
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	switch args[0] {
+	case "start", "resume":
+		embeddingClient, space, err := handler.NewEmbeddingClient(cfg.AI, st)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		runner := reindex.NewRunner(st.Reindex, embeddingClient, space, reindex.Config{
+			BatchSize: cfg.Reindex.BatchSize,
+			Interval:  cfg.Reindex.Interval,
+		})
+
+		if args[0] == "start" {
+			var kbID *uint
+			if id > 0 {
+				kbID = &id
+			}
+			job, err := runner.Create(ctx, kbID)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+			id = job.ID
+			fmt.Printf("已创建任务 %d，需要重建 %d 个分块（向量空间 %d: %s）\n", job.ID, job.Total, space.ID, space.Model)
+		}
+
+		err = runner.Run(ctx, id, func(job *model.ReindexJob) {
+			fmt.Printf("任务 %d: %d/%d\n", job.ID, job.Processed, job.Total)
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		job, err := st.Reindex.Get(context.Background(), id)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		switch job.Status {
+		case model.ReindexCompleted:
+			fmt.Printf("任务 %d 已完成\n", id)
+		case model.ReindexPaused:
+			fmt.Printf("任务 %d 已中断，可通过 server reindex resume %d 继续\n", id, id)
+			return 1
+		default:
+			fmt.Printf("任务 %d 状态: %s\n", id, job.Status)
+		}
+	case "status":
+		var jobs []model.ReindexJob
+		if id > 0 {
+			job, err := st.Reindex.Get(ctx, id)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "任务不存在")
+				return 1
+			}
+			jobs = append(jobs, *job)
+		} else {
+			var err error
+			if jobs, err = st.Reindex.List(ctx, 20); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+		}
+		for _, job := range jobs {
+			scope := "全部"
+			if job.KnowledgeBaseID != nil {
+				scope = fmt.Sprintf("知识库 %d", *job.KnowledgeBaseID)
+			}
+			fmt.Printf("%-6d %-10s %-12s 向量空间 %-4d %d/%d  %s\n",
+				job.ID, job.Status, scope, job.SpaceID, job.Processed, job.Total, job.Error)
+		}
+	case "cancel":
+		canceled, err := st.Reindex.Cancel(ctx, id)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if !canceled {
+			fmt.Fprintln(os.Stderr, "任务不存在或已结束")
+			return 1
+		}
+		fmt.Printf("已取消任务 %d\n", id)
+	default:
+		fmt.Fprintln(os.Stderr, reindexUsage)
+		return 2
+	}
+	return 0
+}
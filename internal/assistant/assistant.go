@@ -0,0 +1,49 @@
+package assistant
+
+import (
+	"context"
+
+	"go-ai-copilot/internal/model"
+	"go-ai-copilot/internal/prompt"
+	"go-ai-copilot/internal/store"
+)
+
+// builtinNames 内置助手名称，与提示词模板的模式一一对应
+var builtinNames = map[string]string{
+	"chat":          "通用助手",
+	"code_generate": "代码生成",
+	"code_explain":  "代码解释",
+	"code_optimize": "代码优化",
+	"code_vuln":     "漏洞检测",
+	"code_test":     "单元测试",
+	"rag":           "知识库问答",
+}
+
+// SeedBuiltins 为每个内置模式写入对应的内置助手（已存在则跳过）
+// 内置助手不设置自定义System Prompt，使用该模式的提示词模板
+func SeedBuiltins(ctx context.Context, assistants store.AssistantRepository) error {
+	for mode, name := range builtinNames {
+		if _, ok := prompt.Defaults[mode]; !ok {
+			continue
+		}
+
+		_, err := assistants.GetBuiltin(ctx, mode)
+		if err == nil {
+			continue
+		}
+		if !store.IsNotFound(err) {
+			return err
+		}
+
+		a := model.Assistant{
+			Name:       name,
+			Mode:       mode,
+			Visibility: model.VisibilityTeam,
+			Builtin:    true,
+		}
+		if err := assistants.Create(ctx, &a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
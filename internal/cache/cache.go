@@ -3,46 +3,68 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"time"
 
-	"github.com/redis/go-redis/v9"
 	"go-ai-copilot/internal/model"
 )
 
-var (
-	Client *redis.Client
-	Ctx    = context.Background()
+// ErrMiss Key不存在或已过期
+var ErrMiss = errors.New("cache: key not found")
+
+// Cache 键值缓存
+type Cache interface {
+	// Get 读取值，Key不存在时返回 ErrMiss
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set 写入值，ttl<=0 表示不过期
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Del 删除Key，Key不存在时不报错
+	Del(ctx context.Context, keys ...string) error
+	// Update 原子地读取并修改Key：fn收到当前值（不存在时为nil）并返回新值，返回nil时删除Key
+	// 并发修改同一个Key时不会丢失更新；fn可能被重复调用，不应有副作用
+	Update(ctx context.Context, key string, ttl time.Duration, fn func(old []byte) ([]byte, error)) error
+	// Ping 检查缓存是否可用
+	Ping(ctx context.Context) error
+	// Name 实现名称，用于日志和监控
+	Name() string
+}
+
+// 缓存实现
+const (
+	DriverRedis  = "redis"  // Redis，不可用时自动切换到内存缓存
+	DriverMemory = "memory" // 进程内LRU缓存，适合本地开发和测试
 )
 
-// Config Redis配置
+// Config 缓存配置
 type Config struct {
-	Addr     string
-	Password string
-	DB       int
+	Driver              string
+	Addr                string
+	Password            string
+	DB                  int
+	MaxEntries          int           // 内存缓存最多保留的Key数
+	HealthCheckInterval time.Duration // Redis健康检查间隔
 }
 
-// Init 初始化Redis连接
-func Init(cfg Config) error {
-	Client = redis.NewClient(&redis.Options{
-		Addr:     cfg.Addr,
-		Password: cfg.Password,
-		DB:       cfg.DB,
-	})
+// New 根据配置创建缓存
+// redis 驱动在启动时连不上Redis不会报错，先使用内存缓存，Redis恢复后自动切回
+func New(cfg Config) (Cache, error) {
+	memory := NewMemory(cfg.MaxEntries)
 
-	// 测试连接
-	_, err := Client.Ping(Ctx).Result()
-	if err != nil {
-		return fmt.Errorf("Redis连接失败: %v", err)
+	switch cfg.Driver {
+	case DriverMemory:
+		return memory, nil
+	case DriverRedis, "":
+		f := NewFailover(NewRedis(cfg.Addr, cfg.Password, cfg.DB), memory, cfg.HealthCheckInterval)
+		if f.Name() == DriverRedis {
+			fmt.Println("Redis连接成功")
+		} else {
+			log.Printf("警告: Redis连接失败，暂时使用内存缓存，恢复后自动切回")
+		}
+		return f, nil
 	}
-
-	fmt.Println("Redis连接成功")
-	return nil
-}
-
-// GetDB 获取数据库实例（为了兼容旧代码）
-func GetDB() *redis.Client {
-	return Client
+	return nil, fmt.Errorf("不支持的缓存驱动: %s", cfg.Driver)
 }
 
 // SessionHistoryKey 会话历史缓存Key
@@ -51,9 +73,8 @@ func SessionHistoryKey(sessionID uint) string {
 }
 
 // GetSessionHistory 获取会话历史缓存
-func GetSessionHistory(sessionID uint) ([]model.Message, error) {
-	key := SessionHistoryKey(sessionID)
-	data, err := Client.Get(Ctx, key).Bytes()
+func GetSessionHistory(c Cache, sessionID uint) ([]model.Message, error) {
+	data, err := c.Get(context.Background(), SessionHistoryKey(sessionID))
 	if err != nil {
 		return nil, err
 	}
@@ -68,18 +89,16 @@ func GetSessionHistory(sessionID uint) ([]model.Message, error) {
 
 // SetSessionHistory 设置会话历史缓存
 // 默认1小时过期
-func SetSessionHistory(sessionID uint, messages []model.Message) error {
-	key := SessionHistoryKey(sessionID)
+func SetSessionHistory(c Cache, sessionID uint, messages []model.Message) error {
 	data, err := json.Marshal(messages)
 	if err != nil {
 		return err
 	}
 
-	return Client.Set(Ctx, key, data, time.Hour).Err()
+	return c.Set(context.Background(), SessionHistoryKey(sessionID), data, time.Hour)
 }
 
 // DelSessionHistory 删除会话历史缓存
-func DelSessionHistory(sessionID uint) error {
-	key := SessionHistoryKey(sessionID)
-	return Client.Del(Ctx, key).Err()
+func DelSessionHistory(c Cache, sessionID uint) error {
+	return c.Del(context.Background(), SessionHistoryKey(sessionID))
 }
@@ -0,0 +1,207 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultHealthCheckInterval 默认健康检查间隔
+const defaultHealthCheckInterval = 10 * time.Second
+
+// maxDirtyKeys 主缓存不可用期间最多记录的Key数
+const maxDirtyKeys = 100000
+
+// Failover 主备缓存
+// 主缓存（Redis）出错或健康检查失败时切换到备用缓存（内存），恢复后切回。
+// 不可用期间写入或删除的Key在切回前从主缓存删除，避免读到中断前的旧数据
+type Failover struct {
+	primary  Cache
+	fallback *Memory
+	interval time.Duration
+
+	healthy  atomic.Bool
+	mu       sync.Mutex          // 保护备用缓存的写入和切换过程
+	dirty    map[string]struct{} // 主缓存不可用期间写入或删除的Key
+	overflow bool                // dirty超出上限，切回时无法完整清理
+	stop     chan struct{}
+}
+
+// NewFailover 创建主备缓存并启动健康检查
+func NewFailover(primary Cache, fallback *Memory, interval time.Duration) *Failover {
+	if interval <= 0 {
+		interval = defaultHealthCheckInterval
+	}
+	f := &Failover{
+		primary:  primary,
+		fallback: fallback,
+		interval: interval,
+		dirty:    make(map[string]struct{}),
+		stop:     make(chan struct{}),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	f.healthy.Store(primary.Ping(ctx) == nil)
+	cancel()
+
+	go f.watch()
+	return f
+}
+
+// Get 读取值
+func (f *Failover) Get(ctx context.Context, key string) ([]byte, error) {
+	if f.healthy.Load() {
+		data, err := f.primary.Get(ctx, key)
+		if err == nil || errors.Is(err, ErrMiss) {
+			return data, err
+		}
+		f.markDown(err)
+	}
+	return f.fallback.Get(ctx, key)
+}
+
+// Set 写入值
+func (f *Failover) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if f.healthy.Load() {
+		err := f.primary.Set(ctx, key, value, ttl)
+		if err == nil {
+			return nil
+		}
+		f.markDown(err)
+	}
+	return f.onFallback(ctx, []string{key}, func(c Cache) error {
+		return c.Set(ctx, key, value, ttl)
+	})
+}
+
+// Del 删除Key
+func (f *Failover) Del(ctx context.Context, keys ...string) error {
+	if f.healthy.Load() {
+		err := f.primary.Del(ctx, keys...)
+		if err == nil {
+			return nil
+		}
+		f.markDown(err)
+	}
+	return f.onFallback(ctx, keys, func(c Cache) error {
+		return c.Del(ctx, keys...)
+	})
+}
+
+// Update 原子地读取并修改Key
+func (f *Failover) Update(ctx context.Context, key string, ttl time.Duration, fn func(old []byte) ([]byte, error)) error {
+	if f.healthy.Load() {
+		// fn返回的错误不是主缓存故障，不切换
+		var fnErr error
+		err := f.primary.Update(ctx, key, ttl, func(old []byte) ([]byte, error) {
+			value, err := fn(old)
+			fnErr = err
+			return value, err
+		})
+		if err == nil || fnErr != nil {
+			return err
+		}
+		f.markDown(err)
+	}
+	return f.onFallback(ctx, []string{key}, func(c Cache) error {
+		return c.Update(ctx, key, ttl, fn)
+	})
+}
+
+// Ping 备用缓存始终可用
+func (f *Failover) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Name 当前使用的缓存
+func (f *Failover) Name() string {
+	if f.healthy.Load() {
+		return f.primary.Name()
+	}
+	return f.fallback.Name()
+}
+
+// Close 停止健康检查
+func (f *Failover) Close() {
+	close(f.stop)
+}
+
+// onFallback 在备用缓存上执行写操作并记录Key
+// 加锁后再次检查状态：等待期间主缓存可能已恢复，此时直接写主缓存
+func (f *Failover) onFallback(ctx context.Context, keys []string, op func(Cache) error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.healthy.Load() {
+		return op(f.primary)
+	}
+	for _, key := range keys {
+		if len(f.dirty) >= maxDirtyKeys {
+			f.overflow = true
+			break
+		}
+		f.dirty[key] = struct{}{}
+	}
+	return op(f.fallback)
+}
+
+// markDown 标记主缓存不可用
+func (f *Failover) markDown(err error) {
+	if f.healthy.CompareAndSwap(true, false) {
+		log.Printf("警告: %s缓存不可用，切换到%s缓存: %v", f.primary.Name(), f.fallback.Name(), err)
+	}
+}
+
+// watch 定期检查主缓存
+func (f *Failover) watch() {
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-f.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), f.interval/2)
+			err := f.primary.Ping(ctx)
+			if err != nil {
+				f.markDown(err)
+			} else if !f.healthy.Load() {
+				f.recover(ctx)
+			}
+			cancel()
+		}
+	}
+}
+
+// recover 主缓存恢复后清理不可用期间变更过的Key并切回
+func (f *Failover) recover(ctx context.Context) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	keys := make([]string, 0, len(f.dirty))
+	for key := range f.dirty {
+		keys = append(keys, key)
+	}
+	for start := 0; start < len(keys); start += 500 {
+		end := start + 500
+		if end > len(keys) {
+			end = len(keys)
+		}
+		if err := f.primary.Del(ctx, keys[start:end]...); err != nil {
+			log.Printf("警告: 清理%s缓存失败，暂不切回: %v", f.primary.Name(), err)
+			return
+		}
+	}
+	if f.overflow {
+		log.Printf("警告: %s缓存不可用期间变更的Key过多，部分缓存可能是旧数据，将在过期后更新", f.primary.Name())
+	}
+
+	f.dirty = make(map[string]struct{})
+	f.overflow = false
+	f.fallback.Flush()
+	f.healthy.Store(true)
+	log.Printf("%s缓存已恢复", f.primary.Name())
+}
@@ -0,0 +1,187 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errDown = errors.New("connection refused")
+
+// flakyCache 可以模拟不可用的主缓存，数据保存在内存中
+type flakyCache struct {
+	*Memory
+	down atomic.Bool
+}
+
+func newFlakyCache() *flakyCache {
+	return &flakyCache{Memory: NewMemory(0)}
+}
+
+func (c *flakyCache) Get(ctx context.Context, key string) ([]byte, error) {
+	if c.down.Load() {
+		return nil, errDown
+	}
+	return c.Memory.Get(ctx, key)
+}
+
+func (c *flakyCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if c.down.Load() {
+		return errDown
+	}
+	return c.Memory.Set(ctx, key, value, ttl)
+}
+
+func (c *flakyCache) Del(ctx context.Context, keys ...string) error {
+	if c.down.Load() {
+		return errDown
+	}
+	return c.Memory.Del(ctx, keys...)
+}
+
+func (c *flakyCache) Update(ctx context.Context, key string, ttl time.Duration, fn func(old []byte) ([]byte, error)) error {
+	if c.down.Load() {
+		return errDown
+	}
+	return c.Memory.Update(ctx, key, ttl, fn)
+}
+
+func (c *flakyCache) Ping(ctx context.Context) error {
+	if c.down.Load() {
+		return errDown
+	}
+	return nil
+}
+
+func (c *flakyCache) Name() string {
+	return DriverRedis
+}
+
+func mustGet(t *testing.T, c Cache, key string) string {
+	t.Helper()
+	data, err := c.Get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Get(%q): %v", key, err)
+	}
+	return string(data)
+}
+
+func TestFailoverSwitchesAndRecovers(t *testing.T) {
+	ctx := context.Background()
+	primary := newFlakyCache()
+	// 健康检查间隔很长，由测试调用 recover
+	f := NewFailover(primary, NewMemory(0), time.Hour)
+	defer f.Close()
+
+	if f.Name() != DriverRedis {
+		t.Fatalf("Name() = %s，期望使用主缓存", f.Name())
+	}
+	f.Set(ctx, "a", []byte("a1"), 0)
+	f.Set(ctx, "b", []byte("b1"), 0)
+	f.Set(ctx, "c", []byte("c1"), 0)
+
+	// 主缓存故障：读写切换到备用缓存
+	primary.down.Store(true)
+	if _, err := f.Get(ctx, "a"); !errors.Is(err, ErrMiss) {
+		t.Fatalf("切换后 Get 错误 = %v，期望 ErrMiss", err)
+	}
+	if f.Name() != DriverMemory {
+		t.Fatalf("Name() = %s，期望切换到备用缓存", f.Name())
+	}
+	if err := f.Set(ctx, "a", []byte("a2"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Del(ctx, "b"); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustGet(t, f, "a"); got != "a2" {
+		t.Fatalf("备用缓存中 a = %s", got)
+	}
+
+	// 主缓存恢复后，不可用期间变更过的Key从主缓存删除，未变更的保留
+	primary.down.Store(false)
+	f.recover(ctx)
+	if f.Name() != DriverRedis {
+		t.Fatalf("Name() = %s，期望切回主缓存", f.Name())
+	}
+	for _, key := range []string{"a", "b"} {
+		if _, err := f.Get(ctx, key); !errors.Is(err, ErrMiss) {
+			t.Errorf("切回后 %s 应已从主缓存删除，错误 = %v", key, err)
+		}
+	}
+	if got := mustGet(t, f, "c"); got != "c1" {
+		t.Fatalf("未变更的 c = %s", got)
+	}
+	if len(f.dirty) != 0 {
+		t.Fatalf("切回后仍有待清理的Key: %v", f.dirty)
+	}
+	if _, err := f.fallback.Get(ctx, "a"); !errors.Is(err, ErrMiss) {
+		t.Fatal("切回后备用缓存应已清空")
+	}
+}
+
+func TestFailoverRecoverKeepsDirtyKeysOnError(t *testing.T) {
+	ctx := context.Background()
+	primary := newFlakyCache()
+	f := NewFailover(primary, NewMemory(0), time.Hour)
+	defer f.Close()
+
+	primary.down.Store(true)
+	f.Set(ctx, "a", []byte("a2"), 0)
+
+	// 主缓存仍不可用时清理失败，不切回，保留待清理的Key
+	f.recover(ctx)
+	if f.Name() != DriverMemory {
+		t.Fatal("清理失败时不应切回主缓存")
+	}
+	if _, ok := f.dirty["a"]; !ok {
+		t.Fatal("清理失败时应保留待清理的Key")
+	}
+}
+
+func TestFailoverHealthCheck(t *testing.T) {
+	primary := newFlakyCache()
+	primary.down.Store(true)
+	f := NewFailover(primary, NewMemory(0), 10*time.Millisecond)
+	defer f.Close()
+
+	if f.Name() != DriverMemory {
+		t.Fatal("启动时主缓存不可用，应使用备用缓存")
+	}
+	primary.down.Store(false)
+	waitFor(t, func() bool { return f.Name() == DriverRedis })
+
+	primary.down.Store(true)
+	waitFor(t, func() bool { return f.Name() == DriverMemory })
+}
+
+func TestFailoverUpdateFnErrorKeepsPrimary(t *testing.T) {
+	primary := newFlakyCache()
+	f := NewFailover(primary, NewMemory(0), time.Hour)
+	defer f.Close()
+
+	errInvalid := errors.New("invalid")
+	err := f.Update(context.Background(), "a", 0, func(old []byte) ([]byte, error) {
+		return nil, errInvalid
+	})
+	if !errors.Is(err, errInvalid) {
+		t.Fatalf("Update 错误 = %v", err)
+	}
+	if f.Name() != DriverRedis {
+		t.Fatal("fn返回的错误不应切换到备用缓存")
+	}
+}
+
+// waitFor 等待条件成立，最多1秒
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("等待超时")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// defaultMaxEntries 内存缓存默认最多保留的Key数
+const defaultMaxEntries = 10000
+
+// Memory 进程内LRU缓存
+// 超过容量时淘汰最久未访问的Key，过期的Key在访问时删除
+type Memory struct {
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List // 最近访问的在前
+	items      map[string]*list.Element
+}
+
+// memoryEntry 缓存条目
+type memoryEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time // 零值表示不过期
+}
+
+// NewMemory 创建内存缓存
+func NewMemory(maxEntries int) *Memory {
+	if maxEntries <= 0 {
+		maxEntries = defaultMaxEntries
+	}
+	return &Memory{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+// Get 读取值
+func (m *Memory) Get(ctx context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.get(key)
+}
+
+// Set 写入值
+func (m *Memory) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.set(key, value, ttl)
+	return nil
+}
+
+// Del 删除Key
+func (m *Memory) Del(ctx context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		if el, ok := m.items[key]; ok {
+			m.remove(el)
+		}
+	}
+	return nil
+}
+
+// Update 原子地读取并修改Key，fn执行期间持有锁
+func (m *Memory) Update(ctx context.Context, key string, ttl time.Duration, fn func(old []byte) ([]byte, error)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old, err := m.get(key)
+	if err != nil && err != ErrMiss {
+		return err
+	}
+	value, err := fn(old)
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		if el, ok := m.items[key]; ok {
+			m.remove(el)
+		}
+		return nil
+	}
+	m.set(key, value, ttl)
+	return nil
+}
+
+// get 读取值（调用方持有锁）
+func (m *Memory) get(key string) ([]byte, error) {
+	el, ok := m.items[key]
+	if !ok {
+		return nil, ErrMiss
+	}
+	entry := el.Value.(*memoryEntry)
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		m.remove(el)
+		return nil, ErrMiss
+	}
+	m.ll.MoveToFront(el)
+	return append([]byte(nil), entry.value...), nil
+}
+
+// set 写入值（调用方持有锁）
+func (m *Memory) set(key string, value []byte, ttl time.Duration) {
+	entry := &memoryEntry{key: key, value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+
+	if el, ok := m.items[key]; ok {
+		el.Value = entry
+		m.ll.MoveToFront(el)
+		return
+	}
+	m.items[key] = m.ll.PushFront(entry)
+	for m.ll.Len() > m.maxEntries {
+		m.remove(m.ll.Back())
+	}
+}
+
+// Ping 内存缓存始终可用
+func (m *Memory) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Name 实现名称
+func (m *Memory) Name() string {
+	return DriverMemory
+}
+
+// Flush 清空缓存
+func (m *Memory) Flush() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ll.Init()
+	m.items = make(map[string]*list.Element)
+}
+
+// remove 删除条目（调用方持有锁）
+func (m *Memory) remove(el *list.Element) {
+	m.ll.Remove(el)
+	delete(m.items, el.Value.(*memoryEntry).key)
+}
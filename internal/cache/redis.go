@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// maxUpdateRetries Update 因并发修改而重试的最大次数
+const maxUpdateRetries = 10
+
+// Redis 基于Redis的缓存
+type Redis struct {
+	client *redis.Client
+}
+
+// NewRedis 创建Redis缓存（不检查连接）
+func NewRedis(addr, password string, db int) *Redis {
+	return &Redis{
+		client: redis.NewClient(&redis.Options{
+			Addr:     addr,
+			Password: password,
+			DB:       db,
+		}),
+	}
+}
+
+// Get 读取值
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := r.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrMiss
+	}
+	return data, err
+}
+
+// Set 写入值
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if ttl < 0 {
+		ttl = 0
+	}
+	return r.client.Set(ctx, key, value, ttl).Err()
+}
+
+// Del 删除Key
+func (r *Redis) Del(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return r.client.Del(ctx, keys...).Err()
+}
+
+// Update 原子地读取并修改Key（WATCH/MULTI，Key被并发修改时重试）
+func (r *Redis) Update(ctx context.Context, key string, ttl time.Duration, fn func(old []byte) ([]byte, error)) error {
+	if ttl < 0 {
+		ttl = 0
+	}
+	txf := func(tx *redis.Tx) error {
+		old, err := tx.Get(ctx, key).Bytes()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return err
+		}
+		value, err := fn(old)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			if value == nil {
+				pipe.Del(ctx, key)
+			} else {
+				pipe.Set(ctx, key, value, ttl)
+			}
+			return nil
+		})
+		return err
+	}
+
+	for i := 0; i < maxUpdateRetries; i++ {
+		err := r.client.Watch(ctx, txf, key)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return fmt.Errorf("更新缓存 %s 失败: 并发修改过多", key)
+}
+
+// Ping 检查连接
+func (r *Redis) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
+// Name 实现名称
+func (r *Redis) Name() string {
+	return DriverRedis
+}
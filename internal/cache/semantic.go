@@ -0,0 +1,276 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-ai-copilot/internal/store"
+)
+
+// SemanticCache 语义响应缓存
+// 以 模式+模型+System Prompt（及所用模板版本）+知识库范围 作为分桶键，桶内按问题向量的余弦相似度匹配
+type SemanticCache struct {
+	store      Cache
+	threshold  float64       // 命中所需的最小相似度
+	ttl        time.Duration // 条目有效期
+	maxEntries int           // 单个分桶最多保留的条目数
+}
+
+// SemanticKey 语义缓存分桶键
+type SemanticKey struct {
+	Mode         string
+	Model        string
+	SystemPrompt string
+	Template     string   // 提示词模板标识（模板ID和版本），为空表示未使用模式模板
+	Scopes       []string // 知识库范围，范围内的文档变化会使缓存失效
+	Filter       string   // 检索过滤条件，为空表示不过滤
+}
+
+// SemanticEntry 语义缓存条目
+type SemanticEntry struct {
+	ID          string    `json:"id"`
+	Question    string    `json:"question"`
+	Embedding   []float32 `json:"embedding"`
+	Reply       string    `json:"reply"`
+	DocumentIDs []uint    `json:"document_ids,omitempty"` // 生成回答时引用的RAG文档
+	CreatedAt   time.Time `json:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// SemanticHit 缓存命中结果
+type SemanticHit struct {
+	Entry      SemanticEntry
+	Similarity float64
+}
+
+// NewSemanticCache 创建语义缓存
+func NewSemanticCache(store Cache, threshold float64, ttl time.Duration, maxEntries int) *SemanticCache {
+	if threshold <= 0 || threshold > 1 {
+		threshold = 0.95
+	}
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	if maxEntries <= 0 {
+		maxEntries = 200
+	}
+	return &SemanticCache{
+		store:      store,
+		threshold:  threshold,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+	}
+}
+
+// bucketKey 分桶缓存Key
+func (k SemanticKey) bucketKey() string {
+	raw := k.Mode + "\x00" + k.Model + "\x00" + k.SystemPrompt + "\x00" + strings.Join(k.Scopes, ",")
+	if k.Filter != "" {
+		// 没有过滤条件时保持原有的分桶Key
+		raw += "\x00" + k.Filter
+	}
+	if k.Template != "" {
+		raw += "\x00tpl:" + k.Template
+	}
+	sum := sha256.Sum256([]byte(raw))
+	return "semcache:bucket:" + hex.EncodeToString(sum[:])
+}
+
+// scopeIndexKey 知识库范围到分桶的索引Key
+func scopeIndexKey(scope string) string {
+	return "semcache:scope:" + scope
+}
+
+// documentIndexKey 文档到缓存条目的索引Key
+func documentIndexKey(docID uint) string {
+	return fmt.Sprintf("semcache:doc:%d", docID)
+}
+
+// Lookup 查找与问题向量最相似且超过阈值的缓存条目
+func (c *SemanticCache) Lookup(key SemanticKey, embedding []float32) (*SemanticHit, error) {
+	entries, err := c.loadBucket(key.bucketKey())
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var best *SemanticHit
+	for _, e := range entries {
+		if now.After(e.ExpiresAt) {
+			continue
+		}
+		score := store.Cosine(embedding, e.Embedding)
+		if score >= c.threshold && (best == nil || score > best.Similarity) {
+			best = &SemanticHit{Entry: e, Similarity: score}
+		}
+	}
+	return best, nil
+}
+
+// Store 写入缓存条目
+// 分桶和索引通过 Update 原子修改，并发写入同一分桶时不会丢失条目
+func (c *SemanticCache) Store(key SemanticKey, question string, embedding []float32, reply string, docIDs []uint) error {
+	bucket := key.bucketKey()
+	now := time.Now()
+	entry := SemanticEntry{
+		ID:          strconv.FormatInt(now.UnixNano(), 36),
+		Question:    question,
+		Embedding:   embedding,
+		Reply:       reply,
+		DocumentIDs: docIDs,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(c.ttl),
+	}
+
+	err := c.updateBucket(bucket, func(entries []SemanticEntry) []SemanticEntry {
+		// 清理过期条目
+		live := entries[:0]
+		for _, e := range entries {
+			if now.Before(e.ExpiresAt) {
+				live = append(live, e)
+			}
+		}
+		live = append(live, entry)
+		if len(live) > c.maxEntries {
+			live = live[len(live)-c.maxEntries:]
+		}
+		return live
+	})
+	if err != nil {
+		return err
+	}
+
+	// 维护索引，便于知识库或文档变化时失效
+	for _, scope := range key.Scopes {
+		if err := c.addToIndex(scopeIndexKey(scope), bucket); err != nil {
+			return err
+		}
+	}
+	for _, docID := range docIDs {
+		if err := c.addToIndex(documentIndexKey(docID), bucket); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InvalidateDocument 删除引用了指定文档的缓存条目
+func (c *SemanticCache) InvalidateDocument(docID uint) error {
+	indexKey := documentIndexKey(docID)
+	buckets, err := c.loadIndex(indexKey)
+	if err != nil {
+		return err
+	}
+
+	for _, bucket := range buckets {
+		err := c.updateBucket(bucket, func(entries []SemanticEntry) []SemanticEntry {
+			kept := entries[:0]
+			for _, e := range entries {
+				if !containsUint(e.DocumentIDs, docID) {
+					kept = append(kept, e)
+				}
+			}
+			return kept
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.store.Del(context.Background(), indexKey)
+}
+
+// InvalidateScope 删除知识库范围内的所有缓存条目
+// 知识库新增文档后，之前"资料中没有相关信息"的回答也可能过时，因此整体失效
+func (c *SemanticCache) InvalidateScope(scope string) error {
+	indexKey := scopeIndexKey(scope)
+	buckets, err := c.loadIndex(indexKey)
+	if err != nil {
+		return err
+	}
+
+	keys := append(buckets, indexKey)
+	return c.store.Del(context.Background(), keys...)
+}
+
+// loadBucket 读取分桶内的条目，不存在时返回空
+func (c *SemanticCache) loadBucket(bucket string) ([]SemanticEntry, error) {
+	var entries []SemanticEntry
+	if err := c.loadJSON(bucket, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// loadIndex 读取索引中的分桶Key
+func (c *SemanticCache) loadIndex(indexKey string) ([]string, error) {
+	var buckets []string
+	if err := c.loadJSON(indexKey, &buckets); err != nil {
+		return nil, err
+	}
+	return buckets, nil
+}
+
+// addToIndex 向索引追加分桶Key
+func (c *SemanticCache) addToIndex(indexKey, bucket string) error {
+	return c.store.Update(context.Background(), indexKey, c.ttl, func(old []byte) ([]byte, error) {
+		var buckets []string
+		if old != nil {
+			if err := json.Unmarshal(old, &buckets); err != nil {
+				return nil, err
+			}
+		}
+		for _, b := range buckets {
+			if b == bucket {
+				return old, nil
+			}
+		}
+		return json.Marshal(append(buckets, bucket))
+	})
+}
+
+// updateBucket 原子修改分桶内的条目，修改后为空时删除分桶
+func (c *SemanticCache) updateBucket(bucket string, fn func([]SemanticEntry) []SemanticEntry) error {
+	return c.store.Update(context.Background(), bucket, c.ttl, func(old []byte) ([]byte, error) {
+		var entries []SemanticEntry
+		if old != nil {
+			if err := json.Unmarshal(old, &entries); err != nil {
+				return nil, err
+			}
+		}
+		entries = fn(entries)
+		if len(entries) == 0 {
+			return nil, nil
+		}
+		return json.Marshal(entries)
+	})
+}
+
+// loadJSON 读取JSON值，Key不存在时保持v为零值
+func (c *SemanticCache) loadJSON(key string, v interface{}) error {
+	data, err := c.store.Get(context.Background(), key)
+	if err != nil {
+		if errors.Is(err, ErrMiss) {
+			return nil
+		}
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// containsUint 判断切片是否包含指定值
+func containsUint(list []uint, v uint) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -14,7 +14,94 @@ type Config struct {
 	AI       AIConfig       `yaml:"ai"`
 	Database DatabaseConfig `yaml:"database"`
 	Redis    RedisConfig    `yaml:"redis"`
+	Cache    CacheConfig    `yaml:"cache"`
 	JWT      JWTConfig      `yaml:"jwt"`
+
+	SemanticCache SemanticCacheConfig `yaml:"semantic_cache"`
+	Admin         AdminConfig         `yaml:"admin"`
+	Search        SearchConfig        `yaml:"search"`
+	Reindex       ReindexConfig       `yaml:"reindex"`
+	Storage       StorageConfig       `yaml:"storage"`
+	Connectors    ConnectorsConfig    `yaml:"connectors"`
+	Crawler       CrawlerConfig       `yaml:"crawler"`
+}
+
+// CrawlerConfig 网页抓取配置（/api/v1/rag/url）
+type CrawlerConfig struct {
+	UserAgent            string        `yaml:"user_agent"`             // 请求的User-Agent，也用于匹配 robots.txt
+	Timeout              time.Duration `yaml:"timeout"`                // 单个请求的超时时间，默认30秒
+	MaxPages             int           `yaml:"max_pages"`              // 每次抓取的页面数上限，默认200
+	MaxDepth             int           `yaml:"max_depth"`              // 抓取层数上限，默认3
+	Delay                time.Duration `yaml:"delay"`                  // 两次请求之间的最小间隔
+	MinRefreshInterval   time.Duration `yaml:"min_refresh_interval"`   // 定期抓取的最小间隔，默认1小时
+	AllowPrivateNetworks bool          `yaml:"allow_private_networks"` // 允许抓取内网地址（默认禁止，防止SSRF）
+}
+
+// ConnectorsConfig 知识库连接器配置
+type ConnectorsConfig struct {
+	Directories []DirectoryConnectorConfig `yaml:"directories"` // 本地目录同步
+	Git         []GitConnectorConfig       `yaml:"git"`         // git仓库索引
+}
+
+// GitConnectorConfig git仓库索引连接器配置
+type GitConnectorConfig struct {
+	Name            string            `yaml:"name"`              // 连接器名称，已索引的提交按名称保存
+	Path            string            `yaml:"path"`              // 本地仓库目录
+	Branch          string            `yaml:"branch"`            // 索引的分支，为空时为仓库当前的 HEAD
+	KnowledgeBaseID uint              `yaml:"knowledge_base_id"` // 写入的知识库，文档归属知识库的创建者
+	Include         []string          `yaml:"include"`           // 索引的文件（glob，支持**），为空时索引全部支持的文件类型
+	Exclude         []string          `yaml:"exclude"`           // 排除的文件（glob）
+	Interval        time.Duration     `yaml:"interval"`          // 检查新提交的间隔，默认10分钟
+	Tags            []string          `yaml:"tags"`              // 写入文档的标签
+	Metadata        map[string]string `yaml:"metadata"`          // 写入文档的元数据
+}
+
+// DirectoryConnectorConfig 本地目录同步连接器配置
+type DirectoryConnectorConfig struct {
+	Name            string            `yaml:"name"`              // 连接器名称，同步记录按名称保存
+	Path            string            `yaml:"path"`              // 同步的目录
+	KnowledgeBaseID uint              `yaml:"knowledge_base_id"` // 写入的知识库，文档归属知识库的创建者
+	Include         []string          `yaml:"include"`           // 同步的文件（glob，支持**），为空时同步全部支持的文件类型
+	Exclude         []string          `yaml:"exclude"`           // 排除的文件（glob）
+	Interval        time.Duration     `yaml:"interval"`          // 定时扫描间隔，默认10分钟
+	Watch           bool              `yaml:"watch"`             // 同时监听文件变化（仅Linux）
+	Tags            []string          `yaml:"tags"`              // 写入文档的标签
+	Metadata        map[string]string `yaml:"metadata"`          // 写入文档的元数据
+}
+
+// StorageConfig 上传文件存储配置
+type StorageConfig struct {
+	Driver string   `yaml:"driver"` // local（默认）/ s3
+	Dir    string   `yaml:"dir"`    // local 的存储目录
+	S3     S3Config `yaml:"s3"`
+}
+
+// S3Config S3兼容存储配置
+type S3Config struct {
+	Endpoint        string `yaml:"endpoint"` // 为空时使用AWS S3对应区域的地址
+	Region          string `yaml:"region"`
+	Bucket          string `yaml:"bucket"`
+	Prefix          string `yaml:"prefix"`            // Key前缀
+	PathStyle       bool   `yaml:"path_style"`        // MinIO等需要开启
+	AccessKeyID     string `yaml:"access_key_id"`     // 也可通过环境变量 S3_ACCESS_KEY_ID 设置
+	SecretAccessKey string `yaml:"secret_access_key"` // 也可通过环境变量 S3_SECRET_ACCESS_KEY 设置
+}
+
+// ReindexConfig 重建向量配置
+type ReindexConfig struct {
+	BatchSize int           `yaml:"batch_size"` // 每批向量化的分块数
+	Interval  time.Duration `yaml:"interval"`   // 每批之间的间隔，限制对向量化接口的请求速率
+}
+
+// SearchConfig 聊天记录搜索配置
+type SearchConfig struct {
+	TextSearchConfig string `yaml:"text_search_config"` // trigram（默认，pg_trgm子串匹配）或PostgreSQL全文检索配置，如 zhparser 创建的 chinese
+	Semantic         bool   `yaml:"semantic"`           // 是否为消息生成向量以支持语义搜索
+}
+
+// AdminConfig 管理员配置
+type AdminConfig struct {
+	Usernames []string `yaml:"usernames"` // 拥有管理员权限的用户名
 }
 
 // ServerConfig 服务配置
@@ -31,10 +118,51 @@ type AIConfig struct {
 	MaxTokens       int     `yaml:"max_tokens"`
 	Timeout         int     `yaml:"timeout"`
 	EmbeddingModel  string  `yaml:"embedding_model"`
+
+	EmbeddingDimensions int    `yaml:"embedding_dimensions"` // 向量维度，默认1536
+	EmbeddingVersion    string `yaml:"embedding_version"`    // 模型版本，模型更新但名称不变时修改，新旧向量不会相互比较
+
+	EmbeddingBatchSize   int `yaml:"embedding_batch_size"`   // 单次请求的最大文本数，默认32
+	EmbeddingBatchTokens int `yaml:"embedding_batch_tokens"` // 单次请求的最大token数（按字符数估算），默认8000
+	EmbeddingConcurrency int `yaml:"embedding_concurrency"`  // 同时发出的批量请求数，默认4
+
+	Retry       RetryConfig       `yaml:"retry"`
+	Breaker     BreakerConfig     `yaml:"breaker"`
+	Fallbacks   []ProviderConfig  `yaml:"fallbacks"`
+	Concurrency ConcurrencyConfig `yaml:"concurrency"`
+}
+
+// ConcurrencyConfig 上游并发限制配置
+type ConcurrencyConfig struct {
+	MaxInFlight int `yaml:"max_in_flight"` // 同时发往提供方的最大请求数，0表示不限制
+	MaxQueue    int `yaml:"max_queue"`     // 最大排队数，0表示不限制
+}
+
+// RetryConfig 上游重试配置
+type RetryConfig struct {
+	MaxAttempts int           `yaml:"max_attempts"`
+	BaseDelay   time.Duration `yaml:"base_delay"`
+	MaxDelay    time.Duration `yaml:"max_delay"`
+}
+
+// BreakerConfig 熔断器配置
+type BreakerConfig struct {
+	FailureThreshold int           `yaml:"failure_threshold"`
+	Cooldown         time.Duration `yaml:"cooldown"`
+}
+
+// ProviderConfig 备用提供方配置
+type ProviderConfig struct {
+	Name      string `yaml:"name"`
+	BaseURL   string `yaml:"base_url"`
+	Model     string `yaml:"model"`
+	APIKeyEnv string `yaml:"api_key_env"` // 读取API Key的环境变量名，为空时沿用AI_API_KEY
 }
 
 // DatabaseConfig 数据库配置
 type DatabaseConfig struct {
+	Driver   string `yaml:"driver"` // postgres（默认）/ sqlite
+	Path     string `yaml:"path"`   // SQLite数据库文件，:memory: 为内存数据库
 	Host     string `yaml:"host"`
 	Port     int    `yaml:"port"`
 	User     string `yaml:"user"`
@@ -50,6 +178,21 @@ type RedisConfig struct {
 	DB       int    `yaml:"db"`
 }
 
+// CacheConfig 缓存配置
+type CacheConfig struct {
+	Driver              string        `yaml:"driver"`                // redis（不可用时自动切换到内存缓存）/ memory
+	MaxEntries          int           `yaml:"max_entries"`           // 内存缓存最多保留的Key数
+	HealthCheckInterval time.Duration `yaml:"health_check_interval"` // Redis健康检查间隔
+}
+
+// SemanticCacheConfig 语义响应缓存配置
+type SemanticCacheConfig struct {
+	Enabled    bool          `yaml:"enabled"`
+	Threshold  float64       `yaml:"threshold"`   // 命中所需的最小余弦相似度
+	TTL        time.Duration `yaml:"ttl"`         // 条目有效期
+	MaxEntries int           `yaml:"max_entries"` // 单个分桶最多保留的条目数
+}
+
 // JWTConfig JWT配置
 type JWTConfig struct {
 	Secret     string        `yaml:"secret"`
@@ -78,6 +221,12 @@ func Load(configPath string) (*Config, error) {
 	if apiKey := os.Getenv("AI_API_KEY"); apiKey != "" {
 		// MVP阶段API_KEY通过环境变量传递
 	}
+	if v := os.Getenv("S3_ACCESS_KEY_ID"); v != "" {
+		cfg.Storage.S3.AccessKeyID = v
+	}
+	if v := os.Getenv("S3_SECRET_ACCESS_KEY"); v != "" {
+		cfg.Storage.S3.SecretAccessKey = v
+	}
 
 	GlobalConfig = &cfg
 	return &cfg, nil
@@ -0,0 +1,185 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"go-ai-copilot/internal/model"
+	"go-ai-copilot/internal/rag"
+	"go-ai-copilot/internal/store"
+)
+
+// memoryFiles 内存中的同步记录
+type memoryFiles struct {
+	mu     sync.Mutex
+	nextID uint
+	files  map[string]model.ConnectorFile // connector/path -> 记录
+	states map[string]model.ConnectorState
+}
+
+func newMemoryFiles() *memoryFiles {
+	return &memoryFiles{files: make(map[string]model.ConnectorFile), states: make(map[string]model.ConnectorState)}
+}
+
+func (m *memoryFiles) List(ctx context.Context, connector string) ([]model.ConnectorFile, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var files []model.ConnectorFile
+	for _, f := range m.files {
+		if f.Connector == connector {
+			files = append(files, f)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+func (m *memoryFiles) Save(ctx context.Context, file *model.ConnectorFile) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := file.Connector + "/" + file.Path
+	if old, ok := m.files[key]; ok {
+		file.ID = old.ID
+	} else {
+		m.nextID++
+		file.ID = m.nextID
+	}
+	m.files[key] = *file
+	return nil
+}
+
+func (m *memoryFiles) Delete(ctx context.Context, id uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, f := range m.files {
+		if f.ID == id {
+			delete(m.files, key)
+		}
+	}
+	return nil
+}
+
+func (m *memoryFiles) State(ctx context.Context, connector string) (*model.ConnectorState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	state, ok := m.states[connector]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+	return &state, nil
+}
+
+func (m *memoryFiles) SaveState(ctx context.Context, state *model.ConnectorState) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.states[state.Connector] = *state
+	return nil
+}
+
+// memoryDocument 写入的文档
+type memoryDocument struct {
+	id      uint
+	content string
+	chunks  []rag.Chunk
+}
+
+// memoryIngester 记录写入和删除的文档，写入时按段落分块以检查行范围
+type memoryIngester struct {
+	mu       sync.Mutex
+	splitter rag.Splitter
+	nextID   uint
+	docs     map[string]*memoryDocument // 文档标识 -> 文档
+	upserts  []string
+	removed  []uint
+}
+
+func newMemoryIngester() *memoryIngester {
+	return &memoryIngester{
+		splitter: &rag.TextSplitter{ChunkSize: 20, ChunkOverlap: 0},
+		docs:     make(map[string]*memoryDocument),
+	}
+}
+
+func (m *memoryIngester) Upsert(ctx context.Context, f File) (uint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	doc, ok := m.docs[f.Path]
+	if !ok {
+		m.nextID++
+		doc = &memoryDocument{id: m.nextID}
+		m.docs[f.Path] = doc
+	}
+	doc.content = string(f.Content)
+	doc.chunks = m.splitter.Split(doc.content)
+	m.upserts = append(m.upserts, f.Path)
+	return doc.id, nil
+}
+
+func (m *memoryIngester) Remove(ctx context.Context, userID, documentID uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for p, doc := range m.docs {
+		if doc.id == documentID {
+			delete(m.docs, p)
+		}
+	}
+	m.removed = append(m.removed, documentID)
+	return nil
+}
+
+// takeUpserts 返回并清空写入过的路径（排序后）
+func (m *memoryIngester) takeUpserts() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	paths := m.upserts
+	m.upserts = nil
+	sort.Strings(paths)
+	return paths
+}
+
+// documentID 文档标识对应的文档ID，不存在时为0
+func (m *memoryIngester) documentID(p string) uint {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if doc, ok := m.docs[p]; ok {
+		return doc.id
+	}
+	return 0
+}
+
+// citations 文档各分块的引用出处（path:起始行-结束行）
+func (m *memoryIngester) citations(p string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	doc, ok := m.docs[p]
+	if !ok {
+		return nil
+	}
+	var refs []string
+	for _, c := range doc.chunks {
+		refs = append(refs, fmt.Sprintf("%s:%d-%d", p, c.StartLine, c.EndLine))
+	}
+	return refs
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func checkResult(t *testing.T, step string, got, want SyncResult) {
+	t.Helper()
+	if got != want {
+		t.Fatalf("%s: 同步结果 = %+v，期望 %+v", step, got, want)
+	}
+}
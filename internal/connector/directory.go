@@ -0,0 +1,328 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go-ai-copilot/internal/model"
+	"go-ai-copilot/internal/rag"
+	"go-ai-copilot/internal/store"
+	"go-ai-copilot/pkg/ai"
+)
+
+const (
+	defaultInterval = 10 * time.Minute
+	// debounceDelay 文件变化后等待的时间，连续的变化（如 git checkout）合并为一次扫描
+	debounceDelay = 2 * time.Second
+)
+
+// File 同步到知识库的文件
+type File struct {
+	UserID          uint
+	KnowledgeBaseID uint
+	Path            string // 相对同步目录的路径，作为文档标识
+	FileName        string
+	Content         []byte
+	Tags            []string          // 为nil时保留文档原有的标签
+	Metadata        map[string]string // 为nil时保留文档原有的元数据
+}
+
+// Ingester 写入知识库
+type Ingester interface {
+	// Upsert 按文档标识写入文件（文档不存在时创建，内容变化时创建新版本），分块和向量化完成后返回文档ID
+	Upsert(ctx context.Context, f File) (uint, error)
+	// Remove 删除文档，文档已不存在时不返回错误
+	Remove(ctx context.Context, userID, documentID uint) error
+}
+
+// Config 本地目录同步配置
+type Config struct {
+	Name            string // 连接器名称，同步记录按名称保存
+	Root            string // 同步的目录
+	UserID          uint   // 文档所属用户
+	KnowledgeBaseID uint
+	Include         []string // 同步的文件（glob，支持**），为空时同步全部支持的文件类型
+	Exclude         []string
+	Interval        time.Duration // 定时扫描间隔
+	Watch           bool          // 同时监听文件变化
+	Tags            []string
+	Metadata        map[string]string
+}
+
+// SyncResult 一次扫描的结果
+type SyncResult struct {
+	Updated   int // 新增或内容变化的文件
+	Removed   int // 删除的文件
+	Unchanged int
+	Failed    int
+}
+
+// Directory 本地目录同步连接器
+// 扫描时按同步记录跳过大小和修改时间未变化的文件，内容变化的文件写入知识库，已删除的文件从知识库移除
+type Directory struct {
+	config   Config
+	files    store.ConnectorFileRepository
+	ingester Ingester
+}
+
+// NewDirectory 创建本地目录同步连接器
+func NewDirectory(files store.ConnectorFileRepository, ingester Ingester, config Config) (*Directory, error) {
+	if config.Name == "" {
+		return nil, errors.New("连接器未配置名称")
+	}
+	if config.Root == "" {
+		return nil, fmt.Errorf("连接器 %s 未配置同步目录", config.Name)
+	}
+	root, err := filepath.Abs(config.Root)
+	if err != nil {
+		return nil, err
+	}
+	config.Root = root
+	if err := validatePatterns(config.Name, config.Include, config.Exclude); err != nil {
+		return nil, err
+	}
+	if config.Interval <= 0 {
+		config.Interval = defaultInterval
+	}
+	return &Directory{config: config, files: files, ingester: ingester}, nil
+}
+
+// Run 启动时扫描一次，之后定时扫描，开启监听时文件变化后也会扫描，ctx结束时返回
+func (d *Directory) Run(ctx context.Context) {
+	var changes <-chan struct{}
+	if d.config.Watch {
+		w, err := newWatcher(d.config.Root)
+		if err != nil {
+			log.Printf("连接器 %s 监听文件变化失败，只定时扫描: %v", d.config.Name, err)
+		} else {
+			defer w.Close()
+			changes = w.changes
+		}
+	}
+
+	d.sync(ctx)
+	ticker := time.NewTicker(d.config.Interval)
+	defer ticker.Stop()
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.sync(ctx)
+		case <-changes:
+			if debounce == nil {
+				debounce = time.After(debounceDelay)
+			}
+		case <-debounce:
+			debounce = nil
+			d.sync(ctx)
+		}
+	}
+}
+
+// sync 扫描一次并记录结果
+func (d *Directory) sync(ctx context.Context) {
+	result, err := d.Sync(ctx)
+	if err != nil {
+		log.Printf("连接器 %s 同步失败: %v", d.config.Name, err)
+		return
+	}
+	if result.Updated > 0 || result.Removed > 0 || result.Failed > 0 {
+		log.Printf("连接器 %s 同步完成: 更新 %d，删除 %d，未变化 %d，失败 %d",
+			d.config.Name, result.Updated, result.Removed, result.Unchanged, result.Failed)
+	}
+}
+
+// Sync 扫描目录并同步到知识库，不能并发调用
+// 遍历目录出错时（如目录不可读）不删除任何文档，避免误删
+func (d *Directory) Sync(ctx context.Context) (SyncResult, error) {
+	var result SyncResult
+	if _, err := os.Stat(d.config.Root); err != nil {
+		return result, err
+	}
+
+	records, err := d.files.List(ctx, d.config.Name)
+	if err != nil {
+		return result, err
+	}
+	synced := make(map[string]model.ConnectorFile, len(records))
+	for _, record := range records {
+		synced[record.Path] = record
+	}
+
+	seen := make(map[string]bool)
+	walkFailed := false
+	var ignore ignoreRules
+	err = filepath.WalkDir(d.config.Root, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			log.Printf("连接器 %s 读取 %s 失败: %v", d.config.Name, p, err)
+			walkFailed = true
+			if entry != nil && entry.IsDir() && p != d.config.Root {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(d.config.Root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if entry.IsDir() {
+			if rel != "." && (entry.Name() == ".git" || ignore.ignored(rel, true)) {
+				return filepath.SkipDir
+			}
+			if rel == "." {
+				rel = ""
+			}
+			if err := ignore.load(p, rel); err != nil {
+				log.Printf("连接器 %s 读取 %s/.gitignore 失败: %v", d.config.Name, p, err)
+				walkFailed = true
+			}
+			return nil
+		}
+		// 只同步普通文件，不跟随符号链接
+		if !entry.Type().IsRegular() || ignore.ignored(rel, false) || !d.selected(rel) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			log.Printf("连接器 %s 读取 %s 失败: %v", d.config.Name, p, err)
+			walkFailed = true
+			return nil
+		}
+		if info.Size() > rag.MaxFileSize {
+			log.Printf("连接器 %s 跳过超过大小限制的文件: %s", d.config.Name, rel)
+			return nil
+		}
+
+		seen[rel] = true
+		record, ok := synced[rel]
+		if ok && record.KnowledgeBaseID == d.config.KnowledgeBaseID &&
+			record.Size == info.Size() && record.ModTime == info.ModTime().UnixNano() {
+			result.Unchanged++
+			return nil
+		}
+		updated, err := d.syncFile(ctx, p, rel, info, record, ok)
+		switch {
+		case err != nil:
+			log.Printf("连接器 %s 同步 %s 失败: %v", d.config.Name, rel, err)
+			result.Failed++
+		case updated:
+			result.Updated++
+		default:
+			result.Unchanged++
+		}
+		return ctx.Err()
+	})
+	if err != nil {
+		return result, err
+	}
+	if walkFailed {
+		return result, nil
+	}
+
+	for _, record := range records {
+		if seen[record.Path] {
+			continue
+		}
+		if err := d.ingester.Remove(ctx, d.config.UserID, record.DocumentID); err != nil {
+			log.Printf("连接器 %s 删除 %s 的文档失败: %v", d.config.Name, record.Path, err)
+			result.Failed++
+			continue
+		}
+		if err := d.files.Delete(ctx, record.ID); err != nil {
+			return result, err
+		}
+		result.Removed++
+	}
+	return result, nil
+}
+
+// selected 文件是否在同步范围内
+func (d *Directory) selected(rel string) bool {
+	return selectFile(d.config.Include, d.config.Exclude, rel)
+}
+
+// selectFile 文件是否为支持的文件类型，且匹配include（为空时不限）、不匹配exclude
+func selectFile(include, exclude []string, rel string) bool {
+	if !rag.IsSupportedFile(rel) {
+		return false
+	}
+	for _, pattern := range exclude {
+		if matchGlob(pattern, rel) {
+			return false
+		}
+	}
+	if len(include) == 0 {
+		return true
+	}
+	for _, pattern := range include {
+		if matchGlob(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// validatePatterns 检查include/exclude中的模式
+func validatePatterns(name string, include, exclude []string) error {
+	for _, pattern := range append(append([]string(nil), include...), exclude...) {
+		if !validGlob(pattern) {
+			return fmt.Errorf("连接器 %s 的文件模式无效: %s", name, pattern)
+		}
+	}
+	return nil
+}
+
+// syncFile 同步一个大小或修改时间变化的文件，内容变化时写入知识库，返回是否写入
+func (d *Directory) syncFile(ctx context.Context, p, rel string, info fs.FileInfo, record model.ConnectorFile, exists bool) (bool, error) {
+	content, err := os.ReadFile(p)
+	if err != nil {
+		return false, err
+	}
+	hash := ai.ContentHash(string(content))
+
+	// 只是修改时间变化（如重新checkout），更新同步记录即可
+	sameKB := record.KnowledgeBaseID == d.config.KnowledgeBaseID
+	updated := !exists || !sameKB || record.ContentHash != hash
+	documentID := record.DocumentID
+	if updated {
+		documentID, err = d.ingester.Upsert(ctx, File{
+			UserID:          d.config.UserID,
+			KnowledgeBaseID: d.config.KnowledgeBaseID,
+			Path:            rel,
+			FileName:        info.Name(),
+			Content:         content,
+			Tags:            d.config.Tags,
+			Metadata:        d.config.Metadata,
+		})
+		if err != nil {
+			return false, err
+		}
+		// 配置的知识库变化后，旧知识库中的文档不再同步
+		if exists && !sameKB {
+			if err := d.ingester.Remove(ctx, d.config.UserID, record.DocumentID); err != nil {
+				log.Printf("连接器 %s 删除 %s 在原知识库中的文档失败: %v", d.config.Name, rel, err)
+			}
+		}
+	}
+
+	return updated, d.files.Save(ctx, &model.ConnectorFile{
+		Connector:       d.config.Name,
+		Path:            rel,
+		KnowledgeBaseID: d.config.KnowledgeBaseID,
+		Size:            info.Size(),
+		ModTime:         info.ModTime().UnixNano(),
+		ContentHash:     hash,
+		DocumentID:      documentID,
+		SyncedAt:        time.Now(),
+	})
+}
@@ -0,0 +1,176 @@
+package connector
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeFile 写入同步目录中的文件，自动创建上层目录
+func writeFile(t *testing.T, root, rel, content string) {
+	t.Helper()
+	p := filepath.Join(root, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDirectorySync(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	writeFile(t, root, "a.md", "第一版")
+	writeFile(t, root, "docs/b.txt", "b")
+	writeFile(t, root, "docs/image.png", "png")  // 不支持的文件类型
+	writeFile(t, root, "skip.md", "skip")        // .gitignore 忽略
+	writeFile(t, root, "build/out.md", "out")    // .gitignore 忽略的目录
+	writeFile(t, root, ".git/HEAD.md", "git")    // .git 目录
+	writeFile(t, root, "private/secret.md", "s") // exclude
+	writeFile(t, root, ".gitignore", "skip.md\nbuild/\n")
+
+	files, ingester := newMemoryFiles(), newMemoryIngester()
+	d, err := NewDirectory(files, ingester, Config{Name: "docs", Root: root, UserID: 1, KnowledgeBaseID: 2, Exclude: []string{"private/**"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := d.Sync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResult(t, "首次同步", result, SyncResult{Updated: 2})
+	if got := ingester.takeUpserts(); !equalStrings(got, []string{"a.md", "docs/b.txt"}) {
+		t.Fatalf("首次同步写入 %v", got)
+	}
+
+	// 没有变化时不读取文件
+	result, err = d.Sync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResult(t, "再次同步", result, SyncResult{Unchanged: 2})
+	if got := ingester.takeUpserts(); len(got) != 0 {
+		t.Fatalf("没有变化时写入 %v", got)
+	}
+
+	// 大小和修改时间都未变化时跳过，即使内容变了
+	p := filepath.Join(root, "a.md")
+	info, err := os.Stat(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, root, "a.md", "第二版")
+	if err := os.Chtimes(p, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	if result, _ = d.Sync(ctx); result.Updated != 0 || len(ingester.takeUpserts()) != 0 {
+		t.Fatalf("大小和修改时间未变化时同步结果 = %+v", result)
+	}
+
+	// 只是修改时间变化时读取文件，内容未变不写入
+	later := info.ModTime().Add(time.Hour)
+	writeFile(t, root, "docs/b.txt", "b")
+	if err := os.Chtimes(filepath.Join(root, "docs/b.txt"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	// 修改时间变化后发现内容变化，写入新内容
+	if err := os.Chtimes(p, later, later); err != nil {
+		t.Fatal(err)
+	}
+	result, err = d.Sync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResult(t, "修改时间变化", result, SyncResult{Updated: 1, Unchanged: 1})
+	if got := ingester.takeUpserts(); !equalStrings(got, []string{"a.md"}) {
+		t.Fatalf("修改时间变化后写入 %v", got)
+	}
+
+	// 删除的文件从知识库移除
+	docID := ingester.documentID("docs/b.txt")
+	if err := os.Remove(filepath.Join(root, "docs/b.txt")); err != nil {
+		t.Fatal(err)
+	}
+	result, err = d.Sync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResult(t, "删除文件", result, SyncResult{Removed: 1, Unchanged: 1})
+	if len(ingester.removed) != 1 || ingester.removed[0] != docID {
+		t.Fatalf("删除的文档 = %v，期望 [%d]", ingester.removed, docID)
+	}
+	if records, _ := files.List(ctx, "docs"); len(records) != 1 || records[0].Path != "a.md" {
+		t.Fatalf("同步记录 = %+v", records)
+	}
+}
+
+func TestDirectorySyncWalkFailure(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	writeFile(t, root, "a.md", "a")
+	writeFile(t, root, "b.md", "b")
+
+	files, ingester := newMemoryFiles(), newMemoryIngester()
+	d, err := NewDirectory(files, ingester, Config{Name: "docs", Root: root, UserID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	ingester.takeUpserts()
+
+	// 子目录的 .gitignore 无法读取（是目录），本次扫描不完整，不删除任何文档
+	if err := os.Remove(filepath.Join(root, "b.md")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "sub", ".gitignore"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, root, "c.md", "c")
+	result, err := d.Sync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResult(t, "读取出错", result, SyncResult{Updated: 1, Unchanged: 1})
+	if len(ingester.removed) != 0 {
+		t.Fatalf("读取出错时删除了文档 %v", ingester.removed)
+	}
+
+	// 恢复后删除已不存在的文件
+	if err := os.RemoveAll(filepath.Join(root, "sub")); err != nil {
+		t.Fatal(err)
+	}
+	result, err = d.Sync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResult(t, "恢复后", result, SyncResult{Removed: 1, Unchanged: 2})
+
+	// 同步目录不存在时返回错误
+	if err := os.RemoveAll(root); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Sync(ctx); err == nil {
+		t.Fatal("同步目录不存在时没有返回错误")
+	}
+	if len(ingester.removed) != 1 {
+		t.Fatalf("同步目录不存在时删除了文档 %v", ingester.removed)
+	}
+}
+
+func TestNewDirectoryValidation(t *testing.T) {
+	if _, err := NewDirectory(nil, nil, Config{Root: "."}); err == nil {
+		t.Error("没有名称时没有返回错误")
+	}
+	if _, err := NewDirectory(nil, nil, Config{Name: "docs"}); err == nil {
+		t.Error("没有同步目录时没有返回错误")
+	}
+	if _, err := NewDirectory(nil, nil, Config{Name: "docs", Root: ".", Include: []string{"docs/["}}); err == nil {
+		t.Error("模式无效时没有返回错误")
+	}
+}
@@ -0,0 +1,312 @@
+package connector
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-ai-copilot/internal/model"
+	"go-ai-copilot/internal/rag"
+	"go-ai-copilot/internal/store"
+	"go-ai-copilot/pkg/ai"
+)
+
+// GitConfig git仓库索引配置
+type GitConfig struct {
+	Name            string // 连接器名称，同步记录和已索引的提交按名称保存
+	Repo            string // 本地仓库目录
+	Branch          string // 索引的分支（或其他可解析为提交的引用），为空时为 HEAD
+	UserID          uint   // 文档所属用户
+	KnowledgeBaseID uint
+	Include         []string // 索引的文件（glob，支持**），为空时索引全部支持的文件类型
+	Exclude         []string
+	Interval        time.Duration // 检查新提交的间隔
+	Tags            []string
+	Metadata        map[string]string
+}
+
+// gitEntry 提交中的文件
+type gitEntry struct {
+	object string // blob的对象ID
+	size   int64
+}
+
+// Git git仓库索引连接器
+// 从提交（而不是工作区）读取受版本控制的文件，首次同步索引全部文件并记录提交，
+// 之后只处理已索引的提交与分支最新提交之间变化的文件
+type Git struct {
+	config   GitConfig
+	files    store.ConnectorFileRepository
+	ingester Ingester
+}
+
+// NewGit 创建git仓库索引连接器
+func NewGit(files store.ConnectorFileRepository, ingester Ingester, config GitConfig) (*Git, error) {
+	if config.Name == "" {
+		return nil, errors.New("连接器未配置名称")
+	}
+	if config.Repo == "" {
+		return nil, fmt.Errorf("连接器 %s 未配置仓库目录", config.Name)
+	}
+	repo, err := filepath.Abs(config.Repo)
+	if err != nil {
+		return nil, err
+	}
+	config.Repo = repo
+	if config.Branch == "" {
+		config.Branch = "HEAD"
+	}
+	if err := validatePatterns(config.Name, config.Include, config.Exclude); err != nil {
+		return nil, err
+	}
+	if config.Interval <= 0 {
+		config.Interval = defaultInterval
+	}
+	return &Git{config: config, files: files, ingester: ingester}, nil
+}
+
+// Run 启动时同步一次，之后定时检查分支的新提交，ctx结束时返回
+func (g *Git) Run(ctx context.Context) {
+	g.sync(ctx)
+	ticker := time.NewTicker(g.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			g.sync(ctx)
+		}
+	}
+}
+
+// sync 同步一次并记录结果
+func (g *Git) sync(ctx context.Context) {
+	result, err := g.Sync(ctx)
+	if err != nil {
+		log.Printf("连接器 %s 同步失败: %v", g.config.Name, err)
+		return
+	}
+	if result.Updated > 0 || result.Removed > 0 || result.Failed > 0 {
+		log.Printf("连接器 %s 同步完成: 更新 %d，删除 %d，未变化 %d，失败 %d",
+			g.config.Name, result.Updated, result.Removed, result.Unchanged, result.Failed)
+	}
+}
+
+// Sync 将分支的最新提交同步到知识库，不能并发调用
+// 已索引的提交不存在（如强制推送后被清理）、知识库或同步范围变化时重新全量比较；
+// 有文件处理失败时不更新已索引的提交，下次同步重试（已成功的文件按blob跳过）
+func (g *Git) Sync(ctx context.Context) (SyncResult, error) {
+	var result SyncResult
+	out, err := g.git(ctx, "rev-parse", "--verify", g.config.Branch+"^{commit}")
+	if err != nil {
+		return result, fmt.Errorf("解析分支 %s 失败: %v", g.config.Branch, err)
+	}
+	head := strings.TrimSpace(string(out))
+
+	state, err := g.files.State(ctx, g.config.Name)
+	if err != nil && !store.IsNotFound(err) {
+		return result, err
+	}
+	configHash := g.configHash()
+	incremental := state != nil && state.KnowledgeBaseID == g.config.KnowledgeBaseID &&
+		state.ConfigHash == configHash && g.hasCommit(ctx, state.CommitSHA)
+	if incremental && state.CommitSHA == head {
+		return result, nil
+	}
+
+	tree, err := g.tree(ctx, head)
+	if err != nil {
+		return result, err
+	}
+	records, err := g.files.List(ctx, g.config.Name)
+	if err != nil {
+		return result, err
+	}
+	synced := make(map[string]model.ConnectorFile, len(records))
+	for _, record := range records {
+		synced[record.Path] = record
+	}
+
+	// 需要处理的文件：增量时为两次提交之间变化的文件，否则为最新提交的全部文件和已同步的文件
+	var paths []string
+	if incremental {
+		if paths, err = g.changedFiles(ctx, state.CommitSHA, head); err != nil {
+			return result, err
+		}
+	} else {
+		for p := range tree {
+			paths = append(paths, p)
+		}
+		for p := range synced {
+			if _, ok := tree[p]; !ok {
+				paths = append(paths, p)
+			}
+		}
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		entry, inTree := tree[p]
+		record, ok := synced[p]
+		if inTree && entry.size > rag.MaxFileSize {
+			log.Printf("连接器 %s 跳过超过大小限制的文件: %s", g.config.Name, p)
+			inTree = false
+		}
+		if !inTree || !selectFile(g.config.Include, g.config.Exclude, p) {
+			if !ok {
+				continue
+			}
+			if err := g.remove(ctx, record); err != nil {
+				log.Printf("连接器 %s 删除 %s 的文档失败: %v", g.config.Name, p, err)
+				result.Failed++
+				continue
+			}
+			result.Removed++
+			continue
+		}
+		if ok && record.KnowledgeBaseID == g.config.KnowledgeBaseID && record.ContentHash == entry.object {
+			result.Unchanged++
+			continue
+		}
+		if err := g.syncFile(ctx, p, entry, record, ok); err != nil {
+			log.Printf("连接器 %s 同步 %s 失败: %v", g.config.Name, p, err)
+			result.Failed++
+			continue
+		}
+		result.Updated++
+	}
+
+	if result.Failed > 0 {
+		return result, nil
+	}
+	return result, g.files.SaveState(ctx, &model.ConnectorState{
+		Connector:       g.config.Name,
+		KnowledgeBaseID: g.config.KnowledgeBaseID,
+		CommitSHA:       head,
+		ConfigHash:      configHash,
+		SyncedAt:        time.Now(),
+	})
+}
+
+// syncFile 将提交中的文件写入知识库并记录blob
+func (g *Git) syncFile(ctx context.Context, p string, entry gitEntry, record model.ConnectorFile, exists bool) error {
+	content, err := g.git(ctx, "cat-file", "blob", entry.object)
+	if err != nil {
+		return err
+	}
+	documentID, err := g.ingester.Upsert(ctx, File{
+		UserID:          g.config.UserID,
+		KnowledgeBaseID: g.config.KnowledgeBaseID,
+		Path:            p,
+		FileName:        path.Base(p),
+		Content:         content,
+		Tags:            g.config.Tags,
+		Metadata:        g.config.Metadata,
+	})
+	if err != nil {
+		return err
+	}
+	// 配置的知识库变化后，旧知识库中的文档不再同步
+	if exists && record.KnowledgeBaseID != g.config.KnowledgeBaseID {
+		if err := g.ingester.Remove(ctx, g.config.UserID, record.DocumentID); err != nil {
+			log.Printf("连接器 %s 删除 %s 在原知识库中的文档失败: %v", g.config.Name, p, err)
+		}
+	}
+	return g.files.Save(ctx, &model.ConnectorFile{
+		Connector:       g.config.Name,
+		Path:            p,
+		KnowledgeBaseID: g.config.KnowledgeBaseID,
+		Size:            entry.size,
+		ContentHash:     entry.object,
+		DocumentID:      documentID,
+		SyncedAt:        time.Now(),
+	})
+}
+
+// remove 删除不再同步的文件的文档和同步记录
+func (g *Git) remove(ctx context.Context, record model.ConnectorFile) error {
+	if err := g.ingester.Remove(ctx, g.config.UserID, record.DocumentID); err != nil {
+		return err
+	}
+	return g.files.Delete(ctx, record.ID)
+}
+
+// configHash 同步范围的哈希
+func (g *Git) configHash() string {
+	return ai.ContentHash(fmt.Sprintf("%q %q", g.config.Include, g.config.Exclude))
+}
+
+// hasCommit 仓库中是否存在该提交
+func (g *Git) hasCommit(ctx context.Context, sha string) bool {
+	_, err := g.git(ctx, "cat-file", "-e", sha+"^{commit}")
+	return err == nil
+}
+
+// tree 提交中的全部文件（不含符号链接和子模块）
+func (g *Git) tree(ctx context.Context, commit string) (map[string]gitEntry, error) {
+	out, err := g.git(ctx, "ls-tree", "-r", "-z", "--long", commit)
+	if err != nil {
+		return nil, err
+	}
+	tree := make(map[string]gitEntry)
+	for _, line := range strings.Split(string(out), "\x00") {
+		// <mode> <type> <object> <size>\t<path>
+		meta, p, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(meta)
+		if len(fields) != 4 || fields[1] != "blob" || fields[0] == "120000" {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			continue
+		}
+		tree[p] = gitEntry{object: fields[2], size: size}
+	}
+	return tree, nil
+}
+
+// changedFiles 两次提交之间新增、修改和删除的文件
+func (g *Git) changedFiles(ctx context.Context, from, to string) ([]string, error) {
+	out, err := g.git(ctx, "diff", "--name-only", "-z", "--no-renames", from, to)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, p := range strings.Split(string(out), "\x00") {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths, nil
+}
+
+// git 在仓库目录中执行git命令
+func (g *Git) git(ctx context.Context, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", g.config.Repo}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return nil, fmt.Errorf("git %s: %v", args[0], err)
+	}
+	return out, nil
+}
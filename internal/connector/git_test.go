@@ -0,0 +1,200 @@
+package connector
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// testRepo 临时git仓库
+type testRepo struct {
+	t    *testing.T
+	root string
+}
+
+func newTestRepo(t *testing.T) *testRepo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("没有安装git")
+	}
+	r := &testRepo{t: t, root: t.TempDir()}
+	r.git("init", "-q", "-b", "main")
+	r.git("config", "user.email", "test@example.com")
+	r.git("config", "user.name", "test")
+	r.git("config", "commit.gpgsign", "false")
+	return r
+}
+
+func (r *testRepo) git(args ...string) string {
+	r.t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", r.root}, args...)...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// commit 提交工作区的全部变化，返回提交ID
+func (r *testRepo) commit(message string) string {
+	r.t.Helper()
+	r.git("add", "-A")
+	r.git("commit", "-q", "-m", message)
+	return r.git("rev-parse", "HEAD")
+}
+
+func TestGitSync(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+	writeFile(t, repo.root, "guide.md", "第一段内容写在这里用来测试\n\n第二段内容写在这里用来测试\n\n第三段内容写在这里用来测试\n")
+	writeFile(t, repo.root, "docs/old.md", "旧文档")
+	writeFile(t, repo.root, "docs/keep.md", "不变的文档")
+	writeFile(t, repo.root, "logo.png", "png")
+	first := repo.commit("first")
+
+	// 工作区未提交的修改不会被索引
+	writeFile(t, repo.root, "draft.md", "草稿")
+
+	files, ingester := newMemoryFiles(), newMemoryIngester()
+	g, err := NewGit(files, ingester, GitConfig{Name: "repo", Repo: repo.root, UserID: 1, KnowledgeBaseID: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := g.Sync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResult(t, "首次同步", result, SyncResult{Updated: 3})
+	if got := ingester.takeUpserts(); !equalStrings(got, []string{"docs/keep.md", "docs/old.md", "guide.md"}) {
+		t.Fatalf("首次同步写入 %v", got)
+	}
+	want := []string{"guide.md:1-1", "guide.md:3-3", "guide.md:5-5"}
+	if got := ingester.citations("guide.md"); !equalStrings(got, want) {
+		t.Fatalf("第一次提交的引用出处 = %v，期望 %v", got, want)
+	}
+	if state, _ := files.State(ctx, "repo"); state == nil || state.CommitSHA != first {
+		t.Fatalf("已索引的提交 = %+v，期望 %s", state, first)
+	}
+
+	// 第二次提交：在开头插入段落、删除文档、新增文档（包括之前未提交的草稿），只处理变化的文件
+	writeFile(t, repo.root, "guide.md", "新增的开头段落内容用来测试\n\n第一段内容写在这里用来测试\n\n第二段内容写在这里用来测试\n\n第三段内容写在这里用来测试\n")
+	writeFile(t, repo.root, "notes.txt", "笔记")
+	oldID := ingester.documentID("docs/old.md")
+	repo.git("rm", "-q", "docs/old.md")
+	second := repo.commit("second")
+
+	result, err = g.Sync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 增量同步只比较两次提交之间变化的文件，未变化的 docs/keep.md 不计入 Unchanged
+	checkResult(t, "增量同步", result, SyncResult{Updated: 3, Removed: 1})
+	if got := ingester.takeUpserts(); !equalStrings(got, []string{"draft.md", "guide.md", "notes.txt"}) {
+		t.Fatalf("增量同步写入 %v", got)
+	}
+	if len(ingester.removed) != 1 || ingester.removed[0] != oldID {
+		t.Fatalf("删除的文档 = %v，期望 [%d]", ingester.removed, oldID)
+	}
+	// 原有段落的行号随插入的段落后移
+	want = []string{"guide.md:1-1", "guide.md:3-3", "guide.md:5-5", "guide.md:7-7"}
+	if got := ingester.citations("guide.md"); !equalStrings(got, want) {
+		t.Fatalf("第二次提交的引用出处 = %v，期望 %v", got, want)
+	}
+	if state, _ := files.State(ctx, "repo"); state == nil || state.CommitSHA != second {
+		t.Fatalf("已索引的提交 = %+v，期望 %s", state, second)
+	}
+
+	// 没有新提交时不做任何处理
+	result, err = g.Sync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResult(t, "没有新提交", result, SyncResult{})
+}
+
+func TestGitSyncMissingCommit(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+	writeFile(t, repo.root, "a.md", "a")
+	writeFile(t, repo.root, "b.md", "b")
+	repo.commit("first")
+
+	files, ingester := newMemoryFiles(), newMemoryIngester()
+	g, err := NewGit(files, ingester, GitConfig{Name: "repo", Repo: repo.root, UserID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	ingester.takeUpserts()
+
+	// 强制推送后已索引的提交不存在：改写历史，并让记录指向一个不存在的提交
+	writeFile(t, repo.root, "b.md", "b2")
+	repo.git("commit", "-q", "-a", "--amend", "-m", "rewritten")
+	head := repo.git("rev-parse", "HEAD")
+	state, _ := files.State(ctx, "repo")
+	state.CommitSHA = strings.Repeat("0", 40)
+	files.SaveState(ctx, state)
+
+	// 重新全量比较：按blob跳过未变化的文件
+	result, err := g.Sync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResult(t, "已索引的提交不存在", result, SyncResult{Updated: 1, Unchanged: 1})
+	if got := ingester.takeUpserts(); !equalStrings(got, []string{"b.md"}) {
+		t.Fatalf("全量比较写入 %v", got)
+	}
+	if state, _ := files.State(ctx, "repo"); state.CommitSHA != head {
+		t.Fatalf("已索引的提交 = %s，期望 %s", state.CommitSHA, head)
+	}
+}
+
+func TestGitSyncScopeChange(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+	writeFile(t, repo.root, "docs/a.md", "a")
+	writeFile(t, repo.root, "src/main.go", "package main")
+	repo.commit("first")
+
+	files, ingester := newMemoryFiles(), newMemoryIngester()
+	g, err := NewGit(files, ingester, GitConfig{Name: "repo", Repo: repo.root, UserID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	ingester.takeUpserts()
+
+	// 同步范围变化后，同一提交也重新比较，范围外的文件从知识库移除
+	g, err = NewGit(files, ingester, GitConfig{Name: "repo", Repo: repo.root, UserID: 1, Include: []string{"docs/**"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := g.Sync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResult(t, "缩小同步范围", result, SyncResult{Removed: 1, Unchanged: 1})
+	if records, _ := files.List(ctx, "repo"); len(records) != 1 || records[0].Path != "docs/a.md" {
+		t.Fatalf("同步记录 = %+v", records)
+	}
+}
+
+func TestGitSyncUnknownBranch(t *testing.T) {
+	repo := newTestRepo(t)
+	writeFile(t, repo.root, "a.md", "a")
+	repo.commit("first")
+
+	g, err := NewGit(newMemoryFiles(), newMemoryIngester(), GitConfig{Name: "repo", Repo: repo.root, Branch: "missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Sync(context.Background()); err == nil {
+		t.Fatal("分支不存在时没有返回错误")
+	}
+}
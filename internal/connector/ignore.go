@@ -0,0 +1,145 @@
+package connector
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreRule .gitignore 中的一条规则
+type ignoreRule struct {
+	base     string // .gitignore 所在目录（相对同步目录，根目录为空）
+	pattern  string
+	negate   bool // !开头，重新包含之前规则排除的文件
+	dirOnly  bool // /结尾，只匹配目录
+	anchored bool // 含有/，相对 .gitignore 所在目录匹配，否则匹配任意层级的文件名
+}
+
+// ignoreRules 遍历目录时逐层加载的 .gitignore 规则
+// 下层目录的规则在上层之后加载，同一文件中后面的规则在后，最后一条匹配的规则生效
+type ignoreRules struct {
+	rules []ignoreRule
+}
+
+// load 加载目录下的 .gitignore，dir为绝对路径，rel为相对同步目录的路径
+func (r *ignoreRules) load(dir, rel string) error {
+	f, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if rule, ok := parseIgnoreRule(scanner.Text(), rel); ok {
+			r.rules = append(r.rules, rule)
+		}
+	}
+	return scanner.Err()
+}
+
+// parseIgnoreRule 解析一行规则，空行和注释返回false
+func parseIgnoreRule(line, base string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+	rule := ignoreRule{base: base}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+	rule.pattern = line
+	return rule, true
+}
+
+// ignored 相对同步目录的路径是否被忽略
+func (r *ignoreRules) ignored(rel string, isDir bool) bool {
+	ignored := false
+	for _, rule := range r.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		sub := rel
+		if rule.base != "" {
+			if !strings.HasPrefix(rel, rule.base+"/") {
+				continue
+			}
+			sub = rel[len(rule.base)+1:]
+		}
+		var matched bool
+		if rule.anchored {
+			matched = matchPath(rule.pattern, sub)
+		} else {
+			matched, _ = path.Match(rule.pattern, path.Base(sub))
+		}
+		if matched {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// matchGlob 路径是否匹配include/exclude中的模式
+// 不含/的模式匹配文件名，否则相对同步目录匹配完整路径
+func matchGlob(pattern, rel string) bool {
+	if !strings.Contains(pattern, "/") {
+		matched, _ := path.Match(pattern, path.Base(rel))
+		return matched
+	}
+	return matchPath(strings.TrimPrefix(pattern, "/"), rel)
+}
+
+// validGlob 检查模式的每一段是否为合法的glob
+func validGlob(pattern string) bool {
+	for _, seg := range strings.Split(pattern, "/") {
+		if _, err := path.Match(seg, ""); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// matchPath 按/分段匹配路径，**匹配任意层（含零层）目录
+func matchPath(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(pattern[0], name[0]); !matched {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
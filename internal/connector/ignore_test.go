@@ -0,0 +1,158 @@
+package connector
+
+import "testing"
+
+func TestParseIgnoreRule(t *testing.T) {
+	tests := []struct {
+		line string
+		want ignoreRule
+		ok   bool
+	}{
+		{"", ignoreRule{}, false},
+		{"# 注释", ignoreRule{}, false},
+		{"   ", ignoreRule{}, false},
+		{"*.txt  ", ignoreRule{pattern: "*.txt"}, true},
+		{"!keep.txt", ignoreRule{pattern: "keep.txt", negate: true}, true},
+		{`\#hash.md`, ignoreRule{pattern: "#hash.md"}, true},
+		{`\!bang.md`, ignoreRule{pattern: "!bang.md"}, true},
+		{"build/", ignoreRule{pattern: "build", dirOnly: true}, true},
+		{"/root.md", ignoreRule{pattern: "root.md", anchored: true}, true},
+		{"docs/*.md", ignoreRule{pattern: "docs/*.md", anchored: true}, true},
+		{"/out/", ignoreRule{pattern: "out", dirOnly: true, anchored: true}, true},
+		{"/", ignoreRule{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseIgnoreRule(tt.line, "")
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("parseIgnoreRule(%q) = %+v, %v，期望 %+v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestIgnoreRules(t *testing.T) {
+	var rules ignoreRules
+	for _, line := range []string{
+		"*.txt",
+		"!keep.txt",
+		"build/",
+		"/root-only.md",
+		"docs/*.md",
+		"**/gen/**",
+		`\#hash.md`,
+	} {
+		rule, _ := parseIgnoreRule(line, "")
+		rules.rules = append(rules.rules, rule)
+	}
+	// sub/.gitignore 的规则在之后加载，可以重新包含上层排除的文件
+	for _, line := range []string{"local.md", "!important.txt"} {
+		rule, _ := parseIgnoreRule(line, "sub")
+		rules.rules = append(rules.rules, rule)
+	}
+
+	tests := []struct {
+		name  string
+		isDir bool
+		want  bool
+	}{
+		// 不含/的模式匹配任意层级的文件名
+		{"a.txt", false, true},
+		{"x/y/a.txt", false, true},
+		{"a.md", false, false},
+		// 否定规则
+		{"keep.txt", false, false},
+		{"x/keep.txt", false, false},
+		// 只匹配目录
+		{"build", true, true},
+		{"x/build", true, true},
+		{"build", false, false},
+		// 含/的模式相对 .gitignore 所在目录匹配
+		{"root-only.md", false, true},
+		{"x/root-only.md", false, false},
+		{"docs/a.md", false, true},
+		{"docs/x/a.md", false, false},
+		{"x/docs/a.md", false, false},
+		// **
+		{"gen/a.go", false, true},
+		{"x/gen/y/a.go", false, true},
+		{"generated/a.go", false, false},
+		// 转义的#
+		{"#hash.md", false, true},
+		// 子目录的 .gitignore 只作用于该目录
+		{"sub/local.md", false, true},
+		{"sub/x/local.md", false, true},
+		{"local.md", false, false},
+		{"sub/important.txt", false, false},
+		{"sub/other.txt", false, true},
+	}
+	for _, tt := range tests {
+		if got := rules.ignored(tt.name, tt.isDir); got != tt.want {
+			t.Errorf("ignored(%q, dir=%v) = %v，期望 %v", tt.name, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*.md", "a.md", true},
+		{"*.md", "a/b/c.md", true},
+		{"*.md", "a.txt", false},
+		{"docs/*.md", "docs/a.md", true},
+		{"docs/*.md", "docs/x/a.md", false},
+		{"/docs/*.md", "docs/a.md", true},
+		{"docs/**/*.md", "docs/a.md", true},
+		{"docs/**/*.md", "docs/x/y/a.md", true},
+		{"docs/**/*.md", "other/a.md", false},
+		{"**/test/*", "a/b/test/c.go", true},
+		{"**/test/*", "test/c.go", true},
+		{"**", "a/b/c.go", true},
+		{"docs/**", "docs", true},
+		{"a/?.go", "a/b.go", true},
+		{"a/[0-9].go", "a/x.go", false},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v，期望 %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidGlob(t *testing.T) {
+	for pattern, want := range map[string]bool{
+		"docs/**/*.md": true,
+		"[a-z].go":     true,
+		"docs/[":       false,
+		`a\`:           false,
+	} {
+		if got := validGlob(pattern); got != want {
+			t.Errorf("validGlob(%q) = %v，期望 %v", pattern, got, want)
+		}
+	}
+}
+
+func TestSelectFile(t *testing.T) {
+	include := []string{"docs/**", "*.go"}
+	exclude := []string{"**/internal/*"}
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"docs/a.md", true},
+		{"main.go", true},
+		{"readme.md", false},          // 不在 include 中
+		{"docs/image.png", false},     // 不支持的文件类型
+		{"docs/internal/a.md", false}, // exclude 优先
+		{"pkg/internal/x/a.go", true}, // * 不跨目录
+		{"pkg/internal/a.go", false},
+	}
+	for _, tt := range tests {
+		if got := selectFile(include, exclude, tt.name); got != tt.want {
+			t.Errorf("selectFile(%q) = %v，期望 %v", tt.name, got, tt.want)
+		}
+	}
+	if !selectFile(nil, nil, "a.yaml") || selectFile(nil, nil, "a.bin") {
+		t.Error("没有 include 时应同步全部支持的文件类型")
+	}
+}
@@ -0,0 +1,121 @@
+//go:build linux
+
+package connector
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"syscall"
+)
+
+const watchMask = syscall.IN_CREATE | syscall.IN_CLOSE_WRITE | syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_ATTRIB
+
+// watcher 通过inotify监听目录树的文件变化，只通知有变化，不区分具体文件（由扫描按同步记录判断）
+type watcher struct {
+	root    string
+	fd      int
+	file    *os.File // 非阻塞的inotify描述符，读取由运行时轮询，Close后读取立即返回
+	changes chan struct{}
+
+	mu     sync.Mutex
+	dirs   map[int32]string // watch描述符 -> 相对同步目录的路径
+	ignore ignoreRules
+}
+
+func newWatcher(root string) (*watcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	w := &watcher{
+		root:    root,
+		fd:      fd,
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		changes: make(chan struct{}, 1),
+		dirs:    make(map[int32]string),
+	}
+	if err := w.addTree(""); err != nil {
+		w.file.Close()
+		return nil, err
+	}
+	go w.read()
+	return w, nil
+}
+
+// Close 停止监听
+func (w *watcher) Close() error {
+	return w.file.Close()
+}
+
+// addTree 监听目录及其全部子目录（跳过 .git 和 .gitignore 忽略的目录）
+func (w *watcher) addTree(rel string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return filepath.WalkDir(filepath.Join(w.root, filepath.FromSlash(rel)), func(p string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.IsDir() {
+			// 目录在监听前已被删除等情况，由扫描处理
+			return nil
+		}
+		dirRel, err := filepath.Rel(w.root, p)
+		if err != nil {
+			return err
+		}
+		dirRel = filepath.ToSlash(dirRel)
+		if dirRel == "." {
+			dirRel = ""
+		} else if entry.Name() == ".git" || w.ignore.ignored(dirRel, true) {
+			return filepath.SkipDir
+		}
+		if err := w.ignore.load(p, dirRel); err != nil {
+			log.Printf("读取 %s/.gitignore 失败: %v", p, err)
+		}
+		wd, err := syscall.InotifyAddWatch(w.fd, p, watchMask)
+		if err != nil {
+			return err
+		}
+		w.dirs[int32(wd)] = dirRel
+		return nil
+	})
+}
+
+// read 读取inotify事件，新建或移入的目录加入监听，描述符关闭时返回
+func (w *watcher) read() {
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			return
+		}
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			wd := int32(binary.NativeEndian.Uint32(buf[offset:]))
+			mask := binary.NativeEndian.Uint32(buf[offset+4:])
+			nameLen := int(binary.NativeEndian.Uint32(buf[offset+12:]))
+			name := string(bytes.TrimRight(buf[offset+syscall.SizeofInotifyEvent:offset+syscall.SizeofInotifyEvent+nameLen], "\x00"))
+			offset += syscall.SizeofInotifyEvent + nameLen
+
+			w.mu.Lock()
+			dir, ok := w.dirs[wd]
+			if mask&syscall.IN_IGNORED != 0 {
+				delete(w.dirs, wd)
+			}
+			w.mu.Unlock()
+
+			if ok && mask&syscall.IN_ISDIR != 0 && mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 {
+				if err := w.addTree(path.Join(dir, name)); err != nil {
+					log.Printf("监听目录 %s 失败: %v", path.Join(dir, name), err)
+				}
+			}
+			select {
+			case w.changes <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
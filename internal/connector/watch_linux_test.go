@@ -0,0 +1,102 @@
+//go:build linux
+
+package connector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// watching 目录是否已加入监听
+func (w *watcher) watching(rel string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, dir := range w.dirs {
+		if dir == rel {
+			return true
+		}
+	}
+	return false
+}
+
+// waitChange 等待变化通知
+func waitChange(t *testing.T, w *watcher, what string) {
+	t.Helper()
+	select {
+	case <-w.changes:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("%s后没有收到变化通知", what)
+	}
+}
+
+// drainChanges 清空已有的变化通知
+func drainChanges(w *watcher) {
+	for {
+		select {
+		case <-w.changes:
+		case <-time.After(50 * time.Millisecond):
+			return
+		}
+	}
+}
+
+func TestWatcherNewDirectories(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, ".gitignore", "ignored/\n")
+	writeFile(t, root, "existing/a.md", "a")
+
+	w, err := newWatcher(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if !w.watching("") || !w.watching("existing") {
+		t.Fatalf("启动时监听的目录 = %v", w.dirs)
+	}
+
+	writeFile(t, root, "existing/b.md", "b")
+	waitChange(t, w, "写入已有目录中的文件")
+
+	// 新建的目录（包括一次创建的多层目录）加入监听，其中文件的变化可以收到通知
+	if err := os.MkdirAll(filepath.Join(root, "new", "deep"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	waitChange(t, w, "新建目录")
+	deadline := time.Now().Add(2 * time.Second)
+	for !w.watching("new") || !w.watching("new/deep") {
+		if time.Now().After(deadline) {
+			t.Fatalf("新建的目录没有加入监听: %v", w.dirs)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	drainChanges(w)
+	writeFile(t, root, "new/deep/c.md", "c")
+	waitChange(t, w, "写入新建目录中的文件")
+
+	// 移入的目录也加入监听
+	moved := filepath.Join(t.TempDir(), "moved")
+	writeFile(t, filepath.Dir(moved), "moved/d.md", "d")
+	if err := os.Rename(moved, filepath.Join(root, "moved")); err != nil {
+		t.Skipf("无法跨目录移动: %v", err)
+	}
+	waitChange(t, w, "移入目录")
+	deadline = time.Now().Add(2 * time.Second)
+	for !w.watching("moved") {
+		if time.Now().After(deadline) {
+			t.Fatalf("移入的目录没有加入监听: %v", w.dirs)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// .gitignore 忽略的目录不监听
+	if err := os.Mkdir(filepath.Join(root, "ignored"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	waitChange(t, w, "新建忽略的目录")
+	time.Sleep(50 * time.Millisecond)
+	if w.watching("ignored") {
+		t.Fatal("监听了 .gitignore 忽略的目录")
+	}
+}
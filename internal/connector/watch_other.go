@@ -0,0 +1,19 @@
+//go:build !linux
+
+package connector
+
+import "errors"
+
+// watcher 当前系统不支持监听文件变化，只定时扫描
+type watcher struct {
+	changes chan struct{}
+}
+
+func newWatcher(root string) (*watcher, error) {
+	return nil, errors.New("当前系统不支持监听文件变化")
+}
+
+// Close 停止监听
+func (w *watcher) Close() error {
+	return nil
+}
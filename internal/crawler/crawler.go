@@ -0,0 +1,367 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	defaultUserAgent   = "go-ai-copilot-crawler/1.0"
+	defaultMaxPages    = 50
+	defaultMaxBodySize = 10 * 1024 * 1024
+	// maxCrawlDelay robots.txt 中 Crawl-delay 的上限，避免单个站点拖住抓取
+	maxCrawlDelay = 10 * time.Second
+	// maxSitemaps 站点地图索引最多展开的子站点地图数
+	maxSitemaps = 50
+)
+
+// Config 抓取配置
+type Config struct {
+	MaxDepth      int           // 从起始页面跟随链接的层数，0表示只抓取起始页面（或站点地图中的页面）
+	MaxPages      int           // 最多抓取的页面数
+	SameHost      bool          // 只抓取与起始地址同一主机的页面
+	RespectRobots bool          // 遵循 robots.txt 和页面的 robots meta（noindex、nofollow）
+	UserAgent     string        // 请求的User-Agent，也用于匹配 robots.txt 的分组
+	Delay         time.Duration // 两次请求之间的最小间隔（robots.txt 的 Crawl-delay 更大时使用后者）
+	MaxBodySize   int64         // 页面大小上限，超过的页面跳过
+}
+
+// Page 抓取到的页面
+type Page struct {
+	URL   string // 跟随重定向后的地址（不含#片段）
+	Title string
+	Text  string // 提取的正文
+}
+
+// Result 抓取结果
+type Result struct {
+	Pages  int      // 抓取到的页面数
+	Failed []string // 暂时无法抓取的页面（网络错误、5xx、429），已有的文档应保留
+	// Truncated 达到页面数上限时队列中还有未抓取的页面，本次未抓取到的页面不一定已从网站移除
+	Truncated bool
+}
+
+// Crawler 网页抓取器，广度优先跟随链接，不能并发使用
+type Crawler struct {
+	client    *http.Client
+	config    Config
+	robots    map[string]*robots // scheme://host -> 规则
+	lastFetch time.Time
+}
+
+// New 创建网页抓取器
+func New(client *http.Client, config Config) *Crawler {
+	if config.MaxPages <= 0 {
+		config.MaxPages = defaultMaxPages
+	}
+	if config.UserAgent == "" {
+		config.UserAgent = defaultUserAgent
+	}
+	if config.MaxBodySize <= 0 {
+		config.MaxBodySize = defaultMaxBodySize
+	}
+	return &Crawler{client: client, config: config, robots: make(map[string]*robots)}
+}
+
+// crawlItem 待抓取的页面
+type crawlItem struct {
+	url   *url.URL
+	depth int
+}
+
+// fetched 一次页面请求的结果
+type fetched struct {
+	url       *url.URL // 最终地址
+	page      *Page    // 不可索引（非文本类型、noindex等）时为空
+	links     []string
+	transient bool // 暂时性失败
+}
+
+// Crawl 从起始地址抓取页面，sitemap为true时起始地址为站点地图，其中的页面作为起始页面
+// 每个页面抓取后调用visit，visit返回错误时停止抓取并返回该错误；起始页面（或站点地图）无法抓取时返回错误
+func (c *Crawler) Crawl(ctx context.Context, start string, sitemap bool, visit func(Page) error) (Result, error) {
+	var result Result
+	startURL, err := Normalize(start)
+	if err != nil {
+		return result, err
+	}
+
+	seeds := []*url.URL{startURL}
+	if sitemap {
+		if seeds, err = c.sitemapPages(ctx, startURL); err != nil {
+			return result, err
+		}
+		if len(seeds) == 0 {
+			return result, errors.New("站点地图中没有页面")
+		}
+	}
+
+	queue := make([]crawlItem, 0, len(seeds))
+	queued := make(map[string]bool)
+	for _, seed := range seeds {
+		if !queued[seed.String()] {
+			queued[seed.String()] = true
+			queue = append(queue, crawlItem{url: seed})
+		}
+	}
+	visited := make(map[string]bool)
+
+	for len(queue) > 0 && result.Pages < c.config.MaxPages {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		item := queue[0]
+		queue = queue[1:]
+		if c.config.SameHost && item.url.Host != startURL.Host {
+			continue
+		}
+		if c.config.RespectRobots && !c.allowed(ctx, item.url) {
+			continue
+		}
+
+		f, err := c.fetch(ctx, item.url)
+		if err != nil {
+			// 只抓取起始页面时，起始页面无法抓取视为整个抓取失败
+			if !sitemap && item.url == startURL {
+				return result, err
+			}
+			if f != nil && f.transient {
+				result.Failed = append(result.Failed, item.url.String())
+			}
+			continue
+		}
+		if visited[f.url.String()] || (c.config.SameHost && f.url.Host != startURL.Host) {
+			continue
+		}
+		visited[f.url.String()] = true
+		visited[item.url.String()] = true
+
+		if f.page != nil {
+			result.Pages++
+			if err := visit(*f.page); err != nil {
+				return result, err
+			}
+		}
+		if item.depth >= c.config.MaxDepth {
+			continue
+		}
+		for _, link := range f.links {
+			u, err := Normalize(link)
+			if err != nil || queued[u.String()] {
+				continue
+			}
+			queued[u.String()] = true
+			queue = append(queue, crawlItem{url: u, depth: item.depth + 1})
+		}
+	}
+	result.Truncated = len(queue) > 0
+	return result, nil
+}
+
+// Normalize 校验并规范化页面地址：只支持http和https，去除#片段，主机名小写
+func Normalize(raw string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, fmt.Errorf("地址格式错误: %s", raw)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("只支持http和https地址: %s", raw)
+	}
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment = ""
+	u.RawFragment = ""
+	u.User = nil
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return u, nil
+}
+
+// fetch 请求页面并提取内容
+func (c *Crawler) fetch(ctx context.Context, u *url.URL) (*fetched, error) {
+	resp, err := c.get(ctx, u, "text/html,application/xhtml+xml,text/plain;q=0.9,*/*;q=0.1")
+	if err != nil {
+		return &fetched{transient: !errors.Is(err, ErrPrivateAddress)}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		transient := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return &fetched{transient: transient}, fmt.Errorf("请求 %s 失败: %s", u, resp.Status)
+	}
+	final := *resp.Request.URL
+	final.Fragment = ""
+	final.RawFragment = ""
+	f := &fetched{url: &final}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, c.config.MaxBodySize+1))
+	if err != nil {
+		f.transient = true
+		return f, err
+	}
+	if int64(len(body)) > c.config.MaxBodySize {
+		return f, nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch {
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml" || (mediaType == "" && looksLikeHTML(body)):
+		doc, err := extract(bytes.NewReader(body), &final)
+		if err != nil {
+			return f, nil
+		}
+		if !c.config.RespectRobots || !doc.nofollow {
+			f.links = doc.links
+		}
+		if (c.config.RespectRobots && doc.noindex) || strings.TrimSpace(doc.text) == "" {
+			return f, nil
+		}
+		title := doc.title
+		if title == "" {
+			title = pageName(&final)
+		}
+		f.page = &Page{URL: final.String(), Title: title, Text: doc.text}
+	case mediaType == "text/plain" || mediaType == "text/markdown":
+		if text := strings.TrimSpace(string(body)); text != "" {
+			f.page = &Page{URL: final.String(), Title: pageName(&final), Text: text}
+		}
+	}
+	return f, nil
+}
+
+// get 发送GET请求，按配置的间隔和 Crawl-delay 限速
+func (c *Crawler) get(ctx context.Context, u *url.URL, accept string) (*http.Response, error) {
+	delay := c.config.Delay
+	if r := c.robots[u.Scheme+"://"+u.Host]; r != nil && r.crawlDelay > delay {
+		delay = r.crawlDelay
+		if delay > maxCrawlDelay {
+			delay = maxCrawlDelay
+		}
+	}
+	if wait := time.Until(c.lastFetch.Add(delay)); wait > 0 {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	c.lastFetch = time.Now()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", c.config.UserAgent)
+	req.Header.Set("Accept", accept)
+	return c.client.Do(req)
+}
+
+// allowed 按站点的 robots.txt 判断是否允许抓取，robots.txt 不存在或无法获取时允许
+func (c *Crawler) allowed(ctx context.Context, u *url.URL) bool {
+	site := u.Scheme + "://" + u.Host
+	r, ok := c.robots[site]
+	if !ok {
+		r = c.fetchRobots(ctx, site)
+		c.robots[site] = r
+	}
+	target := u.EscapedPath()
+	if u.RawQuery != "" {
+		target += "?" + u.RawQuery
+	}
+	return r.allowed(target)
+}
+
+func (c *Crawler) fetchRobots(ctx context.Context, site string) *robots {
+	u, err := url.Parse(site + "/robots.txt")
+	if err != nil {
+		return nil
+	}
+	resp, err := c.get(ctx, u, "text/plain")
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+	return parseRobots(resp.Body, c.config.UserAgent)
+}
+
+// sitemapPages 站点地图中的页面，站点地图索引展开一层
+func (c *Crawler) sitemapPages(ctx context.Context, u *url.URL) ([]*url.URL, error) {
+	pages, children, err := c.fetchSitemap(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	if len(children) > maxSitemaps {
+		children = children[:maxSitemaps]
+	}
+	for _, child := range children {
+		cu, err := Normalize(child)
+		if err != nil || (c.config.SameHost && cu.Host != u.Host) {
+			continue
+		}
+		more, _, err := c.fetchSitemap(ctx, cu)
+		if err != nil {
+			continue
+		}
+		pages = append(pages, more...)
+	}
+
+	var result []*url.URL
+	for _, p := range pages {
+		if pu, err := Normalize(p); err == nil {
+			result = append(result, pu)
+		}
+	}
+	return result, nil
+}
+
+func (c *Crawler) fetchSitemap(ctx context.Context, u *url.URL) (pages, children []string, err error) {
+	resp, err := c.get(ctx, u, "application/xml,text/xml;q=0.9,*/*;q=0.1")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("请求站点地图 %s 失败: %s", u, resp.Status)
+	}
+	var body io.Reader = io.LimitReader(resp.Body, c.config.MaxBodySize)
+	if strings.HasSuffix(u.Path, ".gz") {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer gz.Close()
+		body = io.LimitReader(gz, c.config.MaxBodySize)
+	}
+	pages, children, err = parseSitemap(body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("解析站点地图 %s 失败: %v", u, err)
+	}
+	return pages, children, nil
+}
+
+// pageName 没有标题时使用的页面名称
+func pageName(u *url.URL) string {
+	if name := path.Base(u.Path); name != "/" && name != "." {
+		return name
+	}
+	return u.Host
+}
+
+func looksLikeHTML(body []byte) bool {
+	head := bytes.ToLower(bytes.TrimSpace(body[:min(len(body), 512)]))
+	return bytes.HasPrefix(head, []byte("<!doctype html")) || bytes.HasPrefix(head, []byte("<html"))
+}
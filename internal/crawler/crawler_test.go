@@ -0,0 +1,225 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testSite 测试站点，记录收到的请求路径
+type testSite struct {
+	server *httptest.Server
+	other  *httptest.Server // 另一个主机
+
+	mu        sync.Mutex
+	requested map[string]int
+}
+
+func newTestSite(t *testing.T) *testSite {
+	t.Helper()
+	site := &testSite{requested: make(map[string]int)}
+	site.other = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		site.record("other" + r.URL.Path)
+		writePage(w, "其他站点", "其他站点的页面", nil)
+	}))
+	t.Cleanup(site.other.Close)
+
+	site.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		site.record(r.URL.Path)
+		base := site.server.URL
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprint(w, "User-agent: *\nDisallow: /blocked\nAllow: /blocked/open\n")
+		case "/":
+			writePage(w, "首页", "首页内容", nil, "/a", "/blocked", "/blocked/open", "/noindex", site.other.URL+"/page")
+		case "/a":
+			writePage(w, "A", "A的内容", nil, "/b")
+		case "/b":
+			writePage(w, "B", "B的内容", nil, "/c")
+		case "/c", "/blocked/open", "/from-noindex", "/hidden":
+			writePage(w, r.URL.Path, r.URL.Path+" 的内容", nil)
+		case "/noindex":
+			writePage(w, "不索引", "不应被索引的内容", []string{"noindex"}, "/from-noindex")
+		case "/nofollow":
+			writePage(w, "不跟随", "不跟随链接的页面", []string{"nofollow"}, "/hidden")
+		case "/sitemap_index.xml":
+			fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>%s/sitemap1.xml</loc></sitemap>
+  <sitemap><loc>%s/sitemap2.xml</loc></sitemap>
+  <sitemap><loc>%s/sitemap.xml</loc></sitemap>
+</sitemapindex>`, base, base, site.other.URL)
+		case "/sitemap1.xml":
+			fmt.Fprintf(w, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>%s/a</loc></url><url><loc>%s/c</loc></url></urlset>`, base, base)
+		case "/sitemap2.xml":
+			fmt.Fprintf(w, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>%s/nofollow</loc></url></urlset>`, base)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(site.server.Close)
+	return site
+}
+
+func (s *testSite) record(path string) {
+	s.mu.Lock()
+	s.requested[path]++
+	s.mu.Unlock()
+}
+
+func (s *testSite) wasRequested(path string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requested[path] > 0
+}
+
+// writePage 输出带标题、正文、robots meta和链接的页面
+func writePage(w http.ResponseWriter, title, text string, robots []string, links ...string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, "<!doctype html><html><head><title>%s</title>", title)
+	for _, r := range robots {
+		fmt.Fprintf(w, `<meta name="robots" content="%s">`, r)
+	}
+	fmt.Fprintf(w, "</head><body><p>%s</p>", text)
+	for _, l := range links {
+		fmt.Fprintf(w, `<a href="%s">link</a>`, l)
+	}
+	fmt.Fprint(w, "</body></html>")
+}
+
+// crawl 抓取并返回抓取到的页面路径（排序后）
+func crawl(t *testing.T, site *testSite, config Config, start string, sitemap bool) ([]string, Result) {
+	t.Helper()
+	c := New(site.server.Client(), config)
+	var paths []string
+	result, err := c.Crawl(context.Background(), site.server.URL+start, sitemap, func(p Page) error {
+		u, err := url.Parse(p.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, u.Path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(paths)
+	return paths, result
+}
+
+func equal(a, b []string) bool {
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+func TestCrawlDepthAndSameHost(t *testing.T) {
+	site := newTestSite(t)
+	config := Config{MaxDepth: 1, SameHost: true, RespectRobots: true}
+
+	paths, result := crawl(t, site, config, "/", false)
+	// /noindex 不索引，/blocked 被 robots.txt 禁止，其他主机的页面不抓取
+	if want := []string{"/", "/a", "/blocked/open"}; !equal(paths, want) {
+		t.Fatalf("深度1抓取到 %v，期望 %v", paths, want)
+	}
+	if result.Pages != len(paths) || len(result.Failed) != 0 {
+		t.Fatalf("result = %+v", result)
+	}
+	if site.wasRequested("/blocked") || site.wasRequested("/b") || site.wasRequested("other/page") {
+		t.Fatalf("请求了不应抓取的页面: %v", site.requested)
+	}
+
+	config.MaxDepth = 2
+	paths, _ = crawl(t, site, config, "/", false)
+	// noindex 的页面仍跟随链接
+	if want := []string{"/", "/a", "/b", "/blocked/open", "/from-noindex"}; !equal(paths, want) {
+		t.Fatalf("深度2抓取到 %v，期望 %v", paths, want)
+	}
+
+	// 不限制主机时抓取其他主机的页面
+	config.SameHost = false
+	config.MaxDepth = 1
+	paths, _ = crawl(t, site, config, "/", false)
+	if !site.wasRequested("other/page") || len(paths) != 4 {
+		t.Fatalf("不限制主机时抓取到 %v", paths)
+	}
+
+	// MaxPages 限制页面数
+	config.MaxPages = 2
+	paths, result = crawl(t, site, config, "/", false)
+	if len(paths) != 2 || result.Pages != 2 || !result.Truncated {
+		t.Fatalf("MaxPages=2 抓取到 %v, %+v", paths, result)
+	}
+	config.MaxPages = 10
+	if _, result = crawl(t, site, config, "/", false); result.Truncated {
+		t.Fatalf("未达到页面数上限时 Truncated = true")
+	}
+}
+
+func TestCrawlNofollow(t *testing.T) {
+	site := newTestSite(t)
+
+	paths, _ := crawl(t, site, Config{MaxDepth: 2, RespectRobots: true}, "/nofollow", false)
+	if want := []string{"/nofollow"}; !equal(paths, want) || site.wasRequested("/hidden") {
+		t.Fatalf("nofollow 页面抓取到 %v", paths)
+	}
+
+	// 不遵循 robots 时跟随链接，也索引 noindex 的页面
+	paths, _ = crawl(t, site, Config{MaxDepth: 2}, "/nofollow", false)
+	if want := []string{"/hidden", "/nofollow"}; !equal(paths, want) {
+		t.Fatalf("不遵循 robots 时抓取到 %v，期望 %v", paths, want)
+	}
+	paths, _ = crawl(t, site, Config{}, "/noindex", false)
+	if want := []string{"/noindex"}; !equal(paths, want) {
+		t.Fatalf("不遵循 robots 时抓取到 %v，期望 %v", paths, want)
+	}
+}
+
+func TestCrawlSitemapIndex(t *testing.T) {
+	site := newTestSite(t)
+
+	paths, _ := crawl(t, site, Config{SameHost: true, RespectRobots: true}, "/sitemap_index.xml", true)
+	// 展开子站点地图，其他主机的子站点地图跳过；深度0时不跟随页面中的链接
+	if want := []string{"/a", "/c", "/nofollow"}; !equal(paths, want) {
+		t.Fatalf("站点地图抓取到 %v，期望 %v", paths, want)
+	}
+	if site.wasRequested("other/sitemap.xml") || site.wasRequested("/b") {
+		t.Fatalf("请求了不应抓取的地址: %v", site.requested)
+	}
+}
+
+func TestCrawlStartPageError(t *testing.T) {
+	site := newTestSite(t)
+	c := New(site.server.Client(), Config{})
+	_, err := c.Crawl(context.Background(), site.server.URL+"/missing", false, func(Page) error { return nil })
+	if err == nil {
+		t.Fatal("起始页面不存在时应返回错误")
+	}
+	if _, err := c.Crawl(context.Background(), "ftp://example.com/", false, func(Page) error { return nil }); err == nil {
+		t.Fatal("不支持的协议应返回错误")
+	}
+}
+
+func TestClientRejectsPrivateAddress(t *testing.T) {
+	site := newTestSite(t)
+
+	c := New(NewClient(5*time.Second, false), Config{})
+	_, err := c.Crawl(context.Background(), site.server.URL+"/", false, func(Page) error { return nil })
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Fatalf("抓取回环地址的错误 = %v，期望 ErrPrivateAddress", err)
+	}
+	if site.wasRequested("/") {
+		t.Fatal("不应向回环地址发出请求")
+	}
+
+	// 允许内网地址时可以抓取
+	c = New(NewClient(5*time.Second, true), Config{})
+	if _, err := c.Crawl(context.Background(), site.server.URL+"/", false, func(Page) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,229 @@
+package crawler
+
+import (
+	"io"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// document 从HTML中提取的内容
+type document struct {
+	title    string
+	text     string   // 正文，按块换行，标题以 # 开头
+	links    []string // 页面中的链接（已解析为绝对地址）
+	noindex  bool     // <meta name="robots" content="noindex">
+	nofollow bool     // <meta name="robots" content="nofollow">
+}
+
+// skipped 不属于正文的元素
+var skipped = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Svg: true, atom.Iframe: true, atom.Nav: true, atom.Header: true,
+	atom.Footer: true, atom.Aside: true, atom.Form: true, atom.Button: true,
+}
+
+// blocks 前后换行的块级元素
+var blocks = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true, atom.Main: true,
+	atom.Ul: true, atom.Ol: true, atom.Li: true, atom.Dl: true, atom.Dt: true, atom.Dd: true,
+	atom.Table: true, atom.Tr: true, atom.Blockquote: true, atom.Pre: true, atom.Br: true,
+	atom.Hr: true, atom.Figure: true, atom.Figcaption: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+}
+
+// extract 解析HTML，提取标题、正文和链接
+// 正文优先取 <main>、<article> 或 role="main" 的元素，没有时取 <body>，并去除导航、页眉页脚、脚本等
+func extract(r io.Reader, base *url.URL) (*document, error) {
+	root, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	doc := &document{}
+
+	var body, main *html.Node
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.DataAtom {
+			case atom.Title:
+				if doc.title == "" {
+					doc.title = collapseSpace(textOf(n))
+				}
+			case atom.Base:
+				if href := attr(n, "href"); href != "" {
+					if u, err := base.Parse(href); err == nil {
+						base = u
+					}
+				}
+			case atom.Meta:
+				if strings.EqualFold(attr(n, "name"), "robots") {
+					content := strings.ToLower(attr(n, "content"))
+					doc.noindex = doc.noindex || strings.Contains(content, "noindex") || strings.Contains(content, "none")
+					doc.nofollow = doc.nofollow || strings.Contains(content, "nofollow") || strings.Contains(content, "none")
+				}
+			case atom.Body:
+				body = n
+			case atom.Main, atom.Article:
+				if main == nil {
+					main = n
+				}
+			case atom.A:
+				if href := attr(n, "href"); href != "" && !strings.Contains(strings.ToLower(attr(n, "rel")), "nofollow") {
+					if u, err := base.Parse(strings.TrimSpace(href)); err == nil {
+						doc.links = append(doc.links, u.String())
+					}
+				}
+			}
+			if main == nil && strings.EqualFold(attr(n, "role"), "main") {
+				main = n
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+
+	content := main
+	if content == nil {
+		content = body
+	}
+	if content != nil {
+		var b textBuilder
+		b.node(content)
+		doc.text = b.String()
+	}
+	return doc, nil
+}
+
+// textBuilder 将元素树转换为按块换行的纯文本
+type textBuilder struct {
+	b     strings.Builder
+	space bool // 行内是否有待输出的空白
+}
+
+func (t *textBuilder) node(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		t.text(n.Data)
+		return
+	case html.ElementNode:
+		if skipped[n.DataAtom] || strings.EqualFold(attr(n, "aria-hidden"), "true") {
+			return
+		}
+		if n.DataAtom == atom.Pre {
+			t.newline()
+			t.b.WriteString(strings.TrimRight(textOf(n), "\n"))
+			t.newline()
+			return
+		}
+	}
+
+	block := n.Type == html.ElementNode && blocks[n.DataAtom]
+	if block {
+		t.newline()
+		switch n.DataAtom {
+		case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+			t.b.WriteString(strings.Repeat("#", int(n.Data[1]-'0')) + " ")
+		case atom.Li:
+			t.b.WriteString("- ")
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		t.node(c)
+	}
+	if block {
+		t.newline()
+	}
+}
+
+// text 输出行内文本，连续空白合并为一个空格
+func (t *textBuilder) text(s string) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		if s != "" {
+			t.space = true
+		}
+		return
+	}
+	if startsWithSpace(s) {
+		t.space = true
+	}
+	for i, field := range fields {
+		if i > 0 || (t.space && !t.atLineStart()) {
+			t.b.WriteByte(' ')
+		}
+		t.b.WriteString(field)
+	}
+	t.space = endsWithSpace(s)
+}
+
+// newline 结束当前行（不产生连续的空行）
+func (t *textBuilder) newline() {
+	t.space = false
+	if s := t.b.String(); s == "" || s[len(s)-1] == '\n' {
+		return
+	}
+	t.b.WriteByte('\n')
+}
+
+// atLineStart 是否位于行首（或标题、列表项的标记之后）
+func (t *textBuilder) atLineStart() bool {
+	s := t.b.String()
+	return s == "" || s[len(s)-1] == '\n' || s[len(s)-1] == ' '
+}
+
+func (t *textBuilder) String() string {
+	lines := strings.Split(t.b.String(), "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		// 保留行首缩进（<pre> 中的代码）
+		line = strings.TrimRight(line, " \t\r")
+		// 去掉没有内容的标题和列表项标记
+		if trimmed := strings.TrimSpace(line); trimmed == "" || trimmed == "-" || strings.Trim(trimmed, "#") == "" {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n")
+}
+
+func startsWithSpace(s string) bool {
+	return s != "" && strings.TrimLeft(s, " \t\n\r") != s
+}
+
+func endsWithSpace(s string) bool {
+	return s != "" && strings.TrimRight(s, " \t\n\r") != s
+}
+
+// textOf 元素内的全部文本
+func textOf(n *html.Node) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if strings.EqualFold(a.Key, key) {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,157 @@
+package crawler
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// robotsRule robots.txt 中的一条 Allow / Disallow 规则
+type robotsRule struct {
+	pattern string
+	allow   bool
+}
+
+// robots 站点的 robots.txt 中适用于本抓取器的规则
+type robots struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+	sitemaps   []string
+}
+
+// parseRobots 解析 robots.txt，选取与userAgent匹配的分组，没有时使用 * 分组
+func parseRobots(r io.Reader, userAgent string) *robots {
+	agent := strings.ToLower(userAgent)
+	if i := strings.IndexAny(agent, "/ "); i >= 0 {
+		agent = agent[:i]
+	}
+
+	type group struct {
+		agents []string
+		rules  []robotsRule
+		delay  time.Duration
+	}
+	var groups []*group
+	var current *group
+	inAgents := false
+	result := &robots{}
+
+	scanner := bufio.NewScanner(io.LimitReader(r, 512*1024))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// 连续的 User-agent 行属于同一分组
+			if !inAgents {
+				current = &group{}
+				groups = append(groups, current)
+				inAgents = true
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			inAgents = false
+			if current == nil {
+				continue
+			}
+			// 空的 Disallow 表示不限制
+			if value != "" {
+				current.rules = append(current.rules, robotsRule{pattern: value, allow: key == "allow"})
+			}
+		case "crawl-delay":
+			inAgents = false
+			if current == nil {
+				continue
+			}
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+				current.delay = time.Duration(seconds * float64(time.Second))
+			}
+		case "sitemap":
+			result.sitemaps = append(result.sitemaps, value)
+		default:
+			inAgents = false
+		}
+	}
+
+	var matched, wildcard []*group
+	for _, g := range groups {
+		for _, a := range g.agents {
+			if a == "*" {
+				wildcard = append(wildcard, g)
+			} else if agent != "" && strings.Contains(agent, a) {
+				matched = append(matched, g)
+			}
+		}
+	}
+	if len(matched) == 0 {
+		matched = wildcard
+	}
+	for _, g := range matched {
+		result.rules = append(result.rules, g.rules...)
+		if g.delay > result.crawlDelay {
+			result.crawlDelay = g.delay
+		}
+	}
+	return result
+}
+
+// allowed 路径（含查询字符串）是否允许抓取：最长匹配的规则生效，长度相同时 Allow 优先
+func (r *robots) allowed(path string) bool {
+	if r == nil {
+		return true
+	}
+	best := -1
+	allow := true
+	for _, rule := range r.rules {
+		if !robotsMatch(rule.pattern, path) {
+			continue
+		}
+		if n := len(rule.pattern); n > best || (n == best && rule.allow) {
+			best = n
+			allow = rule.allow
+		}
+	}
+	return allow
+}
+
+// robotsMatch 前缀匹配，支持 * 匹配任意字符和结尾的 $ 锚定
+func robotsMatch(pattern, path string) bool {
+	if strings.HasSuffix(pattern, "$") {
+		return wildcardMatch(strings.TrimSuffix(pattern, "$"), path, true)
+	}
+	return wildcardMatch(pattern, path, false)
+}
+
+// wildcardMatch * 匹配任意字符，full为false时只需匹配s的前缀
+func wildcardMatch(pattern, s string, full bool) bool {
+	for pattern != "" {
+		if pattern[0] == '*' {
+			pattern = strings.TrimLeft(pattern, "*")
+			if pattern == "" {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if wildcardMatch(pattern, s[i:], full) {
+					return true
+				}
+			}
+			return false
+		}
+		if s == "" || s[0] != pattern[0] {
+			return false
+		}
+		pattern, s = pattern[1:], s[1:]
+	}
+	return !full || s == ""
+}
@@ -0,0 +1,77 @@
+package crawler
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRobotsAllowDisallowPrecedence(t *testing.T) {
+	r := parseRobots(strings.NewReader(`
+User-agent: *
+Disallow: /private/
+Allow: /private/public/
+Disallow: /docs
+Allow: /docs
+Disallow: /*.pdf$
+Disallow: /search?q=
+Disallow:
+`), "go-ai-copilot-crawler/1.0")
+
+	tests := []struct {
+		path  string
+		allow bool
+	}{
+		{"/", true},
+		{"/private/", false},
+		{"/private/secret.html", false},
+		{"/private/public/", true},  // 更长的 Allow 优先
+		{"/private/public/a", true}, // 同上
+		{"/docs/intro", true},       // 长度相同时 Allow 优先
+		{"/files/a.pdf", false},     // * 和 $ 匹配
+		{"/files/a.pdf?download=1", true},
+		{"/search?q=go", false},
+		{"/search", true},
+	}
+	for _, tt := range tests {
+		if got := r.allowed(tt.path); got != tt.allow {
+			t.Errorf("allowed(%q) = %v，期望 %v", tt.path, got, tt.allow)
+		}
+	}
+}
+
+func TestRobotsUserAgentGroup(t *testing.T) {
+	text := `
+User-agent: *
+Disallow: /
+
+User-agent: otherbot
+User-agent: go-ai-copilot-crawler
+Allow: /
+Crawl-delay: 2
+
+Sitemap: https://example.com/sitemap.xml
+`
+	r := parseRobots(strings.NewReader(text), "go-ai-copilot-crawler/1.0")
+	if !r.allowed("/page") {
+		t.Error("匹配的分组应优先于 * 分组")
+	}
+	if r.crawlDelay != 2*time.Second {
+		t.Errorf("crawlDelay = %v，期望 2s", r.crawlDelay)
+	}
+	if len(r.sitemaps) != 1 || r.sitemaps[0] != "https://example.com/sitemap.xml" {
+		t.Errorf("sitemaps = %v", r.sitemaps)
+	}
+
+	// 没有匹配的分组时使用 * 分组
+	r = parseRobots(strings.NewReader(text), "unknown/1.0")
+	if r.allowed("/page") {
+		t.Error("应使用 * 分组的 Disallow: /")
+	}
+
+	// 没有 robots.txt 时全部允许
+	var none *robots
+	if !none.allowed("/anything") {
+		t.Error("robots.txt 不存在时应允许抓取")
+	}
+}
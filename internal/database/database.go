@@ -3,18 +3,28 @@ package database
 import (
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
 
-	"go-ai-copilot/internal/model"
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
+// 数据库驱动
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
 // DB 数据库连接实例
 var DB *gorm.DB
 
 // Config 数据库配置
 type Config struct {
+	Driver   string // postgres（默认）/ sqlite
+	Path     string // SQLite数据库文件，:memory: 为内存数据库
 	Host     string
 	Port     int
 	User     string
@@ -23,40 +33,90 @@ type Config struct {
 	SSLMode  string
 }
 
-// Init 初始化数据库连接
+// Init 初始化数据库连接并执行未执行的迁移
 func Init(cfg Config) error {
+	if err := Open(cfg); err != nil {
+		return err
+	}
+
+	count, err := MigrateUp(DB)
+	if err != nil {
+		return fmt.Errorf("数据库迁移失败: %v", err)
+	}
+	if count > 0 {
+		log.Printf("数据库迁移完成，本次执行 %d 个迁移", count)
+	}
+	return nil
+}
+
+// Open 连接数据库（不执行迁移）
+func Open(cfg Config) error {
+	var db *gorm.DB
+	var err error
+	switch cfg.Driver {
+	case DriverPostgres, "":
+		db, err = openPostgres(cfg)
+	case DriverSQLite:
+		db, err = openSQLite(cfg)
+	default:
+		return fmt.Errorf("不支持的数据库驱动: %s", cfg.Driver)
+	}
+	if err != nil {
+		return fmt.Errorf("数据库连接失败: %v", err)
+	}
+
+	DB = db
+	log.Println("数据库连接成功")
+	return nil
+}
+
+// openPostgres 连接PostgreSQL（pgvector扩展由迁移创建）
+func openPostgres(cfg Config) (*gorm.DB, error) {
 	dsn := fmt.Sprintf(
 		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
 		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, cfg.SSLMode,
 	)
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+	return gorm.Open(postgres.Open(dsn), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
 	})
-	if err != nil {
-		return fmt.Errorf("数据库连接失败: %v", err)
+}
+
+// openSQLite 打开SQLite数据库（纯Go实现，无需CGO）
+func openSQLite(cfg Config) (*gorm.DB, error) {
+	path := cfg.Path
+	if path == "" {
+		path = "data/go-ai-copilot.db"
+	}
+	memory := path == ":memory:"
+	if !memory {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
 	}
 
-	// 先创建 pgvector 扩展（必须在表迁移之前创建）
-	db.Exec("CREATE EXTENSION IF NOT EXISTS vector")
-
-	// 自动迁移表结构
-	if err := db.AutoMigrate(
-		&model.User{},
-		&model.Session{},
-		&model.Message{},
-		&model.RAGDocument{},
-		&model.RAGChunk{},
-	); err != nil {
-		return fmt.Errorf("表迁移失败: %v", err)
+	// WAL模式允许读写并发，busy_timeout避免并发写入时立即报错
+	db, err := gorm.Open(sqlite.Open(path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Info),
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// 创建向量索引（如果不存在）
-	db.Exec("CREATE INDEX IF NOT EXISTS idx_rag_chunks_embedding ON rag_chunks USING ivfflat (embedding vector_cosine_ops)")
+	if memory {
+		// 内存数据库每个连接相互独立，只能使用一个连接
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+		sqlDB.SetMaxOpenConns(1)
+	}
+	return db, nil
+}
 
-	DB = db
-	log.Println("数据库连接成功")
-	return nil
+// IsPostgres 当前是否使用PostgreSQL
+func IsPostgres() bool {
+	return DB != nil && DB.Dialector.Name() == DriverPostgres
 }
 
 // GetDB 获取数据库实例
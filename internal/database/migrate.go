@@ -0,0 +1,258 @@
+package database
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+// migrationPattern 迁移文件名：<版本号>_<名称>.up.sql / <版本号>_<名称>.down.sql
+var migrationPattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// migrationLockKey PostgreSQL advisory lock 的键（"goai"），多个实例同时启动时只有一个执行迁移
+const migrationLockKey int64 = 0x676f6169
+
+// 迁移记录表，在执行任何迁移之前创建
+var migrationTableSQL = map[string]string{
+	DriverPostgres: "CREATE TABLE IF NOT EXISTS schema_migrations (version bigint PRIMARY KEY, name varchar(255) NOT NULL, applied_at timestamptz NOT NULL)",
+	DriverSQLite:   "CREATE TABLE IF NOT EXISTS schema_migrations (version integer PRIMARY KEY, name text NOT NULL, applied_at datetime NOT NULL)",
+}
+
+// Migration 数据库迁移
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus 迁移状态
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time // 为空表示未执行
+	Missing   bool       // 已执行但找不到迁移文件（可能是更新版本的程序执行的）
+}
+
+// schemaMigration 已执行的迁移记录
+type schemaMigration struct {
+	Version   int64 `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Migrations 读取数据库方言对应的迁移文件，按版本号排序
+func Migrations(dialect string) ([]Migration, error) {
+	dir := path.Join("migrations", dialect)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("没有 %s 的迁移文件", dialect)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		m := migrationPattern.FindStringSubmatch(entry.Name())
+		if m == nil {
+			return nil, fmt.Errorf("迁移文件名不合法: %s", entry.Name())
+		}
+		version, _ := strconv.ParseInt(m[1], 10, 64)
+		content, err := fs.ReadFile(migrationFiles, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("迁移版本 %d 的名称不一致: %s / %s", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(content)
+		} else {
+			mig.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" || mig.Down == "" {
+			return nil, fmt.Errorf("迁移 %d_%s 缺少 up 或 down 文件", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// MigrateUp 执行全部未执行的迁移，返回本次执行的数量
+func MigrateUp(db *gorm.DB) (int, error) {
+	migrations, err := Migrations(db.Dialector.Name())
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err = withMigrationLock(db, func(conn *gorm.DB) error {
+		applied, err := appliedMigrations(conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			if err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(mig.Up).Error; err != nil {
+					return err
+				}
+				return tx.Create(&schemaMigration{Version: mig.Version, Name: mig.Name, AppliedAt: time.Now()}).Error
+			}); err != nil {
+				return fmt.Errorf("迁移 %d_%s 执行失败: %v", mig.Version, mig.Name, err)
+			}
+			log.Printf("已执行迁移 %d_%s", mig.Version, mig.Name)
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// MigrateDown 按版本号倒序回滚最近执行的steps个迁移，返回本次回滚的数量
+func MigrateDown(db *gorm.DB, steps int) (int, error) {
+	migrations, err := Migrations(db.Dialector.Name())
+	if err != nil {
+		return 0, err
+	}
+	byVersion := make(map[int64]Migration, len(migrations))
+	for _, mig := range migrations {
+		byVersion[mig.Version] = mig
+	}
+
+	count := 0
+	err = withMigrationLock(db, func(conn *gorm.DB) error {
+		var applied []schemaMigration
+		if err := conn.Order("version DESC").Limit(steps).Find(&applied).Error; err != nil {
+			return err
+		}
+		for _, record := range applied {
+			mig, ok := byVersion[record.Version]
+			if !ok {
+				return fmt.Errorf("找不到迁移 %d_%s 的文件，无法回滚", record.Version, record.Name)
+			}
+			if err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(mig.Down).Error; err != nil {
+					return err
+				}
+				return tx.Delete(&schemaMigration{}, record.Version).Error
+			}); err != nil {
+				return fmt.Errorf("迁移 %d_%s 回滚失败: %v", mig.Version, mig.Name, err)
+			}
+			log.Printf("已回滚迁移 %d_%s", mig.Version, mig.Name)
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// MigrationStatuses 获取全部迁移的执行状态，按版本号排序
+func MigrationStatuses(db *gorm.DB) ([]MigrationStatus, error) {
+	migrations, err := Migrations(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	if err := createMigrationTable(db); err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, mig := range migrations {
+		status := MigrationStatus{Version: mig.Version, Name: mig.Name}
+		if record, ok := applied[mig.Version]; ok {
+			status.AppliedAt = &record.AppliedAt
+			delete(applied, mig.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, record := range applied {
+		appliedAt := record.AppliedAt
+		statuses = append(statuses, MigrationStatus{
+			Version:   record.Version,
+			Name:      record.Name,
+			AppliedAt: &appliedAt,
+			Missing:   true,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+// appliedMigrations 已执行的迁移
+func appliedMigrations(db *gorm.DB) (map[int64]schemaMigration, error) {
+	var records []schemaMigration
+	if err := db.Find(&records).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]schemaMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// createMigrationTable 创建迁移记录表
+func createMigrationTable(db *gorm.DB) error {
+	if err := db.Exec(migrationTableSQL[db.Dialector.Name()]).Error; err != nil {
+		return fmt.Errorf("创建迁移记录表失败: %v", err)
+	}
+	return nil
+}
+
+// withMigrationLock 在同一个连接上持有迁移锁执行fn
+// PostgreSQL 使用会话级 advisory lock，其他实例阻塞等待，拿到锁后再读取已执行的迁移；
+// SQLite 只支持单实例部署，直接执行
+func withMigrationLock(db *gorm.DB, fn func(conn *gorm.DB) error) error {
+	if db.Dialector.Name() != DriverPostgres {
+		if err := createMigrationTable(db); err != nil {
+			return err
+		}
+		return fn(db)
+	}
+
+	return db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", migrationLockKey).Error; err != nil {
+			return fmt.Errorf("获取迁移锁失败: %v", err)
+		}
+		defer conn.Exec("SELECT pg_advisory_unlock(?)", migrationLockKey)
+
+		if err := createMigrationTable(conn); err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
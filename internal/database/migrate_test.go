@@ -0,0 +1,240 @@
+package database
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"go-ai-copilot/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// 引入迁移之前 AutoMigrate 使用的模型（基线表结构）
+
+type baselineUser struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+	Username  string         `gorm:"uniqueIndex;size:50;not null"`
+	Password  string         `gorm:"size:255;not null"`
+	Nickname  string         `gorm:"size:100"`
+	Email     string         `gorm:"size:100"`
+}
+
+func (baselineUser) TableName() string { return "users" }
+
+type baselineSession struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+	UserID    uint           `gorm:"index;not null"`
+	Title     string         `gorm:"size:255;not null"`
+	Mode      string         `gorm:"size:20;default:chat"`
+}
+
+func (baselineSession) TableName() string { return "sessions" }
+
+type baselineMessage struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+	SessionID uint           `gorm:"index;not null"`
+	UserID    uint           `gorm:"index;not null"`
+	Role      string         `gorm:"size:20;not null"`
+	Content   string         `gorm:"type:text;not null"`
+}
+
+func (baselineMessage) TableName() string { return "chat_messages" }
+
+type baselineDocument struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+	UserID    uint           `gorm:"index;not null"`
+	FileName  string         `gorm:"size:255;not null"`
+	FileType  string         `gorm:"size:50;not null"`
+	FileSize  int64
+	Status    string `gorm:"size:20;not null;default:pending"`
+}
+
+func (baselineDocument) TableName() string { return "rag_documents" }
+
+type baselineChunk struct {
+	ID         uint `gorm:"primarykey"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	DeletedAt  gorm.DeletedAt `gorm:"index"`
+	DocumentID uint           `gorm:"index;not null"`
+	UserID     uint           `gorm:"index;not null"`
+	Content    string         `gorm:"type:text;not null"`
+	Embedding  model.Vector   `gorm:"type:vector(1536)"`
+	ChunkIndex int            `gorm:"not null"`
+}
+
+func (baselineChunk) TableName() string { return "rag_chunks" }
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := openSQLite(Config{Path: t.TempDir() + "/test.db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Logger = logger.Discard
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func TestMigrationsMatchAcrossDialects(t *testing.T) {
+	pg, err := Migrations(DriverPostgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lite, err := Migrations(DriverSQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pg) != len(lite) {
+		t.Fatalf("PostgreSQL 有 %d 个迁移，SQLite 有 %d 个", len(pg), len(lite))
+	}
+	for i := range pg {
+		if pg[i].Version != lite[i].Version || pg[i].Name != lite[i].Name {
+			t.Errorf("第 %d 个迁移不一致: %d_%s / %d_%s", i, pg[i].Version, pg[i].Name, lite[i].Version, lite[i].Name)
+		}
+		if pg[i].Version != int64(i+1) {
+			t.Errorf("迁移版本号不连续: %d_%s", pg[i].Version, pg[i].Name)
+		}
+	}
+}
+
+// 已有数据库由之前的 AutoMigrate 创建，执行全部迁移后保留原有数据并补全新字段
+func TestMigrateUpFromBaseline(t *testing.T) {
+	db := openTestDB(t)
+	if err := db.AutoMigrate(&baselineUser{}, &baselineSession{}, &baselineMessage{}, &baselineDocument{}, &baselineChunk{}); err != nil {
+		t.Fatal(err)
+	}
+	user := baselineUser{Username: "alice", Password: "x"}
+	db.Create(&user)
+	session := baselineSession{UserID: user.ID, Title: "旧会话", Mode: "chat"}
+	db.Create(&session)
+	db.Create(&baselineMessage{SessionID: session.ID, UserID: user.ID, Role: "user", Content: "你好"})
+	doc := baselineDocument{UserID: user.ID, FileName: "guide.md", FileType: "md", FileSize: 10, Status: "completed"}
+	db.Create(&doc)
+	db.Create(&baselineChunk{DocumentID: doc.ID, UserID: user.ID, Content: "内容", Embedding: model.Vector{1, 0}})
+	// 同名的两次上传在升级前是两个文档
+	dup := baselineDocument{UserID: user.ID, FileName: "guide.md", FileType: "md", FileSize: 12, Status: "completed"}
+	db.Create(&dup)
+
+	migrations, err := Migrations(DriverSQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count, err := MigrateUp(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != len(migrations) {
+		t.Fatalf("执行了 %d 个迁移，期望 %d", count, len(migrations))
+	}
+
+	var u model.User
+	if err := db.First(&u, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if u.Username != "alice" || u.Role != model.RoleUser {
+		t.Fatalf("用户 = %+v", u)
+	}
+
+	var s model.Session
+	if err := db.First(&s, session.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if s.Title != "旧会话" || s.Mode != "chat" || s.AssistantID != nil || s.ActiveMessageID != nil || s.Pinned || s.Archived {
+		t.Fatalf("会话 = %+v", s)
+	}
+
+	var messages []model.Message
+	db.Where("session_id = ?", session.ID).Find(&messages)
+	if len(messages) != 1 || messages[0].ParentID != nil || messages[0].Content != "你好" {
+		t.Fatalf("消息 = %+v", messages)
+	}
+
+	var d model.RAGDocument
+	if err := db.First(&d, doc.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if d.KnowledgeBaseID != 0 || d.SourceKey != "guide.md" || d.Version != 1 {
+		t.Fatalf("文档 = %+v", d)
+	}
+	var d2 model.RAGDocument
+	if err := db.First(&d2, dup.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf("guide.md#%d", dup.ID); d2.SourceKey != want {
+		t.Fatalf("重复的文档标识 = %q，期望 %q", d2.SourceKey, want)
+	}
+	if err := db.Create(&model.RAGDocument{UserID: user.ID, FileName: "guide.md", FileType: "md", SourceKey: "guide.md"}).Error; err == nil {
+		t.Fatal("相同的文档标识写入成功，期望唯一索引冲突")
+	}
+
+	var versions []model.RAGDocumentVersion
+	db.Where("document_id = ?", doc.ID).Find(&versions)
+	if len(versions) != 1 || versions[0].Version != 1 || versions[0].ChunkCount != 1 || versions[0].Status != "completed" {
+		t.Fatalf("文档版本 = %+v", versions)
+	}
+
+	var c model.RAGChunk
+	if err := db.Where("document_id = ?", doc.ID).First(&c).Error; err != nil {
+		t.Fatal(err)
+	}
+	var space model.EmbeddingSpace
+	if err := db.Where("model = ?", "").First(&space).Error; err != nil {
+		t.Fatalf("升级前的向量没有归入向量空间: %v", err)
+	}
+	if c.SpaceID != space.ID || c.Path != "guide.md" || c.Version != 1 || c.RetiredVersion != nil {
+		t.Fatalf("分块 = %+v", c)
+	}
+
+	// 已执行的迁移不再执行
+	if count, err := MigrateUp(db); err != nil || count != 0 {
+		t.Fatalf("重复执行迁移: %d, %v", count, err)
+	}
+}
+
+// 全部迁移可以回滚并重新执行
+func TestMigrateDownUp(t *testing.T) {
+	db := openTestDB(t)
+	count, err := MigrateUp(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	down, err := MigrateDown(db, count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if down != count {
+		t.Fatalf("回滚了 %d 个迁移，期望 %d", down, count)
+	}
+	tables, err := db.Migrator().GetTables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range tables {
+		if table != "schema_migrations" && table != "sqlite_sequence" {
+			t.Errorf("回滚后仍有表 %s", table)
+		}
+	}
+
+	if again, err := MigrateUp(db); err != nil || again != count {
+		t.Fatalf("重新执行迁移: %d, %v", again, err)
+	}
+}
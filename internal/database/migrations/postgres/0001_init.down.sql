@@ -0,0 +1,6 @@
+-- 删除全部表（保留 vector 扩展，其他数据库对象可能依赖它）
+DROP TABLE IF EXISTS rag_chunks;
+DROP TABLE IF EXISTS rag_documents;
+DROP TABLE IF EXISTS chat_messages;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,73 @@
+-- 初始表结构（与引入迁移之前 AutoMigrate 创建的结构一致，已有数据库执行时跳过已存在的表和索引）
+-- 之后新增的表和字段见 0002 及以后的迁移
+CREATE EXTENSION IF NOT EXISTS vector;
+
+CREATE TABLE IF NOT EXISTS users (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    username varchar(50) NOT NULL,
+    password varchar(255) NOT NULL,
+    nickname varchar(100),
+    email varchar(100)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users (username);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    user_id bigint NOT NULL,
+    title varchar(255) NOT NULL,
+    mode varchar(20) DEFAULT 'chat'
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_deleted_at ON sessions (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+
+CREATE TABLE IF NOT EXISTS chat_messages (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    session_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    role varchar(20) NOT NULL,
+    content text NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_chat_messages_deleted_at ON chat_messages (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_chat_messages_session_id ON chat_messages (session_id);
+CREATE INDEX IF NOT EXISTS idx_chat_messages_user_id ON chat_messages (user_id);
+
+CREATE TABLE IF NOT EXISTS rag_documents (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    user_id bigint NOT NULL,
+    file_name varchar(255) NOT NULL,
+    file_type varchar(50) NOT NULL,
+    file_size bigint,
+    status varchar(20) NOT NULL DEFAULT 'pending'
+);
+CREATE INDEX IF NOT EXISTS idx_rag_documents_deleted_at ON rag_documents (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_rag_documents_user_id ON rag_documents (user_id);
+
+CREATE TABLE IF NOT EXISTS rag_chunks (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    document_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    content text NOT NULL,
+    embedding vector(1536),
+    chunk_index bigint NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_rag_chunks_deleted_at ON rag_chunks (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_rag_chunks_document_id ON rag_chunks (document_id);
+CREATE INDEX IF NOT EXISTS idx_rag_chunks_user_id ON rag_chunks (user_id);
+-- 0009_embedding_spaces 删除该索引，改为按向量空间创建 HNSW 部分索引
+CREATE INDEX IF NOT EXISTS idx_rag_chunks_embedding ON rag_chunks USING ivfflat (embedding vector_cosine_ops);
@@ -0,0 +1,5 @@
+ALTER TABLE users DROP COLUMN role;
+DROP INDEX IF EXISTS idx_chat_messages_prompt_template_id;
+ALTER TABLE chat_messages DROP COLUMN prompt_version;
+ALTER TABLE chat_messages DROP COLUMN prompt_template_id;
+DROP TABLE IF EXISTS prompt_templates;
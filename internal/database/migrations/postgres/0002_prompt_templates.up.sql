@@ -0,0 +1,23 @@
+-- 提示词模板：同一模式下按版本号递增保存，回复消息记录生成时使用的模板版本
+CREATE TABLE prompt_templates (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    mode varchar(50) NOT NULL,
+    version bigint NOT NULL,
+    content text NOT NULL,
+    description varchar(255),
+    weight bigint NOT NULL DEFAULT 0,
+    pinned boolean NOT NULL DEFAULT false,
+    created_by bigint
+);
+CREATE UNIQUE INDEX idx_prompt_mode_version ON prompt_templates (mode, version);
+CREATE INDEX idx_prompt_templates_deleted_at ON prompt_templates (deleted_at);
+
+ALTER TABLE chat_messages ADD COLUMN prompt_template_id bigint;
+ALTER TABLE chat_messages ADD COLUMN prompt_version bigint;
+CREATE INDEX idx_chat_messages_prompt_template_id ON chat_messages (prompt_template_id);
+
+-- 用户角色，管理员可以管理提示词模板
+ALTER TABLE users ADD COLUMN role varchar(20) NOT NULL DEFAULT 'user';
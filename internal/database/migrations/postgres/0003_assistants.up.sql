@@ -0,0 +1,44 @@
+-- 知识库：文档和分块归属知识库，0 表示用户的默认知识库
+CREATE TABLE knowledge_bases (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    user_id bigint NOT NULL,
+    name varchar(100) NOT NULL,
+    description varchar(255),
+    visibility varchar(20) NOT NULL DEFAULT 'private'
+);
+CREATE INDEX idx_knowledge_bases_deleted_at ON knowledge_bases (deleted_at);
+CREATE INDEX idx_knowledge_bases_user_id ON knowledge_bases (user_id);
+
+ALTER TABLE rag_documents ADD COLUMN knowledge_base_id bigint NOT NULL DEFAULT 0;
+CREATE INDEX idx_rag_documents_knowledge_base_id ON rag_documents (knowledge_base_id);
+ALTER TABLE rag_chunks ADD COLUMN knowledge_base_id bigint NOT NULL DEFAULT 0;
+CREATE INDEX idx_rag_chunks_knowledge_base_id ON rag_chunks (knowledge_base_id);
+
+-- 助手：打包System Prompt、默认模型与温度、关联知识库和启用的工具，创建会话时选择
+CREATE TABLE assistants (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    user_id bigint NOT NULL,
+    name varchar(100) NOT NULL,
+    description varchar(255),
+    mode varchar(50),
+    system_prompt text,
+    model varchar(100),
+    temperature decimal,
+    knowledge_base_ids text,
+    tools text,
+    visibility varchar(20) NOT NULL DEFAULT 'private',
+    builtin boolean NOT NULL DEFAULT false
+);
+CREATE INDEX idx_assistants_deleted_at ON assistants (deleted_at);
+CREATE INDEX idx_assistants_user_id ON assistants (user_id);
+
+-- 会话使用的助手；模式名与提示词模板的模式一致，不再限制为20个字符
+ALTER TABLE sessions ALTER COLUMN mode TYPE varchar(50);
+ALTER TABLE sessions ADD COLUMN assistant_id bigint;
+CREATE INDEX idx_sessions_assistant_id ON sessions (assistant_id);
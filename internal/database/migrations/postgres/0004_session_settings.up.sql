@@ -0,0 +1,17 @@
+-- 会话级配置，优先于助手的默认配置
+ALTER TABLE sessions ADD COLUMN model varchar(100);
+ALTER TABLE sessions ADD COLUMN temperature decimal;
+ALTER TABLE sessions ADD COLUMN knowledge_base_ids text;
+
+-- 会话模式变更记录
+CREATE TABLE session_mode_changes (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    session_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    from_mode varchar(50),
+    to_mode varchar(50),
+    from_assistant_id bigint,
+    to_assistant_id bigint
+);
+CREATE INDEX idx_session_mode_changes_session_id ON session_mode_changes (session_id);
@@ -0,0 +1,5 @@
+-- 消息分支：同一父消息下的多条消息互为分支，会话记录当前分支的最后一条消息
+-- 已有会话的消息没有父消息，第一次编辑或重新生成时按创建顺序串成一条链
+ALTER TABLE chat_messages ADD COLUMN parent_id bigint;
+CREATE INDEX idx_chat_messages_parent_id ON chat_messages (parent_id);
+ALTER TABLE sessions ADD COLUMN active_message_id bigint;
@@ -0,0 +1,21 @@
+-- 会话分享链接：创建时冻结当前分支的消息
+CREATE TABLE session_shares (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    session_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    token varchar(64) NOT NULL,
+    title varchar(255) NOT NULL,
+    mode varchar(50),
+    messages text,
+    password_hash varchar(255),
+    expires_at timestamptz,
+    revoked_at timestamptz,
+    view_count bigint NOT NULL DEFAULT 0
+);
+CREATE UNIQUE INDEX idx_session_shares_token ON session_shares (token);
+CREATE INDEX idx_session_shares_deleted_at ON session_shares (deleted_at);
+CREATE INDEX idx_session_shares_session_id ON session_shares (session_id);
+CREATE INDEX idx_session_shares_user_id ON session_shares (user_id);
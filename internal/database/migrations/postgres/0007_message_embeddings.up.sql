@@ -0,0 +1,12 @@
+-- 消息向量，用于聊天记录的语义搜索
+CREATE TABLE message_embeddings (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    message_id bigint NOT NULL,
+    session_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    embedding vector(1536)
+);
+CREATE UNIQUE INDEX idx_message_embeddings_message_id ON message_embeddings (message_id);
+CREATE INDEX idx_message_embeddings_session_id ON message_embeddings (session_id);
+CREATE INDEX idx_message_embeddings_user_id ON message_embeddings (user_id);
@@ -0,0 +1,20 @@
+-- 会话整理：标签、置顶、文件夹、归档，以及首轮对话后自动生成标题
+ALTER TABLE sessions ADD COLUMN tags text;
+ALTER TABLE sessions ADD COLUMN pinned boolean NOT NULL DEFAULT false;
+ALTER TABLE sessions ADD COLUMN folder_id bigint;
+ALTER TABLE sessions ADD COLUMN archived boolean NOT NULL DEFAULT false;
+ALTER TABLE sessions ADD COLUMN auto_title boolean NOT NULL DEFAULT false;
+CREATE INDEX idx_sessions_pinned ON sessions (pinned);
+CREATE INDEX idx_sessions_folder_id ON sessions (folder_id);
+CREATE INDEX idx_sessions_archived ON sessions (archived);
+
+CREATE TABLE session_folders (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    user_id bigint NOT NULL,
+    name varchar(100) NOT NULL
+);
+CREATE INDEX idx_session_folders_deleted_at ON session_folders (deleted_at);
+CREATE INDEX idx_session_folders_user_id ON session_folders (user_id);
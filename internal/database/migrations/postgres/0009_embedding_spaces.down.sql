@@ -0,0 +1,16 @@
+-- 只保留 1536 维的向量，其他维度的向量无法放回 vector(1536) 列
+DELETE FROM message_embeddings WHERE vector_dims(embedding) <> 1536;
+DELETE FROM message_embeddings a USING message_embeddings b
+    WHERE a.message_id = b.message_id AND a.id < b.id;
+DROP INDEX IF EXISTS idx_message_embeddings_space_message;
+ALTER TABLE message_embeddings DROP COLUMN space_id;
+ALTER TABLE message_embeddings ALTER COLUMN embedding TYPE vector(1536);
+CREATE UNIQUE INDEX idx_message_embeddings_message_id ON message_embeddings (message_id);
+
+UPDATE rag_chunks SET embedding = NULL WHERE vector_dims(embedding) <> 1536;
+-- 删除列时按空间创建的部分索引一并删除
+ALTER TABLE rag_chunks DROP COLUMN space_id;
+ALTER TABLE rag_chunks ALTER COLUMN embedding TYPE vector(1536);
+CREATE INDEX idx_rag_chunks_embedding ON rag_chunks USING ivfflat (embedding vector_cosine_ops);
+
+DROP TABLE embedding_spaces;
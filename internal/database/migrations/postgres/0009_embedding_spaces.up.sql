@@ -0,0 +1,28 @@
+-- 向量空间：同一空间的向量由同一模型（同一版本）生成、维度相同
+CREATE TABLE embedding_spaces (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    model varchar(100) NOT NULL,
+    version varchar(50) NOT NULL DEFAULT '',
+    dimensions bigint NOT NULL
+);
+CREATE UNIQUE INDEX idx_embedding_spaces_model_version ON embedding_spaces (model, version);
+
+-- 升级前的向量都由当时配置的模型生成，先归入模型未知（model为空）的空间，服务启动时归属到配置的模型
+INSERT INTO embedding_spaces (created_at, model, version, dimensions)
+SELECT now(), '', '', 1536
+WHERE EXISTS (SELECT 1 FROM rag_chunks WHERE embedding IS NOT NULL)
+   OR EXISTS (SELECT 1 FROM message_embeddings);
+
+-- 向量列不再限定维度，按空间创建部分索引（见 store.SpaceRepository）
+DROP INDEX IF EXISTS idx_rag_chunks_embedding;
+ALTER TABLE rag_chunks ALTER COLUMN embedding TYPE vector;
+ALTER TABLE rag_chunks ADD COLUMN space_id bigint NOT NULL DEFAULT 0;
+UPDATE rag_chunks SET space_id = (SELECT id FROM embedding_spaces WHERE model = '') WHERE embedding IS NOT NULL;
+CREATE INDEX idx_rag_chunks_space_id ON rag_chunks (space_id);
+
+ALTER TABLE message_embeddings ALTER COLUMN embedding TYPE vector;
+ALTER TABLE message_embeddings ADD COLUMN space_id bigint NOT NULL DEFAULT 0;
+UPDATE message_embeddings SET space_id = (SELECT id FROM embedding_spaces WHERE model = '');
+DROP INDEX IF EXISTS idx_message_embeddings_message_id;
+CREATE UNIQUE INDEX idx_message_embeddings_space_message ON message_embeddings (message_id, space_id);
@@ -0,0 +1,24 @@
+-- 重建向量任务：进度记录在任务中，中断后可从 last_chunk_id 继续
+CREATE TABLE reindex_jobs (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    space_id bigint NOT NULL,
+    knowledge_base_id bigint,
+    status varchar(20) NOT NULL DEFAULT 'pending',
+    total bigint NOT NULL DEFAULT 0,
+    processed bigint NOT NULL DEFAULT 0,
+    last_chunk_id bigint NOT NULL DEFAULT 0,
+    error text NOT NULL DEFAULT '',
+    finished_at timestamptz
+);
+CREATE INDEX idx_reindex_jobs_knowledge_base_id ON reindex_jobs (knowledge_base_id);
+CREATE INDEX idx_reindex_jobs_status ON reindex_jobs (status);
+
+-- 影子向量：任务完成时在一个事务中替换 rag_chunks 的向量
+CREATE TABLE reindex_embeddings (
+    job_id bigint NOT NULL,
+    chunk_id bigint NOT NULL,
+    embedding vector NOT NULL,
+    PRIMARY KEY (job_id, chunk_id)
+);
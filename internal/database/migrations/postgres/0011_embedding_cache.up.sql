@@ -0,0 +1,9 @@
+-- 向量缓存：按向量空间和内容哈希去重，重复上传或不同用户的相同分块只向量化一次
+-- 缓存可以随时清空，不影响已保存的分块
+CREATE TABLE embedding_cache (
+    space_id bigint NOT NULL,
+    content_hash varchar(64) NOT NULL,
+    embedding vector NOT NULL,
+    created_at timestamptz,
+    PRIMARY KEY (space_id, content_hash)
+);
@@ -0,0 +1,35 @@
+-- 文档标识与当前版本：相同标识的上传作为同一文档的新版本
+ALTER TABLE rag_documents ADD COLUMN source_key varchar(500) NOT NULL DEFAULT '';
+ALTER TABLE rag_documents ADD COLUMN content_hash varchar(64) NOT NULL DEFAULT '';
+ALTER TABLE rag_documents ADD COLUMN version bigint NOT NULL DEFAULT 0;
+UPDATE rag_documents SET source_key = file_name, version = 1;
+CREATE INDEX idx_rag_documents_source ON rag_documents (source_key);
+
+-- 文档版本
+CREATE TABLE rag_document_versions (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    document_id bigint NOT NULL,
+    version bigint NOT NULL,
+    file_name varchar(255) NOT NULL,
+    file_size bigint,
+    file_path varchar(500) NOT NULL DEFAULT '',
+    content_hash varchar(64) NOT NULL DEFAULT '',
+    status varchar(20) NOT NULL DEFAULT 'pending',
+    chunk_count bigint NOT NULL DEFAULT 0,
+    added_chunks bigint NOT NULL DEFAULT 0,
+    removed_chunks bigint NOT NULL DEFAULT 0
+);
+CREATE UNIQUE INDEX idx_rag_document_versions_document_version ON rag_document_versions (document_id, version);
+
+-- 已有文档记为版本1（升级前的上传文件未与文档关联，file_path 为空）
+INSERT INTO rag_document_versions (created_at, document_id, version, file_name, file_size, status, chunk_count, added_chunks)
+SELECT d.created_at, d.id, 1, d.file_name, d.file_size, d.status, c.n, c.n
+FROM rag_documents d
+CROSS JOIN LATERAL (SELECT count(*) AS n FROM rag_chunks WHERE document_id = d.id AND deleted_at IS NULL) c;
+
+-- 分块所属版本；内容哈希在重新上传时按需计算，已有分块留空
+ALTER TABLE rag_chunks ADD COLUMN content_hash varchar(64) NOT NULL DEFAULT '';
+ALTER TABLE rag_chunks ADD COLUMN version bigint NOT NULL DEFAULT 1;
+ALTER TABLE rag_chunks ADD COLUMN retired_version bigint;
+CREATE INDEX idx_rag_chunks_retired_version ON rag_chunks (retired_version);
@@ -0,0 +1,3 @@
+-- 上传文件改为通过文件存储（本地目录或S3兼容存储）读写，记录存储中的Key而不是本地路径
+ALTER TABLE rag_document_versions RENAME COLUMN file_path TO blob_key;
+UPDATE rag_document_versions SET blob_key = substr(blob_key, 9) WHERE blob_key LIKE 'uploads/%';
@@ -0,0 +1,13 @@
+-- 目录同步连接器的同步记录：大小和修改时间未变化的文件不再读取，重启后不重新处理全部文件
+CREATE TABLE connector_files (
+    id bigserial PRIMARY KEY,
+    connector varchar(100) NOT NULL,
+    path varchar(500) NOT NULL,
+    knowledge_base_id bigint NOT NULL,
+    size bigint NOT NULL,
+    mod_time bigint NOT NULL,
+    content_hash varchar(64) NOT NULL,
+    document_id bigint NOT NULL,
+    synced_at timestamptz
+);
+CREATE UNIQUE INDEX idx_connector_files_path ON connector_files (connector, path);
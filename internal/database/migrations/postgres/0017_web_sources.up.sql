@@ -0,0 +1,25 @@
+-- 网页来源：抓取到的页面存为文档，同步记录复用 connector_files（连接器为 web:<id>）
+CREATE TABLE web_sources (
+    id bigserial PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    user_id bigint NOT NULL,
+    knowledge_base_id bigint NOT NULL,
+    url varchar(500) NOT NULL,
+    sitemap boolean NOT NULL DEFAULT false,
+    max_depth bigint NOT NULL DEFAULT 0,
+    max_pages bigint NOT NULL DEFAULT 0,
+    same_host boolean NOT NULL DEFAULT true,
+    respect_robots boolean NOT NULL DEFAULT true,
+    refresh_interval bigint NOT NULL DEFAULT 0,
+    tags text NOT NULL DEFAULT '[]',
+    metadata text NOT NULL DEFAULT '{}',
+    status varchar(20) NOT NULL DEFAULT 'pending',
+    error text NOT NULL DEFAULT '',
+    page_count bigint NOT NULL DEFAULT 0,
+    last_crawled_at timestamptz,
+    next_crawl_at timestamptz
+);
+CREATE INDEX idx_web_sources_user_id ON web_sources (user_id);
+CREATE INDEX idx_web_sources_knowledge_base_id ON web_sources (knowledge_base_id);
+CREATE INDEX idx_web_sources_next_crawl_at ON web_sources (next_crawl_at);
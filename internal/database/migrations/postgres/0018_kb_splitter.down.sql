@@ -0,0 +1,3 @@
+ALTER TABLE knowledge_bases DROP COLUMN chunk_overlap;
+ALTER TABLE knowledge_bases DROP COLUMN chunk_size;
+ALTER TABLE knowledge_bases DROP COLUMN chunk_strategy;
@@ -0,0 +1,4 @@
+-- 知识库的分块设置，chunk_strategy 为空时使用默认设置
+ALTER TABLE knowledge_bases ADD COLUMN chunk_strategy varchar(20) NOT NULL DEFAULT '';
+ALTER TABLE knowledge_bases ADD COLUMN chunk_size bigint NOT NULL DEFAULT 0;
+ALTER TABLE knowledge_bases ADD COLUMN chunk_overlap bigint NOT NULL DEFAULT 0;
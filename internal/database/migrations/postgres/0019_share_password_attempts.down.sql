@@ -0,0 +1,2 @@
+ALTER TABLE session_shares DROP COLUMN locked_until;
+ALTER TABLE session_shares DROP COLUMN password_failures;
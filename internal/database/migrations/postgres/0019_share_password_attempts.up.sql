@@ -0,0 +1,3 @@
+-- 分享链接的密码错误次数，连续错误达到上限后暂时锁定
+ALTER TABLE session_shares ADD COLUMN password_failures bigint NOT NULL DEFAULT 0;
+ALTER TABLE session_shares ADD COLUMN locked_until timestamptz;
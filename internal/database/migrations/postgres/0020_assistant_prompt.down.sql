@@ -0,0 +1 @@
+ALTER TABLE assistants DROP COLUMN templated;
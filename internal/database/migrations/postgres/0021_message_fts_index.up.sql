@@ -0,0 +1,3 @@
+-- 聊天记录关键词搜索的全文检索索引（默认配置 simple），表达式必须与 store.Keyword 中的一致
+-- 其他配置（如 zhparser 的 chinese）的索引由运维创建，启动时检查，见 store.CheckTextIndex
+CREATE INDEX IF NOT EXISTS idx_chat_messages_fts_simple ON chat_messages USING gin (to_tsvector('simple', content));
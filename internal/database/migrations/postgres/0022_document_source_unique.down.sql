@@ -0,0 +1,3 @@
+-- 去重时追加的文档ID保留
+DROP INDEX IF EXISTS idx_rag_documents_source;
+CREATE INDEX idx_rag_documents_source ON rag_documents (source_key);
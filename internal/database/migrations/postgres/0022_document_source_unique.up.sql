@@ -0,0 +1,11 @@
+-- 同一用户同一知识库内文档标识唯一（并发上传同一文档时只有一个能创建）
+-- 升级前同名上传回填出的重复标识，保留最早的文档，其余追加文档ID
+UPDATE rag_documents SET source_key = source_key || '#' || id
+WHERE deleted_at IS NULL AND EXISTS (
+    SELECT 1 FROM rag_documents o
+    WHERE o.deleted_at IS NULL AND o.user_id = rag_documents.user_id
+      AND o.knowledge_base_id = rag_documents.knowledge_base_id
+      AND o.source_key = rag_documents.source_key AND o.id < rag_documents.id
+);
+DROP INDEX IF EXISTS idx_rag_documents_source;
+CREATE UNIQUE INDEX idx_rag_documents_source ON rag_documents (user_id, knowledge_base_id, source_key) WHERE deleted_at IS NULL;
@@ -0,0 +1 @@
+ALTER TABLE rag_document_versions DROP COLUMN updated_at;
@@ -0,0 +1,3 @@
+-- 处理中版本的心跳，处理的实例退出后由其他实例（或重启后的服务）接管
+ALTER TABLE rag_document_versions ADD COLUMN updated_at timestamptz;
+UPDATE rag_document_versions SET updated_at = created_at;
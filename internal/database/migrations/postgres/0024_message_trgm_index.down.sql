@@ -0,0 +1,2 @@
+-- pg_trgm 扩展可能被其他对象使用，保留
+DROP INDEX IF EXISTS idx_chat_messages_content_trgm;
@@ -0,0 +1,4 @@
+-- 聊天记录关键词搜索默认使用 pg_trgm 三元组索引（text_search_config: trigram），
+-- 子串匹配（ILIKE）可以使用索引，中文等不分词的语言也能匹配，表达式必须与 store.Keyword 中的一致
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX IF NOT EXISTS idx_chat_messages_content_trgm ON chat_messages USING gin (content gin_trgm_ops);
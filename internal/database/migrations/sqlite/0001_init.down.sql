@@ -0,0 +1,6 @@
+-- 删除全部表
+DROP TABLE IF EXISTS rag_chunks;
+DROP TABLE IF EXISTS rag_documents;
+DROP TABLE IF EXISTS chat_messages;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS users;
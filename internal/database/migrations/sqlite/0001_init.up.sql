@@ -0,0 +1,69 @@
+-- 初始表结构（与 PostgreSQL 的 0001_init 对应），之后新增的表和字段见 0002 及以后的迁移
+
+CREATE TABLE IF NOT EXISTS users (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    username text NOT NULL,
+    password text NOT NULL,
+    nickname text,
+    email text
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users (username);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    user_id integer NOT NULL,
+    title text NOT NULL,
+    mode text DEFAULT 'chat'
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_deleted_at ON sessions (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+
+CREATE TABLE IF NOT EXISTS chat_messages (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    session_id integer NOT NULL,
+    user_id integer NOT NULL,
+    role text NOT NULL,
+    content text NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_chat_messages_deleted_at ON chat_messages (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_chat_messages_session_id ON chat_messages (session_id);
+CREATE INDEX IF NOT EXISTS idx_chat_messages_user_id ON chat_messages (user_id);
+
+CREATE TABLE IF NOT EXISTS rag_documents (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    user_id integer NOT NULL,
+    file_name text NOT NULL,
+    file_type text NOT NULL,
+    file_size integer,
+    status text NOT NULL DEFAULT 'pending'
+);
+CREATE INDEX IF NOT EXISTS idx_rag_documents_deleted_at ON rag_documents (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_rag_documents_user_id ON rag_documents (user_id);
+
+CREATE TABLE IF NOT EXISTS rag_chunks (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    document_id integer NOT NULL,
+    user_id integer NOT NULL,
+    content text NOT NULL,
+    embedding text,
+    chunk_index integer NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_rag_chunks_deleted_at ON rag_chunks (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_rag_chunks_document_id ON rag_chunks (document_id);
+CREATE INDEX IF NOT EXISTS idx_rag_chunks_user_id ON rag_chunks (user_id);
@@ -0,0 +1,23 @@
+-- 提示词模板：同一模式下按版本号递增保存，回复消息记录生成时使用的模板版本
+CREATE TABLE prompt_templates (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    mode text NOT NULL,
+    version integer NOT NULL,
+    content text NOT NULL,
+    description text,
+    weight integer NOT NULL DEFAULT 0,
+    pinned numeric NOT NULL DEFAULT false,
+    created_by integer
+);
+CREATE UNIQUE INDEX idx_prompt_mode_version ON prompt_templates (mode, version);
+CREATE INDEX idx_prompt_templates_deleted_at ON prompt_templates (deleted_at);
+
+ALTER TABLE chat_messages ADD COLUMN prompt_template_id integer;
+ALTER TABLE chat_messages ADD COLUMN prompt_version integer;
+CREATE INDEX idx_chat_messages_prompt_template_id ON chat_messages (prompt_template_id);
+
+-- 用户角色，管理员可以管理提示词模板
+ALTER TABLE users ADD COLUMN role text NOT NULL DEFAULT 'user';
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_sessions_assistant_id;
+ALTER TABLE sessions DROP COLUMN assistant_id;
+
+DROP TABLE IF EXISTS assistants;
+
+DROP INDEX IF EXISTS idx_rag_chunks_knowledge_base_id;
+ALTER TABLE rag_chunks DROP COLUMN knowledge_base_id;
+DROP INDEX IF EXISTS idx_rag_documents_knowledge_base_id;
+ALTER TABLE rag_documents DROP COLUMN knowledge_base_id;
+
+DROP TABLE IF EXISTS knowledge_bases;
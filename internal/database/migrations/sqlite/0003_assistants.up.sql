@@ -0,0 +1,43 @@
+-- 知识库：文档和分块归属知识库，0 表示用户的默认知识库
+CREATE TABLE knowledge_bases (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    user_id integer NOT NULL,
+    name text NOT NULL,
+    description text,
+    visibility text NOT NULL DEFAULT 'private'
+);
+CREATE INDEX idx_knowledge_bases_deleted_at ON knowledge_bases (deleted_at);
+CREATE INDEX idx_knowledge_bases_user_id ON knowledge_bases (user_id);
+
+ALTER TABLE rag_documents ADD COLUMN knowledge_base_id integer NOT NULL DEFAULT 0;
+CREATE INDEX idx_rag_documents_knowledge_base_id ON rag_documents (knowledge_base_id);
+ALTER TABLE rag_chunks ADD COLUMN knowledge_base_id integer NOT NULL DEFAULT 0;
+CREATE INDEX idx_rag_chunks_knowledge_base_id ON rag_chunks (knowledge_base_id);
+
+-- 助手：打包System Prompt、默认模型与温度、关联知识库和启用的工具，创建会话时选择
+CREATE TABLE assistants (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    user_id integer NOT NULL,
+    name text NOT NULL,
+    description text,
+    mode text,
+    system_prompt text,
+    model text,
+    temperature real,
+    knowledge_base_ids text,
+    tools text,
+    visibility text NOT NULL DEFAULT 'private',
+    builtin numeric NOT NULL DEFAULT false
+);
+CREATE INDEX idx_assistants_deleted_at ON assistants (deleted_at);
+CREATE INDEX idx_assistants_user_id ON assistants (user_id);
+
+-- 会话使用的助手
+ALTER TABLE sessions ADD COLUMN assistant_id integer;
+CREATE INDEX idx_sessions_assistant_id ON sessions (assistant_id);
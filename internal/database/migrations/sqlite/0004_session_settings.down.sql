@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS session_mode_changes;
+ALTER TABLE sessions DROP COLUMN knowledge_base_ids;
+ALTER TABLE sessions DROP COLUMN temperature;
+ALTER TABLE sessions DROP COLUMN model;
@@ -0,0 +1,17 @@
+-- 会话级配置，优先于助手的默认配置
+ALTER TABLE sessions ADD COLUMN model text;
+ALTER TABLE sessions ADD COLUMN temperature real;
+ALTER TABLE sessions ADD COLUMN knowledge_base_ids text;
+
+-- 会话模式变更记录
+CREATE TABLE session_mode_changes (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    session_id integer NOT NULL,
+    user_id integer NOT NULL,
+    from_mode text,
+    to_mode text,
+    from_assistant_id integer,
+    to_assistant_id integer
+);
+CREATE INDEX idx_session_mode_changes_session_id ON session_mode_changes (session_id);
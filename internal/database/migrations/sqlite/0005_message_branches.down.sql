@@ -0,0 +1,3 @@
+ALTER TABLE sessions DROP COLUMN active_message_id;
+DROP INDEX IF EXISTS idx_chat_messages_parent_id;
+ALTER TABLE chat_messages DROP COLUMN parent_id;
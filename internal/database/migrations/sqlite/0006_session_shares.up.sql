@@ -0,0 +1,21 @@
+-- 会话分享链接：创建时冻结当前分支的消息
+CREATE TABLE session_shares (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    session_id integer NOT NULL,
+    user_id integer NOT NULL,
+    token text NOT NULL,
+    title text NOT NULL,
+    mode text,
+    messages text,
+    password_hash text,
+    expires_at datetime,
+    revoked_at datetime,
+    view_count integer NOT NULL DEFAULT 0
+);
+CREATE UNIQUE INDEX idx_session_shares_token ON session_shares (token);
+CREATE INDEX idx_session_shares_deleted_at ON session_shares (deleted_at);
+CREATE INDEX idx_session_shares_session_id ON session_shares (session_id);
+CREATE INDEX idx_session_shares_user_id ON session_shares (user_id);
@@ -0,0 +1,12 @@
+-- 消息向量，用于聊天记录的语义搜索
+CREATE TABLE message_embeddings (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    message_id integer NOT NULL,
+    session_id integer NOT NULL,
+    user_id integer NOT NULL,
+    embedding text
+);
+CREATE UNIQUE INDEX idx_message_embeddings_message_id ON message_embeddings (message_id);
+CREATE INDEX idx_message_embeddings_session_id ON message_embeddings (session_id);
+CREATE INDEX idx_message_embeddings_user_id ON message_embeddings (user_id);
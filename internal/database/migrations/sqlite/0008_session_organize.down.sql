@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS session_folders;
+DROP INDEX IF EXISTS idx_sessions_archived;
+DROP INDEX IF EXISTS idx_sessions_folder_id;
+DROP INDEX IF EXISTS idx_sessions_pinned;
+ALTER TABLE sessions DROP COLUMN auto_title;
+ALTER TABLE sessions DROP COLUMN archived;
+ALTER TABLE sessions DROP COLUMN folder_id;
+ALTER TABLE sessions DROP COLUMN pinned;
+ALTER TABLE sessions DROP COLUMN tags;
@@ -0,0 +1,9 @@
+DELETE FROM message_embeddings WHERE id NOT IN (SELECT MAX(id) FROM message_embeddings GROUP BY message_id);
+DROP INDEX IF EXISTS idx_message_embeddings_space_message;
+ALTER TABLE message_embeddings DROP COLUMN space_id;
+CREATE UNIQUE INDEX idx_message_embeddings_message_id ON message_embeddings (message_id);
+
+DROP INDEX IF EXISTS idx_rag_chunks_space_id;
+ALTER TABLE rag_chunks DROP COLUMN space_id;
+
+DROP TABLE embedding_spaces;
@@ -0,0 +1,24 @@
+-- 向量空间：同一空间的向量由同一模型（同一版本）生成、维度相同
+CREATE TABLE embedding_spaces (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    model text NOT NULL,
+    version text NOT NULL DEFAULT '',
+    dimensions integer NOT NULL
+);
+CREATE UNIQUE INDEX idx_embedding_spaces_model_version ON embedding_spaces (model, version);
+
+-- 升级前的向量都由当时配置的模型生成，先归入模型未知（model为空）的空间，服务启动时归属到配置的模型
+INSERT INTO embedding_spaces (created_at, model, version, dimensions)
+SELECT CURRENT_TIMESTAMP, '', '', 1536
+WHERE EXISTS (SELECT 1 FROM rag_chunks WHERE embedding IS NOT NULL)
+   OR EXISTS (SELECT 1 FROM message_embeddings);
+
+ALTER TABLE rag_chunks ADD COLUMN space_id integer NOT NULL DEFAULT 0;
+UPDATE rag_chunks SET space_id = (SELECT id FROM embedding_spaces WHERE model = '') WHERE embedding IS NOT NULL;
+CREATE INDEX idx_rag_chunks_space_id ON rag_chunks (space_id);
+
+ALTER TABLE message_embeddings ADD COLUMN space_id integer NOT NULL DEFAULT 0;
+UPDATE message_embeddings SET space_id = (SELECT id FROM embedding_spaces WHERE model = '');
+DROP INDEX IF EXISTS idx_message_embeddings_message_id;
+CREATE UNIQUE INDEX idx_message_embeddings_space_message ON message_embeddings (message_id, space_id);
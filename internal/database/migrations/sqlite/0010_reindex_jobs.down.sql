@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS reindex_embeddings;
+DROP TABLE IF EXISTS reindex_jobs;
@@ -0,0 +1,24 @@
+-- 重建向量任务：进度记录在任务中，中断后可从 last_chunk_id 继续
+CREATE TABLE reindex_jobs (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    space_id integer NOT NULL,
+    knowledge_base_id integer,
+    status text NOT NULL DEFAULT 'pending',
+    total integer NOT NULL DEFAULT 0,
+    processed integer NOT NULL DEFAULT 0,
+    last_chunk_id integer NOT NULL DEFAULT 0,
+    error text NOT NULL DEFAULT '',
+    finished_at datetime
+);
+CREATE INDEX idx_reindex_jobs_knowledge_base_id ON reindex_jobs (knowledge_base_id);
+CREATE INDEX idx_reindex_jobs_status ON reindex_jobs (status);
+
+-- 影子向量：任务完成时在一个事务中替换 rag_chunks 的向量
+CREATE TABLE reindex_embeddings (
+    job_id integer NOT NULL,
+    chunk_id integer NOT NULL,
+    embedding text NOT NULL,
+    PRIMARY KEY (job_id, chunk_id)
+);
@@ -0,0 +1,13 @@
+-- 只保留当前版本的分块
+DELETE FROM rag_chunks WHERE retired_version IS NOT NULL;
+DROP INDEX IF EXISTS idx_rag_chunks_retired_version;
+ALTER TABLE rag_chunks DROP COLUMN retired_version;
+ALTER TABLE rag_chunks DROP COLUMN version;
+ALTER TABLE rag_chunks DROP COLUMN content_hash;
+
+DROP TABLE IF EXISTS rag_document_versions;
+
+DROP INDEX IF EXISTS idx_rag_documents_source;
+ALTER TABLE rag_documents DROP COLUMN version;
+ALTER TABLE rag_documents DROP COLUMN content_hash;
+ALTER TABLE rag_documents DROP COLUMN source_key;
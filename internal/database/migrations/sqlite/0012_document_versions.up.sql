@@ -0,0 +1,36 @@
+-- 文档标识与当前版本：相同标识的上传作为同一文档的新版本
+ALTER TABLE rag_documents ADD COLUMN source_key text NOT NULL DEFAULT '';
+ALTER TABLE rag_documents ADD COLUMN content_hash text NOT NULL DEFAULT '';
+ALTER TABLE rag_documents ADD COLUMN version integer NOT NULL DEFAULT 0;
+UPDATE rag_documents SET source_key = file_name, version = 1;
+CREATE INDEX idx_rag_documents_source ON rag_documents (source_key);
+
+-- 文档版本
+CREATE TABLE rag_document_versions (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    document_id integer NOT NULL,
+    version integer NOT NULL,
+    file_name text NOT NULL,
+    file_size integer,
+    file_path text NOT NULL DEFAULT '',
+    content_hash text NOT NULL DEFAULT '',
+    status text NOT NULL DEFAULT 'pending',
+    chunk_count integer NOT NULL DEFAULT 0,
+    added_chunks integer NOT NULL DEFAULT 0,
+    removed_chunks integer NOT NULL DEFAULT 0
+);
+CREATE UNIQUE INDEX idx_rag_document_versions_document_version ON rag_document_versions (document_id, version);
+
+-- 已有文档记为版本1（升级前的上传文件未与文档关联，file_path 为空）
+INSERT INTO rag_document_versions (created_at, document_id, version, file_name, file_size, status, chunk_count, added_chunks)
+SELECT d.created_at, d.id, 1, d.file_name, d.file_size, d.status,
+       (SELECT count(*) FROM rag_chunks WHERE document_id = d.id AND deleted_at IS NULL),
+       (SELECT count(*) FROM rag_chunks WHERE document_id = d.id AND deleted_at IS NULL)
+FROM rag_documents d;
+
+-- 分块所属版本；内容哈希在重新上传时按需计算，已有分块留空
+ALTER TABLE rag_chunks ADD COLUMN content_hash text NOT NULL DEFAULT '';
+ALTER TABLE rag_chunks ADD COLUMN version integer NOT NULL DEFAULT 1;
+ALTER TABLE rag_chunks ADD COLUMN retired_version integer;
+CREATE INDEX idx_rag_chunks_retired_version ON rag_chunks (retired_version);
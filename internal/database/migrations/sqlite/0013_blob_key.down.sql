@@ -0,0 +1,2 @@
+UPDATE rag_document_versions SET blob_key = 'uploads/' || blob_key WHERE blob_key <> '';
+ALTER TABLE rag_document_versions RENAME COLUMN blob_key TO file_path;
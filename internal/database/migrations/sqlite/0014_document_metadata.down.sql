@@ -0,0 +1,4 @@
+ALTER TABLE rag_chunks DROP COLUMN metadata;
+ALTER TABLE rag_chunks DROP COLUMN tags;
+ALTER TABLE rag_documents DROP COLUMN metadata;
+ALTER TABLE rag_documents DROP COLUMN tags;
@@ -0,0 +1,5 @@
+-- 文档的标签和元数据（JSON），分块冗余所属文档的标签和元数据，检索时与向量检索在同一条SQL中过滤
+ALTER TABLE rag_documents ADD COLUMN tags text NOT NULL DEFAULT '[]';
+ALTER TABLE rag_documents ADD COLUMN metadata text NOT NULL DEFAULT '{}';
+ALTER TABLE rag_chunks ADD COLUMN tags text NOT NULL DEFAULT '[]';
+ALTER TABLE rag_chunks ADD COLUMN metadata text NOT NULL DEFAULT '{}';
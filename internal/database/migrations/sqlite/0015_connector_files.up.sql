@@ -0,0 +1,13 @@
+-- 目录同步连接器的同步记录：大小和修改时间未变化的文件不再读取，重启后不重新处理全部文件
+CREATE TABLE connector_files (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    connector text NOT NULL,
+    path text NOT NULL,
+    knowledge_base_id integer NOT NULL,
+    size integer NOT NULL,
+    mod_time integer NOT NULL,
+    content_hash text NOT NULL,
+    document_id integer NOT NULL,
+    synced_at datetime
+);
+CREATE UNIQUE INDEX idx_connector_files_path ON connector_files (connector, path);
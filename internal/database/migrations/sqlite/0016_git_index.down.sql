@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS connector_states;
+ALTER TABLE rag_chunks DROP COLUMN end_line;
+ALTER TABLE rag_chunks DROP COLUMN start_line;
+ALTER TABLE rag_chunks DROP COLUMN path;
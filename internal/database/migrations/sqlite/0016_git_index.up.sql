@@ -0,0 +1,16 @@
+-- 分块在原文件中的位置：冗余所属文档的路径，以及分块的起止行号，回答可以引用 path:line
+-- 升级前的分块没有行号（为0），文档重新上传或同步后补全
+ALTER TABLE rag_chunks ADD COLUMN path text NOT NULL DEFAULT '';
+ALTER TABLE rag_chunks ADD COLUMN start_line integer NOT NULL DEFAULT 0;
+ALTER TABLE rag_chunks ADD COLUMN end_line integer NOT NULL DEFAULT 0;
+UPDATE rag_chunks SET path = (SELECT source_key FROM rag_documents WHERE rag_documents.id = rag_chunks.document_id)
+WHERE EXISTS (SELECT 1 FROM rag_documents WHERE rag_documents.id = rag_chunks.document_id);
+
+-- 连接器的同步状态：git连接器记录已索引的提交，之后只处理该提交与最新提交之间变化的文件
+CREATE TABLE connector_states (
+    connector text PRIMARY KEY,
+    knowledge_base_id integer NOT NULL,
+    commit_sha text NOT NULL,
+    config_hash text NOT NULL DEFAULT '',
+    synced_at datetime
+);
@@ -0,0 +1,25 @@
+-- 网页来源：抓取到的页面存为文档，同步记录复用 connector_files（连接器为 web:<id>）
+CREATE TABLE web_sources (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime,
+    updated_at datetime,
+    user_id integer NOT NULL,
+    knowledge_base_id integer NOT NULL,
+    url text NOT NULL,
+    sitemap numeric NOT NULL DEFAULT false,
+    max_depth integer NOT NULL DEFAULT 0,
+    max_pages integer NOT NULL DEFAULT 0,
+    same_host numeric NOT NULL DEFAULT true,
+    respect_robots numeric NOT NULL DEFAULT true,
+    refresh_interval integer NOT NULL DEFAULT 0,
+    tags text NOT NULL DEFAULT '[]',
+    metadata text NOT NULL DEFAULT '{}',
+    status text NOT NULL DEFAULT 'pending',
+    error text NOT NULL DEFAULT '',
+    page_count integer NOT NULL DEFAULT 0,
+    last_crawled_at datetime,
+    next_crawl_at datetime
+);
+CREATE INDEX idx_web_sources_user_id ON web_sources (user_id);
+CREATE INDEX idx_web_sources_knowledge_base_id ON web_sources (knowledge_base_id);
+CREATE INDEX idx_web_sources_next_crawl_at ON web_sources (next_crawl_at);
@@ -0,0 +1,4 @@
+-- 助手的自定义System Prompt默认原样使用，templated 为 true 时按 text/template 渲染
+-- 已有的含模板语法的System Prompt在创建时已通过模板校验，保持按模板渲染
+ALTER TABLE assistants ADD COLUMN templated numeric NOT NULL DEFAULT false;
+UPDATE assistants SET templated = true WHERE system_prompt LIKE '%{{%';
@@ -0,0 +1,2 @@
+-- SQLite没有全文检索索引，无需回滚
+SELECT 1;
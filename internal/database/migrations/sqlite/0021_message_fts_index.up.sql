@@ -0,0 +1,2 @@
+-- SQLite没有全文检索，关键词搜索使用子串匹配，无需索引
+SELECT 1;
@@ -0,0 +1,71 @@
+package events
+
+import (
+	"sync"
+)
+
+// 事件类型
+const (
+	TypeSessionUpdated = "session_updated" // 会话标题、标签等由后台任务更新
+)
+
+// Event 推送给客户端的事件
+type Event struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+// subscriberBuffer 每个订阅者的缓冲区大小，写满时丢弃新事件，避免慢客户端阻塞发布方
+const subscriberBuffer = 16
+
+// Hub 按用户分发事件
+// 同一用户可以有多个订阅者（多个标签页或设备）
+type Hub struct {
+	mu   sync.RWMutex
+	subs map[uint]map[chan Event]struct{}
+}
+
+// NewHub 创建事件中心
+func NewHub() *Hub {
+	return &Hub{subs: make(map[uint]map[chan Event]struct{})}
+}
+
+// Subscribe 订阅用户的事件，返回事件通道和取消订阅函数
+func (h *Hub) Subscribe(userID uint) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	h.mu.Lock()
+	if h.subs[userID] == nil {
+		h.subs[userID] = make(map[chan Event]struct{})
+	}
+	h.subs[userID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subs[userID], ch)
+			if len(h.subs[userID]) == 0 {
+				delete(h.subs, userID)
+			}
+			h.mu.Unlock()
+		})
+	}
+}
+
+// Publish 向用户的所有订阅者推送事件，hub为nil时忽略
+func (h *Hub) Publish(userID uint, event Event) {
+	if h == nil {
+		return
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for ch := range h.subs[userID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package export
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+)
+
+// chatGPTConversation ChatGPT导出的conversations.json中的单个会话
+type chatGPTConversation struct {
+	Title       string                 `json:"title"`
+	CreateTime  float64                `json:"create_time"`
+	CurrentNode string                 `json:"current_node"`
+	Mapping     map[string]chatGPTNode `json:"mapping"`
+}
+
+// chatGPTNode 消息树节点
+type chatGPTNode struct {
+	ID       string          `json:"id"`
+	Parent   string          `json:"parent"`
+	Children []string        `json:"children"`
+	Message  *chatGPTMessage `json:"message"`
+}
+
+// chatGPTMessage 节点上的消息
+type chatGPTMessage struct {
+	Author struct {
+		Role string `json:"role"`
+	} `json:"author"`
+	CreateTime float64 `json:"create_time"`
+	Content    struct {
+		ContentType string            `json:"content_type"`
+		Parts       []json.RawMessage `json:"parts"`
+		Text        string            `json:"text"`
+	} `json:"content"`
+	Metadata struct {
+		ModelSlug string `json:"model_slug"`
+	} `json:"metadata"`
+}
+
+// parseChatGPT 解析ChatGPT导出的会话
+func parseChatGPT(data []byte, isArray bool) ([]Transcript, error) {
+	var conversations []chatGPTConversation
+	if isArray {
+		if err := json.Unmarshal(data, &conversations); err != nil {
+			return nil, err
+		}
+	} else {
+		var c chatGPTConversation
+		if err := json.Unmarshal(data, &c); err != nil {
+			return nil, err
+		}
+		conversations = append(conversations, c)
+	}
+
+	transcripts := make([]Transcript, 0, len(conversations))
+	for _, c := range conversations {
+		transcripts = append(transcripts, c.transcript())
+	}
+	return transcripts, nil
+}
+
+// transcript 转换为会话记录
+// 只保留user和assistant的文本消息，跳过的节点（system、工具调用等）的子节点挂到最近的保留祖先下
+func (c chatGPTConversation) transcript() Transcript {
+	t := Transcript{
+		Format:    Format,
+		Version:   Version,
+		Title:     c.Title,
+		Mode:      "chat",
+		CreatedAt: fromUnix(c.CreateTime),
+	}
+
+	// 按节点在树中的深度优先顺序编号，保证父消息排在子消息之前
+	// 导入文件不可信，visited防止环形引用导致死循环
+	ids := make(map[string]uint)
+	visited := make(map[string]bool)
+	var walk func(nodeID string, parent *uint)
+	walk = func(nodeID string, parent *uint) {
+		node, ok := c.Mapping[nodeID]
+		if !ok || visited[nodeID] {
+			return
+		}
+		visited[nodeID] = true
+
+		next := parent
+		if content, role, ok := node.Message.text(); ok {
+			id := uint(len(t.Messages) + 1)
+			ids[nodeID] = id
+			t.Messages = append(t.Messages, Message{
+				ID:        id,
+				ParentID:  parent,
+				Role:      role,
+				Content:   content,
+				CreatedAt: fromUnix(node.Message.CreateTime),
+			})
+			if t.Model == "" && node.Message.Metadata.ModelSlug != "" {
+				t.Model = node.Message.Metadata.ModelSlug
+			}
+			next = &id
+		}
+
+		for _, child := range node.Children {
+			walk(child, next)
+		}
+	}
+
+	var roots []string
+	for id, node := range c.Mapping {
+		if _, ok := c.Mapping[node.Parent]; node.Parent == "" || !ok {
+			roots = append(roots, id)
+		}
+	}
+	sort.Strings(roots)
+	for _, root := range roots {
+		walk(root, nil)
+	}
+
+	// 当前节点可能是被跳过的节点，回溯到最近的保留节点
+	for nodeID, steps := c.CurrentNode, 0; nodeID != "" && steps < len(c.Mapping); nodeID, steps = c.Mapping[nodeID].Parent, steps+1 {
+		if id, ok := ids[nodeID]; ok {
+			t.ActiveMessageID = &id
+			break
+		}
+	}
+	return t
+}
+
+// text 提取user/assistant消息的文本内容
+func (m *chatGPTMessage) text() (string, string, bool) {
+	if m == nil {
+		return "", "", false
+	}
+	role := m.Author.Role
+	if role != "user" && role != "assistant" {
+		return "", "", false
+	}
+
+	var parts []string
+	switch m.Content.ContentType {
+	case "text", "":
+		for _, raw := range m.Content.Parts {
+			var s string
+			if err := json.Unmarshal(raw, &s); err == nil && s != "" {
+				parts = append(parts, s)
+			}
+		}
+	case "code":
+		if m.Content.Text != "" {
+			parts = append(parts, "```\n"+m.Content.Text+"\n```")
+		}
+	default:
+		return "", "", false
+	}
+
+	content := strings.TrimSpace(strings.Join(parts, "\n"))
+	if content == "" {
+		return "", "", false
+	}
+	return content, role, true
+}
+
+// fromUnix 将秒级浮点时间戳转换为时间
+func fromUnix(sec float64) time.Time {
+	if sec <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(sec*float64(time.Second)))
+}
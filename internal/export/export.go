@@ -0,0 +1,161 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"go-ai-copilot/internal/model"
+)
+
+// Format 本项目导出JSON的格式标识
+const Format = "go-ai-copilot"
+
+// Version 导出JSON的格式版本
+const Version = 1
+
+// ErrUnknownFormat 无法识别的导入格式
+var ErrUnknownFormat = errors.New("无法识别的导入格式，支持本项目导出的JSON和ChatGPT的conversations.json")
+
+// Transcript 会话记录
+// Messages 包含所有分支，按 ParentID 组成消息树；ActiveMessageID 为当前分支的最后一条消息
+type Transcript struct {
+	Format          string    `json:"format"`
+	Version         int       `json:"version"`
+	Title           string    `json:"title"`
+	Mode            string    `json:"mode"`
+	Model           string    `json:"model,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	ExportedAt      time.Time `json:"exported_at,omitempty"`
+	ActiveMessageID *uint     `json:"active_message_id,omitempty"`
+	Messages        []Message `json:"messages"`
+}
+
+// Message 会话记录中的消息，ID只在同一份记录内有意义
+type Message struct {
+	ID            uint      `json:"id"`
+	ParentID      *uint     `json:"parent_id,omitempty"`
+	Role          string    `json:"role"`
+	Content       string    `json:"content"`
+	CreatedAt     time.Time `json:"created_at"`
+	PromptVersion int       `json:"prompt_version,omitempty"`
+}
+
+// FromSession 由会话和消息构建会话记录
+func FromSession(session model.Session, modelName string, messages []model.Message) *Transcript {
+	t := &Transcript{
+		Format:          Format,
+		Version:         Version,
+		Title:           session.Title,
+		Mode:            session.Mode,
+		Model:           modelName,
+		CreatedAt:       session.CreatedAt,
+		ExportedAt:      time.Now(),
+		ActiveMessageID: session.ActiveMessageID,
+		Messages:        make([]Message, 0, len(messages)),
+	}
+	for _, m := range messages {
+		t.Messages = append(t.Messages, Message{
+			ID:            m.ID,
+			ParentID:      m.ParentID,
+			Role:          m.Role,
+			Content:       m.Content,
+			CreatedAt:     m.CreatedAt,
+			PromptVersion: m.PromptVersion,
+		})
+	}
+	return t
+}
+
+// ActivePath 当前分支上的消息（从旧到新）
+// 未记录当前分支时取最后一条消息所在的分支
+func (t *Transcript) ActivePath() []Message {
+	if len(t.Messages) == 0 {
+		return nil
+	}
+
+	byID := make(map[uint]Message, len(t.Messages))
+	for _, m := range t.Messages {
+		byID[m.ID] = m
+	}
+
+	leaf := t.Messages[len(t.Messages)-1].ID
+	if t.ActiveMessageID != nil {
+		if _, ok := byID[*t.ActiveMessageID]; ok {
+			leaf = *t.ActiveMessageID
+		}
+	}
+
+	var path []Message
+	seen := make(map[uint]bool)
+	for id := &leaf; id != nil && !seen[*id]; {
+		m, ok := byID[*id]
+		if !ok {
+			break
+		}
+		seen[m.ID] = true
+		path = append(path, m)
+		id = m.ParentID
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// JSON 导出为JSON
+func JSON(t *Transcript) ([]byte, error) {
+	return json.MarshalIndent(t, "", "  ")
+}
+
+// Parse 解析导入文件，自动识别本项目的JSON（单个会话或数组）和ChatGPT的conversations.json
+func Parse(data []byte) ([]Transcript, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, ErrUnknownFormat
+	}
+
+	var probe []struct {
+		Format  string          `json:"format"`
+		Mapping json.RawMessage `json:"mapping"`
+	}
+	isArray := data[0] == '['
+	if isArray {
+		if err := json.Unmarshal(data, &probe); err != nil {
+			return nil, err
+		}
+	} else {
+		var one struct {
+			Format  string          `json:"format"`
+			Mapping json.RawMessage `json:"mapping"`
+		}
+		if err := json.Unmarshal(data, &one); err != nil {
+			return nil, err
+		}
+		probe = append(probe, one)
+	}
+	if len(probe) == 0 {
+		return nil, nil
+	}
+
+	switch {
+	case probe[0].Format == Format:
+		var transcripts []Transcript
+		if isArray {
+			if err := json.Unmarshal(data, &transcripts); err != nil {
+				return nil, err
+			}
+		} else {
+			var t Transcript
+			if err := json.Unmarshal(data, &t); err != nil {
+				return nil, err
+			}
+			transcripts = append(transcripts, t)
+		}
+		return transcripts, nil
+	case len(probe[0].Mapping) > 0:
+		return parseChatGPT(data, isArray)
+	}
+	return nil, ErrUnknownFormat
+}
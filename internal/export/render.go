@@ -0,0 +1,140 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+)
+
+// timeLayout 导出时使用的时间格式
+const timeLayout = "2006-01-02 15:04:05"
+
+// roleNames 角色显示名称
+var roleNames = map[string]string{
+	"user":      "用户",
+	"assistant": "助手",
+	"system":    "系统",
+}
+
+// roleName 角色显示名称
+func roleName(role string) string {
+	if name, ok := roleNames[role]; ok {
+		return name
+	}
+	return role
+}
+
+// formatTime 格式化时间，零值显示为空
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format(timeLayout)
+}
+
+// Markdown 导出当前分支为Markdown，消息内容原样保留（含代码块）
+func Markdown(t *Transcript) []byte {
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "# %s\n\n", t.Title)
+	fmt.Fprintf(&b, "- 模式: %s\n", t.Mode)
+	if t.Model != "" {
+		fmt.Fprintf(&b, "- 模型: %s\n", t.Model)
+	}
+	fmt.Fprintf(&b, "- 创建时间: %s\n", formatTime(t.CreatedAt))
+	fmt.Fprintf(&b, "- 导出时间: %s\n", formatTime(t.ExportedAt))
+
+	for _, m := range t.ActivePath() {
+		fmt.Fprintf(&b, "\n---\n\n### %s · %s\n\n", roleName(m.Role), formatTime(m.CreatedAt))
+		b.WriteString(m.Content)
+		b.WriteString("\n")
+	}
+	return b.Bytes()
+}
+
+// segment 消息内容片段（普通文本或代码块）
+type segment struct {
+	Code     bool
+	Language string
+	Text     string
+}
+
+// splitCodeBlocks 按 ``` 围栏拆分消息内容
+func splitCodeBlocks(content string) []segment {
+	var segments []segment
+	var buf []string
+	inCode := false
+	language := ""
+
+	flush := func() {
+		text := strings.Join(buf, "\n")
+		if inCode || strings.TrimSpace(text) != "" {
+			segments = append(segments, segment{Code: inCode, Language: language, Text: text})
+		}
+		buf = buf[:0]
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			flush()
+			if inCode {
+				inCode, language = false, ""
+			} else {
+				inCode, language = true, strings.TrimSpace(strings.TrimPrefix(trimmed, "```"))
+			}
+			continue
+		}
+		buf = append(buf, line)
+	}
+	flush()
+	return segments
+}
+
+// htmlTemplate 导出HTML的页面模板
+var htmlTemplate = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"roleName":   roleName,
+	"formatTime": formatTime,
+	"segments":   splitCodeBlocks,
+}).Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", "PingFang SC", sans-serif; max-width: 860px; margin: 2em auto; padding: 0 1em; color: #222; }
+.meta { color: #666; font-size: 0.9em; }
+.message { border-top: 1px solid #eee; padding: 1em 0; }
+.message h3 { margin: 0 0 0.5em; font-size: 1em; }
+.message h3 time { color: #999; font-weight: normal; margin-left: 0.5em; }
+.user h3 { color: #1a73e8; }
+.assistant h3 { color: #188038; }
+.text { white-space: pre-wrap; line-height: 1.6; }
+pre { background: #f6f8fa; padding: 0.8em; overflow-x: auto; border-radius: 4px; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="meta">模式: {{.Mode}}{{if .Model}} · 模型: {{.Model}}{{end}} · 创建时间: {{formatTime .CreatedAt}} · 导出时间: {{formatTime .ExportedAt}}</p>
+{{range .Messages}}<div class="message {{.Role}}">
+<h3>{{roleName .Role}}<time>{{formatTime .CreatedAt}}</time></h3>
+{{range segments .Content}}{{if .Code}}<pre><code{{if .Language}} class="language-{{.Language}}"{{end}}>{{.Text}}</code></pre>
+{{else}}<div class="text">{{.Text}}</div>
+{{end}}{{end}}</div>
+{{end}}</body>
+</html>
+`))
+
+// HTML 导出当前分支为独立的HTML页面
+func HTML(t *Transcript) ([]byte, error) {
+	view := *t
+	view.Messages = t.ActivePath()
+
+	var b bytes.Buffer
+	if err := htmlTemplate.Execute(&b, &view); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,282 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go-ai-copilot/internal/model"
+	"go-ai-copilot/internal/prompt"
+	"go-ai-copilot/internal/store"
+)
+
+// AssistantHandler 助手处理器
+type AssistantHandler struct {
+	store *store.Store
+}
+
+// NewAssistantHandler 创建助手处理器
+func NewAssistantHandler(st *store.Store) *AssistantHandler {
+	return &AssistantHandler{store: st}
+}
+
+// CreateAssistantRequest 创建助手请求
+// SystemPrompt 为空时使用 Mode 对应的提示词模板；Templated 为 true 时 SystemPrompt 按提示词模板语法渲染，否则原样使用
+type CreateAssistantRequest struct {
+	Name             string   `json:"name" binding:"required,max=100"`
+	Description      string   `json:"description" binding:"max=255"`
+	Mode             string   `json:"mode" binding:"max=50"`
+	SystemPrompt     string   `json:"system_prompt"`
+	Templated        bool     `json:"templated"`
+	Model            string   `json:"model" binding:"max=100"`
+	Temperature      *float64 `json:"temperature" binding:"omitempty,min=0,max=2"`
+	KnowledgeBaseIDs []uint   `json:"knowledge_base_ids"`
+	Tools            []string `json:"tools" binding:"max=20,dive,max=50"` // 只保存，对话暂不支持工具调用
+	Visibility       string   `json:"visibility" binding:"omitempty,oneof=private team"`
+}
+
+// UpdateAssistantRequest 更新助手请求
+type UpdateAssistantRequest struct {
+	Name             *string   `json:"name" binding:"omitempty,max=100"`
+	Description      *string   `json:"description" binding:"omitempty,max=255"`
+	Mode             *string   `json:"mode" binding:"omitempty,max=50"`
+	SystemPrompt     *string   `json:"system_prompt"`
+	Templated        *bool     `json:"templated"`
+	Model            *string   `json:"model" binding:"omitempty,max=100"`
+	Temperature      *float64  `json:"temperature" binding:"omitempty,min=0,max=2"`
+	KnowledgeBaseIDs *[]uint   `json:"knowledge_base_ids"`
+	Tools            *[]string `json:"tools" binding:"omitempty,max=20,dive,max=50"`
+	Visibility       *string   `json:"visibility" binding:"omitempty,oneof=private team"`
+}
+
+// validateAssistant 校验助手的模式、提示词模板和知识库
+func (h *AssistantHandler) validateAssistant(ctx context.Context, userID uint, a *model.Assistant) error {
+	if a.Mode == "" && a.SystemPrompt == "" {
+		a.Mode = prompt.DefaultMode
+	}
+	if a.Mode != "" {
+		ok, err := prompt.Exists(ctx, h.store.Prompts, a.Mode)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("模式不存在: " + a.Mode)
+		}
+	}
+	if a.SystemPrompt != "" && a.Templated {
+		if _, err := prompt.Parse(a.SystemPrompt); err != nil {
+			return err
+		}
+	}
+
+	a.Tools = uniqueNames(a.Tools)
+
+	// 只能关联自己可访问的知识库
+	a.KnowledgeBaseIDs = uniqueIDs(a.KnowledgeBaseIDs)
+	accessible, err := h.store.KnowledgeBases.Accessible(ctx, userID, a.KnowledgeBaseIDs)
+	if err != nil {
+		return err
+	}
+	if len(accessible) != len(a.KnowledgeBaseIDs) {
+		return errKnowledgeBaseForbidden
+	}
+	return nil
+}
+
+// ListAssistants 获取可用的助手列表（内置、团队共享和自己创建的）
+func (h *AssistantHandler) ListAssistants(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	assistants, err := h.store.Assistants.List(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "获取助手列表失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+		Data:    assistants,
+	})
+}
+
+// GetAssistant 获取助手详情
+func (h *AssistantHandler) GetAssistant(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	a, err := h.store.Assistants.GetVisible(c.Request.Context(), userID, idParam(c, "id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, AuthResponse{
+			Code:    404,
+			Message: "助手不存在",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+		Data:    a,
+	})
+}
+
+// CreateAssistant 创建助手
+func (h *AssistantHandler) CreateAssistant(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var req CreateAssistantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	visibility := req.Visibility
+	if visibility == "" {
+		visibility = model.VisibilityPrivate
+	}
+
+	a := model.Assistant{
+		UserID:           userID,
+		Name:             req.Name,
+		Description:      req.Description,
+		Mode:             req.Mode,
+		SystemPrompt:     req.SystemPrompt,
+		Templated:        req.Templated,
+		Model:            req.Model,
+		Temperature:      req.Temperature,
+		KnowledgeBaseIDs: req.KnowledgeBaseIDs,
+		Tools:            req.Tools,
+		Visibility:       visibility,
+	}
+	if err := h.validateAssistant(c.Request.Context(), userID, &a); err != nil {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := h.store.Assistants.Create(c.Request.Context(), &a); err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "助手创建失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+		Data:    a,
+	})
+}
+
+// UpdateAssistant 更新助手（仅创建者，内置助手不可修改）
+func (h *AssistantHandler) UpdateAssistant(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var req UpdateAssistantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	a, err := h.store.Assistants.GetOwned(c.Request.Context(), userID, idParam(c, "id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, AuthResponse{
+			Code:    404,
+			Message: "助手不存在",
+		})
+		return
+	}
+
+	if req.Name != nil {
+		a.Name = *req.Name
+	}
+	if req.Description != nil {
+		a.Description = *req.Description
+	}
+	if req.Mode != nil {
+		a.Mode = *req.Mode
+	}
+	if req.SystemPrompt != nil {
+		a.SystemPrompt = *req.SystemPrompt
+	}
+	if req.Templated != nil {
+		a.Templated = *req.Templated
+	}
+	if req.Model != nil {
+		a.Model = *req.Model
+	}
+	if req.Temperature != nil {
+		a.Temperature = req.Temperature
+	}
+	if req.KnowledgeBaseIDs != nil {
+		a.KnowledgeBaseIDs = *req.KnowledgeBaseIDs
+	}
+	if req.Tools != nil {
+		a.Tools = *req.Tools
+	}
+	if req.Visibility != nil {
+		a.Visibility = *req.Visibility
+	}
+
+	if err := h.validateAssistant(c.Request.Context(), userID, a); err != nil {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := h.store.Assistants.Save(c.Request.Context(), a); err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "更新失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+		Data:    a,
+	})
+}
+
+// DeleteAssistant 删除助手（仅创建者，内置助手不可删除）
+// 已使用该助手的会话回退到会话记录的模式
+func (h *AssistantHandler) DeleteAssistant(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	err := h.store.Assistants.Delete(c.Request.Context(), userID, idParam(c, "id"))
+	if store.IsNotFound(err) {
+		c.JSON(http.StatusNotFound, AuthResponse{
+			Code:    404,
+			Message: "助手不存在",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "删除失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+	})
+}
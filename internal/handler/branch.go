@@ -0,0 +1,236 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go-ai-copilot/internal/model"
+)
+
+// EditMessageRequest 编辑消息请求
+type EditMessageRequest struct {
+	SessionID uint   `json:"session_id" binding:"required"`
+	MessageID uint   `json:"message_id" binding:"required"` // 被编辑的user消息
+	Content   string `json:"content" binding:"required"`
+	Language  string `json:"language,omitempty"`
+}
+
+// RegenerateRequest 重新生成请求
+type RegenerateRequest struct {
+	SessionID   uint     `json:"session_id" binding:"required"`
+	MessageID   uint     `json:"message_id" binding:"required"` // 要重新生成的assistant消息（或其对应的user消息）
+	Model       string   `json:"model,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty" binding:"omitempty,min=0,max=2"`
+	Language    string   `json:"language,omitempty"`
+}
+
+// EditMessage 编辑历史提问
+// 在原消息的父消息下创建新的user消息作为新分支并生成回复，原分支保留
+func (h *ChatHandler) EditMessage(c *gin.Context) {
+	if h.client == nil {
+		c.JSON(http.StatusServiceUnavailable, ChatResponse{
+			Code:    503,
+			Message: "AI服务暂不可用，请配置API_KEY",
+		})
+		return
+	}
+
+	userID := c.GetUint("userID")
+
+	var req EditMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ChatResponse{
+			Code:    400,
+			Message: "参数错误",
+		})
+		return
+	}
+
+	original, ok := h.loadBranchMessage(c, userID, req.SessionID, req.MessageID)
+	if !ok {
+		return
+	}
+	if original.Role != "user" {
+		c.JSON(http.StatusBadRequest, ChatResponse{
+			Code:    400,
+			Message: "只能编辑提问消息",
+		})
+		return
+	}
+
+	h.generateBranch(c, userID, ChatRequest{
+		Message:   req.Content,
+		SessionID: req.SessionID,
+		Language:  req.Language,
+	}, original.ParentID, nil)
+}
+
+// RegenerateMessage 重新生成回复，新回复与原回复互为兄弟分支
+func (h *ChatHandler) RegenerateMessage(c *gin.Context) {
+	if h.client == nil {
+		c.JSON(http.StatusServiceUnavailable, ChatResponse{
+			Code:    503,
+			Message: "AI服务暂不可用，请配置API_KEY",
+		})
+		return
+	}
+
+	userID := c.GetUint("userID")
+
+	var req RegenerateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ChatResponse{
+			Code:    400,
+			Message: "参数错误",
+		})
+		return
+	}
+
+	msg, ok := h.loadBranchMessage(c, userID, req.SessionID, req.MessageID)
+	if !ok {
+		return
+	}
+
+	// 找到对应的提问
+	question := msg
+	if msg.Role != "user" {
+		if msg.ParentID == nil {
+			c.JSON(http.StatusBadRequest, ChatResponse{
+				Code:    400,
+				Message: "该消息没有对应的提问",
+			})
+			return
+		}
+		if question, ok = h.loadBranchMessage(c, userID, req.SessionID, *msg.ParentID); !ok {
+			return
+		}
+	}
+
+	h.generateBranch(c, userID, ChatRequest{
+		Message:     question.Content,
+		SessionID:   req.SessionID,
+		Model:       req.Model,
+		Temperature: req.Temperature,
+		Language:    req.Language,
+	}, question.ParentID, question)
+}
+
+// loadBranchMessage 加载会话中的消息，失败时直接写入响应
+// 支持分支之前的会话先将平铺消息串成分支，否则消息没有父消息，新分支会丢失之前的上下文
+func (h *ChatHandler) loadBranchMessage(c *gin.Context, userID, sessionID, messageID uint) (*model.Message, bool) {
+	ctx := c.Request.Context()
+	if _, err := h.store.Sessions.Get(ctx, userID, sessionID); err != nil {
+		c.JSON(http.StatusNotFound, ChatResponse{
+			Code:    404,
+			Message: "会话不存在",
+		})
+		return nil, false
+	}
+	if err := h.sessionHandler.ensureTree(sessionID); err != nil {
+		c.JSON(http.StatusInternalServerError, ChatResponse{
+			Code:    500,
+			Message: "加载会话失败",
+		})
+		return nil, false
+	}
+	msg, err := h.store.Messages.Get(ctx, sessionID, messageID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ChatResponse{
+			Code:    404,
+			Message: "消息不存在",
+		})
+		return nil, false
+	}
+	return msg, true
+}
+
+// generateBranch 以parentID之前的分支为上下文生成回复
+// question为nil时在parentID下新建user消息（编辑），否则在已有提问下追加新回复（重新生成）
+func (h *ChatHandler) generateBranch(c *gin.Context, userID uint, req ChatRequest, parentID *uint, question *model.Message) {
+	ctx := c.Request.Context()
+
+	settings, err := h.resolveSettings(userID, req)
+	if err != nil {
+		status := settingsErrorStatus(err)
+		c.JSON(status, ChatResponse{
+			Code:    status,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	history, err := h.sessionHandler.Path(req.SessionID, parentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ChatResponse{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	systemPrompt, _, err := h.retrieve(ctx, settings, req.Message, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ChatResponse{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
+	messages := buildMessagesFrom(h.sessionHandler.recent(history), req.Message, systemPrompt)
+
+	reply, err := h.chat(ctx, userID, messages, settings.callOptions()...)
+	if err != nil {
+		status := aiErrorStatus(err)
+		c.JSON(status, ChatResponse{
+			Code:    status,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// 编辑时先保存新的提问
+	if question == nil {
+		question = &model.Message{
+			SessionID: req.SessionID,
+			UserID:    userID,
+			Role:      "user",
+			Content:   req.Message,
+		}
+		if err := h.sessionHandler.SaveMessageUnder(question, parentID); err != nil {
+			c.JSON(http.StatusInternalServerError, ChatResponse{
+				Code:    500,
+				Message: "消息保存失败",
+			})
+			return
+		}
+	}
+
+	answer := &model.Message{
+		SessionID: req.SessionID,
+		UserID:    userID,
+		Role:      "assistant",
+		Content:   reply,
+	}
+	if settings.resolved != nil {
+		answer.PromptTemplateID = settings.resolved.TemplateID
+		answer.PromptVersion = settings.resolved.Version
+	}
+	if err := h.sessionHandler.SaveMessageUnder(answer, &question.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, ChatResponse{
+			Code:    500,
+			Message: "消息保存失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, ChatResponse{
+		Code:    0,
+		Message: "success",
+		Data: gin.H{
+			"reply":                reply,
+			"session_id":           req.SessionID,
+			"user_message_id":      question.ID,
+			"assistant_message_id": answer.ID,
+		},
+	})
+}
@@ -2,48 +2,148 @@ package handler
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"os"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sashabaranov/go-openai"
+	"go-ai-copilot/internal/cache"
 	"go-ai-copilot/internal/config"
+	"go-ai-copilot/internal/events"
+	"go-ai-copilot/internal/model"
+	"go-ai-copilot/internal/prompt"
+	"go-ai-copilot/internal/search"
+	"go-ai-copilot/internal/store"
 	"go-ai-copilot/pkg/ai"
 )
 
 // ChatHandler 对话处理器
 type ChatHandler struct {
 	client        *ai.Client
+	scheduler     *ai.Scheduler // 上游并发调度器，为nil时不限制
 	sessionHandler *SessionHandler
+	hub            *events.Hub // 推送后台任务结果（如自动生成的会话标题）
+	store          *store.Store
+
+	// 语义响应缓存，未启用时为nil
+	semanticCache *cache.SemanticCache
+	// 向量化客户端，用于语义缓存和助手知识库检索
+	embeddingClient *ai.EmbeddingClient
+	space           *model.EmbeddingSpace
 }
 
 // NewChatHandler 创建对话处理器
-func NewChatHandler() (*ChatHandler, error) {
+func NewChatHandler(scheduler *ai.Scheduler, hub *events.Hub, c cache.Cache, st *store.Store, searcher *search.Searcher) (*ChatHandler, error) {
 	cfg := config.GlobalConfig
-	client, err := ai.NewClient(
-		"", // API_KEY从环境变量读取
-		cfg.AI.BaseURL,
-		cfg.AI.Model,
-		cfg.AI.Temperature,
-		cfg.AI.MaxTokens,
-		cfg.AI.Timeout,
-	)
+	client, err := newAIClient(cfg.AI)
 	if err != nil {
 		return nil, err
 	}
 
-	return &ChatHandler{
+	h := &ChatHandler{
 		client:        client,
-		sessionHandler: NewSessionHandler(),
-	}, nil
+		scheduler:     scheduler,
+		sessionHandler: NewSessionHandler(c, st, searcher),
+		hub:            hub,
+		store:          st,
+	}
+
+	embeddingClient, space, err := NewEmbeddingClient(cfg.AI, st)
+	if err != nil {
+		log.Printf("警告: 向量化客户端初始化失败，语义缓存和助手知识库检索不可用: %v", err)
+	} else {
+		h.embeddingClient = embeddingClient
+		h.space = space
+	}
+
+	if cfg.SemanticCache.Enabled && h.embeddingClient != nil {
+		h.semanticCache = cache.NewSemanticCache(
+			c,
+			cfg.SemanticCache.Threshold,
+			cfg.SemanticCache.TTL,
+			cfg.SemanticCache.MaxEntries,
+		)
+	}
+
+	return h, nil
+}
+
+// NewScheduler 根据配置创建上游并发调度器，未配置并发上限时返回nil
+func NewScheduler(cfg config.ConcurrencyConfig) *ai.Scheduler {
+	if cfg.MaxInFlight <= 0 {
+		return nil
+	}
+	return ai.NewScheduler(cfg.MaxInFlight, cfg.MaxQueue)
+}
+
+// acquire 获取上游并发额度，排队期间通过onPosition汇报排队位置
+func (h *ChatHandler) acquire(ctx context.Context, userID uint, prio ai.Priority, onPosition func(int)) (func(), error) {
+	if h.scheduler == nil {
+		return func() {}, nil
+	}
+	return h.scheduler.Acquire(ctx, userID, prio, onPosition)
+}
+
+// newAIClient 根据配置创建AI客户端（含重试、熔断和备用提供方）
+func newAIClient(cfg config.AIConfig) (*ai.Client, error) {
+	fallbacks := make([]ai.Provider, 0, len(cfg.Fallbacks))
+	for _, fb := range cfg.Fallbacks {
+		p := ai.Provider{
+			Name:    fb.Name,
+			BaseURL: fb.BaseURL,
+			Model:   fb.Model,
+		}
+		if fb.APIKeyEnv != "" {
+			p.APIKey = os.Getenv(fb.APIKeyEnv)
+		}
+		fallbacks = append(fallbacks, p)
+	}
+
+	return ai.NewClient(
+		"", // API_KEY从环境变量读取
+		cfg.BaseURL,
+		cfg.Model,
+		cfg.Temperature,
+		cfg.MaxTokens,
+		cfg.Timeout,
+		ai.WithRetryPolicy(ai.RetryPolicy{
+			MaxAttempts: cfg.Retry.MaxAttempts,
+			BaseDelay:   cfg.Retry.BaseDelay,
+			MaxDelay:    cfg.Retry.MaxDelay,
+		}),
+		ai.WithCircuitBreaker(cfg.Breaker.FailureThreshold, cfg.Breaker.Cooldown),
+		ai.WithFallbacks(fallbacks...),
+	)
+}
+
+// aiErrorStatus 上游调用失败时返回的HTTP状态码
+// 重试耗尽或全部熔断时返回503，排队已满时返回429，便于前端提示稍后重试
+func aiErrorStatus(err error) int {
+	if errors.Is(err, ai.ErrUnavailable) {
+		return http.StatusServiceUnavailable
+	}
+	if errors.Is(err, ai.ErrQueueFull) {
+		return http.StatusTooManyRequests
+	}
+	return http.StatusInternalServerError
 }
 
 // ChatRequest 对话请求
 type ChatRequest struct {
 	Message   string `json:"message" binding:"required"`
 	SessionID uint   `json:"session_id,omitempty"` // 会话ID
+	Mode      string `json:"mode,omitempty"`       // 对话模式，仅在未指定会话时生效（会话的模式见 UpdateSession）
 	APIKey    string `json:"api_key,omitempty"`    // 用户可选传入自己的API_KEY
 	Model     string `json:"model,omitempty"`     // 用户可选指定模型
-	Temperature float64 `json:"temperature,omitempty"` // 用户可选温度
+	Temperature *float64 `json:"temperature,omitempty" binding:"omitempty,min=0,max=2"` // 用户可选温度，可以为0
+	UseCache  bool   `json:"use_cache,omitempty"`  // 是否使用语义缓存
+	Language  string `json:"language,omitempty"`   // 编程语言，用于提示词模板
+	KnowledgeBaseIDs []uint `json:"knowledge_base_ids,omitempty"` // 本次检索的知识库，覆盖会话和助手关联的知识库
+	Filter           *RetrievalFilter `json:"filter,omitempty"`   // 本次检索的过滤条件
 }
 
 // ChatResponse 对话响应
@@ -75,12 +175,55 @@ func (h *ChatHandler) Chat(c *gin.Context) {
 		return
 	}
 
-	// 构建消息（包含上下文）
-	messages := h.buildMessages(req.SessionID, userID, req.Message, "")
+	settings, err := h.resolveSettings(userID, req)
+	if err != nil {
+		status := settingsErrorStatus(err)
+		c.JSON(status, ChatResponse{
+			Code:    status,
+			Message: err.Error(),
+		})
+		return
+	}
 
-	// 调用AI
+	h.respond(c, userID, req, settings)
+}
+
+// respond 执行一次同步对话并返回结果（命中语义缓存时直接返回缓存的回答）
+func (h *ChatHandler) respond(c *gin.Context, userID uint, req ChatRequest, settings *chatSettings) {
 	ctx := c.Request.Context()
-	reply, err := h.client.Chat(ctx, messages)
+
+	// 查询语义缓存
+	var cacheKey cache.SemanticKey
+	var embedding []float32
+	if h.cacheable(req) {
+		var err error
+		if cacheKey, err = h.cacheKey(settings); err != nil {
+			c.JSON(http.StatusInternalServerError, ChatResponse{
+				Code:    500,
+				Message: err.Error(),
+			})
+			return
+		}
+		var hit *cache.SemanticHit
+		embedding, hit = h.lookupCache(ctx, cacheKey, nil, req.Message)
+		if hit != nil {
+			h.saveExchange(req.SessionID, userID, req.Message, hit.Entry.Reply, settings.resolved)
+			c.JSON(http.StatusOK, ChatResponse{
+				Code:    0,
+				Message: "success",
+				Data: gin.H{
+					"reply":      hit.Entry.Reply,
+					"session_id": req.SessionID,
+					"cached":     true,
+					"similarity": hit.Similarity,
+				},
+			})
+			return
+		}
+	}
+
+	// 检索助手关联的知识库并生成System Prompt
+	systemPrompt, docIDs, err := h.retrieve(ctx, settings, req.Message, embedding)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ChatResponse{
 			Code:    500,
@@ -89,21 +232,143 @@ func (h *ChatHandler) Chat(c *gin.Context) {
 		return
 	}
 
-	// 保存消息到数据库
-	if req.SessionID > 0 {
-		h.sessionHandler.AddMessage(req.SessionID, userID, "user", req.Message)
-		h.sessionHandler.AddMessage(req.SessionID, userID, "assistant", reply)
+	// 构建消息（包含上下文）
+	messages := h.buildMessages(req.SessionID, userID, req.Message, systemPrompt)
+
+	// 调用AI
+	reply, err := h.chat(ctx, userID, messages, settings.callOptions()...)
+	if err != nil {
+		status := aiErrorStatus(err)
+		c.JSON(status, ChatResponse{
+			Code:    status,
+			Message: err.Error(),
+		})
+		return
 	}
 
+	// 保存消息到数据库
+	h.saveExchange(req.SessionID, userID, req.Message, reply, settings.resolved)
+	h.storeCache(cacheKey, req.Message, embedding, reply, docIDs)
+
 	c.JSON(http.StatusOK, ChatResponse{
 		Code:    0,
 		Message: "success",
-		Data:    gin.H{"reply": reply, "session_id": req.SessionID},
+		Data:    gin.H{"reply": reply, "session_id": req.SessionID, "cached": false},
 	})
 }
 
-// buildMessages 构建消息列表（包含上下文）
+// saveExchange 保存一轮问答到会话，assistant消息记录所用的提示词模板版本
+func (h *ChatHandler) saveExchange(sessionID, userID uint, question, reply string, tpl *prompt.Resolved) {
+	if sessionID == 0 {
+		return
+	}
+	h.sessionHandler.AddMessage(sessionID, userID, "user", question)
+
+	msg := &model.Message{
+		SessionID: sessionID,
+		UserID:    userID,
+		Role:      "assistant",
+		Content:   reply,
+	}
+	if tpl != nil {
+		msg.PromptTemplateID = tpl.TemplateID
+		msg.PromptVersion = tpl.Version
+	}
+	h.sessionHandler.SaveMessage(msg)
+
+	h.generateTitle(sessionID, userID, question, reply)
+}
+
+// systemPrompt 解析并渲染模式对应的提示词模板
+// A/B测试按会话分流（无会话时按用户），保证同一会话内版本一致
+func (h *ChatHandler) systemPrompt(userID, sessionID uint, mode string, vars prompt.Vars) (string, *prompt.Resolved, error) {
+	stickyKey := sessionID
+	if stickyKey == 0 {
+		stickyKey = userID
+	}
+
+	tpl, err := prompt.Resolve(context.Background(), h.store.Prompts, mode, stickyKey)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if vars.Nickname == "" {
+		if user, err := h.store.Users.Get(context.Background(), userID); err == nil {
+			vars.Nickname = user.Nickname
+		}
+	}
+
+	content, err := prompt.Render(tpl.Content, vars)
+	if err != nil {
+		return "", nil, err
+	}
+	return content, tpl, nil
+}
+
+// cacheable 判断本次请求能否使用语义缓存
+// 需要服务端启用且请求方显式开启；会话已有上下文时回答依赖前文，不复用缓存
+func (h *ChatHandler) cacheable(req ChatRequest) bool {
+	if h.semanticCache == nil || !req.UseCache {
+		return false
+	}
+	if req.SessionID > 0 && len(h.sessionHandler.GetHistoryForContext(req.SessionID)) > 0 {
+		return false
+	}
+	return true
+}
+
+// lookupCache 查询语义缓存
+// embedding为空时先对问题向量化；返回问题向量（用于写回缓存）和命中结果
+func (h *ChatHandler) lookupCache(ctx context.Context, key cache.SemanticKey, embedding []float32, question string) ([]float32, *cache.SemanticHit) {
+	if embedding == nil {
+		var err error
+		embedding, err = h.embeddingClient.GetEmbedding(ctx, question)
+		if err != nil {
+			log.Printf("语义缓存向量化失败: %v", err)
+			return nil, nil
+		}
+	}
+
+	hit, err := h.semanticCache.Lookup(key, embedding)
+	if err != nil {
+		log.Printf("语义缓存查询失败: %v", err)
+		return embedding, nil
+	}
+	return embedding, hit
+}
+
+// storeCache 写入语义缓存，未查询过缓存（embedding为空）时跳过
+func (h *ChatHandler) storeCache(key cache.SemanticKey, question string, embedding []float32, reply string, docIDs []uint) {
+	if embedding == nil || h.semanticCache == nil {
+		return
+	}
+	if err := h.semanticCache.Store(key, question, embedding, reply, docIDs); err != nil {
+		log.Printf("语义缓存写入失败: %v", err)
+	}
+}
+
+// chat 在并发额度内调用AI（同步对话）
+func (h *ChatHandler) chat(ctx context.Context, userID uint, messages []openai.ChatCompletionMessage, opts ...ai.CallOption) (string, error) {
+	release, err := h.acquire(ctx, userID, ai.PriorityStandard, nil)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	return h.client.Chat(ctx, messages, opts...)
+}
+
+// buildMessages 构建消息列表（包含当前分支的上下文）
 func (h *ChatHandler) buildMessages(sessionID, userID uint, newMessage, systemPrompt string) []openai.ChatCompletionMessage {
+	var history []model.Message
+	if sessionID > 0 {
+		history = h.sessionHandler.GetHistoryForContext(sessionID)
+	}
+	return buildMessagesFrom(history, newMessage, systemPrompt)
+}
+
+// buildMessagesFrom 由给定的历史消息构建消息列表
+func buildMessagesFrom(history []model.Message, newMessage, systemPrompt string) []openai.ChatCompletionMessage {
 	var messages []openai.ChatCompletionMessage
 
 	// 添加系统提示
@@ -115,14 +380,11 @@ func (h *ChatHandler) buildMessages(sessionID, userID uint, newMessage, systemPr
 	}
 
 	// 添加历史上下文
-	if sessionID > 0 {
-		history := h.sessionHandler.GetHistoryForContext(sessionID)
-		for _, msg := range history {
-			messages = append(messages, openai.ChatCompletionMessage{
-				Role:    msg.Role,
-				Content: msg.Content,
-			})
-		}
+	for _, msg := range history {
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:    msg.Role,
+			Content: msg.Content,
+		})
 	}
 
 	// 添加当前消息
@@ -157,22 +419,86 @@ func (h *ChatHandler) StreamChat(c *gin.Context) {
 		return
 	}
 
-	// 设置SSE响应头
-	c.Header("Content-Type", "text/event-stream")
-	c.Header("Cache-Control", "no-cache")
-	c.Header("Connection", "keep-alive")
-	c.Header("X-Accel-Buffering", "no")
-
 	// 创建带超时的上下文（默认2分钟超时）
 	ctx, cancel := context.WithCancel(c.Request.Context())
 	defer cancel()
 
-	// 构建消息（包含上下文）
-	messages := h.buildMessages(req.SessionID, userID, req.Message, "")
+	settings, err := h.resolveSettings(userID, req)
+	if err != nil {
+		status := settingsErrorStatus(err)
+		c.JSON(status, ChatResponse{
+			Code:    status,
+			Message: err.Error(),
+		})
+		return
+	}
 
 	// 用于收集完整回复
 	fullReply := ""
 
+	// 主Goroutine：监听Channel和Context
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, ChatResponse{
+			Code:    500,
+			Message: "不支持流式响应",
+		})
+		return
+	}
+
+	// 设置SSE响应头（对话设置解析之后，此前的错误以普通JSON返回）
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	// 查询语义缓存，命中时直接推送完整回答
+	var cacheKey cache.SemanticKey
+	var embedding []float32
+	if h.cacheable(req) {
+		if cacheKey, err = h.cacheKey(settings); err != nil {
+			c.SSEvent("error", err.Error())
+			flusher.Flush()
+			return
+		}
+		var hit *cache.SemanticHit
+		embedding, hit = h.lookupCache(ctx, cacheKey, nil, req.Message)
+		if hit != nil {
+			h.saveExchange(req.SessionID, userID, req.Message, hit.Entry.Reply, settings.resolved)
+			c.SSEvent("cached", gin.H{"similarity": hit.Similarity})
+			c.SSEvent("message", hit.Entry.Reply)
+			c.SSEvent("done", map[string]string{"status": "completed"})
+			flusher.Flush()
+			return
+		}
+	}
+
+	// 检索助手关联的知识库并构建消息（包含上下文）
+	systemPrompt, docIDs, err := h.retrieve(ctx, settings, req.Message, embedding)
+	if err != nil {
+		c.SSEvent("error", err.Error())
+		flusher.Flush()
+		return
+	}
+	messages := h.buildMessages(req.SessionID, userID, req.Message, systemPrompt)
+
+	// 获取上游并发额度，排队期间向前端推送排队位置
+	queued := false
+	release, err := h.acquire(ctx, userID, ai.PriorityInteractive, func(position int) {
+		queued = true
+		c.SSEvent("queue", gin.H{"position": position})
+		flusher.Flush()
+	})
+	if err != nil {
+		c.SSEvent("error", err.Error())
+		flusher.Flush()
+		return
+	}
+	if queued {
+		c.SSEvent("queue", gin.H{"position": 0})
+		flusher.Flush()
+	}
+
 	// 创建Channel用于传递token
 	tokenChan := make(chan string, 100)
 	errChan := make(chan error, 1)
@@ -180,27 +506,18 @@ func (h *ChatHandler) StreamChat(c *gin.Context) {
 	// 启动Goroutine调用AI流式接口
 	// 核心亮点：将AI调用放到独立Goroutine，通过Channel实时推送Token
 	go func() {
+		defer release()
 		err := h.client.StreamChat(ctx, messages, func(chunk string) error {
 			fullReply += chunk
 			tokenChan <- chunk
 			return nil
-		})
+		}, settings.callOptions()...)
 		if err != nil {
 			errChan <- err
 		}
 		close(tokenChan)
 	}()
 
-	// 主Goroutine：监听Channel和Context
-	flusher, ok := c.Writer.(http.Flusher)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, ChatResponse{
-			Code:    500,
-			Message: "不支持流式响应",
-		})
-		return
-	}
-
 	for {
 		select {
 		case <-ctx.Done():
@@ -209,9 +526,9 @@ func (h *ChatHandler) StreamChat(c *gin.Context) {
 		case token, ok := <-tokenChan:
 			if !ok {
 				// 流结束，保存消息到数据库
-				if req.SessionID > 0 && fullReply != "" {
-					h.sessionHandler.AddMessage(req.SessionID, userID, "user", req.Message)
-					h.sessionHandler.AddMessage(req.SessionID, userID, "assistant", fullReply)
+				if fullReply != "" {
+					h.saveExchange(req.SessionID, userID, req.Message, fullReply, settings.resolved)
+					h.storeCache(cacheKey, req.Message, embedding, fullReply, docIDs)
 				}
 				// 发送结束标记
 				c.SSEvent("done", map[string]string{"status": "completed"})
@@ -238,9 +555,61 @@ func (h *ChatHandler) Health(c *gin.Context) {
 	})
 }
 
+// Metrics 上游调用监控指标（Prometheus文本格式）
+// 包含并发数、各优先级排队深度、排队等待时间和提供方熔断状态
+func (h *ChatHandler) Metrics(c *gin.Context) {
+	var b strings.Builder
+
+	if h.scheduler != nil {
+		stats := h.scheduler.Stats()
+		b.WriteString("# HELP ai_upstream_limit Maximum concurrent upstream requests.\n")
+		b.WriteString("# TYPE ai_upstream_limit gauge\n")
+		fmt.Fprintf(&b, "ai_upstream_limit %d\n", stats.Limit)
+		b.WriteString("# HELP ai_upstream_in_flight Upstream requests currently in flight.\n")
+		b.WriteString("# TYPE ai_upstream_in_flight gauge\n")
+		fmt.Fprintf(&b, "ai_upstream_in_flight %d\n", stats.InFlight)
+		b.WriteString("# HELP ai_upstream_queue_depth Requests waiting for an upstream slot.\n")
+		b.WriteString("# TYPE ai_upstream_queue_depth gauge\n")
+		for _, prio := range []ai.Priority{ai.PriorityInteractive, ai.PriorityStandard, ai.PriorityBatch} {
+			fmt.Fprintf(&b, "ai_upstream_queue_depth{priority=%q} %d\n", prio.String(), stats.Queued[prio.String()])
+		}
+		b.WriteString("# HELP ai_upstream_queue_wait_seconds Time spent waiting in the upstream queue.\n")
+		b.WriteString("# TYPE ai_upstream_queue_wait_seconds summary\n")
+		fmt.Fprintf(&b, "ai_upstream_queue_wait_seconds_sum %f\n", stats.WaitSum.Seconds())
+		fmt.Fprintf(&b, "ai_upstream_queue_wait_seconds_count %d\n", stats.WaitCount)
+		b.WriteString("# HELP ai_upstream_queue_wait_seconds_max Longest time spent waiting in the upstream queue.\n")
+		b.WriteString("# TYPE ai_upstream_queue_wait_seconds_max gauge\n")
+		fmt.Fprintf(&b, "ai_upstream_queue_wait_seconds_max %f\n", stats.WaitMax.Seconds())
+	}
+
+	if h.client != nil {
+		b.WriteString("# HELP ai_provider_circuit_open Whether the provider circuit breaker is open (1) or half open (0.5).\n")
+		b.WriteString("# TYPE ai_provider_circuit_open gauge\n")
+		for name, state := range h.client.ProviderStates() {
+			value := "0"
+			switch state {
+			case "open":
+				value = "1"
+			case "half_open":
+				value = "0.5"
+			}
+			fmt.Fprintf(&b, "ai_provider_circuit_open{provider=%q} %s\n", name, value)
+		}
+	}
+
+	if h.sessionHandler != nil && h.sessionHandler.cache != nil {
+		b.WriteString("# HELP cache_backend Cache backend currently in use.\n")
+		b.WriteString("# TYPE cache_backend gauge\n")
+		fmt.Fprintf(&b, "cache_backend{backend=%q} 1\n", h.sessionHandler.cache.Name())
+	}
+
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(b.String()))
+}
+
 // HandleChatWithMode 处理带模式的对话请求
 // mode: chat(通用对话) / code_generate(代码生成) / code_explain(代码解释)
 //                            / code_optimize(代码优化) / code_vuln(漏洞检测) / code_test(单元测试)
+// System Prompt 来自数据库中该模式的提示词模板，见 prompt.Resolve；指定会话时使用会话的模式
 func (h *ChatHandler) HandleChatWithMode(c *gin.Context) {
 	// 检查AI客户端是否可用
 	if h.client == nil {
@@ -252,10 +621,6 @@ func (h *ChatHandler) HandleChatWithMode(c *gin.Context) {
 	}
 
 	userID := c.GetUint("userID")
-	mode := c.DefaultPostForm("mode", "chat")
-
-	// 根据模式选择不同的System Prompt
-	systemPrompt := getSystemPromptByMode(mode)
 
 	var req ChatRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -266,47 +631,19 @@ func (h *ChatHandler) HandleChatWithMode(c *gin.Context) {
 		return
 	}
 
-	// 构建消息（包含上下文）
-	messages := h.buildMessages(req.SessionID, userID, req.Message, systemPrompt)
-
-	// 调用AI
-	ctx := c.Request.Context()
-	reply, err := h.client.Chat(ctx, messages)
+	// 根据模式选择提示词模板（支持版本固定和A/B测试），有会话时使用会话的模式和配置
+	if req.Mode == "" {
+		req.Mode = prompt.DefaultMode
+	}
+	settings, err := h.resolveSettings(userID, req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ChatResponse{
-			Code:    500,
+		status := settingsErrorStatus(err)
+		c.JSON(status, ChatResponse{
+			Code:    status,
 			Message: err.Error(),
 		})
 		return
 	}
 
-	// 保存消息到数据库
-	if req.SessionID > 0 {
-		h.sessionHandler.AddMessage(req.SessionID, userID, "user", req.Message)
-		h.sessionHandler.AddMessage(req.SessionID, userID, "assistant", reply)
-	}
-
-	c.JSON(http.StatusOK, ChatResponse{
-		Code:    0,
-		Message: "success",
-		Data:    gin.H{"reply": reply},
-	})
-}
-
-// getSystemPromptByMode 根据模式获取System Prompt
-func getSystemPromptByMode(mode string) string {
-	switch mode {
-	case "code_generate":
-		return "你是一个专业的Go后端开发工程师。请根据用户需求生成符合Go规范的代码，包含错误处理、注释、单元测试。"
-	case "code_explain":
-		return "你是一个专业的Go后端开发工程师。请逐行解释用户提供的Go代码的逻辑、用途、设计思路。"
-	case "code_optimize":
-		return "你是一个专业的Go后端开发工程师。请优化用户提供的Go代码的性能、可读性、规范度，指出优化点。"
-	case "code_vuln":
-		return "你是一个专业的Go安全工程师。请检测用户提供的Go代码中的安全漏洞、内存泄漏、并发问题、错误处理缺陷。"
-	case "code_test":
-		return "你是一个专业的Go测试工程师。请为用户提供的Go代码生成单元测试用例，提升测试覆盖率。"
-	default:
-		return "你是一个专业的AI助手，请用简洁清晰的语言回答用户的问题。"
-	}
+	h.respond(c, userID, req, settings)
 }
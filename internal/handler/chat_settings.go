@@ -0,0 +1,276 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go-ai-copilot/internal/cache"
+	"go-ai-copilot/internal/model"
+	"go-ai-copilot/internal/prompt"
+	"go-ai-copilot/internal/rag"
+	"go-ai-copilot/internal/store"
+	"go-ai-copilot/pkg/ai"
+)
+
+// errSessionNotFound 会话不存在或不属于当前用户
+var errSessionNotFound = errors.New("会话不存在")
+
+// chatSettings 本次对话生效的配置
+// 优先级：请求参数 > 会话配置 > 会话助手 > 服务端配置
+type chatSettings struct {
+	userID           uint
+	knowledgeBases   store.KnowledgeBaseRepository
+	assistants       store.AssistantRepository
+	mode             string
+	template         string           // 提示词模板原文，为空时不设置System Prompt
+	literal          bool             // template为助手的普通文本System Prompt，原样使用不按模板渲染
+	resolved         *prompt.Resolved // 使用模式模板时记录所用版本，自定义System Prompt时为nil
+	vars             prompt.Vars
+	model            string            // 为空时使用服务端配置
+	temperature      *float64          // 为空时使用服务端配置
+	knowledgeBaseIDs []uint            // 对话时自动检索的知识库
+	retrieveAll      bool              // rag模式未指定知识库时检索用户的全部文档
+	filter           store.ChunkFilter // 本次检索的过滤条件
+}
+
+// resolveSettings 解析本次对话的配置
+// 有会话时由会话的模式、助手和会话级配置决定，请求中的mode只在没有会话时生效；
+// 请求中的model、temperature、knowledge_base_ids、filter对本次调用生效
+func (h *ChatHandler) resolveSettings(userID uint, req ChatRequest) (*chatSettings, error) {
+	s := &chatSettings{
+		userID:         userID,
+		knowledgeBases: h.store.KnowledgeBases,
+		assistants:     h.store.Assistants,
+		mode:           req.Mode,
+		vars:           prompt.Vars{Language: req.Language},
+	}
+
+	if req.SessionID > 0 {
+		session, err := h.store.Sessions.Get(context.Background(), userID, req.SessionID)
+		if err != nil {
+			if store.IsNotFound(err) {
+				return nil, errSessionNotFound
+			}
+			return nil, err
+		}
+		if err := s.applySession(session); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Model != "" {
+		s.model = req.Model
+	}
+	if req.Temperature != nil {
+		s.temperature = req.Temperature
+	}
+	if len(req.KnowledgeBaseIDs) > 0 {
+		scope, err := retrievalScope(context.Background(), h.store.KnowledgeBases, userID, req.KnowledgeBaseIDs)
+		if err != nil {
+			return nil, err
+		}
+		s.knowledgeBaseIDs = scope.KnowledgeBaseIDs
+	}
+	filter, err := req.Filter.chunkFilter()
+	if err != nil {
+		return nil, err
+	}
+	s.filter = filter
+
+	// 未使用自定义System Prompt时按模式选择模板（A/B测试按会话分流）
+	if s.template == "" && s.mode != "" {
+		stickyKey := req.SessionID
+		if stickyKey == 0 {
+			stickyKey = userID
+		}
+		tpl, err := prompt.Resolve(context.Background(), h.store.Prompts, s.mode, stickyKey)
+		if err != nil {
+			return nil, err
+		}
+		s.mode = tpl.Mode
+		s.template = tpl.Content
+		s.resolved = tpl
+	}
+	s.retrieveAll = s.mode == "rag" && len(s.knowledgeBaseIDs) == 0
+
+	if user, err := h.store.Users.Get(context.Background(), userID); err == nil {
+		s.vars.Nickname = user.Nickname
+	}
+	return s, nil
+}
+
+// applySession 应用会话的模式、助手和会话级配置
+// 助手被删除或不再可见时回退到会话记录的模式
+func (s *chatSettings) applySession(session *model.Session) error {
+	s.mode = session.Mode
+	if s.mode == "custom" {
+		s.mode = ""
+	}
+
+	if session.AssistantID != nil {
+		if a, err := s.assistants.GetVisible(context.Background(), s.userID, *session.AssistantID); err == nil {
+			if err := s.apply(a); err != nil {
+				return err
+			}
+		}
+	}
+
+	if session.Model != "" {
+		s.model = session.Model
+	}
+	if session.Temperature != nil {
+		s.temperature = session.Temperature
+	}
+	if len(session.KnowledgeBaseIDs) > 0 {
+		kbIDs, err := s.knowledgeBases.Accessible(context.Background(), s.userID, session.KnowledgeBaseIDs)
+		if err != nil {
+			return err
+		}
+		s.knowledgeBaseIDs = kbIDs
+	}
+	return nil
+}
+
+// apply 应用助手的配置
+// 团队共享的助手可能关联了当前用户无权访问的知识库，这些知识库会被忽略
+func (s *chatSettings) apply(a *model.Assistant) error {
+	s.mode = a.Mode
+	if a.SystemPrompt != "" {
+		s.mode = "custom"
+		s.template = a.SystemPrompt
+		s.literal = !a.Templated
+	}
+	s.model = a.Model
+	s.temperature = a.Temperature
+
+	kbIDs, err := s.knowledgeBases.Accessible(context.Background(), s.userID, a.KnowledgeBaseIDs)
+	if err != nil {
+		return err
+	}
+	s.knowledgeBaseIDs = kbIDs
+	return nil
+}
+
+// settingsErrorStatus 解析对话配置失败时返回的HTTP状态码
+func settingsErrorStatus(err error) int {
+	if errors.Is(err, errSessionNotFound) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, errKnowledgeBaseForbidden) {
+		return http.StatusForbidden
+	}
+	if errors.Is(err, errInvalidFilter) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// callOptions 单次调用的模型和温度
+func (s *chatSettings) callOptions() []ai.CallOption {
+	var opts []ai.CallOption
+	if s.model != "" {
+		opts = append(opts, ai.WithModel(s.model))
+	}
+	if s.temperature != nil {
+		opts = append(opts, ai.WithTemperature(*s.temperature))
+	}
+	return opts
+}
+
+// render 渲染System Prompt
+// 模板未引用 {{.Context}} 时，检索到的参考资料追加在末尾
+func (s *chatSettings) render(context string) (string, error) {
+	content := ""
+	if s.literal {
+		content = s.template
+	} else if s.template != "" {
+		vars := s.vars
+		vars.Context = context
+		var err error
+		content, err = prompt.Render(s.template, vars)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if context != "" && (s.literal || !strings.Contains(s.template, ".Context")) {
+		if content != "" {
+			content += "\n\n"
+		}
+		content += "参考资料：\n" + context
+	}
+	return content, nil
+}
+
+// cacheKey 语义缓存分桶键（System Prompt取未注入参考资料的版本）
+func (h *ChatHandler) cacheKey(s *chatSettings) (cache.SemanticKey, error) {
+	base, err := s.render("")
+	if err != nil {
+		return cache.SemanticKey{}, err
+	}
+
+	key := cache.SemanticKey{
+		Mode:         s.mode,
+		Model:        s.model,
+		SystemPrompt: base,
+		Template:     templateIdentity(s.resolved),
+	}
+	if key.Mode == "" {
+		key.Mode = prompt.DefaultMode
+	}
+	if key.Model == "" {
+		key.Model = h.client.Model()
+	}
+	if len(s.knowledgeBaseIDs) > 0 || s.retrieveAll {
+		key.Scopes = cacheScopes(s.scope())
+		key.Filter = filterCacheKey(s.filter)
+	}
+	return key, nil
+}
+
+// templateIdentity 提示词模板标识（模式、模板ID和版本），模板更新或A/B测试分流到其他版本时不复用语义缓存
+func templateIdentity(tpl *prompt.Resolved) string {
+	if tpl == nil {
+		return ""
+	}
+	if tpl.TemplateID == nil {
+		return tpl.Mode + "@builtin"
+	}
+	return fmt.Sprintf("%s#%d@%d", tpl.Mode, *tpl.TemplateID, tpl.Version)
+}
+
+// scope 检索范围
+func (s *chatSettings) scope() rag.Scope {
+	return rag.Scope{UserID: s.userID, KnowledgeBaseIDs: s.knowledgeBaseIDs, Filter: s.filter}
+}
+
+// retrieve 从关联的知识库检索参考资料，返回最终的System Prompt和引用的文档
+// embedding为问题向量，为空时按需计算
+func (h *ChatHandler) retrieve(ctx context.Context, s *chatSettings, question string, embedding []float32) (string, []uint, error) {
+	if (len(s.knowledgeBaseIDs) == 0 && !s.retrieveAll) || h.embeddingClient == nil {
+		systemPrompt, err := s.render("")
+		return systemPrompt, nil, err
+	}
+
+	if embedding == nil {
+		var err error
+		embedding, err = h.embeddingClient.GetEmbedding(ctx, question)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	results, err := rag.Search(ctx, h.store.Chunks, h.space, s.scope(), embedding, 3, 0.5)
+	if err != nil {
+		return "", nil, err
+	}
+
+	systemPrompt, err := s.render(rag.FormatContext(results))
+	if err != nil {
+		return "", nil, err
+	}
+	return systemPrompt, rag.DocumentIDs(results), nil
+}
@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"go-ai-copilot/internal/model"
+	"go-ai-copilot/internal/rag"
+)
+
+// maxPreviewBodySize 分块预览请求体大小上限：内容上限加上JSON转义和表单字段的余量
+const maxPreviewBodySize = rag.MaxFileSize + 1<<20
+
+// PreviewChunksRequest 分块预览请求，分块设置未指定的字段使用知识库（或默认）的设置
+type PreviewChunksRequest struct {
+	Text            string `json:"text"`
+	KnowledgeBaseID uint   `json:"knowledge_base_id"`
+	rag.SplitterConfig
+}
+
+// ChunkPreview 预览的分块
+type ChunkPreview struct {
+	Index     int    `json:"index"`
+	Text      string `json:"text"`
+	Chars     int    `json:"chars"`
+	Bytes     int    `json:"bytes"`
+	Tokens    int    `json:"tokens"` // 估算值
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+}
+
+// PreviewChunksResponse 分块预览结果
+type PreviewChunksResponse struct {
+	Splitter    rag.SplitterConfig `json:"splitter"` // 实际使用的分块设置
+	TotalChunks int                `json:"total_chunks"`
+	TotalTokens int                `json:"total_tokens"`
+	MaxTokens   int                `json:"max_tokens"` // 最大分块的token数
+	Chunks      []ChunkPreview     `json:"chunks"`
+}
+
+// PreviewChunks 按分块设置预览文本（或文件）会被分成的块，不向量化也不保存
+// 请求体为JSON（text）或表单（file 或 text，分块设置为同名表单字段）
+func (h *RAGHandler) PreviewChunks(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	req, err := bindPreviewRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+	if strings.TrimSpace(req.Text) == "" {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: "内容不能为空",
+		})
+		return
+	}
+	if len(req.Text) > rag.MaxFileSize {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: "内容不能超过10MB",
+		})
+		return
+	}
+
+	// 以知识库的设置为基础（可访问的知识库，含团队共享的）
+	var config rag.SplitterConfig
+	if req.KnowledgeBaseID != 0 {
+		ctx := c.Request.Context()
+		accessible, err := h.store.KnowledgeBases.Accessible(ctx, userID, []uint{req.KnowledgeBaseID})
+		if err != nil || len(accessible) == 0 {
+			c.JSON(http.StatusNotFound, AuthResponse{
+				Code:    404,
+				Message: "知识库不存在",
+			})
+			return
+		}
+		kb, err := h.store.KnowledgeBases.Get(ctx, req.KnowledgeBaseID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, AuthResponse{
+				Code:    404,
+				Message: "知识库不存在",
+			})
+			return
+		}
+		config = splitterConfig(*kb)
+	}
+	config = mergeSplitterConfig(config, req.SplitterConfig)
+
+	config, err = config.Normalize()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+	splitter, err := rag.NewSplitter(config)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	resp := PreviewChunksResponse{Splitter: config, Chunks: []ChunkPreview{}}
+	for i, chunk := range splitter.Split(req.Text) {
+		tokens := rag.EstimateTokens(chunk.Text)
+		resp.Chunks = append(resp.Chunks, ChunkPreview{
+			Index:     i,
+			Text:      chunk.Text,
+			Chars:     utf8.RuneCountInString(chunk.Text),
+			Bytes:     len(chunk.Text),
+			Tokens:    tokens,
+			StartLine: chunk.StartLine,
+			EndLine:   chunk.EndLine,
+		})
+		resp.TotalTokens += tokens
+		resp.MaxTokens = max(resp.MaxTokens, tokens)
+	}
+	resp.TotalChunks = len(resp.Chunks)
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+		Data:    resp,
+	})
+}
+
+// bindPreviewRequest 解析JSON或表单格式的分块预览请求
+// 请求体超过 maxPreviewBodySize 时不再继续读取
+func bindPreviewRequest(c *gin.Context) (*PreviewChunksRequest, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxPreviewBodySize)
+
+	var req PreviewChunksRequest
+	if !strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			return nil, previewBodyError(err)
+		}
+		return &req, nil
+	}
+	if _, err := c.MultipartForm(); err != nil {
+		return nil, previewBodyError(err)
+	}
+
+	req.Text = c.PostForm("text")
+	req.Strategy = c.PostForm("strategy")
+	if v := c.PostForm("knowledge_base_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return nil, errors.New("knowledge_base_id 格式错误")
+		}
+		req.KnowledgeBaseID = uint(id)
+	}
+	if v := c.PostForm("chunk_size"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, errors.New("chunk_size 格式错误")
+		}
+		req.ChunkSize = size
+	}
+	if v := c.PostForm("chunk_overlap"); v != "" {
+		overlap, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, errors.New("chunk_overlap 格式错误")
+		}
+		req.ChunkOverlap = &overlap
+	}
+
+	// 上传的文件优先于text
+	file, err := c.FormFile("file")
+	if err != nil {
+		return &req, nil
+	}
+	if !rag.IsSupportedFile(file.Filename) {
+		return nil, errors.New("不支持的文件类型，仅支持: " + strings.Join(rag.SupportedFileTypes, ", "))
+	}
+	if file.Size > rag.MaxFileSize {
+		return nil, errors.New("文件大小不能超过10MB")
+	}
+	src, err := file.Open()
+	if err != nil {
+		return nil, errors.New("文件读取失败")
+	}
+	defer src.Close()
+	content, err := io.ReadAll(src)
+	if err != nil {
+		return nil, errors.New("文件读取失败")
+	}
+	req.Text = string(content)
+	return &req, nil
+}
+
+// previewBodyError 请求体解析失败的提示
+func previewBodyError(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return errors.New("内容不能超过10MB")
+	}
+	return errors.New("参数错误: " + err.Error())
+}
+
+// splitterConfig 知识库的分块设置，没有设置时为空（使用默认设置）
+func splitterConfig(kb model.KnowledgeBase) rag.SplitterConfig {
+	if kb.ChunkStrategy == "" {
+		return rag.SplitterConfig{}
+	}
+	overlap := kb.ChunkOverlap
+	return rag.SplitterConfig{Strategy: kb.ChunkStrategy, ChunkSize: kb.ChunkSize, ChunkOverlap: &overlap}
+}
+
+// mergeSplitterConfig 用指定的字段覆盖分块设置
+func mergeSplitterConfig(base, override rag.SplitterConfig) rag.SplitterConfig {
+	if override.Strategy != "" {
+		base.Strategy = override.Strategy
+	}
+	if override.ChunkSize != 0 {
+		base.ChunkSize = override.ChunkSize
+	}
+	if override.ChunkOverlap != nil {
+		base.ChunkOverlap = override.ChunkOverlap
+	}
+	return base
+}
+
+// documentSplitter 文档所在知识库的分块器，知识库没有设置（或文档不在知识库中）时使用默认设置
+func (h *RAGHandler) documentSplitter(ctx context.Context, kbID uint) (rag.Splitter, error) {
+	var config rag.SplitterConfig
+	if kbID != 0 {
+		if kb, err := h.store.KnowledgeBases.Get(ctx, kbID); err == nil {
+			config = splitterConfig(*kb)
+		}
+	}
+	return rag.NewSplitter(config)
+}
@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"go-ai-copilot/internal/config"
+	"go-ai-copilot/internal/connector"
+	"go-ai-copilot/internal/model"
+	"go-ai-copilot/internal/store"
+)
+
+// startConnectors 启动配置的连接器，配置错误的连接器记录日志后跳过
+func (h *RAGHandler) startConnectors(ctx context.Context, cfg config.ConnectorsConfig) {
+	for _, c := range cfg.Directories {
+		syncer, err := h.newDirectoryConnector(c)
+		if err != nil {
+			log.Printf("连接器 %s 启动失败: %v", c.Name, err)
+			continue
+		}
+		go syncer.Run(ctx)
+	}
+	for _, c := range cfg.Git {
+		syncer, err := h.newGitConnector(c)
+		if err != nil {
+			log.Printf("连接器 %s 启动失败: %v", c.Name, err)
+			continue
+		}
+		go syncer.Run(ctx)
+	}
+}
+
+// newDirectoryConnector 创建目录同步连接器
+func (h *RAGHandler) newDirectoryConnector(c config.DirectoryConnectorConfig) (*connector.Directory, error) {
+	kb, err := h.connectorKnowledgeBase(c.KnowledgeBaseID)
+	if err != nil {
+		return nil, err
+	}
+	tags, metadata, err := connectorLabels(c.Tags, c.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	return connector.NewDirectory(h.store.ConnectorFiles, connectorIngester{h}, connector.Config{
+		Name:            c.Name,
+		Root:            c.Path,
+		UserID:          kb.UserID,
+		KnowledgeBaseID: kb.ID,
+		Include:         c.Include,
+		Exclude:         c.Exclude,
+		Interval:        c.Interval,
+		Watch:           c.Watch,
+		Tags:            tags,
+		Metadata:        metadata,
+	})
+}
+
+// newGitConnector 创建git仓库索引连接器
+func (h *RAGHandler) newGitConnector(c config.GitConnectorConfig) (*connector.Git, error) {
+	kb, err := h.connectorKnowledgeBase(c.KnowledgeBaseID)
+	if err != nil {
+		return nil, err
+	}
+	tags, metadata, err := connectorLabels(c.Tags, c.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	return connector.NewGit(h.store.ConnectorFiles, connectorIngester{h}, connector.GitConfig{
+		Name:            c.Name,
+		Repo:            c.Path,
+		Branch:          c.Branch,
+		UserID:          kb.UserID,
+		KnowledgeBaseID: kb.ID,
+		Include:         c.Include,
+		Exclude:         c.Exclude,
+		Interval:        c.Interval,
+		Tags:            tags,
+		Metadata:        metadata,
+	})
+}
+
+// connectorKnowledgeBase 连接器写入的知识库，文档归属知识库的创建者
+func (h *RAGHandler) connectorKnowledgeBase(id uint) (*model.KnowledgeBase, error) {
+	kb, err := h.store.KnowledgeBases.Get(context.Background(), id)
+	if err != nil {
+		return nil, fmt.Errorf("知识库 %d 不存在", id)
+	}
+	return kb, nil
+}
+
+// connectorLabels 校验连接器配置的标签和元数据，未配置时为nil（保留文档原有的值）
+func connectorLabels(tags []string, metadata map[string]string) ([]string, map[string]string, error) {
+	if tags != nil {
+		tags = normalizeTags(tags)
+	}
+	if metadata != nil {
+		var err error
+		if metadata, err = normalizeMetadata(metadata); err != nil {
+			return nil, nil, err
+		}
+	}
+	return tags, metadata, nil
+}
+
+// connectorIngester 连接器写入知识库，与上传文档使用相同的版本和处理流程
+type connectorIngester struct {
+	h *RAGHandler
+}
+
+func (i connectorIngester) Upsert(ctx context.Context, f connector.File) (uint, error) {
+	sourceKey, err := documentSourceKey(f.Path, f.FileName)
+	if err != nil {
+		return 0, err
+	}
+	req := ingestRequest{
+		UserID:          f.UserID,
+		KnowledgeBaseID: f.KnowledgeBaseID,
+		SourceKey:       sourceKey,
+		FileName:        f.FileName,
+		Content:         f.Content,
+	}
+	if f.Tags != nil {
+		req.Tags = &f.Tags
+	}
+	if f.Metadata != nil {
+		req.Metadata = &f.Metadata
+	}
+
+	result, err := i.h.ingest(ctx, req)
+	if err != nil {
+		return 0, err
+	}
+	if !result.Unchanged {
+		// 同步处理，向量化完成后才记录同步进度，失败的文件下次扫描时重试
+		if err := i.h.processVersion(result.Document, result.Version, string(f.Content)); err != nil {
+			return 0, err
+		}
+	}
+	return result.Document.ID, nil
+}
+
+func (i connectorIngester) Remove(ctx context.Context, userID, documentID uint) error {
+	doc, err := i.h.store.Documents.Get(ctx, userID, documentID)
+	if store.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := i.h.store.Documents.Delete(ctx, doc); err != nil {
+		return err
+	}
+	i.h.invalidateSemanticCache(*doc)
+	i.h.deleteBlobs(ctx, []uint{doc.ID})
+	return nil
+}
@@ -0,0 +1,224 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"go-ai-copilot/internal/model"
+	"go-ai-copilot/internal/store"
+)
+
+const (
+	maxMetadataKeys       = 20
+	maxMetadataKeyRunes   = 50
+	maxMetadataValueRunes = 200
+)
+
+// errInvalidFilter 检索过滤条件错误
+var errInvalidFilter = errors.New("检索过滤条件错误")
+
+// RetrievalFilter 检索过滤条件，按文档的标签、元数据、文件类型和上传日期限定检索范围
+type RetrievalFilter struct {
+	Tags      []string          `json:"tags"`       // 同时包含全部标签
+	Metadata  map[string]string `json:"metadata"`   // 元数据全部匹配
+	FileTypes []string          `json:"file_types"` // 文件类型之一，如 md、go
+	From      string            `json:"from"`       // 上传日期，支持 2006-01-02 和 RFC3339
+	To        string            `json:"to"`         // 只给出日期时包含当天
+}
+
+// chunkFilter 校验并转换为分块过滤条件
+func (f *RetrievalFilter) chunkFilter() (store.ChunkFilter, error) {
+	var filter store.ChunkFilter
+	if f == nil {
+		return filter, nil
+	}
+
+	if len(f.Tags) > 0 {
+		filter.Tags = normalizeTags(f.Tags)
+	}
+	metadata, err := normalizeMetadata(f.Metadata)
+	if err != nil {
+		return filter, fmt.Errorf("%w: %v", errInvalidFilter, err)
+	}
+	if len(metadata) > 0 {
+		filter.Metadata = metadata
+	}
+	for _, t := range f.FileTypes {
+		if t = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(t)), "."); t != "" {
+			filter.FileTypes = append(filter.FileTypes, t)
+		}
+	}
+
+	if filter.From, err = parseDate(f.From); err != nil {
+		return filter, fmt.Errorf("%w: from 日期格式错误", errInvalidFilter)
+	}
+	if filter.To, err = parseDate(f.To); err != nil {
+		return filter, fmt.Errorf("%w: to 日期格式错误", errInvalidFilter)
+	}
+	if filter.To != nil && len(f.To) == len("2006-01-02") {
+		end := filter.To.AddDate(0, 0, 1)
+		filter.To = &end
+	}
+	return filter, nil
+}
+
+// filterCacheKey 过滤条件在语义缓存分桶键中的表示，没有过滤条件时为空
+func filterCacheKey(f store.ChunkFilter) string {
+	if f.IsEmpty() {
+		return ""
+	}
+	f.Tags = append([]string(nil), f.Tags...)
+	sort.Strings(f.Tags)
+	f.FileTypes = append([]string(nil), f.FileTypes...)
+	sort.Strings(f.FileTypes)
+	if f.From != nil {
+		from := f.From.UTC()
+		f.From = &from
+	}
+	if f.To != nil {
+		to := f.To.UTC()
+		f.To = &to
+	}
+	data, _ := json.Marshal(f)
+	return string(data)
+}
+
+// normalizeMetadata 去除键值首尾空白，忽略空键，限制数量和长度
+func normalizeMetadata(metadata map[string]string) (map[string]string, error) {
+	result := make(map[string]string, len(metadata))
+	for key, value := range metadata {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		if utf8.RuneCountInString(key) > maxMetadataKeyRunes {
+			return nil, fmt.Errorf("元数据的键不能超过%d个字符", maxMetadataKeyRunes)
+		}
+		value = strings.TrimSpace(value)
+		if utf8.RuneCountInString(value) > maxMetadataValueRunes {
+			return nil, fmt.Errorf("元数据 %s 的值不能超过%d个字符", key, maxMetadataValueRunes)
+		}
+		result[key] = value
+	}
+	if len(result) > maxMetadataKeys {
+		return nil, fmt.Errorf("元数据不能超过%d项", maxMetadataKeys)
+	}
+	return result, nil
+}
+
+// uploadLabels 上传表单中的标签（tags，逗号分隔或多个字段）和元数据（metadata，JSON对象）
+// 未传入的字段返回nil，重新上传时保留文档原有的值
+func uploadLabels(c *gin.Context) (*[]string, *map[string]string, error) {
+	var tags *[]string
+	if values, ok := c.GetPostFormArray("tags"); ok {
+		var split []string
+		for _, v := range values {
+			split = append(split, strings.Split(v, ",")...)
+		}
+		normalized := normalizeTags(split)
+		tags = &normalized
+	}
+
+	var metadata *map[string]string
+	if raw, ok := c.GetPostForm("metadata"); ok {
+		m := map[string]string{}
+		if strings.TrimSpace(raw) != "" {
+			if err := json.Unmarshal([]byte(raw), &m); err != nil {
+				return nil, nil, errors.New("metadata 必须是值为字符串的JSON对象")
+			}
+		}
+		normalized, err := normalizeMetadata(m)
+		if err != nil {
+			return nil, nil, err
+		}
+		metadata = &normalized
+	}
+	return tags, metadata, nil
+}
+
+// UpdateDocumentRequest 更新文档请求，只更新传入的字段
+type UpdateDocumentRequest struct {
+	Tags     *[]string          `json:"tags"`
+	Metadata *map[string]string `json:"metadata"`
+}
+
+// UpdateDocument 更新文档的标签和元数据，文档的分块同时更新
+func (h *RAGHandler) UpdateDocument(c *gin.Context) {
+	userID := c.GetUint("userID")
+	ctx := c.Request.Context()
+
+	var req UpdateDocumentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: "参数错误",
+		})
+		return
+	}
+
+	doc, err := h.store.Documents.Get(ctx, userID, idParam(c, "id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, AuthResponse{
+			Code:    404,
+			Message: "文档不存在",
+		})
+		return
+	}
+
+	if req.Tags != nil {
+		tags := normalizeTags(*req.Tags)
+		req.Tags = &tags
+	}
+	if req.Metadata != nil {
+		metadata, err := normalizeMetadata(*req.Metadata)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AuthResponse{
+				Code:    400,
+				Message: err.Error(),
+			})
+			return
+		}
+		req.Metadata = &metadata
+	}
+
+	if err := h.setLabels(ctx, doc, req.Tags, req.Metadata); err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "更新失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+		Data:    doc,
+	})
+}
+
+// setLabels 更新文档（及其分块）的标签和元数据，为nil的字段保持不变
+func (h *RAGHandler) setLabels(ctx context.Context, doc *model.RAGDocument, tags *[]string, metadata *map[string]string) error {
+	if tags == nil && metadata == nil {
+		return nil
+	}
+	newTags, newMetadata := doc.Tags, doc.Metadata
+	if tags != nil {
+		newTags = *tags
+	}
+	if metadata != nil {
+		newMetadata = *metadata
+	}
+	if err := h.store.Documents.SetMetadata(ctx, doc.ID, newTags, newMetadata); err != nil {
+		return err
+	}
+	doc.Tags, doc.Metadata = newTags, newMetadata
+	h.invalidateSemanticCache(*doc)
+	return nil
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-ai-copilot/internal/events"
+)
+
+// eventsKeepalive 心跳间隔，防止代理断开空闲连接
+const eventsKeepalive = 30 * time.Second
+
+// EventsHandler 事件推送处理器
+type EventsHandler struct {
+	hub *events.Hub
+}
+
+// NewEventsHandler 创建事件推送处理器
+func NewEventsHandler(hub *events.Hub) *EventsHandler {
+	return &EventsHandler{hub: hub}
+}
+
+// Stream SSE推送当前用户的事件（如后台生成的会话标题）
+func (h *EventsHandler) Stream(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "不支持流式响应",
+		})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	ch, unsubscribe := h.hub.Subscribe(userID)
+	defer unsubscribe()
+
+	c.SSEvent("ready", gin.H{"status": "connected"})
+	flusher.Flush()
+
+	ticker := time.NewTicker(eventsKeepalive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event := <-ch:
+			c.SSEvent(event.Type, event.Data)
+			flusher.Flush()
+		case <-ticker.C:
+			c.SSEvent("ping", gin.H{"time": time.Now().Unix()})
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go-ai-copilot/internal/model"
+)
+
+// FolderRequest 创建/重命名文件夹请求
+type FolderRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// GetFolders 获取文件夹列表
+func (h *SessionHandler) GetFolders(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	folders, err := h.store.Folders.List(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "获取文件夹列表失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+		Data:    folders,
+	})
+}
+
+// CreateFolder 创建文件夹
+func (h *SessionHandler) CreateFolder(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var req FolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	folder := model.Folder{UserID: userID, Name: req.Name}
+	if err := h.store.Folders.Create(c.Request.Context(), &folder); err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "文件夹创建失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+		Data:    folder,
+	})
+}
+
+// UpdateFolder 重命名文件夹
+func (h *SessionHandler) UpdateFolder(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var req FolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	folder, err := h.store.Folders.Get(c.Request.Context(), userID, idParam(c, "id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, AuthResponse{
+			Code:    404,
+			Message: "文件夹不存在",
+		})
+		return
+	}
+
+	folder.Name = req.Name
+	if err := h.store.Folders.Save(c.Request.Context(), folder); err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "更新失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+		Data:    folder,
+	})
+}
+
+// DeleteFolder 删除文件夹，其中的会话移出文件夹（不删除会话）
+func (h *SessionHandler) DeleteFolder(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	folder, err := h.store.Folders.Get(c.Request.Context(), userID, idParam(c, "id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, AuthResponse{
+			Code:    404,
+			Message: "文件夹不存在",
+		})
+		return
+	}
+
+	if err := h.store.Folders.Delete(c.Request.Context(), folder); err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "删除失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+	})
+}
@@ -0,0 +1,232 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go-ai-copilot/internal/model"
+	"go-ai-copilot/internal/rag"
+	"go-ai-copilot/internal/store"
+)
+
+// CreateKnowledgeBaseRequest 创建知识库请求
+type CreateKnowledgeBaseRequest struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Description string `json:"description" binding:"max=255"`
+	Visibility  string `json:"visibility" binding:"omitempty,oneof=private team"`
+
+	// 分块设置（可选），未指定的字段使用默认值
+	ChunkStrategy string `json:"chunk_strategy"`
+	ChunkSize     int    `json:"chunk_size"`
+	ChunkOverlap  *int   `json:"chunk_overlap"`
+}
+
+// UpdateKnowledgeBaseRequest 更新知识库请求
+type UpdateKnowledgeBaseRequest struct {
+	Name        *string `json:"name" binding:"omitempty,max=100"`
+	Description *string `json:"description" binding:"omitempty,max=255"`
+	Visibility  *string `json:"visibility" binding:"omitempty,oneof=private team"`
+
+	// 分块设置，未指定的字段保留原有的值，修改后对之后上传（或同步）的文档生效
+	ChunkStrategy *string `json:"chunk_strategy"`
+	ChunkSize     *int    `json:"chunk_size"`
+	ChunkOverlap  *int    `json:"chunk_overlap"`
+}
+
+// setSplitter 校验分块设置并写入知识库
+func setSplitter(kb *model.KnowledgeBase, config rag.SplitterConfig) error {
+	config, err := config.Normalize()
+	if err != nil {
+		return err
+	}
+	kb.ChunkStrategy = config.Strategy
+	kb.ChunkSize = config.ChunkSize
+	kb.ChunkOverlap = *config.ChunkOverlap
+	return nil
+}
+
+// ListKnowledgeBases 获取可访问的知识库列表（自己创建的和团队共享的）
+func (h *RAGHandler) ListKnowledgeBases(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	kbs, err := h.store.KnowledgeBases.List(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "获取知识库列表失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+		Data:    kbs,
+	})
+}
+
+// CreateKnowledgeBase 创建知识库
+func (h *RAGHandler) CreateKnowledgeBase(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var req CreateKnowledgeBaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	visibility := req.Visibility
+	if visibility == "" {
+		visibility = model.VisibilityPrivate
+	}
+
+	kb := model.KnowledgeBase{
+		UserID:      userID,
+		Name:        req.Name,
+		Description: req.Description,
+		Visibility:  visibility,
+	}
+	if req.ChunkStrategy != "" || req.ChunkSize != 0 || req.ChunkOverlap != nil {
+		config := rag.SplitterConfig{Strategy: req.ChunkStrategy, ChunkSize: req.ChunkSize, ChunkOverlap: req.ChunkOverlap}
+		if err := setSplitter(&kb, config); err != nil {
+			c.JSON(http.StatusBadRequest, AuthResponse{
+				Code:    400,
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+	if err := h.store.KnowledgeBases.Create(c.Request.Context(), &kb); err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "知识库创建失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+		Data:    kb,
+	})
+}
+
+// UpdateKnowledgeBase 更新知识库（仅创建者）
+func (h *RAGHandler) UpdateKnowledgeBase(c *gin.Context) {
+	userID := c.GetUint("userID")
+	id := idParam(c, "id")
+	ctx := c.Request.Context()
+
+	var req UpdateKnowledgeBaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: "参数错误",
+		})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.Visibility != nil {
+		updates["visibility"] = *req.Visibility
+	}
+	if req.ChunkStrategy != nil || req.ChunkSize != nil || req.ChunkOverlap != nil {
+		kb, err := h.store.KnowledgeBases.GetOwned(ctx, userID, id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, AuthResponse{
+				Code:    404,
+				Message: "知识库不存在",
+			})
+			return
+		}
+		var override rag.SplitterConfig
+		if req.ChunkStrategy != nil {
+			override.Strategy = *req.ChunkStrategy
+		}
+		if req.ChunkSize != nil {
+			override.ChunkSize = *req.ChunkSize
+		}
+		override.ChunkOverlap = req.ChunkOverlap
+		if err := setSplitter(kb, mergeSplitterConfig(splitterConfig(*kb), override)); err != nil {
+			c.JSON(http.StatusBadRequest, AuthResponse{
+				Code:    400,
+				Message: err.Error(),
+			})
+			return
+		}
+		updates["chunk_strategy"] = kb.ChunkStrategy
+		updates["chunk_size"] = kb.ChunkSize
+		updates["chunk_overlap"] = kb.ChunkOverlap
+	}
+	if len(updates) == 0 {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: "没有需要更新的字段",
+		})
+		return
+	}
+
+	if err := h.store.KnowledgeBases.Update(ctx, userID, id, updates); err != nil {
+		if store.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, AuthResponse{
+				Code:    404,
+				Message: "知识库不存在",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "更新失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+	})
+}
+
+// DeleteKnowledgeBase 删除知识库及其中的文档（仅创建者）
+func (h *RAGHandler) DeleteKnowledgeBase(c *gin.Context) {
+	userID := c.GetUint("userID")
+	id := idParam(c, "id")
+	ctx := c.Request.Context()
+
+	kb, err := h.store.KnowledgeBases.GetOwned(ctx, userID, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, AuthResponse{
+			Code:    404,
+			Message: "知识库不存在",
+		})
+		return
+	}
+
+	docIDs, err := h.store.KnowledgeBases.Delete(ctx, kb)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "删除失败",
+		})
+		return
+	}
+
+	for _, docID := range docIDs {
+		h.invalidateSemanticCache(model.RAGDocument{ID: docID, UserID: userID, KnowledgeBaseID: kb.ID})
+	}
+	h.deleteBlobs(ctx, docIDs)
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+	})
+}
@@ -0,0 +1,274 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go-ai-copilot/internal/model"
+	"go-ai-copilot/internal/prompt"
+	"go-ai-copilot/internal/store"
+)
+
+// PromptHandler 提示词模板管理处理器（管理员）
+type PromptHandler struct {
+	store *store.Store
+}
+
+// NewPromptHandler 创建提示词模板管理处理器
+func NewPromptHandler(st *store.Store) *PromptHandler {
+	return &PromptHandler{store: st}
+}
+
+// CreatePromptRequest 创建模板版本请求
+type CreatePromptRequest struct {
+	Mode        string `json:"mode" binding:"required,max=50"`
+	Content     string `json:"content" binding:"required"`
+	Description string `json:"description" binding:"max=255"`
+	Weight      int    `json:"weight" binding:"min=0"`
+}
+
+// UpdatePromptRequest 更新模板请求（内容不可修改，修改内容请发布新版本）
+type UpdatePromptRequest struct {
+	Description *string `json:"description" binding:"omitempty,max=255"`
+	Weight      *int    `json:"weight" binding:"omitempty,min=0"`
+}
+
+// ListPrompts 获取模板列表，可按 mode 过滤
+func (h *PromptHandler) ListPrompts(c *gin.Context) {
+	templates, err := h.store.Prompts.List(c.Request.Context(), c.Query("mode"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "获取模板列表失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+		Data:    templates,
+	})
+}
+
+// GetPrompt 获取单个模板
+func (h *PromptHandler) GetPrompt(c *gin.Context) {
+	tpl, err := h.store.Prompts.Get(c.Request.Context(), idParam(c, "id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, AuthResponse{
+			Code:    404,
+			Message: "模板不存在",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+		Data:    tpl,
+	})
+}
+
+// CreatePrompt 发布模板新版本
+func (h *PromptHandler) CreatePrompt(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var req CreatePromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	// 校验模板语法
+	if _, err := prompt.Parse(req.Content); err != nil {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	tpl := model.PromptTemplate{
+		Mode:        req.Mode,
+		Content:     req.Content,
+		Description: req.Description,
+		Weight:      req.Weight,
+		CreatedBy:   userID,
+	}
+	if err := h.store.Prompts.Create(c.Request.Context(), &tpl); err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "模板创建失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+		Data:    tpl,
+	})
+}
+
+// UpdatePrompt 更新模板描述或A/B测试权重
+func (h *PromptHandler) UpdatePrompt(c *gin.Context) {
+	var req UpdatePromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: "参数错误",
+		})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.Weight != nil {
+		updates["weight"] = *req.Weight
+	}
+	if len(updates) == 0 {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: "没有需要更新的字段",
+		})
+		return
+	}
+
+	err := h.store.Prompts.Update(c.Request.Context(), idParam(c, "id"), updates)
+	if store.IsNotFound(err) {
+		c.JSON(http.StatusNotFound, AuthResponse{
+			Code:    404,
+			Message: "模板不存在",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "更新失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+	})
+}
+
+// DeletePrompt 删除模板版本（软删除，历史消息仍可追溯版本号）
+func (h *PromptHandler) DeletePrompt(c *gin.Context) {
+	err := h.store.Prompts.Delete(c.Request.Context(), idParam(c, "id"))
+	if store.IsNotFound(err) {
+		c.JSON(http.StatusNotFound, AuthResponse{
+			Code:    404,
+			Message: "模板不存在",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "删除失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+	})
+}
+
+// PinPrompt 固定模式使用该版本（同一模式只能固定一个版本）
+func (h *PromptHandler) PinPrompt(c *gin.Context) {
+	tpl, err := h.store.Prompts.Get(c.Request.Context(), idParam(c, "id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, AuthResponse{
+			Code:    404,
+			Message: "模板不存在",
+		})
+		return
+	}
+
+	if err := h.store.Prompts.Pin(c.Request.Context(), tpl); err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "固定版本失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+	})
+}
+
+// UnpinPrompt 取消固定，恢复按权重分流或使用最新版本
+func (h *PromptHandler) UnpinPrompt(c *gin.Context) {
+	err := h.store.Prompts.Update(c.Request.Context(), idParam(c, "id"), map[string]interface{}{"pinned": false})
+	if store.IsNotFound(err) {
+		c.JSON(http.StatusNotFound, AuthResponse{
+			Code:    404,
+			Message: "模板不存在",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "取消固定失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+	})
+}
+
+// PreviewPromptRequest 模板预览请求
+type PreviewPromptRequest struct {
+	Content  string `json:"content" binding:"required"`
+	Language string `json:"language"`
+	Nickname string `json:"nickname"`
+	Context  string `json:"context"`
+}
+
+// PreviewPrompt 使用给定变量渲染模板，便于发布前检查
+func (h *PromptHandler) PreviewPrompt(c *gin.Context) {
+	var req PreviewPromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: "参数错误",
+		})
+		return
+	}
+
+	rendered, err := prompt.Render(req.Content, prompt.Vars{
+		Language: req.Language,
+		Nickname: req.Nickname,
+		Context:  req.Context,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+		Data:    gin.H{"rendered": rendered},
+	})
+}
@@ -1,36 +1,96 @@
 package handler
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"mime"
 	"mime/multipart"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sashabaranov/go-openai"
+	"go-ai-copilot/internal/cache"
 	"go-ai-copilot/internal/config"
-	"go-ai-copilot/internal/database"
+	"go-ai-copilot/internal/crawler"
 	"go-ai-copilot/internal/model"
+	"go-ai-copilot/internal/pagination"
+	"go-ai-copilot/internal/prompt"
 	"go-ai-copilot/internal/rag"
+	"go-ai-copilot/internal/reindex"
+	"go-ai-copilot/internal/storage"
+	"go-ai-copilot/internal/store"
 	"go-ai-copilot/pkg/ai"
 )
 
+const (
+	// versionWatchInterval 检查心跳超时的处理中版本的间隔
+	versionWatchInterval = time.Minute
+	// versionHeartbeatInterval 处理中版本的心跳间隔
+	versionHeartbeatInterval = time.Minute
+	// versionStaleAfter 处理中的版本超过该时间没有心跳，视为处理的实例已退出
+	versionStaleAfter = 5 * time.Minute
+)
+
 // RAGHandler RAG处理器
 type RAGHandler struct {
 	embeddingClient *ai.EmbeddingClient
-	textSplitter    *rag.TextSplitter
+	chatHandler     *ChatHandler
+	store           *store.Store
+	space           *model.EmbeddingSpace // 向量化模型对应的向量空间
+	reindex         *reindex.Runner
+	blobs           storage.BlobStore // 上传的原始文件
+	crawlClient     *http.Client      // 抓取网页使用的HTTP客户端
 }
 
 // NewRAGHandler 创建RAG处理器
-func NewRAGHandler() (*RAGHandler, error) {
-	cfg := config.GlobalConfig
+// chatHandler 用于RAG对话，复用其AI客户端和并发调度器
+func NewRAGHandler(chatHandler *ChatHandler, st *store.Store, blobs storage.BlobStore) (*RAGHandler, error) {
+	embeddingClient, space, err := NewEmbeddingClient(config.GlobalConfig.AI, st)
+	if err != nil {
+		return nil, err
+	}
 
+	runner := reindex.NewRunner(st.Reindex, embeddingClient, space, reindex.Config{
+		BatchSize: config.GlobalConfig.Reindex.BatchSize,
+		Interval:  config.GlobalConfig.Reindex.Interval,
+	})
+	// 继续执行服务重启前未完成的重建向量任务
+	go runner.Watch(context.Background())
+
+	h := &RAGHandler{
+		embeddingClient: embeddingClient,
+		chatHandler:     chatHandler,
+		store:           st,
+		space:           space,
+		reindex:         runner,
+		blobs:           blobs,
+		crawlClient:     crawler.NewClient(crawlerConfig().Timeout, config.GlobalConfig.Crawler.AllowPrivateNetworks),
+	}
+	h.startConnectors(context.Background(), config.GlobalConfig.Connectors)
+	go h.watchWebSources(context.Background())
+	// 继续处理服务重启前（或其他实例退出前）未处理完成的版本
+	go h.watchVersions(context.Background())
+	return h, nil
+}
+
+// defaultEmbeddingDimensions 未配置向量维度时的默认值
+const defaultEmbeddingDimensions = 1536
+
+// NewEmbeddingClient 根据配置创建embedding客户端，并获取模型对应的向量空间
+func NewEmbeddingClient(cfg config.AIConfig, st *store.Store) (*ai.EmbeddingClient, *model.EmbeddingSpace, error) {
 	// 从配置获取embedding模型，如果没有配置则使用DeepSeek的默认模型
-	embeddingModel := cfg.AI.EmbeddingModel
+	embeddingModel := cfg.EmbeddingModel
 	if embeddingModel == "" {
 		embeddingModel = "deepseek-embedding"
 	}
@@ -38,17 +98,68 @@ func NewRAGHandler() (*RAGHandler, error) {
 	// 创建embedding客户端
 	embeddingClient, err := ai.NewEmbeddingClient(
 		os.Getenv("EMBEDDING_API_KEY"),
-		cfg.AI.BaseURL, // 使用与AI相同的base URL
+		cfg.BaseURL, // 使用与AI相同的base URL
 		embeddingModel,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("embedding客户端初始化失败: %v", err)
+		return nil, nil, fmt.Errorf("embedding客户端初始化失败: %v", err)
 	}
 
-	return &RAGHandler{
-		embeddingClient: embeddingClient,
-		textSplitter:    rag.NewTextSplitter(1024, 256),
-	}, nil
+	dimensions := cfg.EmbeddingDimensions
+	if dimensions <= 0 {
+		dimensions = defaultEmbeddingDimensions
+	}
+	embeddingClient.SetDimensions(dimensions)
+
+	embeddingClient.SetBatchLimits(cfg.EmbeddingBatchSize, cfg.EmbeddingBatchTokens)
+	embeddingClient.SetConcurrency(cfg.EmbeddingConcurrency)
+	embeddingClient.SetRetryPolicy(ai.RetryPolicy{
+		MaxAttempts: cfg.Retry.MaxAttempts,
+		BaseDelay:   cfg.Retry.BaseDelay,
+		MaxDelay:    cfg.Retry.MaxDelay,
+	})
+
+	space, err := st.Spaces.Ensure(context.Background(), embeddingModel, cfg.EmbeddingVersion, dimensions)
+	if err != nil {
+		return nil, nil, fmt.Errorf("向量空间初始化失败: %v", err)
+	}
+	// 相同内容（同一向量空间内）只向量化一次
+	embeddingClient.SetCache(st.EmbeddingCache.ForSpace(space.ID))
+	return embeddingClient, space, nil
+}
+
+// errKnowledgeBaseForbidden 知识库不存在或无权访问
+var errKnowledgeBaseForbidden = errors.New("知识库不存在或无权访问")
+
+// ragCacheScope 用户全部文档对应的语义缓存范围
+func ragCacheScope(userID uint) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// kbCacheScope 知识库对应的语义缓存范围
+func kbCacheScope(kbID uint) string {
+	return fmt.Sprintf("kb:%d", kbID)
+}
+
+// invalidateSemanticCache 文档变化后使相关的语义缓存失效
+func (h *RAGHandler) invalidateSemanticCache(doc model.RAGDocument) {
+	if h.chatHandler == nil || h.chatHandler.semanticCache == nil {
+		return
+	}
+	sc := h.chatHandler.semanticCache
+	if err := sc.InvalidateDocument(doc.ID); err != nil {
+		log.Printf("语义缓存失效失败: %v", err)
+	}
+
+	scopes := []string{ragCacheScope(doc.UserID)}
+	if doc.KnowledgeBaseID > 0 {
+		scopes = append(scopes, kbCacheScope(doc.KnowledgeBaseID))
+	}
+	for _, scope := range scopes {
+		if err := sc.InvalidateScope(scope); err != nil {
+			log.Printf("语义缓存失效失败: %v", err)
+		}
+	}
 }
 
 // UploadRequest 上传请求
@@ -71,25 +182,16 @@ func (h *RAGHandler) UploadDocument(c *gin.Context) {
 	}
 
 	// 验证文件类型
-	ext := strings.ToLower(filepath.Ext(file.Filename))
-	allowedExts := []string{".txt", ".md", ".go", ".json", ".yaml", ".yml"}
-	isAllowed := false
-	for _, e := range allowedExts {
-		if ext == e {
-			isAllowed = true
-			break
-		}
-	}
-	if !isAllowed {
+	if !rag.IsSupportedFile(file.Filename) {
 		c.JSON(http.StatusBadRequest, AuthResponse{
 			Code:    400,
-			Message: "不支持的文件类型，仅支持: .txt, .md, .go, .json, .yaml, .yml",
+			Message: "不支持的文件类型，仅支持: " + strings.Join(rag.SupportedFileTypes, ", "),
 		})
 		return
 	}
 
 	// 验证文件大小 (10MB)
-	if file.Size > 10*1024*1024 {
+	if file.Size > rag.MaxFileSize {
 		c.JSON(http.StatusBadRequest, AuthResponse{
 			Code:    400,
 			Message: "文件大小不能超过10MB",
@@ -97,24 +199,43 @@ func (h *RAGHandler) UploadDocument(c *gin.Context) {
 		return
 	}
 
-	// 创建上传目录
-	uploadDir := "./uploads"
-	if _, err := os.Stat(uploadDir); os.IsNotExist(err) {
-		os.Mkdir(uploadDir, 0755)
+	// 目标知识库（可选，只能上传到自己的知识库）
+	var kbID uint
+	if v := c.PostForm("knowledge_base_id"); v != "" {
+		id, _ := strconv.ParseUint(v, 10, 32)
+		kb, err := h.store.KnowledgeBases.GetOwned(c.Request.Context(), userID, uint(id))
+		if err != nil {
+			c.JSON(http.StatusNotFound, AuthResponse{
+				Code:    404,
+				Message: "知识库不存在",
+			})
+			return
+		}
+		kbID = kb.ID
 	}
 
-	// 保存文件
-	filePath := filepath.Join(uploadDir, fmt.Sprintf("%d_%d%s", userID, time.Now().Unix(), ext))
-	if err := c.SaveUploadedFile(file, filePath); err != nil {
-		c.JSON(http.StatusInternalServerError, AuthResponse{
-			Code:    500,
-			Message: "文件保存失败",
+	// 文档标识：相同标识的上传作为同一文档的新版本
+	sourceKey, err := documentSourceKey(c.PostForm("path"), file.Filename)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// 标签和元数据（可选）
+	tags, metadata, err := uploadLabels(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: err.Error(),
 		})
 		return
 	}
 
 	// 读取文件内容
-	content, err := os.ReadFile(filePath)
+	src, err := file.Open()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, AuthResponse{
 			Code:    500,
@@ -122,81 +243,475 @@ func (h *RAGHandler) UploadDocument(c *gin.Context) {
 		})
 		return
 	}
-
-	// 创建文档记录
-	doc := model.RAGDocument{
-		UserID:   userID,
-		FileName: file.Filename,
-		FileType: ext[1:], // 去掉点
-		FileSize: file.Size,
-		Status:   "processing",
-	}
-
-	if err := database.DB.Create(&doc).Error; err != nil {
+	content, err := io.ReadAll(src)
+	src.Close()
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, AuthResponse{
 			Code:    500,
-			Message: "文档创建失败",
+			Message: "文件读取失败",
+		})
+		return
+	}
+
+	result, err := h.ingest(c.Request.Context(), ingestRequest{
+		UserID:          userID,
+		KnowledgeBaseID: kbID,
+		SourceKey:       sourceKey,
+		FileName:        file.Filename,
+		Content:         content,
+		Tags:            tags,
+		Metadata:        metadata,
+	})
+	if err != nil {
+		status, message := http.StatusInternalServerError, "文档创建失败"
+		var ie *ingestError
+		if errors.As(err, &ie) {
+			status, message = ie.status, ie.message
+		}
+		c.JSON(status, AuthResponse{
+			Code:    status,
+			Message: message,
+		})
+		return
+	}
+	if result.Unchanged {
+		c.JSON(http.StatusOK, AuthResponse{
+			Code:    0,
+			Message: "文档内容未变化",
+			Data: gin.H{
+				"document_id": result.Document.ID,
+				"file_name":   result.Document.FileName,
+				"version":     result.Document.Version,
+				"status":      result.Document.Status,
+				"unchanged":   true,
+			},
 		})
 		return
 	}
 
 	// 异步处理文档（分块、向量化）
-	go h.processDocument(doc.ID, userID, string(content))
+	go h.processVersion(result.Document, result.Version, string(content))
 
 	c.JSON(http.StatusOK, AuthResponse{
 		Code:    0,
 		Message: "文档上传成功，正在处理中",
 		Data: gin.H{
-			"document_id": doc.ID,
-			"file_name":   doc.FileName,
+			"document_id": result.Document.ID,
+			"file_name":   result.Version.FileName,
+			"version":     result.Version.Version,
 			"status":      "processing",
 		},
 	})
 }
 
-// processDocument 处理文档（分块、向量化）
-func (h *RAGHandler) processDocument(docID, userID uint, content string) {
-	// 文本分块
-	chunks := h.textSplitter.SplitText(content)
-	if len(chunks) == 0 {
-		database.DB.Model(&model.RAGDocument{}).Where("id = ?", docID).Update("status", "failed")
-		return
+// ingestRequest 按文档标识写入知识库的文件（上传或连接器同步）
+type ingestRequest struct {
+	UserID          uint
+	KnowledgeBaseID uint
+	SourceKey       string
+	FileName        string
+	FileType        string // 为空时按文件名的扩展名
+	Content         []byte
+	Tags            *[]string          // 为nil时保留文档原有的值
+	Metadata        *map[string]string // 为nil时保留文档原有的值
+}
+
+// ingestResult 写入结果
+type ingestResult struct {
+	Document  model.RAGDocument
+	Version   model.RAGDocumentVersion // 内容未变化时为空
+	Unchanged bool
+}
+
+// ingestError 写入失败，带有返回给客户端的状态码和提示
+type ingestError struct {
+	status  int
+	message string
+}
+
+func (e *ingestError) Error() string {
+	return e.message
+}
+
+// errDocumentProcessing 文档的上一个版本仍在处理中
+var errDocumentProcessing = &ingestError{http.StatusConflict, "文档正在处理中，请稍后再上传"}
+
+// ingest 按文档标识写入文件：文档不存在时创建，内容变化时创建新版本，内容未变化时只更新标签和元数据
+// 创建的版本需要调用 processVersion 分块和向量化
+func (h *RAGHandler) ingest(ctx context.Context, req ingestRequest) (*ingestResult, error) {
+	ext := strings.ToLower(filepath.Ext(req.FileName))
+	fileType := strings.TrimPrefix(ext, ".")
+	if req.FileType != "" {
+		// 文件名不是真实的文件名（如网页标题），原始文件按纯文本保存
+		fileType, ext = req.FileType, ""
+	}
+	contentHash := ai.ContentHash(string(req.Content))
+
+	existing, err := h.store.Documents.FindBySource(ctx, req.UserID, req.KnowledgeBaseID, req.SourceKey)
+	if err != nil && !store.IsNotFound(err) {
+		log.Printf("查询文档失败: %v", err)
+		return nil, &ingestError{http.StatusInternalServerError, "查询文档失败"}
+	}
+	if existing != nil {
+		if existing.Status == "processing" {
+			return nil, errDocumentProcessing
+		}
+		if err := h.setLabels(ctx, existing, req.Tags, req.Metadata); err != nil {
+			log.Printf("更新文档标签失败: %v", err)
+			return nil, &ingestError{http.StatusInternalServerError, "更新标签失败"}
+		}
+		if existing.ContentHash == contentHash {
+			return &ingestResult{Document: *existing, Unchanged: true}, nil
+		}
+	}
+
+	// 保存原始文件，按用户和内容哈希命名，相同内容只保存一份
+	blobKey := uploadBlobKey(req.UserID, contentHash, ext)
+	if err := h.blobs.Put(ctx, blobKey, bytes.NewReader(req.Content), int64(len(req.Content)), uploadContentType(ext)); err != nil {
+		log.Printf("保存上传文件失败: %v", err)
+		return nil, &ingestError{http.StatusInternalServerError, "文件保存失败"}
+	}
+
+	// 创建文档（或新版本）记录
+	version := model.RAGDocumentVersion{
+		FileName:    req.FileName,
+		FileSize:    int64(len(req.Content)),
+		BlobKey:     blobKey,
+		ContentHash: contentHash,
+		Status:      "processing",
+	}
+	var doc model.RAGDocument
+	if existing == nil {
+		doc = model.RAGDocument{
+			UserID:          req.UserID,
+			KnowledgeBaseID: req.KnowledgeBaseID,
+			FileName:        req.FileName,
+			FileType:        fileType,
+			FileSize:        version.FileSize,
+			Status:          "processing",
+			SourceKey:       req.SourceKey,
+			Tags:            []string{},
+			Metadata:        map[string]string{},
+		}
+		if req.Tags != nil {
+			doc.Tags = *req.Tags
+		}
+		if req.Metadata != nil {
+			doc.Metadata = *req.Metadata
+		}
+		err = h.store.Documents.Create(ctx, &doc, &version)
+	} else {
+		doc = *existing
+		doc.FileType = fileType
+		version.DocumentID = doc.ID
+		err = h.store.Documents.CreateVersion(ctx, &version)
+	}
+	if err != nil {
+		// 并发上传同一文档时，文档标识的唯一索引（或版本号）冲突，另一个上传正在处理
+		if current, ferr := h.store.Documents.FindBySource(ctx, req.UserID, req.KnowledgeBaseID, req.SourceKey); ferr == nil && current.Status == "processing" {
+			return nil, errDocumentProcessing
+		}
+		log.Printf("文档创建失败: %v", err)
+		return nil, &ingestError{http.StatusInternalServerError, "文档创建失败"}
+	}
+	return &ingestResult{Document: doc, Version: version}, nil
+}
+
+// documentSourceKey 文档标识：统一为以/分隔的相对路径，未指定路径时使用文件名
+func documentSourceKey(p, fileName string) (string, error) {
+	key := strings.TrimSpace(p)
+	if key == "" {
+		key = fileName
+	}
+	key = strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(key, "\\", "/")), "/")
+	if key == "" {
+		return "", errors.New("文档路径不能为空")
+	}
+	if len(key) > 500 {
+		return "", errors.New("文档路径不能超过500个字符")
+	}
+	return key, nil
+}
+
+// uploadBlobKey 上传文件在文件存储中的Key
+func uploadBlobKey(userID uint, contentHash, ext string) string {
+	return fmt.Sprintf("%d/%s%s", userID, contentHash, ext)
+}
+
+// uploadContentType 上传文件的Content-Type（允许上传的都是文本文件）
+func uploadContentType(ext string) string {
+	if t := mime.TypeByExtension(ext); t != "" {
+		return t
 	}
+	return "text/plain; charset=utf-8"
+}
 
-	// 批量向量化
-	ctx := database.DB.Statement.Context
-	embeddings, err := h.embeddingClient.GetEmbeddings(ctx, chunks)
+// deleteBlobs 删除不再被任何文档引用的上传文件，失败只记录日志
+func (h *RAGHandler) deleteBlobs(ctx context.Context, documentIDs []uint) {
+	keys, err := h.store.Documents.UnreferencedBlobs(ctx, documentIDs)
 	if err != nil {
-		database.DB.Model(&model.RAGDocument{}).Where("id = ?", docID).Update("status", "failed")
+		log.Printf("查询待删除的上传文件失败: %v", err)
 		return
 	}
+	for _, key := range keys {
+		if err := h.blobs.Delete(ctx, key); err != nil {
+			log.Printf("删除上传文件 %s 失败: %v", key, err)
+		}
+	}
+}
 
-	// 保存分块和向量
-	for i, chunk := range chunks {
-		chunkModel := model.RAGChunk{
-			DocumentID: docID,
-			UserID:     userID,
-			Content:    chunk,
-			ChunkIndex: i,
+// processVersion 处理文档的新版本（分块、向量化）
+// 与当前版本按内容比较分块，只向量化新内容，全部完成后一次性切换为当前版本
+func (h *RAGHandler) processVersion(doc model.RAGDocument, version model.RAGDocumentVersion, content string) error {
+	ctx := context.Background()
+	stop := h.versionHeartbeat(version.ID)
+	defer stop()
+	fail := func(err error) error {
+		log.Printf("文档处理失败: document=%d version=%d err=%v", doc.ID, version.Version, err)
+		if err := h.store.Documents.FailVersion(ctx, &doc, &version); err != nil {
+			log.Printf("更新文档状态失败: document=%d err=%v", doc.ID, err)
 		}
-		if i < len(embeddings) {
-			chunkModel.Embedding = embeddings[i]
+		return err
+	}
+
+	// 按知识库的分块设置分块（记录每块的行范围）
+	splitter, err := h.documentSplitter(ctx, doc.KnowledgeBaseID)
+	if err != nil {
+		return fail(err)
+	}
+	split := splitter.Split(content)
+	if len(split) == 0 {
+		return fail(errors.New("内容为空"))
+	}
+	texts := make([]string, len(split))
+	for i, c := range split {
+		texts[i] = c.Text
+	}
+
+	var current []model.RAGChunk
+	if doc.Version > 0 {
+		var err error
+		if current, err = h.store.Chunks.List(ctx, doc.ID); err != nil {
+			return fail(err)
+		}
+	}
+	diff := rag.DiffChunks(current, texts)
+
+	// 只向量化新内容
+	added := make([]string, len(diff.Added))
+	for i, index := range diff.Added {
+		added[i] = texts[index]
+	}
+	var embeddings [][]float32
+	if len(added) > 0 {
+		var err error
+		if embeddings, err = h.embeddingClient.GetEmbeddings(ctx, added); err != nil {
+			return fail(err)
+		}
+	}
+
+	chunks := store.VersionChunks{
+		Added:   make([]model.RAGChunk, len(diff.Added)),
+		Moved:   make(map[uint]store.ChunkPosition),
+		Retired: diff.Retired,
+	}
+	for i, index := range diff.Added {
+		chunks.Added[i] = model.RAGChunk{
+			DocumentID:      doc.ID,
+			UserID:          doc.UserID,
+			KnowledgeBaseID: doc.KnowledgeBaseID,
+			Content:         texts[index],
+			ContentHash:     ai.ContentHash(texts[index]),
+			Embedding:       embeddings[i],
+			SpaceID:         h.space.ID,
+			ChunkIndex:      index,
+			Path:            doc.SourceKey,
+			StartLine:       split[index].StartLine,
+			EndLine:         split[index].EndLine,
+			Version:         version.Version,
+		}
+	}
+	for _, chunk := range current {
+		index, ok := diff.Kept[chunk.ID]
+		if !ok {
+			continue
+		}
+		pos := store.ChunkPosition{Index: index, StartLine: split[index].StartLine, EndLine: split[index].EndLine}
+		if pos.Index != chunk.ChunkIndex || pos.StartLine != chunk.StartLine || pos.EndLine != chunk.EndLine {
+			chunks.Moved[chunk.ID] = pos
+		}
+	}
+
+	version.ChunkCount = len(texts)
+	if err := h.store.Documents.ApplyVersion(ctx, &doc, &version, chunks); err != nil {
+		return fail(err)
+	}
+
+	// 知识库内容变化，之前缓存的回答可能已过时
+	h.invalidateSemanticCache(doc)
+	return nil
+}
+
+// versionHeartbeat 处理期间定期更新版本的心跳，返回停止心跳的函数
+func (h *RAGHandler) versionHeartbeat(id uint) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		ticker := time.NewTicker(versionHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := h.store.Documents.VersionHeartbeat(ctx, id); err != nil {
+					log.Printf("更新文档版本 %d 心跳失败: %v", id, err)
+				}
+			}
+		}
+	}()
+	return cancel
+}
+
+// watchVersions 定期接管心跳超时的处理中版本（服务重启或其他实例退出后继续处理），ctx结束时返回
+func (h *RAGHandler) watchVersions(ctx context.Context) {
+	ticker := time.NewTicker(versionWatchInterval)
+	defer ticker.Stop()
+	for {
+		staleBefore := time.Now().Add(-versionStaleAfter)
+		versions, err := h.store.Documents.StaleVersions(ctx, staleBefore)
+		if err != nil {
+			log.Printf("查询未处理完成的文档版本失败: %v", err)
+		}
+		for _, version := range versions {
+			claimed, err := h.store.Documents.ClaimVersion(ctx, version.ID, staleBefore)
+			if err != nil {
+				log.Printf("文档版本 %d 接管失败: %v", version.ID, err)
+				continue
+			}
+			if claimed {
+				go h.resumeVersion(version)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// resumeVersion 从保存的上传文件重新处理版本（调用前需已领取），
+// 没有上传文件（升级前的上传）或文件读取失败时将版本置为失败，文档可以重新上传
+func (h *RAGHandler) resumeVersion(version model.RAGDocumentVersion) {
+	ctx := context.Background()
+	doc, err := h.store.Documents.GetByID(ctx, version.DocumentID)
+	if err != nil {
+		log.Printf("文档版本 %d 所属的文档不存在: %v", version.ID, err)
+		return
+	}
+
+	content, err := h.readBlob(ctx, version.BlobKey)
+	if err != nil {
+		log.Printf("文档处理失败: document=%d version=%d err=%v", doc.ID, version.Version, err)
+		if err := h.store.Documents.FailVersion(ctx, doc, &version); err != nil {
+			log.Printf("更新文档状态失败: document=%d err=%v", doc.ID, err)
 		}
-		database.DB.Create(&chunkModel)
+		return
 	}
+	log.Printf("继续处理文档: document=%d version=%d", doc.ID, version.Version)
+	h.processVersion(*doc, version, string(content))
+}
 
-	// 更新文档状态
-	database.DB.Model(&model.RAGDocument{}).Where("id = ?", docID).Update("status", "completed")
+// readBlob 读取保存的上传文件
+func (h *RAGHandler) readBlob(ctx context.Context, key string) ([]byte, error) {
+	if key == "" {
+		return nil, errors.New("没有保存上传文件")
+	}
+	r, _, err := h.blobs.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
 }
 
-// GetDocuments 获取文档列表
+// documentSort 文档列表的排序字段
+var documentSort = pagination.Sort{
+	Options: map[string]string{
+		"created_at": "created_at",
+		"file_name":  "file_name",
+		"file_size":  "file_size",
+	},
+	Default:  "created_at",
+	Tiebreak: "id",
+}
+
+// GetDocuments 分页获取文档列表
+// 支持按知识库（knowledge_base_id）、处理状态（status）、文件类型（file_type）和上传日期（from、to）过滤
 func (h *RAGHandler) GetDocuments(c *gin.Context) {
 	userID := c.GetUint("userID")
 
-	var documents []model.RAGDocument
-	if err := database.DB.Where("user_id = ?", userID).
-		Order("created_at DESC").
-		Find(&documents).Error; err != nil {
+	page, err := pagination.FromQuery(c, documentSort)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	q := store.DocumentQuery{
+		UserID:   userID,
+		Status:   c.Query("status"),
+		FileType: strings.TrimPrefix(strings.ToLower(c.Query("file_type")), "."),
+		From:     from,
+		To:       to,
+		Tags:     normalizeTags(c.QueryArray("tag")),
+	}
+	if v := c.Query("knowledge_base_id"); v != "" {
+		kbID, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AuthResponse{
+				Code:    400,
+				Message: "knowledge_base_id 参数错误",
+			})
+			return
+		}
+		id := uint(kbID)
+		q.KnowledgeBaseID = &id
+	}
+
+	documents, err := h.store.Documents.List(c.Request.Context(), q, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "获取文档列表失败",
+		})
+		return
+	}
+
+	result, err := pagination.Build(page, documents, func(d model.RAGDocument) []interface{} {
+		var value interface{}
+		switch page.Field {
+		case "file_name":
+			value = d.FileName
+		case "file_size":
+			value = d.FileSize
+		default:
+			value = d.CreatedAt
+		}
+		return []interface{}{value, d.ID}
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, AuthResponse{
 			Code:    500,
 			Message: "获取文档列表失败",
@@ -207,18 +722,16 @@ func (h *RAGHandler) GetDocuments(c *gin.Context) {
 	c.JSON(http.StatusOK, AuthResponse{
 		Code:    0,
 		Message: "success",
-		Data:    documents,
+		Data:    result,
 	})
 }
 
 // GetDocument 获取单个文档
 func (h *RAGHandler) GetDocument(c *gin.Context) {
 	userID := c.GetUint("userID")
-	docIDStr := c.Param("id")
-	docID, _ := strconv.ParseUint(docIDStr, 10, 32)
 
-	var doc model.RAGDocument
-	if err := database.DB.Where("id = ? AND user_id = ?", docID, userID).First(&doc).Error; err != nil {
+	doc, err := h.store.Documents.Get(c.Request.Context(), userID, idParam(c, "id"))
+	if err != nil {
 		c.JSON(http.StatusNotFound, AuthResponse{
 			Code:    404,
 			Message: "文档不存在",
@@ -227,8 +740,7 @@ func (h *RAGHandler) GetDocument(c *gin.Context) {
 	}
 
 	// 获取分块
-	var chunks []model.RAGChunk
-	database.DB.Where("document_id = ?", docID).Order("chunk_index").Find(&chunks)
+	chunks, _ := h.store.Chunks.List(c.Request.Context(), doc.ID)
 
 	c.JSON(http.StatusOK, AuthResponse{
 		Code:    0,
@@ -240,15 +752,161 @@ func (h *RAGHandler) GetDocument(c *gin.Context) {
 	})
 }
 
+// ListDocumentVersions 获取文档的版本历史
+func (h *RAGHandler) ListDocumentVersions(c *gin.Context) {
+	userID := c.GetUint("userID")
+	ctx := c.Request.Context()
+
+	doc, err := h.store.Documents.Get(ctx, userID, idParam(c, "id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, AuthResponse{
+			Code:    404,
+			Message: "文档不存在",
+		})
+		return
+	}
+
+	versions, err := h.store.Documents.Versions(ctx, doc.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "获取版本历史失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+		Data: gin.H{
+			"current":  doc.Version,
+			"versions": versions,
+		},
+	})
+}
+
+// GetDocumentVersion 获取文档的指定版本及其分块（含之后版本中已移除的分块）
+func (h *RAGHandler) GetDocumentVersion(c *gin.Context) {
+	userID := c.GetUint("userID")
+	ctx := c.Request.Context()
+
+	doc, err := h.store.Documents.Get(ctx, userID, idParam(c, "id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, AuthResponse{
+			Code:    404,
+			Message: "文档不存在",
+		})
+		return
+	}
+
+	number, _ := strconv.Atoi(c.Param("version"))
+	version, err := h.store.Documents.GetVersion(ctx, doc.ID, number)
+	if err != nil {
+		c.JSON(http.StatusNotFound, AuthResponse{
+			Code:    404,
+			Message: "版本不存在",
+		})
+		return
+	}
+
+	// 处理失败的版本没有分块
+	chunks := []model.RAGChunk{}
+	if version.Status == "completed" {
+		if chunks, err = h.store.Chunks.ListVersion(ctx, doc.ID, version.Version); err != nil {
+			c.JSON(http.StatusInternalServerError, AuthResponse{
+				Code:    500,
+				Message: "获取分块失败",
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+		Data: gin.H{
+			"version": version,
+			"chunks":  chunks,
+		},
+	})
+}
+
+// DownloadDocument 下载文档的原始文件，version为空时下载当前版本
+func (h *RAGHandler) DownloadDocument(c *gin.Context) {
+	userID := c.GetUint("userID")
+	ctx := c.Request.Context()
+
+	doc, err := h.store.Documents.Get(ctx, userID, idParam(c, "id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, AuthResponse{
+			Code:    404,
+			Message: "文档不存在",
+		})
+		return
+	}
+
+	var version *model.RAGDocumentVersion
+	if v := c.Query("version"); v != "" {
+		number, _ := strconv.Atoi(v)
+		version, err = h.store.Documents.GetVersion(ctx, doc.ID, number)
+	} else if doc.Version > 0 {
+		version, err = h.store.Documents.GetVersion(ctx, doc.ID, doc.Version)
+	} else {
+		// 首个版本尚未处理完成，下载最新上传的版本
+		var versions []model.RAGDocumentVersion
+		if versions, err = h.store.Documents.Versions(ctx, doc.ID); err == nil && len(versions) == 0 {
+			err = store.ErrNotFound
+		}
+		if err == nil {
+			version = &versions[0]
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, AuthResponse{
+			Code:    404,
+			Message: "版本不存在",
+		})
+		return
+	}
+
+	// 升级前上传的文件未与文档关联
+	if version.BlobKey == "" {
+		c.JSON(http.StatusNotFound, AuthResponse{
+			Code:    404,
+			Message: "原始文件不存在",
+		})
+		return
+	}
+	body, size, err := h.blobs.Get(ctx, version.BlobKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, AuthResponse{
+				Code:    404,
+				Message: "原始文件不存在",
+			})
+			return
+		}
+		log.Printf("读取上传文件 %s 失败: %v", version.BlobKey, err)
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "文件读取失败",
+		})
+		return
+	}
+	defer body.Close()
+
+	c.DataFromReader(http.StatusOK, size, uploadContentType(filepath.Ext(version.FileName)), body, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": version.FileName}),
+	})
+}
+
 // DeleteDocument 删除文档
 func (h *RAGHandler) DeleteDocument(c *gin.Context) {
 	userID := c.GetUint("userID")
-	docIDStr := c.Param("id")
-	docID, _ := strconv.ParseUint(docIDStr, 10, 32)
 
 	// 检查文档是否存在
-	var doc model.RAGDocument
-	if err := database.DB.Where("id = ? AND user_id = ?", docID, userID).First(&doc).Error; err != nil {
+	doc, err := h.store.Documents.Get(c.Request.Context(), userID, idParam(c, "id"))
+	if err != nil {
 		c.JSON(http.StatusNotFound, AuthResponse{
 			Code:    404,
 			Message: "文档不存在",
@@ -256,11 +914,17 @@ func (h *RAGHandler) DeleteDocument(c *gin.Context) {
 		return
 	}
 
-	// 删除分块
-	database.DB.Where("document_id = ?", docID).Delete(&model.RAGChunk{})
+	// 删除文档及其分块
+	if err := h.store.Documents.Delete(c.Request.Context(), doc); err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "删除失败",
+		})
+		return
+	}
 
-	// 删除文档
-	database.DB.Delete(&doc)
+	h.invalidateSemanticCache(*doc)
+	h.deleteBlobs(c.Request.Context(), []uint{doc.ID})
 
 	c.JSON(http.StatusOK, AuthResponse{
 		Code:    0,
@@ -270,9 +934,42 @@ func (h *RAGHandler) DeleteDocument(c *gin.Context) {
 
 // SearchRequest 搜索请求
 type SearchRequest struct {
-	Query     string  `json:"query" binding:"required"`
-	TopK      int     `json:"top_k"`
-	Threshold float64 `json:"threshold"`
+	Query            string           `json:"query" binding:"required"`
+	TopK             int              `json:"top_k"`
+	Threshold        float64          `json:"threshold"`
+	KnowledgeBaseIDs []uint           `json:"knowledge_base_ids"` // 为空时检索用户的全部文档
+	Filter           *RetrievalFilter `json:"filter"`             // 按文档的标签、元数据、文件类型和上传日期过滤
+}
+
+// retrievalScope 校验请求的知识库并构建检索范围
+func retrievalScope(ctx context.Context, kbs store.KnowledgeBaseRepository, userID uint, kbIDs []uint) (rag.Scope, error) {
+	scope := rag.Scope{UserID: userID}
+	if len(kbIDs) == 0 {
+		return scope, nil
+	}
+
+	accessible, err := kbs.Accessible(ctx, userID, kbIDs)
+	if err != nil {
+		return scope, err
+	}
+	if len(accessible) != len(uniqueIDs(kbIDs)) {
+		return scope, errKnowledgeBaseForbidden
+	}
+	scope.KnowledgeBaseIDs = accessible
+	return scope, nil
+}
+
+// cacheScopes 检索范围对应的语义缓存范围
+func cacheScopes(scope rag.Scope) []string {
+	if len(scope.KnowledgeBaseIDs) == 0 {
+		return []string{ragCacheScope(scope.UserID)}
+	}
+	scopes := make([]string, 0, len(scope.KnowledgeBaseIDs))
+	for _, id := range scope.KnowledgeBaseIDs {
+		scopes = append(scopes, kbCacheScope(id))
+	}
+	sort.Strings(scopes)
+	return scopes
 }
 
 // Search 搜索相关文档
@@ -295,6 +992,22 @@ func (h *RAGHandler) Search(c *gin.Context) {
 		req.Threshold = 0.5
 	}
 
+	scope, err := retrievalScope(c.Request.Context(), h.store.KnowledgeBases, userID, req.KnowledgeBaseIDs)
+	if err != nil {
+		c.JSON(http.StatusForbidden, AuthResponse{
+			Code:    403,
+			Message: err.Error(),
+		})
+		return
+	}
+	if scope.Filter, err = req.Filter.chunkFilter(); err != nil {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
 	// 将查询向量化
 	ctx := c.Request.Context()
 	embedding, err := h.embeddingClient.GetEmbedding(ctx, req.Query)
@@ -306,76 +1019,23 @@ func (h *RAGHandler) Search(c *gin.Context) {
 		return
 	}
 
-	// 使用余弦相似度搜索（简化版本：取前TopK）
-	var chunks []model.RAGChunk
-	database.DB.Where("user_id = ?", userID).
-		Order("created_at DESC").
-		Limit(req.TopK * 2).
-		Find(&chunks)
-
-	// 简单相似度计算（实际生产应该用SQL的向量运算）
-	type scoredChunk struct {
-		chunk     model.RAGChunk
-		score     float64
-	}
-	var scoredChunks []scoredChunk
-
-	for _, chunk := range chunks {
-		if len(chunk.Embedding) == 0 {
-			continue
-		}
-		score := cosineSimilarity(embedding, chunk.Embedding)
-		if score >= req.Threshold {
-			scoredChunks = append(scoredChunks, scoredChunk{chunk: chunk, score: score})
-		}
-	}
-
-	// 按相似度排序
-	for i := 0; i < len(scoredChunks)-1; i++ {
-		for j := i + 1; j < len(scoredChunks); j++ {
-			if scoredChunks[j].score > scoredChunks[i].score {
-				scoredChunks[i], scoredChunks[j] = scoredChunks[j], scoredChunks[i]
-			}
-		}
-	}
-
-	// 取TopK
-	result := make([]gin.H, 0, req.TopK)
-	for i := 0; i < len(scoredChunks) && i < req.TopK; i++ {
-		result = append(result, gin.H{
-			"content":    scoredChunks[i].chunk.Content,
-			"score":      scoredChunks[i].score,
-			"chunk_index": scoredChunks[i].chunk.ChunkIndex,
+	// 使用pgvector按余弦相似度检索
+	results, err := rag.Search(ctx, h.store.Chunks, h.space, scope, embedding, req.TopK, req.Threshold)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: err.Error(),
 		})
+		return
 	}
 
 	c.JSON(http.StatusOK, AuthResponse{
 		Code:    0,
 		Message: "success",
-		Data:    result,
+		Data:    results,
 	})
 }
 
-// cosineSimilarity 计算余弦相似度
-func cosineSimilarity(a, b []float32) float64 {
-	if len(a) != len(b) || len(a) == 0 {
-		return 0
-	}
-
-	var dotProduct, normA, normB float64
-	for i := range a {
-		dotProduct += float64(a[i]) * float64(b[i])
-		normA += float64(a[i]) * float64(a[i])
-		normB += float64(b[i]) * float64(b[i])
-	}
-
-	if normA == 0 || normB == 0 {
-		return 0
-	}
-
-	return dotProduct / (normA * normB)
-}
-
 // RAGChat RAG增强的对话
 func (h *RAGHandler) RAGChat(c *gin.Context) {
 	userID := c.GetUint("userID")
@@ -389,6 +1049,30 @@ func (h *RAGHandler) RAGChat(c *gin.Context) {
 		return
 	}
 
+	if h.chatHandler == nil || h.chatHandler.client == nil {
+		c.JSON(http.StatusServiceUnavailable, AuthResponse{
+			Code:    503,
+			Message: "AI服务暂不可用，请配置API_KEY",
+		})
+		return
+	}
+
+	scope, err := retrievalScope(c.Request.Context(), h.store.KnowledgeBases, userID, req.KnowledgeBaseIDs)
+	if err != nil {
+		c.JSON(http.StatusForbidden, AuthResponse{
+			Code:    403,
+			Message: err.Error(),
+		})
+		return
+	}
+	if scope.Filter, err = req.Filter.chunkFilter(); err != nil {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
 	// 1. 将问题向量化
 	ctx := c.Request.Context()
 	embedding, err := h.embeddingClient.GetEmbedding(ctx, req.Message)
@@ -400,76 +1084,121 @@ func (h *RAGHandler) RAGChat(c *gin.Context) {
 		return
 	}
 
-	// 2. 搜索相关文档
-	var chunks []model.RAGChunk
-	database.DB.Where("user_id = ?", userID).
-		Order("created_at DESC").
-		Limit(5).
-		Find(&chunks)
-
-	// 计算相似度，取Top3
-	type scoredChunk struct {
-		chunk model.RAGChunk
-		score float64
-	}
-	var scoredChunks []scoredChunk
-
-	for _, chunk := range chunks {
-		if len(chunk.Embedding) == 0 {
-			continue
+	// 查询语义缓存（复用问题向量）
+	var cacheKey cache.SemanticKey
+	var cacheEmbedding []float32
+	if h.chatHandler.cacheable(req) {
+		// 分桶键使用未注入参考资料的System Prompt和所用的模板版本
+		base, tpl, err := h.chatHandler.systemPrompt(userID, 0, "rag", prompt.Vars{Language: req.Language})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, AuthResponse{
+				Code:    500,
+				Message: err.Error(),
+			})
+			return
 		}
-		score := cosineSimilarity(embedding, chunk.Embedding)
-		if score >= 0.5 {
-			scoredChunks = append(scoredChunks, scoredChunk{chunk: chunk, score: score})
+		cacheKey = cache.SemanticKey{
+			Mode:         "rag",
+			Model:        h.chatHandler.client.Model(),
+			SystemPrompt: base,
+			Template:     templateIdentity(tpl),
+			Scopes:       cacheScopes(scope),
+			Filter:       filterCacheKey(scope.Filter),
 		}
-	}
-
-	// 排序
-	for i := 0; i < len(scoredChunks)-1; i++ {
-		for j := i + 1; j < len(scoredChunks); j++ {
-			if scoredChunks[j].score > scoredChunks[i].score {
-				scoredChunks[i], scoredChunks[j] = scoredChunks[j], scoredChunks[i]
-			}
+		var hit *cache.SemanticHit
+		cacheEmbedding, hit = h.chatHandler.lookupCache(ctx, cacheKey, embedding, req.Message)
+		if hit != nil {
+			c.JSON(http.StatusOK, AuthResponse{
+				Code:    0,
+				Message: "success",
+				Data: gin.H{
+					"reply":        hit.Entry.Reply,
+					"document_ids": hit.Entry.DocumentIDs,
+					"cached":       true,
+					"similarity":   hit.Similarity,
+				},
+			})
+			return
 		}
 	}
 
-	// 3. 构建Prompt（包含检索到的知识）
-	var context strings.Builder
-	for i := 0; i < len(scoredChunks) && i < 3; i++ {
-		context.WriteString(fmt.Sprintf("[相关文档 %d]:\n%s\n\n", i+1, scoredChunks[i].chunk.Content))
+	// 2. 搜索相关文档，取Top3
+	results, err := rag.Search(ctx, h.store.Chunks, h.space, scope, embedding, 3, 0.5)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
 	}
 
-	prompt := fmt.Sprintf(`你是一个专业的AI助手。请根据以下参考资料回答用户的问题。
-
-参考资料：
-%s
-
-用户问题：%s
-
-请根据参考资料回答，如果参考资料中没有相关信息，请如实说明。`, context.String(), req.Message)
-
-	// 4. 调用AI
-	chatHandler, _ := NewChatHandler()
+	// 3. 构建Prompt（参考资料通过rag模式的提示词模板注入System Prompt）
+	context := rag.FormatContext(results)
+	docIDs := rag.DocumentIDs(results)
+	systemPrompt, _, err := h.chatHandler.systemPrompt(userID, 0, "rag", prompt.Vars{
+		Language: req.Language,
+		Context:  context,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: err.Error(),
+		})
+		return
+	}
 
-	// 简单调用（非流式）
+	// 4. 调用AI（非流式）
 	messages := []openai.ChatCompletionMessage{
-		{Role: openai.ChatMessageRoleUser, Content: prompt},
+		{Role: openai.ChatMessageRoleSystem, Content: systemPrompt},
+		{Role: openai.ChatMessageRoleUser, Content: req.Message},
 	}
-	reply, err := chatHandler.client.Chat(ctx, messages)
+	reply, err := h.chatHandler.chat(ctx, userID, messages)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, AuthResponse{
-			Code:    500,
+		status := aiErrorStatus(err)
+		c.JSON(status, AuthResponse{
+			Code:    status,
 			Message: err.Error(),
 		})
 		return
 	}
 
+	h.chatHandler.storeCache(cacheKey, req.Message, cacheEmbedding, reply, docIDs)
+
 	c.JSON(http.StatusOK, AuthResponse{
 		Code:    0,
 		Message: "success",
 		Data: gin.H{
-			"reply":   reply,
-			"context": context.String(),
+			"reply":        reply,
+			"context":      context,
+			"document_ids": docIDs,
+			"cached":       false,
 		},
 	})
 }
+
+// uniqueIDs 去重
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	out := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// uniqueNames 去除首尾空白，去掉空值和重复值（保持顺序）
+func uniqueNames(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	out := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name != "" && !seen[name] {
+			seen[name] = true
+			out = append(out, name)
+		}
+	}
+	return out
+}
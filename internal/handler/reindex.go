@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go-ai-copilot/internal/model"
+	"go-ai-copilot/internal/store"
+)
+
+// CreateReindexRequest 创建重建向量任务请求
+type CreateReindexRequest struct {
+	KnowledgeBaseID *uint `json:"knowledge_base_id"` // 为空时重建全部分块
+}
+
+// ReindexJobResponse 重建向量任务及进度
+type ReindexJobResponse struct {
+	model.ReindexJob
+	Progress float64 `json:"progress"` // 0~1
+}
+
+// newReindexJobResponse 计算任务进度
+func newReindexJobResponse(job model.ReindexJob) ReindexJobResponse {
+	resp := ReindexJobResponse{ReindexJob: job}
+	switch {
+	case job.Status == model.ReindexCompleted:
+		resp.Progress = 1
+	case job.Total > 0:
+		// 任务执行期间新增的分块也会被处理，已处理数可能超过创建时的总数
+		resp.Progress = float64(job.Processed) / float64(job.Total)
+		if resp.Progress > 1 {
+			resp.Progress = 1
+		}
+	}
+	return resp
+}
+
+// CreateReindexJob 使用当前向量化模型为知识库（或全部分块）重新生成向量，任务在后台执行
+func (h *RAGHandler) CreateReindexJob(c *gin.Context) {
+	// 请求体可以为空
+	var req CreateReindexRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if req.KnowledgeBaseID != nil {
+		if _, err := h.store.KnowledgeBases.Get(c.Request.Context(), *req.KnowledgeBaseID); err != nil {
+			c.JSON(http.StatusNotFound, AuthResponse{
+				Code:    404,
+				Message: "知识库不存在",
+			})
+			return
+		}
+	}
+
+	job, err := h.reindex.Create(c.Request.Context(), req.KnowledgeBaseID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "创建任务失败",
+		})
+		return
+	}
+	if _, err := h.reindex.Start(job.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "任务启动失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, AuthResponse{
+		Code:    0,
+		Message: "任务已创建，正在后台执行",
+		Data:    newReindexJobResponse(*job),
+	})
+}
+
+// ListReindexJobs 最近的重建向量任务
+func (h *RAGHandler) ListReindexJobs(c *gin.Context) {
+	jobs, err := h.store.Reindex.List(c.Request.Context(), 50)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "获取任务列表失败",
+		})
+		return
+	}
+
+	list := make([]ReindexJobResponse, len(jobs))
+	for i, job := range jobs {
+		list[i] = newReindexJobResponse(job)
+	}
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+		Data:    list,
+	})
+}
+
+// GetReindexJob 获取重建向量任务的进度
+func (h *RAGHandler) GetReindexJob(c *gin.Context) {
+	job, err := h.store.Reindex.Get(c.Request.Context(), idParam(c, "id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, AuthResponse{
+			Code:    404,
+			Message: "任务不存在",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+		Data:    newReindexJobResponse(*job),
+	})
+}
+
+// ResumeReindexJob 继续执行已中断或失败的任务，已生成的向量会保留
+func (h *RAGHandler) ResumeReindexJob(c *gin.Context) {
+	ctx := c.Request.Context()
+	job, err := h.store.Reindex.Get(ctx, idParam(c, "id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, AuthResponse{
+			Code:    404,
+			Message: "任务不存在",
+		})
+		return
+	}
+
+	started, err := h.reindex.Start(job.ID)
+	if err != nil {
+		c.JSON(http.StatusConflict, AuthResponse{
+			Code:    409,
+			Message: err.Error(),
+		})
+		return
+	}
+	if !started {
+		c.JSON(http.StatusConflict, AuthResponse{
+			Code:    409,
+			Message: "任务正在执行或已结束",
+		})
+		return
+	}
+
+	if job, err = h.store.Reindex.Get(ctx, job.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "获取任务失败",
+		})
+		return
+	}
+	c.JSON(http.StatusAccepted, AuthResponse{
+		Code:    0,
+		Message: "任务已继续执行",
+		Data:    newReindexJobResponse(*job),
+	})
+}
+
+// CancelReindexJob 取消任务，已生成的向量会被丢弃，分块保持原来的向量
+func (h *RAGHandler) CancelReindexJob(c *gin.Context) {
+	ctx := c.Request.Context()
+	canceled, err := h.reindex.Cancel(ctx, idParam(c, "id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "取消任务失败",
+		})
+		return
+	}
+	if !canceled {
+		if _, err := h.store.Reindex.Get(ctx, idParam(c, "id")); store.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, AuthResponse{
+				Code:    404,
+				Message: "任务不存在",
+			})
+			return
+		}
+		c.JSON(http.StatusConflict, AuthResponse{
+			Code:    409,
+			Message: "任务已结束",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+	})
+}
@@ -1,32 +1,48 @@
 package handler
 
 import (
+	"context"
 	"net/http"
 	"strconv"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"go-ai-copilot/internal/cache"
-	"go-ai-copilot/internal/database"
 	"go-ai-copilot/internal/model"
+	"go-ai-copilot/internal/pagination"
+	"go-ai-copilot/internal/prompt"
+	"go-ai-copilot/internal/search"
+	"go-ai-copilot/internal/store"
 )
 
 // SessionHandler 会话处理器
 type SessionHandler struct {
-	historyLimit int // 上下文记忆轮数
+	store        *store.Store
+	cache        cache.Cache // 缓存当前分支最近的消息，用于拼接上下文
+	historyLimit int         // 上下文记忆轮数
+	search       *search.Searcher
 }
 
 // NewSessionHandler 创建会话处理器
-func NewSessionHandler() *SessionHandler {
+func NewSessionHandler(c cache.Cache, st *store.Store, searcher *search.Searcher) *SessionHandler {
 	return &SessionHandler{
+		store:        st,
+		cache:        c,
 		historyLimit: 10, // 默认最近10轮对话
+		search:       searcher,
 	}
 }
 
+// idParam 解析路径中的ID参数，格式错误时返回0（查询结果为不存在）
+func idParam(c *gin.Context, name string) uint {
+	id, _ := strconv.ParseUint(c.Param(name), 10, 32)
+	return uint(id)
+}
+
 // CreateSessionRequest 创建会话请求
 type CreateSessionRequest struct {
-	Title string `json:"title" binding:"required,max=255"`
-	Mode  string `json:"mode"`
+	Title       string `json:"title" binding:"max=255"` // 为空时在首轮对话后自动生成
+	Mode        string `json:"mode"`                    // 兼容旧接口：未指定助手时使用该模式的内置助手
+	AssistantID *uint  `json:"assistant_id"`            // 会话使用的助手
 }
 
 // CreateSession 创建会话
@@ -48,19 +64,20 @@ func (h *SessionHandler) CreateSession(c *gin.Context) {
 		title = "新会话"
 	}
 
-	// 默认模式为 chat
-	mode := req.Mode
-	if mode == "" {
-		mode = "chat"
-	}
-
 	session := model.Session{
-		UserID: userID,
-		Title:  title,
-		Mode:   mode,
+		UserID:    userID,
+		Title:     title,
+		AutoTitle: req.Title == "",
+	}
+	if err := selectAssistant(c.Request.Context(), h.store, userID, &session, req.Mode, req.AssistantID); err != nil {
+		c.JSON(err.status, AuthResponse{
+			Code:    err.status,
+			Message: err.message,
+		})
+		return
 	}
 
-	if err := database.DB.Create(&session).Error; err != nil {
+	if err := h.store.Sessions.Create(c.Request.Context(), &session); err != nil {
 		c.JSON(http.StatusInternalServerError, AuthResponse{
 			Code:    500,
 			Message: "会话创建失败",
@@ -75,14 +92,103 @@ func (h *SessionHandler) CreateSession(c *gin.Context) {
 	})
 }
 
-// GetSessions 获取会话列表
+// sessionSort 会话列表的排序字段，置顶的会话始终排在前面
+var sessionSort = pagination.Sort{
+	Options: map[string]string{
+		"updated_at": "updated_at",
+		"created_at": "created_at",
+		"title":      "title",
+	},
+	Default:  "updated_at",
+	Prefix:   []pagination.Order{{Column: "pinned", Desc: true}},
+	Tiebreak: "id",
+}
+
+// GetSessions 分页获取会话列表，置顶的会话排在前面
+// 支持按模式（mode）、创建日期（from、to）、文件夹（folder_id，0表示不在文件夹中的会话）、
+// 置顶（pinned）、归档（archived，默认不含已归档）和标签（tag）过滤
 func (h *SessionHandler) GetSessions(c *gin.Context) {
 	userID := c.GetUint("userID")
 
-	var sessions []model.Session
-	if err := database.DB.Where("user_id = ?", userID).
-		Order("updated_at DESC").
-		Find(&sessions).Error; err != nil {
+	page, err := pagination.FromQuery(c, sessionSort)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	q := store.SessionQuery{
+		UserID: userID,
+		Mode:   c.Query("mode"),
+		From:   from,
+		To:     to,
+		Tag:    c.Query("tag"),
+	}
+	if v, ok := c.GetQuery("folder_id"); ok {
+		folderID, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AuthResponse{
+				Code:    400,
+				Message: "folder_id 参数错误",
+			})
+			return
+		}
+		id := uint(folderID)
+		q.FolderID = &id
+	}
+	if v, ok := c.GetQuery("pinned"); ok {
+		pinned, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, AuthResponse{
+				Code:    400,
+				Message: "pinned 参数错误",
+			})
+			return
+		}
+		q.Pinned = &pinned
+	}
+	if v, ok := c.GetQuery("archived"); ok {
+		if q.Archived, err = strconv.ParseBool(v); err != nil {
+			c.JSON(http.StatusBadRequest, AuthResponse{
+				Code:    400,
+				Message: "archived 参数错误",
+			})
+			return
+		}
+	}
+
+	sessions, err := h.store.Sessions.List(c.Request.Context(), q, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "获取会话列表失败",
+		})
+		return
+	}
+
+	result, err := pagination.Build(page, sessions, func(s model.Session) []interface{} {
+		var value interface{}
+		switch page.Field {
+		case "created_at":
+			value = s.CreatedAt
+		case "title":
+			value = s.Title
+		default:
+			value = s.UpdatedAt
+		}
+		return []interface{}{s.Pinned, value, s.ID}
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, AuthResponse{
 			Code:    500,
 			Message: "获取会话列表失败",
@@ -93,17 +199,16 @@ func (h *SessionHandler) GetSessions(c *gin.Context) {
 	c.JSON(http.StatusOK, AuthResponse{
 		Code:    0,
 		Message: "success",
-		Data:    sessions,
+		Data:    result,
 	})
 }
 
 // GetSession 获取单个会话
 func (h *SessionHandler) GetSession(c *gin.Context) {
 	userID := c.GetUint("userID")
-	sessionID := c.Param("id")
 
-	var session model.Session
-	if err := database.DB.Where("id = ? AND user_id = ?", sessionID, userID).First(&session).Error; err != nil {
+	session, err := h.store.Sessions.Get(c.Request.Context(), userID, idParam(c, "id"))
+	if err != nil {
 		c.JSON(http.StatusNotFound, AuthResponse{
 			Code:    404,
 			Message: "会话不存在",
@@ -118,14 +223,55 @@ func (h *SessionHandler) GetSession(c *gin.Context) {
 	})
 }
 
-// UpdateSession 更新会话
+// UpdateSessionRequest 更新会话请求，只更新传入的字段
 type UpdateSessionRequest struct {
-	Title string `json:"title" binding:"required,max=255"`
+	Title            *string   `json:"title" binding:"omitempty,min=1,max=255"`
+	Mode             *string   `json:"mode" binding:"omitempty,max=50"` // 切换到该模式的内置助手
+	AssistantID      *uint     `json:"assistant_id"`                    // 切换助手，优先于mode
+	Model            *string   `json:"model" binding:"omitempty,max=100"`
+	Temperature      *float64  `json:"temperature" binding:"omitempty,min=0,max=2"`
+	KnowledgeBaseIDs *[]uint   `json:"knowledge_base_ids"`
+	Pinned           *bool     `json:"pinned"`
+	Archived         *bool     `json:"archived"`
+	FolderID         *uint     `json:"folder_id"` // 0 表示移出文件夹
+	Tags             *[]string `json:"tags"`
+}
+
+// sessionError 会话参数校验错误
+type sessionError struct {
+	status  int
+	message string
 }
 
+// selectAssistant 根据助手ID或模式设置会话的助手，会话的模式由助手决定（Assistant.SessionMode）
+// 只指定模式时（兼容旧接口）使用该模式的内置助手，没有内置助手的模式需要先创建使用该模式的助手；
+// 在事务中调用时传入事务内的 st
+func selectAssistant(ctx context.Context, st *store.Store, userID uint, session *model.Session, mode string, assistantID *uint) *sessionError {
+	var a *model.Assistant
+	if assistantID != nil {
+		var err error
+		if a, err = st.Assistants.GetVisible(ctx, userID, *assistantID); err != nil {
+			return &sessionError{http.StatusNotFound, "助手不存在"}
+		}
+	} else {
+		if mode == "" {
+			mode = prompt.DefaultMode
+		}
+		var err error
+		if a, err = st.Assistants.GetBuiltin(ctx, mode); err != nil {
+			return &sessionError{http.StatusBadRequest, "不支持的模式: " + mode}
+		}
+	}
+
+	session.AssistantID = &a.ID
+	session.Mode = a.SessionMode()
+	return nil
+}
+
+// UpdateSession 更新会话标题、模式（助手）、会话级配置和整理状态（置顶、归档、文件夹、标签）
+// 模式或助手变化时记录一条变更记录
 func (h *SessionHandler) UpdateSession(c *gin.Context) {
 	userID := c.GetUint("userID")
-	sessionID := c.Param("id")
 
 	var req UpdateSessionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -136,11 +282,90 @@ func (h *SessionHandler) UpdateSession(c *gin.Context) {
 		return
 	}
 
-	result := database.DB.Model(&model.Session{}).
-		Where("id = ? AND user_id = ?", sessionID, userID).
-		Update("title", req.Title)
+	session, err := h.store.Sessions.Get(c.Request.Context(), userID, idParam(c, "id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, AuthResponse{
+			Code:    404,
+			Message: "会话不存在",
+		})
+		return
+	}
+
+	change := model.SessionModeChange{
+		SessionID:       session.ID,
+		UserID:          userID,
+		FromMode:        session.Mode,
+		FromAssistantID: session.AssistantID,
+	}
+
+	if req.Title != nil {
+		session.Title = *req.Title
+		session.AutoTitle = false
+	}
+	if req.AssistantID != nil || req.Mode != nil {
+		mode := ""
+		if req.Mode != nil {
+			mode = *req.Mode
+		}
+		if err := selectAssistant(c.Request.Context(), h.store, userID, session, mode, req.AssistantID); err != nil {
+			c.JSON(err.status, AuthResponse{
+				Code:    err.status,
+				Message: err.message,
+			})
+			return
+		}
+	}
+	if req.Model != nil {
+		session.Model = *req.Model
+	}
+	if req.Temperature != nil {
+		session.Temperature = req.Temperature
+	}
+	if req.KnowledgeBaseIDs != nil {
+		ids := uniqueIDs(*req.KnowledgeBaseIDs)
+		accessible, err := h.store.KnowledgeBases.Accessible(c.Request.Context(), userID, ids)
+		if err != nil || len(accessible) != len(ids) {
+			c.JSON(http.StatusForbidden, AuthResponse{
+				Code:    403,
+				Message: errKnowledgeBaseForbidden.Error(),
+			})
+			return
+		}
+		session.KnowledgeBaseIDs = ids
+	}
+	if req.Pinned != nil {
+		session.Pinned = *req.Pinned
+	}
+	if req.Archived != nil {
+		session.Archived = *req.Archived
+	}
+	if req.FolderID != nil {
+		session.FolderID = nil
+		if *req.FolderID > 0 {
+			folder, err := h.store.Folders.Get(c.Request.Context(), userID, *req.FolderID)
+			if err != nil {
+				c.JSON(http.StatusNotFound, AuthResponse{
+					Code:    404,
+					Message: "文件夹不存在",
+				})
+				return
+			}
+			session.FolderID = &folder.ID
+		}
+	}
+	if req.Tags != nil {
+		session.Tags = normalizeTags(*req.Tags)
+	}
+
+	change.ToMode = session.Mode
+	change.ToAssistantID = session.AssistantID
+	changed := change.FromMode != change.ToMode || !sameID(change.FromAssistantID, change.ToAssistantID)
 
-	if result.Error != nil {
+	var modeChange *model.SessionModeChange
+	if changed {
+		modeChange = &change
+	}
+	if err := h.store.Sessions.Save(c.Request.Context(), session, modeChange); err != nil {
 		c.JSON(http.StatusInternalServerError, AuthResponse{
 			Code:    500,
 			Message: "更新失败",
@@ -148,7 +373,19 @@ func (h *SessionHandler) UpdateSession(c *gin.Context) {
 		return
 	}
 
-	if result.RowsAffected == 0 {
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+		Data:    session,
+	})
+}
+
+// GetModeChanges 获取会话的模式变更记录
+func (h *SessionHandler) GetModeChanges(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	session, err := h.store.Sessions.Get(c.Request.Context(), userID, idParam(c, "id"))
+	if err != nil {
 		c.JSON(http.StatusNotFound, AuthResponse{
 			Code:    404,
 			Message: "会话不存在",
@@ -156,21 +393,37 @@ func (h *SessionHandler) UpdateSession(c *gin.Context) {
 		return
 	}
 
+	changes, err := h.store.Sessions.ModeChanges(c.Request.Context(), session.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "获取变更记录失败",
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, AuthResponse{
 		Code:    0,
 		Message: "success",
+		Data:    changes,
 	})
 }
 
+// sameID 比较两个可为空的ID
+func sameID(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
 // DeleteSession 删除会话
 func (h *SessionHandler) DeleteSession(c *gin.Context) {
 	userID := c.GetUint("userID")
-	sessionIDStr := c.Param("id")
-	sessionIDUint, _ := strconv.ParseUint(sessionIDStr, 10, 32)
 
 	// 检查会话是否存在
-	var session model.Session
-	if err := database.DB.Where("id = ? AND user_id = ?", sessionIDUint, userID).First(&session).Error; err != nil {
+	session, err := h.store.Sessions.Get(c.Request.Context(), userID, idParam(c, "id"))
+	if err != nil {
 		c.JSON(http.StatusNotFound, AuthResponse{
 			Code:    404,
 			Message: "会话不存在",
@@ -178,8 +431,8 @@ func (h *SessionHandler) DeleteSession(c *gin.Context) {
 		return
 	}
 
-	// 删除会话（软删除）
-	if err := database.DB.Delete(&session).Error; err != nil {
+	// 删除会话（软删除）及其下的所有消息，并撤销分享链接
+	if err := h.store.Sessions.Delete(c.Request.Context(), session); err != nil {
 		c.JSON(http.StatusInternalServerError, AuthResponse{
 			Code:    500,
 			Message: "删除失败",
@@ -187,11 +440,8 @@ func (h *SessionHandler) DeleteSession(c *gin.Context) {
 		return
 	}
 
-	// 删除会话下的所有消息
-	database.DB.Where("session_id = ?", sessionIDUint).Delete(&model.Message{})
-
-	// 删除Redis缓存
-	cache.DelSessionHistory(uint(sessionIDUint))
+	// 删除缓存
+	cache.DelSessionHistory(h.cache, session.ID)
 
 	c.JSON(http.StatusOK, AuthResponse{
 		Code:    0,
@@ -199,15 +449,15 @@ func (h *SessionHandler) DeleteSession(c *gin.Context) {
 	})
 }
 
-// GetHistory 获取会话历史
+// GetHistory 分页获取会话历史，始终从数据库读取完整分支（不受上下文缓存影响）
+// 默认返回当前分支，可通过 leaf_id 指定返回到某条消息为止的分支路径；
+// 默认从最新的消息开始（order=asc 时从第一条开始），支持按角色（role）和日期（from、to）过滤
 func (h *SessionHandler) GetHistory(c *gin.Context) {
 	userID := c.GetUint("userID")
-	sessionIDStr := c.Param("id")
-	sessionIDUint, _ := strconv.ParseUint(sessionIDStr, 10, 32)
 
 	// 检查会话是否存在
-	var session model.Session
-	if err := database.DB.Where("id = ? AND user_id = ?", sessionIDUint, userID).First(&session).Error; err != nil {
+	session, err := h.store.Sessions.Get(c.Request.Context(), userID, idParam(c, "id"))
+	if err != nil {
 		c.JSON(http.StatusNotFound, AuthResponse{
 			Code:    404,
 			Message: "会话不存在",
@@ -215,31 +465,212 @@ func (h *SessionHandler) GetHistory(c *gin.Context) {
 		return
 	}
 
-	// 优先从Redis获取
-	messages, err := cache.GetSessionHistory(uint(sessionIDUint))
-	if err == nil && len(messages) > 0 {
+	page, err := pagination.FromQuery(c, historySort)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+	role := c.Query("role")
+	if role != "" && role != "user" && role != "assistant" {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: "role 只能是 user 或 assistant",
+		})
+		return
+	}
+
+	leaf, err := h.activeLeaf(session.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "获取历史失败",
+		})
+		return
+	}
+	if leafIDStr := c.Query("leaf_id"); leafIDStr != "" {
+		leafID, _ := strconv.ParseUint(leafIDStr, 10, 32)
+		id := uint(leafID)
+		leaf = &id
+	}
+
+	if leaf == nil {
 		c.JSON(http.StatusOK, AuthResponse{
 			Code:    0,
 			Message: "success",
-			Data:    messages,
+			Data:    pagination.Page[model.Message]{Items: []model.Message{}},
+		})
+		return
+	}
+
+	messages, err := h.store.Messages.History(c.Request.Context(), store.HistoryQuery{
+		SessionID: session.ID,
+		LeafID:    *leaf,
+		Role:      role,
+		From:      from,
+		To:        to,
+	}, page)
+	if store.IsNotFound(err) {
+		c.JSON(http.StatusNotFound, AuthResponse{
+			Code:    404,
+			Message: "消息不存在",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "获取历史失败",
 		})
 		return
 	}
 
-	// 从数据库获取
-	if err := database.DB.Where("session_id = ?", sessionIDUint).
-		Order("created_at ASC").
-		Find(&messages).Error; err != nil {
+	branch, err := pagination.Build(page, messages, func(m store.BranchMessage) []interface{} {
+		return []interface{}{m.Depth, m.ID}
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, AuthResponse{
 			Code:    500,
 			Message: "获取历史失败",
 		})
 		return
 	}
+	result := &pagination.Page[model.Message]{
+		Items:      make([]model.Message, len(branch.Items)),
+		NextCursor: branch.NextCursor,
+		HasMore:    branch.HasMore,
+	}
+	for i, m := range branch.Items {
+		result.Items[i] = m.Message
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+		Data:    result,
+	})
+}
+
+// historySort 历史消息按在分支上的位置排列（即创建的先后顺序），默认从最新的消息开始
+var historySort = pagination.Sort{
+	Options:  map[string]string{"created_at": "depth"},
+	Default:  "created_at",
+	Tiebreak: "id",
+}
+
+// GetTree 获取会话的全部消息（含各分支），前端按 parent_id 组装消息树
+func (h *SessionHandler) GetTree(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	session, err := h.store.Sessions.Get(c.Request.Context(), userID, idParam(c, "id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, AuthResponse{
+			Code:    404,
+			Message: "会话不存在",
+		})
+		return
+	}
+
+	leaf, err := h.activeLeaf(session.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "获取消息失败",
+		})
+		return
+	}
+
+	messages, err := h.store.Messages.List(c.Request.Context(), session.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "获取消息失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+		Data: gin.H{
+			"active_message_id": leaf,
+			"messages":          messages,
+		},
+	})
+}
+
+// SwitchBranchRequest 切换分支请求
+type SwitchBranchRequest struct {
+	MessageID uint `json:"message_id" binding:"required"`
+}
+
+// SwitchBranch 切换当前分支
+// 指定的消息不是叶子时，沿最新的子消息走到分支末尾
+func (h *SessionHandler) SwitchBranch(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var req SwitchBranchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: "参数错误",
+		})
+		return
+	}
+
+	session, err := h.store.Sessions.Get(c.Request.Context(), userID, idParam(c, "id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, AuthResponse{
+			Code:    404,
+			Message: "会话不存在",
+		})
+		return
+	}
+	if err := h.ensureTree(session.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "切换分支失败",
+		})
+		return
+	}
 
-	// 更新会话时间
-	database.DB.Model(&session).Update("updated_at", time.Now())
+	msg, err := h.store.Messages.Get(c.Request.Context(), session.ID, req.MessageID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, AuthResponse{
+			Code:    404,
+			Message: "消息不存在",
+		})
+		return
+	}
+
+	leaf := msg.ID
+	for {
+		child, err := h.store.Messages.LatestChild(c.Request.Context(), session.ID, leaf)
+		if err != nil {
+			break
+		}
+		leaf = child.ID
+	}
+
+	if err := h.setActive(session.ID, &leaf); err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "切换分支失败",
+		})
+		return
+	}
 
+	messages, _ := h.Path(session.ID, &leaf)
 	c.JSON(http.StatusOK, AuthResponse{
 		Code:    0,
 		Message: "success",
@@ -249,55 +680,139 @@ func (h *SessionHandler) GetHistory(c *gin.Context) {
 
 // AddMessage 添加消息到会话
 func (h *SessionHandler) AddMessage(sessionID, userID uint, role, content string) error {
-	msg := model.Message{
+	return h.SaveMessage(&model.Message{
 		SessionID: sessionID,
 		UserID:    userID,
 		Role:      role,
 		Content:   content,
+	})
+}
+
+// SaveMessage 保存消息到当前分支末尾（可携带提示词模板版本等附加信息）
+func (h *SessionHandler) SaveMessage(msg *model.Message) error {
+	parentID, err := h.activeLeaf(msg.SessionID)
+	if err != nil {
+		return err
 	}
+	return h.SaveMessageUnder(msg, parentID)
+}
 
-	if err := database.DB.Create(&msg).Error; err != nil {
+// SaveMessageUnder 在指定父消息下保存消息并切换到该分支
+// parentID为nil表示作为会话的第一条消息（如编辑第一条提问）
+func (h *SessionHandler) SaveMessageUnder(msg *model.Message, parentID *uint) error {
+	msg.ParentID = parentID
+	if err := h.store.Messages.Create(context.Background(), msg); err != nil {
 		return err
 	}
+	h.search.IndexMessage(*msg)
+	return h.setActive(msg.SessionID, &msg.ID)
+}
 
-	// 更新会话时间
-	database.DB.Model(&model.Session{}).Where("id = ?", sessionID).Update("updated_at", time.Now())
+// setActive 设置当前分支，同时更新会话时间和上下文缓存
+func (h *SessionHandler) setActive(sessionID uint, leafID *uint) error {
+	if err := h.store.Sessions.SetActive(context.Background(), sessionID, leafID); err != nil {
+		return err
+	}
 
-	// 更新Redis缓存
+	// 更新缓存
 	h.updateSessionHistoryCache(sessionID)
-
 	return nil
 }
 
-// updateSessionHistoryCache 更新会话历史缓存
-func (h *SessionHandler) updateSessionHistoryCache(sessionID uint) {
-	var messages []model.Message
-	database.DB.Where("session_id = ?", sessionID).
-		Order("created_at DESC").
-		Limit(h.historyLimit).
-		Find(&messages)
+// activeLeaf 获取当前分支的最后一条消息ID，会话没有消息时返回nil
+func (h *SessionHandler) activeLeaf(sessionID uint) (*uint, error) {
+	if err := h.ensureTree(sessionID); err != nil {
+		return nil, err
+	}
+	return h.store.Sessions.ActiveMessageID(context.Background(), sessionID)
+}
+
+// ensureTree 将支持分支之前的平铺消息按时间顺序串成一条分支
+func (h *SessionHandler) ensureTree(sessionID uint) error {
+	active, err := h.store.Sessions.ActiveMessageID(context.Background(), sessionID)
+	if err != nil {
+		return err
+	}
+	if active != nil {
+		return nil
+	}
+	return h.store.Messages.Chain(context.Background(), sessionID)
+}
+
+// Path 获取从第一条消息到leafID的分支路径，leafID为nil时返回空
+func (h *SessionHandler) Path(sessionID uint, leafID *uint) ([]model.Message, error) {
+	if leafID == nil {
+		return []model.Message{}, nil
+	}
+
+	all, err := h.store.Messages.List(context.Background(), sessionID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]model.Message, len(all))
+	for _, m := range all {
+		byID[m.ID] = m
+	}
+
+	if _, ok := byID[*leafID]; !ok {
+		return nil, store.ErrNotFound
+	}
+
+	var path []model.Message
+	for id := leafID; id != nil; {
+		m, ok := byID[*id]
+		if !ok {
+			break
+		}
+		path = append(path, m)
+		id = m.ParentID
+	}
 
 	// 反转顺序（从旧到新）
-	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
-		messages[i], messages[j] = messages[j], messages[i]
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
 	}
+	return path, nil
+}
 
-	cache.SetSessionHistory(sessionID, messages)
+// recent 取最近N条消息作为上下文
+func (h *SessionHandler) recent(messages []model.Message) []model.Message {
+	if len(messages) > h.historyLimit {
+		return messages[len(messages)-h.historyLimit:]
+	}
+	return messages
+}
+
+// updateSessionHistoryCache 更新会话历史缓存（当前分支最近N条消息）
+func (h *SessionHandler) updateSessionHistoryCache(sessionID uint) {
+	leaf, err := h.store.Sessions.ActiveMessageID(context.Background(), sessionID)
+	if err != nil {
+		return
+	}
+	messages, err := h.Path(sessionID, leaf)
+	if err != nil {
+		return
+	}
+
+	cache.SetSessionHistory(h.cache, sessionID, h.recent(messages))
 }
 
 // GetHistoryForContext 获取用于上下文的会话历史
-// 返回最近N轮对话，用于拼接到Prompt
+// 返回当前分支最近N条消息，用于拼接到Prompt
 func (h *SessionHandler) GetHistoryForContext(sessionID uint) []model.Message {
 	// 先尝试从Redis获取
-	messages, err := cache.GetSessionHistory(sessionID)
+	messages, err := cache.GetSessionHistory(h.cache, sessionID)
 	if err == nil && len(messages) > 0 {
 		return messages
 	}
 
-	// 从数据库获取
-	database.DB.Where("session_id = ?", sessionID).
-		Order("created_at ASC").
-		Find(&messages)
-
-	return messages
+	leaf, err := h.activeLeaf(sessionID)
+	if err != nil {
+		return nil
+	}
+	messages, err = h.Path(sessionID, leaf)
+	if err != nil {
+		return nil
+	}
+	return h.recent(messages)
 }
@@ -0,0 +1,290 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"go-ai-copilot/internal/config"
+	"go-ai-copilot/internal/export"
+	"go-ai-copilot/internal/model"
+	"go-ai-copilot/internal/store"
+)
+
+// maxImportSize 导入文件大小上限
+const maxImportSize = 50 << 20
+
+// ExportSession 导出会话
+// format: md（默认）/ json / html；md和html导出当前分支，json包含所有分支
+func (h *SessionHandler) ExportSession(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	session, err := h.store.Sessions.Get(c.Request.Context(), userID, idParam(c, "id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, AuthResponse{
+			Code:    404,
+			Message: "会话不存在",
+		})
+		return
+	}
+	leaf, err := h.activeLeaf(session.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "导出失败",
+		})
+		return
+	}
+	session.ActiveMessageID = leaf
+
+	messages, err := h.store.Messages.List(c.Request.Context(), session.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "导出失败",
+		})
+		return
+	}
+
+	t := export.FromSession(*session, sessionModel(c.Request.Context(), h.store, userID, session), messages)
+
+	var (
+		data        []byte
+		contentType string
+		ext         string
+	)
+	switch c.DefaultQuery("format", "md") {
+	case "md", "markdown":
+		data, contentType, ext = export.Markdown(t), "text/markdown; charset=utf-8", "md"
+	case "json":
+		data, err = export.JSON(t)
+		contentType, ext = "application/json; charset=utf-8", "json"
+	case "html":
+		data, err = export.HTML(t)
+		contentType, ext = "text/html; charset=utf-8", "html"
+	default:
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: "不支持的导出格式，可选 md / json / html",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "导出失败",
+		})
+		return
+	}
+
+	filename := fmt.Sprintf("%s.%s", session.Title, ext)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"session-%d.%s\"; filename*=UTF-8''%s",
+		session.ID, ext, url.PathEscape(filename)))
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// sessionModel 会话实际使用的模型：会话配置 > 助手配置 > 服务端配置
+func sessionModel(ctx context.Context, st *store.Store, userID uint, session *model.Session) string {
+	if session.Model != "" {
+		return session.Model
+	}
+	if session.AssistantID != nil {
+		if a, err := st.Assistants.GetVisible(ctx, userID, *session.AssistantID); err == nil && a.Model != "" {
+			return a.Model
+		}
+	}
+	if config.GlobalConfig != nil {
+		return config.GlobalConfig.AI.Model
+	}
+	return ""
+}
+
+// ImportSessions 导入会话
+// 支持本项目导出的JSON和ChatGPT导出的conversations.json，可通过表单字段file上传或直接作为请求体
+func (h *SessionHandler) ImportSessions(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	var reader io.Reader = c.Request.Body
+	if file, _, err := c.Request.FormFile("file"); err == nil {
+		defer file.Close()
+		reader = file
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: "读取导入文件失败，文件不能超过50MB",
+		})
+		return
+	}
+
+	transcripts, err := export.Parse(data)
+	if err != nil {
+		message := "导入文件格式错误: " + err.Error()
+		if errors.Is(err, export.ErrUnknownFormat) {
+			message = err.Error()
+		}
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: message,
+		})
+		return
+	}
+
+	var imported []gin.H
+	var indexed []model.Message
+	ctx := c.Request.Context()
+	err = h.store.Transaction(ctx, func(tx *store.Store) error {
+		for i := range transcripts {
+			session, messages, err := importTranscript(ctx, tx, userID, &transcripts[i])
+			if err != nil {
+				return err
+			}
+			indexed = append(indexed, messages...)
+			imported = append(imported, gin.H{
+				"id":            session.ID,
+				"title":         session.Title,
+				"mode":          session.Mode,
+				"message_count": len(messages),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "导入失败",
+		})
+		return
+	}
+	// 提交后再生成向量，事务回滚时不会留下无主的向量
+	h.search.IndexMessages(indexed)
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+		Data:    imported,
+	})
+}
+
+// importTranscript 将一份会话记录写入数据库，返回创建的会话和消息
+// 导入的会话使用记录中的模式（不存在时回退为chat），模型等配置使用服务端默认值
+func importTranscript(ctx context.Context, tx *store.Store, userID uint, t *export.Transcript) (*model.Session, []model.Message, error) {
+	title := t.Title
+	if title == "" {
+		title = "导入的会话"
+	}
+	if len([]rune(title)) > 255 {
+		title = string([]rune(title)[:255])
+	}
+
+	session := model.Session{UserID: userID, Title: title}
+	if err := selectAssistant(ctx, tx, userID, &session, t.Mode, nil); err != nil {
+		selectAssistant(ctx, tx, userID, &session, "chat", nil)
+	}
+	if !t.CreatedAt.IsZero() {
+		session.CreatedAt = t.CreatedAt
+	}
+	if err := tx.Sessions.Create(ctx, &session); err != nil {
+		return nil, nil, err
+	}
+
+	// 只导入user和assistant消息，跳过的消息（system、工具调用等）的子消息挂到最近的保留祖先下
+	byID := make(map[uint]export.Message, len(t.Messages))
+	for _, m := range t.Messages {
+		byID[m.ID] = m
+	}
+	kept := func(m export.Message) bool {
+		return m.Role == "user" || m.Role == "assistant"
+	}
+	// keptAncestor 从id开始向上查找最近的保留消息，导入文件不可信，步数限制防止环形引用
+	keptAncestor := func(id *uint) *uint {
+		for steps := 0; id != nil && steps < len(t.Messages); steps++ {
+			m, ok := byID[*id]
+			if !ok {
+				return nil
+			}
+			if kept(m) {
+				return id
+			}
+			id = m.ParentID
+		}
+		return nil
+	}
+
+	var pending []export.Message
+	for _, m := range t.Messages {
+		if !kept(m) {
+			continue
+		}
+		m.ParentID = keptAncestor(m.ParentID)
+		if m.ParentID != nil && *m.ParentID == m.ID {
+			m.ParentID = nil
+		}
+		pending = append(pending, m)
+	}
+
+	// 按父子关系依次写入，父消息不在记录中的作为根消息
+	newIDs := make(map[uint]uint, len(pending))
+	var messages []model.Message
+	for len(pending) > 0 {
+		var next []export.Message
+		for _, m := range pending {
+			var parentID *uint
+			if m.ParentID != nil {
+				id, ok := newIDs[*m.ParentID]
+				if !ok {
+					next = append(next, m)
+					continue
+				}
+				parentID = &id
+			}
+
+			msg := model.Message{
+				SessionID:     session.ID,
+				UserID:        userID,
+				ParentID:      parentID,
+				Role:          m.Role,
+				Content:       m.Content,
+				PromptVersion: m.PromptVersion,
+			}
+			if !m.CreatedAt.IsZero() {
+				msg.CreatedAt = m.CreatedAt
+			}
+			if err := tx.Messages.Create(ctx, &msg); err != nil {
+				return nil, nil, err
+			}
+			newIDs[m.ID] = msg.ID
+			messages = append(messages, msg)
+		}
+		// 剩余消息的父消息无法写入（环形引用），作为根消息处理
+		if len(next) == len(pending) {
+			for i := range next {
+				next[i].ParentID = nil
+			}
+		}
+		pending = next
+	}
+
+	if len(messages) > 0 {
+		// 使用记录中的当前分支（当前消息被跳过时取最近的保留祖先），没有时使用记录中最后一条保留的消息
+		var active uint
+		if id := keptAncestor(t.ActiveMessageID); id != nil {
+			active = newIDs[*id]
+		}
+		for i := len(t.Messages) - 1; active == 0 && i >= 0; i-- {
+			active = newIDs[t.Messages[i].ID]
+		}
+		if err := tx.Sessions.SetActive(ctx, session.ID, &active); err != nil {
+			return nil, nil, err
+		}
+		session.ActiveMessageID = &active
+	}
+	return &session, messages, nil
+}
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-ai-copilot/internal/config"
+	"go-ai-copilot/internal/model"
+	"go-ai-copilot/internal/pagination"
+	"go-ai-copilot/internal/search"
+	"go-ai-copilot/internal/store"
+	"go-ai-copilot/pkg/ai"
+)
+
+// NewSearcher 创建聊天记录搜索（全文检索索引，开启语义搜索时创建向量化客户端）
+func NewSearcher(cfg config.SearchConfig, aiCfg config.AIConfig, st *store.Store) (*search.Searcher, error) {
+	var client *ai.EmbeddingClient
+	var space *model.EmbeddingSpace
+	if cfg.Semantic {
+		c, s, err := NewEmbeddingClient(aiCfg, st)
+		if err != nil {
+			log.Printf("警告: 语义搜索未启用: %v", err)
+		} else {
+			client, space = c, s
+		}
+	}
+	return search.New(st, cfg.TextSearchConfig, client, space)
+}
+
+// parseDate 解析日期参数，支持 2006-01-02 和 RFC3339
+func parseDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// parseDateRange 解析 from、to 日期参数，to 只给出日期时包含当天
+func parseDateRange(c *gin.Context) (*time.Time, *time.Time, error) {
+	from, err := parseDate(c.Query("from"))
+	if err != nil {
+		return nil, nil, errors.New("from 日期格式错误")
+	}
+	to, err := parseDate(c.Query("to"))
+	if err != nil {
+		return nil, nil, errors.New("to 日期格式错误")
+	}
+	if to != nil && len(c.Query("to")) == len("2006-01-02") {
+		end := to.AddDate(0, 0, 1)
+		to = &end
+	}
+	return from, to, nil
+}
+
+// SearchMessages 搜索聊天记录
+// q: 关键词；mode: 会话模式；role: user / assistant；from、to: 日期范围（to当天包含在内）；
+// semantic=true 时按语义相似度搜索；limit、cursor: 分页（cursor 为上一页返回的 next_cursor）
+func (h *SessionHandler) SearchMessages(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	keyword := c.Query("q")
+	if keyword == "" {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: "请输入搜索关键词",
+		})
+		return
+	}
+
+	role := c.Query("role")
+	if role != "" && role != "user" && role != "assistant" {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: "role 只能是 user 或 assistant",
+		})
+		return
+	}
+
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	threshold, _ := strconv.ParseFloat(c.DefaultQuery("threshold", "0.5"), 64)
+
+	q := search.Query{
+		UserID:    userID,
+		Keyword:   keyword,
+		Mode:      c.Query("mode"),
+		Role:      role,
+		From:      from,
+		To:        to,
+		Limit:     limit,
+		Cursor:    c.Query("cursor"),
+		Threshold: threshold,
+	}
+
+	var page *pagination.Page[search.Hit]
+	if c.Query("semantic") == "true" {
+		if !h.search.SemanticEnabled() {
+			c.JSON(http.StatusBadRequest, AuthResponse{
+				Code:    400,
+				Message: "未启用语义搜索",
+			})
+			return
+		}
+		page, err = h.search.Semantic(c.Request.Context(), q)
+	} else {
+		page, err = h.search.Keyword(c.Request.Context(), q)
+	}
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "搜索失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+		Data:    page,
+	})
+}
@@ -0,0 +1,321 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-ai-copilot/internal/assistant"
+	"go-ai-copilot/internal/cache"
+	"go-ai-copilot/internal/database"
+	"go-ai-copilot/internal/model"
+	"go-ai-copilot/internal/pagination"
+	"go-ai-copilot/internal/prompt"
+	"go-ai-copilot/internal/store"
+	"gorm.io/gorm/logger"
+)
+
+// newTestSessionHandler 基于内存SQLite数据库（只有一个连接，已执行迁移并写入内置助手）创建会话处理器
+// 只有一个连接时，事务中绕过事务访问数据库会一直等待连接，可以发现这类死锁
+func newTestSessionHandler(t *testing.T) *SessionHandler {
+	t.Helper()
+	if err := database.Open(database.Config{Driver: database.DriverSQLite, Path: ":memory:"}); err != nil {
+		t.Fatal(err)
+	}
+	db := database.DB
+	db.Logger = logger.Discard
+	if _, err := database.MigrateUp(db); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	st := store.New(db)
+	if err := prompt.SeedDefaults(context.Background(), st.Prompts); err != nil {
+		t.Fatal(err)
+	}
+	if err := assistant.SeedBuiltins(context.Background(), st.Assistants); err != nil {
+		t.Fatal(err)
+	}
+	return NewSessionHandler(cache.NewMemory(100), st, nil)
+}
+
+// newTestRouter 注册会话接口，请求以指定用户身份执行
+func newTestRouter(h *SessionHandler, userID uint) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", userID)
+		c.Next()
+	})
+	r.GET("/session/list", h.GetSessions)
+	r.POST("/session", h.CreateSession)
+	r.POST("/session/import", h.ImportSessions)
+	r.GET("/session/:id", h.GetSession)
+	r.PUT("/session/:id", h.UpdateSession)
+	r.DELETE("/session/:id", h.DeleteSession)
+	r.GET("/session/:id/history", h.GetHistory)
+	return r
+}
+
+// do 发送请求并解析响应中的 data，请求超时时（如死锁）测试失败
+func do(t *testing.T, r *gin.Engine, method, path string, body interface{}, data interface{}) int {
+	t.Helper()
+	var reader *bytes.Reader
+	switch b := body.(type) {
+	case nil:
+		reader = bytes.NewReader(nil)
+	case []byte:
+		reader = bytes.NewReader(b)
+	default:
+		raw, err := json.Marshal(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(raw)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		r.ServeHTTP(w, req)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("%s %s 超时", method, path)
+	}
+
+	if data != nil && w.Code == http.StatusOK {
+		var resp struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(resp.Data, data); err != nil {
+			t.Fatalf("%s %s: %v, %s", method, path, err, w.Body.String())
+		}
+	}
+	return w.Code
+}
+
+// contents 消息内容
+func contents(messages []model.Message) []string {
+	result := make([]string, len(messages))
+	for i, m := range messages {
+		result[i] = m.Content
+	}
+	return result
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSessionCRUD(t *testing.T) {
+	h := newTestSessionHandler(t)
+	r := newTestRouter(h, 1)
+
+	var session model.Session
+	if code := do(t, r, http.MethodPost, "/session", gin.H{"mode": "chat"}, &session); code != http.StatusOK {
+		t.Fatalf("创建会话: %d", code)
+	}
+	if session.ID == 0 || session.Title != "新会话" || !session.AutoTitle || session.Mode != "chat" || session.AssistantID == nil {
+		t.Fatalf("会话 = %+v", session)
+	}
+	if code := do(t, r, http.MethodPost, "/session", gin.H{"mode": "unknown"}, nil); code != http.StatusBadRequest {
+		t.Fatalf("不支持的模式: %d", code)
+	}
+
+	// 会话的模式由助手决定
+	custom := model.Assistant{UserID: 1, Name: "周报助手", SystemPrompt: "帮我写周报", Mode: "chat", Tools: []string{"web_search"}}
+	if err := h.store.Assistants.Create(context.Background(), &custom); err != nil {
+		t.Fatal(err)
+	}
+	var withAssistant model.Session
+	do(t, r, http.MethodPost, "/session", gin.H{"assistant_id": custom.ID, "mode": "code_test"}, &withAssistant)
+	if withAssistant.Mode != "custom" || withAssistant.AssistantID == nil || *withAssistant.AssistantID != custom.ID {
+		t.Fatalf("使用自定义助手的会话 = %+v", withAssistant)
+	}
+	var ragSession model.Session
+	do(t, r, http.MethodPost, "/session", gin.H{"mode": "rag"}, &ragSession)
+	if ragSession.Mode != "rag" || ragSession.AssistantID == nil {
+		t.Fatalf("rag模式的会话 = %+v", ragSession)
+	}
+	if err := h.store.Sessions.Delete(context.Background(), &withAssistant); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.store.Sessions.Delete(context.Background(), &ragSession); err != nil {
+		t.Fatal(err)
+	}
+	if saved, err := h.store.Assistants.GetOwned(context.Background(), 1, custom.ID); err != nil || len(saved.Tools) != 1 || saved.Tools[0] != "web_search" {
+		t.Fatalf("助手的工具 = %+v, %v", saved, err)
+	}
+
+	var updated model.Session
+	path := fmt.Sprintf("/session/%d", session.ID)
+	if code := do(t, r, http.MethodPut, path, gin.H{"title": "周报", "pinned": true}, &updated); code != http.StatusOK {
+		t.Fatalf("更新会话: %d", code)
+	}
+	var got model.Session
+	do(t, r, http.MethodGet, path, nil, &got)
+	if got.Title != "周报" || got.AutoTitle || !got.Pinned {
+		t.Fatalf("更新后的会话 = %+v", got)
+	}
+
+	// 其他用户看不到该会话
+	if code := do(t, newTestRouter(h, 2), http.MethodGet, path, nil, nil); code != http.StatusNotFound {
+		t.Fatalf("其他用户获取会话: %d", code)
+	}
+
+	var list pagination.Page[model.Session]
+	do(t, r, http.MethodGet, "/session/list", nil, &list)
+	if len(list.Items) != 1 || list.Items[0].ID != session.ID {
+		t.Fatalf("会话列表 = %+v", list.Items)
+	}
+
+	if code := do(t, r, http.MethodDelete, path, nil, nil); code != http.StatusOK {
+		t.Fatalf("删除会话: %d", code)
+	}
+	if code := do(t, r, http.MethodGet, path, nil, nil); code != http.StatusNotFound {
+		t.Fatalf("删除后获取会话: %d", code)
+	}
+	do(t, r, http.MethodGet, "/session/list", nil, &list)
+	if len(list.Items) != 0 {
+		t.Fatalf("删除后的会话列表 = %+v", list.Items)
+	}
+}
+
+func TestSessionHistory(t *testing.T) {
+	h := newTestSessionHandler(t)
+	r := newTestRouter(h, 1)
+
+	var session model.Session
+	do(t, r, http.MethodPost, "/session", gin.H{"title": "历史"}, &session)
+	for i := 1; i <= 5; i++ {
+		role := "user"
+		if i%2 == 0 {
+			role = "assistant"
+		}
+		if err := h.AddMessage(session.ID, 1, role, fmt.Sprintf("消息%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 默认从最新的消息开始，按游标翻页
+	path := fmt.Sprintf("/session/%d/history", session.ID)
+	var page pagination.Page[model.Message]
+	do(t, r, http.MethodGet, path+"?limit=2", nil, &page)
+	if got := contents(page.Items); !equalStrings(got, []string{"消息5", "消息4"}) || !page.HasMore {
+		t.Fatalf("第一页 = %v, has_more = %v", got, page.HasMore)
+	}
+	var all []string
+	all = append(all, contents(page.Items)...)
+	for page.HasMore {
+		cursor := page.NextCursor
+		page = pagination.Page[model.Message]{}
+		if code := do(t, r, http.MethodGet, path+"?limit=2&cursor="+cursor, nil, &page); code != http.StatusOK {
+			t.Fatalf("翻页: %d", code)
+		}
+		all = append(all, contents(page.Items)...)
+	}
+	if !equalStrings(all, []string{"消息5", "消息4", "消息3", "消息2", "消息1"}) {
+		t.Fatalf("全部历史 = %v", all)
+	}
+
+	do(t, r, http.MethodGet, path+"?order=asc&role=assistant", nil, &page)
+	if got := contents(page.Items); !equalStrings(got, []string{"消息2", "消息4"}) {
+		t.Fatalf("助手消息 = %v", got)
+	}
+
+	if code := do(t, newTestRouter(h, 2), http.MethodGet, path, nil, nil); code != http.StatusNotFound {
+		t.Fatalf("其他用户获取历史: %d", code)
+	}
+	if code := do(t, r, http.MethodGet, path+"?leaf_id=999", nil, nil); code != http.StatusNotFound {
+		t.Fatalf("不存在的消息: %d", code)
+	}
+
+	// 查看历史不改变会话的更新时间
+	var before, after model.Session
+	do(t, r, http.MethodGet, fmt.Sprintf("/session/%d", session.ID), nil, &before)
+	time.Sleep(10 * time.Millisecond)
+	do(t, r, http.MethodGet, path, nil, &page)
+	do(t, r, http.MethodGet, fmt.Sprintf("/session/%d", session.ID), nil, &after)
+	if !after.UpdatedAt.Equal(before.UpdatedAt) {
+		t.Fatalf("查看历史后更新时间从 %v 变为 %v", before.UpdatedAt, after.UpdatedAt)
+	}
+}
+
+func TestImportSessions(t *testing.T) {
+	h := newTestSessionHandler(t)
+	r := newTestRouter(h, 1)
+
+	// 消息2有两个回答（分支），当前分支为消息4；system消息跳过，其子消息挂到最近的保留祖先下
+	active := uint(4)
+	parent := func(id uint) *uint { return &id }
+	transcript := gin.H{
+		"format":            "go-ai-copilot",
+		"version":           1,
+		"title":             "导入的周报",
+		"mode":              "code_explain",
+		"active_message_id": active,
+		"messages": []gin.H{
+			{"id": 1, "role": "system", "content": "系统提示"},
+			{"id": 2, "parent_id": parent(1), "role": "user", "content": "问题"},
+			{"id": 3, "parent_id": parent(2), "role": "assistant", "content": "回答一"},
+			{"id": 4, "parent_id": parent(2), "role": "assistant", "content": "回答二"},
+		},
+	}
+	raw, _ := json.Marshal([]gin.H{transcript, {"format": "go-ai-copilot", "version": 1, "mode": "unknown"}})
+
+	var imported []struct {
+		ID           uint   `json:"id"`
+		Title        string `json:"title"`
+		Mode         string `json:"mode"`
+		MessageCount int    `json:"message_count"`
+	}
+	if code := do(t, r, http.MethodPost, "/session/import", raw, &imported); code != http.StatusOK {
+		t.Fatalf("导入: %d", code)
+	}
+	if len(imported) != 2 {
+		t.Fatalf("导入结果 = %+v", imported)
+	}
+	if imported[0].Title != "导入的周报" || imported[0].Mode != "code_explain" || imported[0].MessageCount != 3 {
+		t.Fatalf("第一个会话 = %+v", imported[0])
+	}
+	// 不支持的模式回退为chat
+	if imported[1].Title != "导入的会话" || imported[1].Mode != "chat" || imported[1].MessageCount != 0 {
+		t.Fatalf("第二个会话 = %+v", imported[1])
+	}
+
+	var page pagination.Page[model.Message]
+	do(t, r, http.MethodGet, fmt.Sprintf("/session/%d/history?order=asc", imported[0].ID), nil, &page)
+	if got := contents(page.Items); !equalStrings(got, []string{"问题", "回答二"}) {
+		t.Fatalf("导入的当前分支 = %v", got)
+	}
+
+	if code := do(t, r, http.MethodPost, "/session/import", []byte(`{"foo": 1}`), nil); code != http.StatusBadRequest {
+		t.Fatalf("无法识别的格式: %d", code)
+	}
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sashabaranov/go-openai"
+	"go-ai-copilot/internal/events"
+	"go-ai-copilot/internal/store"
+	"go-ai-copilot/pkg/ai"
+)
+
+// 自动标题的长度限制
+const (
+	maxTitleRunes   = 30
+	maxTags         = 5
+	maxTagRunes     = 20
+	titleInputRunes = 1000 // 生成标题时截取的问答长度
+)
+
+// titlePrompt 生成会话标题和标签的提示词
+const titlePrompt = `根据下面的一轮对话，为会话生成一个简洁的标题（不超过20个字）和1到3个主题标签。
+只输出JSON，不要输出其他内容，格式为：{"title": "标题", "tags": ["标签1", "标签2"]}`
+
+// titleResult 模型返回的标题和标签
+type titleResult struct {
+	Title string   `json:"title"`
+	Tags  []string `json:"tags"`
+}
+
+// generateTitle 首轮问答后在后台生成会话标题和标签，完成后推送给客户端
+// 通过条件更新认领任务，同一会话只生成一次；用户手动改过标题的会话不再生成
+func (h *ChatHandler) generateTitle(sessionID, userID uint, question, reply string) {
+	if h.client == nil {
+		return
+	}
+
+	placeholder, err := h.store.Sessions.ClaimAutoTitle(context.Background(), userID, sessionID)
+	if err != nil {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+
+		result, err := h.requestTitle(ctx, userID, question, reply)
+		if err != nil {
+			log.Printf("会话标题生成失败: %v", err)
+			// 恢复标记，下一轮对话后重试
+			h.store.Sessions.ReleaseAutoTitle(ctx, sessionID, placeholder)
+			return
+		}
+
+		// 生成期间用户手动修改了标题时不覆盖，用户已手动设置标签时保留
+		session, err := h.store.Sessions.SetGeneratedTitle(ctx, userID, sessionID, placeholder, result.Title, result.Tags)
+		if errors.Is(err, store.ErrTitleChanged) {
+			return
+		}
+		if err != nil {
+			log.Printf("会话标题保存失败: %v", err)
+			return
+		}
+
+		h.hub.Publish(userID, events.Event{Type: events.TypeSessionUpdated, Data: session})
+	}()
+}
+
+// requestTitle 调用模型生成标题和标签（使用批处理优先级，不占用交互请求的额度）
+func (h *ChatHandler) requestTitle(ctx context.Context, userID uint, question, reply string) (*titleResult, error) {
+	release, err := h.acquire(ctx, userID, ai.PriorityBatch, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	content, err := h.client.Chat(ctx, []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: titlePrompt},
+		{Role: openai.ChatMessageRoleUser, Content: "用户：" + truncateRunes(question, titleInputRunes) + "\n\n助手：" + truncateRunes(reply, titleInputRunes)},
+	}, ai.WithTemperature(0.3))
+	if err != nil {
+		return nil, err
+	}
+	return parseTitle(content)
+}
+
+// parseTitle 解析模型输出，兼容前后带说明文字或代码块的情况
+func parseTitle(content string) (*titleResult, error) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return nil, errors.New("模型输出不是JSON: " + truncateRunes(content, 100))
+	}
+
+	var result titleResult
+	if err := json.Unmarshal([]byte(content[start:end+1]), &result); err != nil {
+		return nil, err
+	}
+
+	result.Title = truncateRunes(strings.Trim(strings.TrimSpace(result.Title), `"“”《》`), maxTitleRunes)
+	if result.Title == "" {
+		return nil, errors.New("模型未生成标题")
+	}
+	result.Tags = normalizeTags(result.Tags)
+	return &result, nil
+}
+
+// normalizeTags 去除标签首尾空白和#号，去重并限制数量和长度
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = truncateRunes(strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(tag), "#")), maxTagRunes)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+		if len(result) == maxTags {
+			break
+		}
+	}
+	return result
+}
+
+// truncateRunes 按字符截断字符串
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
@@ -0,0 +1,330 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-ai-copilot/internal/export"
+	"go-ai-copilot/internal/model"
+	"go-ai-copilot/internal/store"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ShareHandler 会话分享处理器
+type ShareHandler struct {
+	store          *store.Store
+	sessionHandler *SessionHandler
+}
+
+// NewShareHandler 创建会话分享处理器
+func NewShareHandler(sessionHandler *SessionHandler) *ShareHandler {
+	return &ShareHandler{store: sessionHandler.store, sessionHandler: sessionHandler}
+}
+
+// CreateShareRequest 创建分享请求
+type CreateShareRequest struct {
+	ExpiresIn int    `json:"expires_in" binding:"min=0"` // 有效期（秒），0表示永久有效
+	Password  string `json:"password" binding:"max=72"`  // 访问密码，可选
+}
+
+// SharePasswordRequest 通过 POST 请求体提供分享的访问密码
+type SharePasswordRequest struct {
+	Password string `json:"password"`
+}
+
+// 分享密码的尝试限制
+const (
+	shareMaxPasswordFailures = 5                // 连续输错的次数上限
+	shareLockDuration        = 15 * time.Minute // 达到上限后的锁定时间
+)
+
+// newShareToken 生成分享Token
+func newShareToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CreateShare 创建分享链接，冻结会话当前分支的消息
+func (h *ShareHandler) CreateShare(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var req CreateShareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	session, err := h.store.Sessions.Get(c.Request.Context(), userID, idParam(c, "id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, AuthResponse{
+			Code:    404,
+			Message: "会话不存在",
+		})
+		return
+	}
+
+	leaf, err := h.sessionHandler.activeLeaf(session.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "分享创建失败",
+		})
+		return
+	}
+	messages, err := h.sessionHandler.Path(session.ID, leaf)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "分享创建失败",
+		})
+		return
+	}
+
+	token, err := newShareToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "分享创建失败",
+		})
+		return
+	}
+
+	share := model.SessionShare{
+		SessionID: session.ID,
+		UserID:    userID,
+		Token:     token,
+		Title:     session.Title,
+		Mode:      session.Mode,
+		Messages:  make([]model.SharedMessage, 0, len(messages)),
+	}
+	for _, m := range messages {
+		share.Messages = append(share.Messages, model.SharedMessage{
+			Role:      m.Role,
+			Content:   m.Content,
+			CreatedAt: m.CreatedAt,
+		})
+	}
+	if req.ExpiresIn > 0 {
+		expiresAt := time.Now().Add(time.Duration(req.ExpiresIn) * time.Second)
+		share.ExpiresAt = &expiresAt
+	}
+	if req.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, AuthResponse{
+				Code:    500,
+				Message: "分享创建失败",
+			})
+			return
+		}
+		share.PasswordHash = string(hash)
+	}
+
+	if err := h.store.Shares.Create(c.Request.Context(), &share); err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "分享创建失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+		Data: gin.H{
+			"share":         share,
+			"path":          "/api/v1/share/" + share.Token,
+			"has_password":  share.PasswordHash != "",
+			"message_count": len(share.Messages),
+		},
+	})
+}
+
+// ListShares 获取会话的分享链接列表
+func (h *ShareHandler) ListShares(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	shares, err := h.store.Shares.List(c.Request.Context(), userID, idParam(c, "id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "获取分享列表失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+		Data:    shares,
+	})
+}
+
+// RevokeShare 撤销分享链接
+func (h *ShareHandler) RevokeShare(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	err := h.store.Shares.Revoke(c.Request.Context(), userID, idParam(c, "id"), idParam(c, "shareId"))
+	if store.IsNotFound(err) {
+		c.JSON(http.StatusNotFound, AuthResponse{
+			Code:    404,
+			Message: "分享不存在",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "撤销失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+	})
+}
+
+// GetShare 查看分享内容（无需登录）
+// 设置了密码时通过 X-Share-Password 请求头或 POST 请求体的 password 提供；format: json（默认）/ md / html
+func (h *ShareHandler) GetShare(c *gin.Context) {
+	share, err := h.store.Shares.GetByToken(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, AuthResponse{
+			Code:    404,
+			Message: "分享不存在",
+		})
+		return
+	}
+
+	if share.RevokedAt != nil || (share.ExpiresAt != nil && time.Now().After(*share.ExpiresAt)) {
+		c.JSON(http.StatusGone, AuthResponse{
+			Code:    410,
+			Message: "分享已失效",
+		})
+		return
+	}
+
+	if share.PasswordHash != "" && !h.checkSharePassword(c, share) {
+		return
+	}
+
+	h.store.Shares.AddView(c.Request.Context(), share.ID)
+
+	t := shareTranscript(share)
+	switch c.DefaultQuery("format", "json") {
+	case "md", "markdown":
+		c.Data(http.StatusOK, "text/markdown; charset=utf-8", export.Markdown(t))
+	case "html":
+		data, err := export.HTML(t)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, AuthResponse{
+				Code:    500,
+				Message: "渲染失败",
+			})
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", data)
+	default:
+		c.JSON(http.StatusOK, AuthResponse{
+			Code:    0,
+			Message: "success",
+			Data: gin.H{
+				"title":     share.Title,
+				"mode":      share.Mode,
+				"shared_at": share.CreatedAt,
+				"messages":  share.Messages,
+			},
+		})
+	}
+}
+
+// checkSharePassword 校验分享的访问密码，失败时直接写入响应
+// 每次校验前先占用一次尝试次数，连续输错 shareMaxPasswordFailures 次后锁定 shareLockDuration
+func (h *ShareHandler) checkSharePassword(c *gin.Context, share *model.SessionShare) bool {
+	password := c.GetHeader("X-Share-Password")
+	if password == "" && c.Request.Method == http.MethodPost {
+		var req SharePasswordRequest
+		if err := c.ShouldBindJSON(&req); err == nil {
+			password = req.Password
+		}
+	}
+	if password == "" {
+		c.JSON(http.StatusUnauthorized, AuthResponse{
+			Code:    401,
+			Message: "需要正确的访问密码",
+		})
+		return false
+	}
+
+	now := time.Now()
+	reserved, err := h.store.Shares.ReservePasswordAttempt(c.Request.Context(), share.ID, shareMaxPasswordFailures, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "获取分享失败",
+		})
+		return false
+	}
+	if !reserved {
+		if share.LockedUntil != nil && share.LockedUntil.After(now) {
+			c.Header("Retry-After", strconv.Itoa(int(share.LockedUntil.Sub(now).Seconds())+1))
+		}
+		c.JSON(http.StatusTooManyRequests, AuthResponse{
+			Code:    429,
+			Message: "密码错误次数过多，请稍后再试",
+		})
+		return false
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(share.PasswordHash), []byte(password)) != nil {
+		// 达到上限时锁定并清零计数，锁定结束后重新计数
+		h.store.Shares.LockPassword(c.Request.Context(), share.ID, shareMaxPasswordFailures, now.Add(shareLockDuration))
+		c.JSON(http.StatusUnauthorized, AuthResponse{
+			Code:    401,
+			Message: "需要正确的访问密码",
+		})
+		return false
+	}
+
+	h.store.Shares.ResetPasswordFailures(c.Request.Context(), share.ID)
+	return true
+}
+
+// shareTranscript 将分享快照转换为会话记录，用于渲染
+func shareTranscript(share *model.SessionShare) *export.Transcript {
+	t := &export.Transcript{
+		Format:     export.Format,
+		Version:    export.Version,
+		Title:      share.Title,
+		Mode:       share.Mode,
+		CreatedAt:  share.CreatedAt,
+		ExportedAt: share.CreatedAt,
+		Messages:   make([]export.Message, 0, len(share.Messages)),
+	}
+	for i, m := range share.Messages {
+		msg := export.Message{
+			ID:        uint(i + 1),
+			Role:      m.Role,
+			Content:   m.Content,
+			CreatedAt: m.CreatedAt,
+		}
+		if i > 0 {
+			parent := uint(i)
+			msg.ParentID = &parent
+		}
+		t.Messages = append(t.Messages, msg)
+	}
+	return t
+}
@@ -5,19 +5,20 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
-	"go-ai-copilot/internal/database"
 	"go-ai-copilot/internal/model"
+	"go-ai-copilot/internal/store"
 	"go-ai-copilot/pkg/jwt"
 )
 
 // UserHandler 用户处理器
 type UserHandler struct {
-	jwt *jwt.JWT
+	jwt   *jwt.JWT
+	users store.UserRepository
 }
 
 // NewUserHandler 创建用户处理器
-func NewUserHandler(jwtTool *jwt.JWT) *UserHandler {
-	return &UserHandler{jwt: jwtTool}
+func NewUserHandler(jwtTool *jwt.JWT, st *store.Store) *UserHandler {
+	return &UserHandler{jwt: jwtTool, users: st.Users}
 }
 
 // RegisterRequest 注册请求
@@ -53,8 +54,7 @@ func (h *UserHandler) Register(c *gin.Context) {
 	}
 
 	// 检查用户名是否已存在
-	var existingUser model.User
-	if err := database.DB.Where("username = ?", req.Username).First(&existingUser).Error; err == nil {
+	if _, err := h.users.GetByUsername(c.Request.Context(), req.Username); err == nil {
 		c.JSON(http.StatusBadRequest, AuthResponse{
 			Code:    400,
 			Message: "用户名已存在",
@@ -80,7 +80,7 @@ func (h *UserHandler) Register(c *gin.Context) {
 		Email:    req.Email,
 	}
 
-	if err := database.DB.Create(&user).Error; err != nil {
+	if err := h.users.Create(c.Request.Context(), &user); err != nil {
 		c.JSON(http.StatusInternalServerError, AuthResponse{
 			Code:    500,
 			Message: "用户创建失败",
@@ -125,8 +125,8 @@ func (h *UserHandler) Login(c *gin.Context) {
 	}
 
 	// 查找用户
-	var user model.User
-	if err := database.DB.Where("username = ?", req.Username).First(&user).Error; err != nil {
+	user, err := h.users.GetByUsername(c.Request.Context(), req.Username)
+	if err != nil {
 		c.JSON(http.StatusUnauthorized, AuthResponse{
 			Code:    401,
 			Message: "用户名或密码错误",
@@ -144,7 +144,7 @@ func (h *UserHandler) Login(c *gin.Context) {
 	}
 
 	// 生成Token
-	token, err := h.jwt.GenerateToken(&user)
+	token, err := h.jwt.GenerateToken(user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, AuthResponse{
 			Code:    500,
@@ -180,8 +180,8 @@ func (h *UserHandler) GetUserInfo(c *gin.Context) {
 		return
 	}
 
-	var user model.User
-	if err := database.DB.First(&user, userID).Error; err != nil {
+	user, err := h.users.Get(c.Request.Context(), userID.(uint))
+	if err != nil {
 		c.JSON(http.StatusNotFound, AuthResponse{
 			Code:    404,
 			Message: "用户不存在",
@@ -226,14 +226,10 @@ func (h *UserHandler) UpdateUserInfo(c *gin.Context) {
 		return
 	}
 
-	result := database.DB.Model(&model.User{}).
-		Where("id = ?", userID).
-		Updates(map[string]interface{}{
-			"nickname": req.Nickname,
-			"email":    req.Email,
-		})
-
-	if result.Error != nil {
+	if err := h.users.Update(c.Request.Context(), userID.(uint), map[string]interface{}{
+		"nickname": req.Nickname,
+		"email":    req.Email,
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, AuthResponse{
 			Code:    500,
 			Message: "更新失败",
@@ -272,8 +268,8 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 
-	var user model.User
-	if err := database.DB.First(&user, userID).Error; err != nil {
+	user, err := h.users.Get(c.Request.Context(), userID.(uint))
+	if err != nil {
 		c.JSON(http.StatusNotFound, AuthResponse{
 			Code:    404,
 			Message: "用户不存在",
@@ -300,7 +296,9 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 
-	if err := database.DB.Model(&user).Update("password", string(hashedPassword)).Error; err != nil {
+	if err := h.users.Update(c.Request.Context(), user.ID, map[string]interface{}{
+		"password": string(hashedPassword),
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, AuthResponse{
 			Code:    500,
 			Message: "密码更新失败",
@@ -0,0 +1,438 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-ai-copilot/internal/config"
+	"go-ai-copilot/internal/crawler"
+	"go-ai-copilot/internal/model"
+	"go-ai-copilot/internal/store"
+	"go-ai-copilot/pkg/ai"
+)
+
+const (
+	defaultCrawlTimeout       = 30 * time.Second
+	defaultCrawlMaxPages      = 200
+	defaultCrawlMaxDepth      = 3
+	defaultMinRefreshInterval = time.Hour
+	// webSourceWatchInterval 检查需要重新抓取的网页来源的间隔
+	webSourceWatchInterval = time.Minute
+	// webSourceStaleAfter 抓取中的网页来源超过该时间没有心跳，视为执行的实例已退出
+	webSourceStaleAfter = 15 * time.Minute
+)
+
+// CreateWebSourceRequest 抓取网页请求
+type CreateWebSourceRequest struct {
+	URL             string            `json:"url" binding:"required"`
+	Sitemap         bool              `json:"sitemap"` // url为站点地图（sitemap.xml），抓取其中的页面
+	KnowledgeBaseID uint              `json:"knowledge_base_id"`
+	MaxDepth        *int              `json:"max_depth"`        // 跟随链接的层数，默认1（站点地图默认0，只抓取其中的页面）
+	MaxPages        int               `json:"max_pages"`        // 页面数上限，默认为配置的上限
+	SameHost        *bool             `json:"same_host"`        // 只抓取同一主机的页面，默认true
+	RespectRobots   *bool             `json:"respect_robots"`   // 遵循 robots.txt，默认true
+	RefreshInterval string            `json:"refresh_interval"` // 定期重新抓取的间隔，如 "24h"，为空时只抓取一次
+	Tags            []string          `json:"tags"`
+	Metadata        map[string]string `json:"metadata"`
+}
+
+// crawlerConfig 补全默认值后的网页抓取配置
+func crawlerConfig() config.CrawlerConfig {
+	cfg := config.GlobalConfig.Crawler
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultCrawlTimeout
+	}
+	if cfg.MaxPages <= 0 {
+		cfg.MaxPages = defaultCrawlMaxPages
+	}
+	if cfg.MaxDepth <= 0 {
+		cfg.MaxDepth = defaultCrawlMaxDepth
+	}
+	if cfg.MinRefreshInterval <= 0 {
+		cfg.MinRefreshInterval = defaultMinRefreshInterval
+	}
+	return cfg
+}
+
+// CreateWebSource 抓取网页（或站点地图中的页面）写入知识库，抓取在后台执行，可定期重新抓取
+func (h *RAGHandler) CreateWebSource(c *gin.Context) {
+	userID := c.GetUint("userID")
+	var req CreateWebSourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	source, err := newWebSource(userID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, AuthResponse{
+			Code:    400,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// 目标知识库（可选，只能写入自己的知识库）
+	if req.KnowledgeBaseID != 0 {
+		if _, err := h.store.KnowledgeBases.GetOwned(c.Request.Context(), userID, req.KnowledgeBaseID); err != nil {
+			c.JSON(http.StatusNotFound, AuthResponse{
+				Code:    404,
+				Message: "知识库不存在",
+			})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	if err := h.store.WebSources.Create(ctx, source); err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "创建失败",
+		})
+		return
+	}
+	if claimed, err := h.store.WebSources.Claim(ctx, source.ID, time.Now().Add(-webSourceStaleAfter)); err != nil || !claimed {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "抓取启动失败",
+		})
+		return
+	}
+	source.Status = model.WebSourceCrawling
+	go h.crawlWebSource(*source)
+
+	c.JSON(http.StatusAccepted, AuthResponse{
+		Code:    0,
+		Message: "正在后台抓取",
+		Data:    source,
+	})
+}
+
+// newWebSource 校验请求并补全默认值
+func newWebSource(userID uint, req CreateWebSourceRequest) (*model.WebSource, error) {
+	cfg := crawlerConfig()
+	u, err := crawler.Normalize(req.URL)
+	if err != nil {
+		return nil, err
+	}
+	if len(u.String()) > 500 {
+		return nil, errors.New("地址不能超过500个字符")
+	}
+
+	source := &model.WebSource{
+		UserID:          userID,
+		KnowledgeBaseID: req.KnowledgeBaseID,
+		URL:             u.String(),
+		Sitemap:         req.Sitemap,
+		MaxPages:        cfg.MaxPages,
+		SameHost:        req.SameHost == nil || *req.SameHost,
+		RespectRobots:   req.RespectRobots == nil || *req.RespectRobots,
+		Tags:            normalizeTags(req.Tags),
+		Metadata:        map[string]string{},
+	}
+	if !req.Sitemap {
+		source.MaxDepth = 1
+	}
+	if req.MaxDepth != nil {
+		source.MaxDepth = *req.MaxDepth
+	}
+	if source.MaxDepth < 0 || source.MaxDepth > cfg.MaxDepth {
+		return nil, fmt.Errorf("max_depth 需在0~%d之间", cfg.MaxDepth)
+	}
+	if req.MaxPages != 0 {
+		if req.MaxPages < 0 || req.MaxPages > cfg.MaxPages {
+			return nil, fmt.Errorf("max_pages 需在1~%d之间", cfg.MaxPages)
+		}
+		source.MaxPages = req.MaxPages
+	}
+	if req.RefreshInterval != "" {
+		interval, err := time.ParseDuration(req.RefreshInterval)
+		if err != nil {
+			return nil, errors.New("refresh_interval 格式错误，如 24h")
+		}
+		if interval < cfg.MinRefreshInterval {
+			return nil, fmt.Errorf("refresh_interval 不能小于%s", cfg.MinRefreshInterval)
+		}
+		source.RefreshInterval = int64(interval / time.Second)
+	}
+	if req.Metadata != nil {
+		if source.Metadata, err = normalizeMetadata(req.Metadata); err != nil {
+			return nil, err
+		}
+	}
+	return source, nil
+}
+
+// ListWebSources 用户的网页来源及抓取状态
+func (h *RAGHandler) ListWebSources(c *gin.Context) {
+	sources, err := h.store.WebSources.List(c.Request.Context(), c.GetUint("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "获取列表失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+		Data:    sources,
+	})
+}
+
+// RefreshWebSource 立即重新抓取网页来源，未变化的页面不会重新向量化
+func (h *RAGHandler) RefreshWebSource(c *gin.Context) {
+	ctx := c.Request.Context()
+	source, err := h.store.WebSources.Get(ctx, c.GetUint("userID"), idParam(c, "id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, AuthResponse{
+			Code:    404,
+			Message: "网页来源不存在",
+		})
+		return
+	}
+
+	claimed, err := h.store.WebSources.Claim(ctx, source.ID, time.Now().Add(-webSourceStaleAfter))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "抓取启动失败",
+		})
+		return
+	}
+	if !claimed {
+		c.JSON(http.StatusConflict, AuthResponse{
+			Code:    409,
+			Message: "正在抓取中",
+		})
+		return
+	}
+	source.Status = model.WebSourceCrawling
+	source.Error = ""
+	go h.crawlWebSource(*source)
+
+	c.JSON(http.StatusAccepted, AuthResponse{
+		Code:    0,
+		Message: "正在后台抓取",
+		Data:    source,
+	})
+}
+
+// DeleteWebSource 删除网页来源及其抓取的文档，正在进行的抓取随之停止
+func (h *RAGHandler) DeleteWebSource(c *gin.Context) {
+	ctx := c.Request.Context()
+	source, err := h.store.WebSources.Get(ctx, c.GetUint("userID"), idParam(c, "id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, AuthResponse{
+			Code:    404,
+			Message: "网页来源不存在",
+		})
+		return
+	}
+
+	if err := h.store.WebSources.Delete(ctx, source.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "删除失败",
+		})
+		return
+	}
+	if err := h.removeWebPages(ctx, *source, nil); err != nil {
+		log.Printf("删除网页来源 %d 的文档失败: %v", source.ID, err)
+		c.JSON(http.StatusInternalServerError, AuthResponse{
+			Code:    500,
+			Message: "删除文档失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		Code:    0,
+		Message: "success",
+	})
+}
+
+// watchWebSources 定期重新抓取到期的网页来源，并接管心跳超时的抓取（服务重启或其他实例退出后继续），ctx结束时返回
+func (h *RAGHandler) watchWebSources(ctx context.Context) {
+	ticker := time.NewTicker(webSourceWatchInterval)
+	defer ticker.Stop()
+	for {
+		staleBefore := time.Now().Add(-webSourceStaleAfter)
+		sources, err := h.store.WebSources.Due(ctx, time.Now(), staleBefore)
+		if err != nil {
+			log.Printf("查询待抓取的网页来源失败: %v", err)
+		}
+		for _, source := range sources {
+			claimed, err := h.store.WebSources.Claim(ctx, source.ID, staleBefore)
+			if err != nil {
+				log.Printf("网页来源 %d 抓取启动失败: %v", source.ID, err)
+				continue
+			}
+			if claimed {
+				go h.crawlWebSource(source)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// webSourceConnector 网页来源在同步记录中的连接器名称
+func webSourceConnector(id uint) string {
+	return fmt.Sprintf("web:%d", id)
+}
+
+// crawlWebSource 抓取网页来源（调用前需已领取），内容未变化的页面跳过，
+// 抓取完成后删除网站上已不存在的页面的文档（暂时无法访问的页面保留）
+func (h *RAGHandler) crawlWebSource(source model.WebSource) {
+	ctx := context.Background()
+	name := webSourceConnector(source.ID)
+	records, err := h.store.ConnectorFiles.List(ctx, name)
+	if err != nil {
+		h.finishWebSource(ctx, &source, model.WebSourceFailed, "查询已抓取的页面失败", 0)
+		return
+	}
+	synced := make(map[string]model.ConnectorFile, len(records))
+	for _, record := range records {
+		synced[record.Path] = record
+	}
+
+	cfg := crawlerConfig()
+	c := crawler.New(h.crawlClient, crawler.Config{
+		MaxDepth:      source.MaxDepth,
+		MaxPages:      source.MaxPages,
+		SameHost:      source.SameHost,
+		RespectRobots: source.RespectRobots,
+		UserAgent:     cfg.UserAgent,
+		Delay:         cfg.Delay,
+	})
+
+	seen := make(map[string]bool)
+	pages, failed := 0, 0
+	result, err := c.Crawl(ctx, source.URL, source.Sitemap, func(p crawler.Page) error {
+		// 同时检查网页来源是否已被删除
+		if err := h.store.WebSources.Heartbeat(ctx, source.ID, pages); err != nil {
+			return err
+		}
+		if len(p.URL) > 500 || seen[p.URL] {
+			return nil
+		}
+		seen[p.URL] = true
+		pages++
+
+		contentHash := ai.ContentHash(p.Text)
+		if record, ok := synced[p.URL]; ok && record.KnowledgeBaseID == source.KnowledgeBaseID && record.ContentHash == contentHash {
+			return nil
+		}
+		if err := h.ingestWebPage(ctx, source, p, contentHash); err != nil {
+			log.Printf("网页来源 %d 写入页面 %s 失败: %v", source.ID, p.URL, err)
+			failed++
+		}
+		return nil
+	})
+	if errors.Is(err, store.ErrSourceDeleted) {
+		// 删除时已写入的页面已被清理，这里清理之后写入的页面
+		if err := h.removeWebPages(ctx, source, nil); err != nil {
+			log.Printf("删除网页来源 %d 的文档失败: %v", source.ID, err)
+		}
+		return
+	}
+	if err != nil {
+		log.Printf("网页来源 %d 抓取失败: %v", source.ID, err)
+		h.finishWebSource(ctx, &source, model.WebSourceFailed, err.Error(), pages)
+		return
+	}
+
+	// 完整抓取时本次没有抓取到的页面视为已从网站移除，暂时无法访问的页面保留
+	// 达到页面数上限时无法区分已移除和未抓取到的页面，保留全部已有页面
+	if !result.Truncated {
+		for _, u := range result.Failed {
+			seen[u] = true
+		}
+		if err := h.removeWebPages(ctx, source, seen); err != nil {
+			log.Printf("删除网页来源 %d 已移除页面的文档失败: %v", source.ID, err)
+		}
+	}
+
+	message := ""
+	if n := len(result.Failed) + failed; n > 0 {
+		message = fmt.Sprintf("%d 个页面抓取失败，下次抓取时重试", n)
+	}
+	h.finishWebSource(ctx, &source, model.WebSourceCompleted, message, pages)
+}
+
+// finishWebSource 记录抓取结果，失败只记录日志
+func (h *RAGHandler) finishWebSource(ctx context.Context, source *model.WebSource, status, message string, pages int) {
+	if err := h.store.WebSources.Finish(ctx, source, status, message, pages); err != nil {
+		log.Printf("更新网页来源 %d 的状态失败: %v", source.ID, err)
+	}
+}
+
+// ingestWebPage 将页面写入知识库（以页面地址为文档标识）并记录同步进度
+func (h *RAGHandler) ingestWebPage(ctx context.Context, source model.WebSource, p crawler.Page, contentHash string) error {
+	title := strings.TrimSpace(p.Title)
+	if title == "" {
+		title = p.URL
+	}
+	result, err := h.ingest(ctx, ingestRequest{
+		UserID:          source.UserID,
+		KnowledgeBaseID: source.KnowledgeBaseID,
+		SourceKey:       p.URL,
+		FileName:        truncateRunes(title, 255),
+		FileType:        "html",
+		Content:         []byte(p.Text),
+		Tags:            &source.Tags,
+		Metadata:        &source.Metadata,
+	})
+	if err != nil {
+		return err
+	}
+	if !result.Unchanged {
+		if err := h.processVersion(result.Document, result.Version, p.Text); err != nil {
+			return err
+		}
+	}
+	return h.store.ConnectorFiles.Save(ctx, &model.ConnectorFile{
+		Connector:       webSourceConnector(source.ID),
+		Path:            p.URL,
+		KnowledgeBaseID: source.KnowledgeBaseID,
+		Size:            int64(len(p.Text)),
+		ContentHash:     contentHash,
+		DocumentID:      result.Document.ID,
+		SyncedAt:        time.Now(),
+	})
+}
+
+// removeWebPages 删除网页来源中不在keep里的页面的文档和同步记录，keep为nil时删除全部
+func (h *RAGHandler) removeWebPages(ctx context.Context, source model.WebSource, keep map[string]bool) error {
+	records, err := h.store.ConnectorFiles.List(ctx, webSourceConnector(source.ID))
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if keep[record.Path] {
+			continue
+		}
+		if err := (connectorIngester{h}).Remove(ctx, source.UserID, record.DocumentID); err != nil {
+			return err
+		}
+		if err := h.store.ConnectorFiles.Delete(ctx, record.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go-ai-copilot/internal/model"
+	"go-ai-copilot/internal/store"
+)
+
+// AdminMiddleware 管理员权限中间件（需在JWT认证之后使用）
+type AdminMiddleware struct {
+	usernames map[string]bool // 配置文件中指定的管理员用户名
+	users     store.UserRepository
+}
+
+// NewAdminMiddleware 创建管理员权限中间件
+func NewAdminMiddleware(usernames []string, users store.UserRepository) *AdminMiddleware {
+	m := &AdminMiddleware{usernames: make(map[string]bool, len(usernames)), users: users}
+	for _, name := range usernames {
+		m.usernames[name] = true
+	}
+	return m
+}
+
+// Handler 管理员校验处理函数
+// 用户角色为admin或用户名在配置的管理员列表中时放行
+func (m *AdminMiddleware) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if m.usernames[c.GetString("username")] {
+			c.Next()
+			return
+		}
+
+		user, err := m.users.Get(c.Request.Context(), c.GetUint("userID"))
+		if err != nil || user.Role != model.RoleAdmin {
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":    403,
+				"message": "需要管理员权限",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Assistant 助手模型
+// 打包System Prompt、默认模型与温度和关联知识库，创建会话时选择
+type Assistant struct {
+	ID               uint           `gorm:"primarykey" json:"id"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
+	UserID           uint           `gorm:"index;not null" json:"user_id"` // 创建者，内置助手为0
+	Name             string         `gorm:"size:100;not null" json:"name"`
+	Description      string         `gorm:"size:255" json:"description"`
+	Mode             string         `gorm:"size:50" json:"mode"`                     // 关联的提示词模板模式，SystemPrompt为空时使用该模式的模板
+	SystemPrompt     string         `gorm:"type:text" json:"system_prompt"`          // 自定义System Prompt，默认原样使用
+	Templated        bool           `gorm:"not null;default:false" json:"templated"` // SystemPrompt按text/template渲染（可引用 {{.Nickname}} 等变量）
+	Model            string         `gorm:"size:100" json:"model"`                   // 默认模型，为空时使用服务端配置
+	Temperature      *float64       `json:"temperature"`                             // 默认温度，为空时使用服务端配置
+	KnowledgeBaseIDs []uint         `gorm:"serializer:json;type:text" json:"knowledge_base_ids"`
+	// 启用的工具名称；对话暂不支持工具调用，只保存不生效（构建对话请求时不会传给模型）
+	Tools      []string `gorm:"serializer:json;type:text" json:"tools"`
+	Visibility string   `gorm:"size:20;not null;default:private" json:"visibility"` // private / team
+	Builtin    bool     `gorm:"not null;default:false" json:"builtin"`
+}
+
+// TableName 表名
+func (Assistant) TableName() string {
+	return "assistants"
+}
+
+// SessionMode 使用该助手的会话的模式
+// 使用自定义System Prompt（或未关联模式）的助手为 custom，否则为关联的提示词模板模式
+func (a *Assistant) SessionMode() string {
+	if a.SystemPrompt != "" || a.Mode == "" {
+		return "custom"
+	}
+	return a.Mode
+}
+
+// 可见范围
+const (
+	VisibilityPrivate = "private" // 仅创建者可见
+	VisibilityTeam    = "team"    // 团队内所有用户可见
+)
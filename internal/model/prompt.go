@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PromptTemplate 提示词模板模型
+// 同一模式下按版本号递增保存，内容不可修改；修改内容即发布新版本
+type PromptTemplate struct {
+	ID          uint           `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	Mode        string         `gorm:"size:50;not null;uniqueIndex:idx_prompt_mode_version" json:"mode"`
+	Version     int            `gorm:"not null;uniqueIndex:idx_prompt_mode_version" json:"version"`
+	Content     string         `gorm:"type:text;not null" json:"content"` // Go text/template 格式
+	Description string         `gorm:"size:255" json:"description"`
+	Weight      int            `gorm:"not null;default:0" json:"weight"`     // A/B测试权重，>0的版本按权重分流
+	Pinned      bool           `gorm:"not null;default:false" json:"pinned"` // 固定使用该版本（优先于A/B测试）
+	CreatedBy   uint           `json:"created_by"`
+}
+
+// TableName 表名
+func (PromptTemplate) TableName() string {
+	return "prompt_templates"
+}
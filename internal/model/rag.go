@@ -6,6 +6,28 @@ import (
 	"gorm.io/gorm"
 )
 
+// KnowledgeBase 知识库模型
+type KnowledgeBase struct {
+	ID          uint           `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	UserID      uint           `gorm:"index;not null" json:"user_id"`
+	Name        string         `gorm:"size:100;not null" json:"name"`
+	Description string         `gorm:"size:255" json:"description"`
+	Visibility  string         `gorm:"size:20;not null;default:private" json:"visibility"` // private / team
+
+	// 分块设置，ChunkStrategy为空时使用默认设置；修改后对之后上传（或同步）的文档生效
+	ChunkStrategy string `gorm:"size:20;not null;default:''" json:"chunk_strategy"`
+	ChunkSize     int    `gorm:"not null;default:0" json:"chunk_size"`
+	ChunkOverlap  int    `gorm:"not null;default:0" json:"chunk_overlap"`
+}
+
+// TableName 表名
+func (KnowledgeBase) TableName() string {
+	return "knowledge_bases"
+}
+
 // RAGDocument RAG文档模型
 type RAGDocument struct {
 	ID        uint           `gorm:"primarykey" json:"id"`
@@ -13,10 +35,18 @@ type RAGDocument struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 	UserID    uint           `gorm:"index;not null" json:"user_id"`
+	KnowledgeBaseID uint     `gorm:"index;not null;default:0" json:"knowledge_base_id"` // 0表示用户的默认知识库
 	FileName  string         `gorm:"size:255;not null" json:"file_name"`
 	FileType  string         `gorm:"size:50;not null" json:"file_type"`
 	FileSize  int64          `json:"file_size"`
 	Status    string         `gorm:"size:20;not null;default:pending" json:"status"` // pending, processing, completed, failed
+	// 文档标识：同一用户同一知识库内相同标识的上传视为同一文档的新版本
+	SourceKey   string `gorm:"size:500;not null;default:'';index:idx_rag_documents_source" json:"source_key"` // 上传时指定的路径，默认为文件名
+	ContentHash string `gorm:"size:64;not null;default:''" json:"content_hash"`                            // 当前版本内容的SHA-256
+	Version     int    `gorm:"not null;default:0" json:"version"`                                         // 当前可检索的版本，0表示首个版本尚未处理完成
+	// 用户定义的标签和元数据，分块继承，检索时可按其过滤
+	Tags     []string          `gorm:"serializer:json;type:text;not null;default:'[]'" json:"tags"`
+	Metadata map[string]string `gorm:"serializer:json;type:text;not null;default:'{}'" json:"metadata"`
 }
 
 // TableName 表名
@@ -24,6 +54,28 @@ func (RAGDocument) TableName() string {
 	return "rag_documents"
 }
 
+// RAGDocumentVersion 文档版本（每次内容变化的上传）
+type RAGDocumentVersion struct {
+	ID            uint      `gorm:"primarykey" json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"` // 处理中的版本定期更新，作为处理实例的心跳
+	DocumentID    uint      `gorm:"not null;uniqueIndex:idx_rag_document_versions_document_version" json:"document_id"`
+	Version       int       `gorm:"not null;uniqueIndex:idx_rag_document_versions_document_version" json:"version"`
+	FileName      string    `gorm:"size:255;not null" json:"file_name"`
+	FileSize      int64     `json:"file_size"`
+	BlobKey       string    `gorm:"size:500;not null;default:''" json:"-"` // 上传文件在文件存储中的Key，升级前的上传为空
+	ContentHash   string    `gorm:"size:64;not null;default:''" json:"content_hash"`
+	Status        string    `gorm:"size:20;not null;default:pending" json:"status"` // processing, completed, failed
+	ChunkCount    int       `gorm:"not null;default:0" json:"chunk_count"`
+	AddedChunks   int       `gorm:"not null;default:0" json:"added_chunks"`   // 相比上一版本新增（需要向量化）的分块数
+	RemovedChunks int       `gorm:"not null;default:0" json:"removed_chunks"` // 相比上一版本移除的分块数
+}
+
+// TableName 表名
+func (RAGDocumentVersion) TableName() string {
+	return "rag_document_versions"
+}
+
 // RAGChunk RAG文档分块模型
 type RAGChunk struct {
 	ID         uint           `gorm:"primarykey" json:"id"`
@@ -32,13 +84,170 @@ type RAGChunk struct {
 	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
 	DocumentID uint           `gorm:"index;not null" json:"document_id"`
 	UserID     uint           `gorm:"index;not null" json:"user_id"`
+	KnowledgeBaseID uint      `gorm:"index;not null;default:0" json:"knowledge_base_id"` // 冗余文档所属知识库，便于检索过滤
 	Content    string         `gorm:"type:text;not null" json:"content"`
-	// 向量字段使用 pgvector 的 vector 类型
-	Embedding []float32       `gorm:"type:vector(1536)" json:"-"`
+	// 向量字段使用 pgvector 的 vector 类型（不限定维度，维度由所属向量空间决定）
+	Embedding Vector          `gorm:"type:vector" json:"-"`
+	SpaceID   uint            `gorm:"index;not null;default:0" json:"space_id"` // 生成向量的模型所在的向量空间
 	ChunkIndex int            `gorm:"not null" json:"chunk_index"`
+	// 分块在原文件中的位置，回答可以引用 path:line；升级前的分块行号为0
+	Path      string `gorm:"size:500;not null;default:''" json:"path"` // 冗余所属文档的路径（文档标识）
+	StartLine int    `gorm:"not null;default:0" json:"start_line"`
+	EndLine   int    `gorm:"not null;default:0" json:"end_line"`
+	ContentHash    string `gorm:"size:64;not null;default:''" json:"-"`       // 分块内容的SHA-256，重新上传时内容未变的分块直接复用
+	Version        int    `gorm:"not null;default:1" json:"version"`          // 分块加入文档的版本
+	RetiredVersion *int   `gorm:"index" json:"retired_version,omitempty"`     // 从该版本起不再属于文档（保留用于审计），为空表示属于当前版本
+	// 冗余所属文档的标签和元数据，便于检索过滤
+	Tags     []string          `gorm:"serializer:json;type:text;not null;default:'[]'" json:"-"`
+	Metadata map[string]string `gorm:"serializer:json;type:text;not null;default:'{}'" json:"-"`
 }
 
 // TableName 表名
 func (RAGChunk) TableName() string {
 	return "rag_chunks"
 }
+
+// EmbeddingSpace 向量空间
+// 同一空间内的向量由同一模型（同一版本）生成、维度相同，只有同一空间的向量可以相互比较
+type EmbeddingSpace struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	Model      string    `gorm:"size:100;not null;uniqueIndex:idx_embedding_spaces_model_version" json:"model"`
+	Version    string    `gorm:"size:50;not null;default:'';uniqueIndex:idx_embedding_spaces_model_version" json:"version"`
+	Dimensions int       `gorm:"not null" json:"dimensions"`
+}
+
+// TableName 表名
+func (EmbeddingSpace) TableName() string {
+	return "embedding_spaces"
+}
+
+// 重建向量任务状态
+const (
+	ReindexPending   = "pending"
+	ReindexRunning   = "running"
+	ReindexPaused    = "paused" // 被中断，可继续执行
+	ReindexFailed    = "failed" // 向量化失败，可继续执行
+	ReindexCompleted = "completed"
+	ReindexCanceled  = "canceled"
+)
+
+// ReindexJob 重建向量任务
+// 使用当前向量化模型为不在目标向量空间的分块重新生成向量，新向量先写入影子表，全部完成后一次性替换
+type ReindexJob struct {
+	ID              uint       `gorm:"primarykey" json:"id"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`                     // 执行中每批更新一次，作为心跳
+	SpaceID         uint       `gorm:"not null" json:"space_id"`       // 目标向量空间
+	KnowledgeBaseID *uint      `gorm:"index" json:"knowledge_base_id"` // 为空表示全部分块
+	Status          string     `gorm:"size:20;not null;default:pending;index" json:"status"`
+	Total           int64      `gorm:"not null;default:0" json:"total"`         // 创建任务时需要重建的分块数
+	Processed       int64      `gorm:"not null;default:0" json:"processed"`     // 已生成向量的分块数
+	LastChunkID     uint       `gorm:"not null;default:0" json:"last_chunk_id"` // 已处理的最大分块ID，继续执行时从这里开始
+	Error           string     `gorm:"type:text;not null;default:''" json:"error,omitempty"`
+	FinishedAt      *time.Time `json:"finished_at"`
+}
+
+// TableName 表名
+func (ReindexJob) TableName() string {
+	return "reindex_jobs"
+}
+
+// Unfinished 任务是否未结束
+func (j ReindexJob) Unfinished() bool {
+	return j.Status != ReindexCompleted && j.Status != ReindexCanceled
+}
+
+// ReindexEmbedding 重建向量任务生成的影子向量，任务完成时替换分块的向量
+type ReindexEmbedding struct {
+	JobID     uint   `gorm:"primaryKey;autoIncrement:false"`
+	ChunkID   uint   `gorm:"primaryKey;autoIncrement:false"`
+	Embedding Vector `gorm:"type:vector;not null"`
+}
+
+// TableName 表名
+func (ReindexEmbedding) TableName() string {
+	return "reindex_embeddings"
+}
+
+// EmbeddingCacheEntry 向量缓存，相同内容（同一向量空间内）只向量化一次
+type EmbeddingCacheEntry struct {
+	SpaceID     uint      `gorm:"primaryKey;autoIncrement:false"`
+	ContentHash string    `gorm:"primaryKey;size:64"` // 文本内容的SHA-256
+	Embedding   Vector    `gorm:"type:vector;not null"`
+	CreatedAt   time.Time
+}
+
+// TableName 表名
+func (EmbeddingCacheEntry) TableName() string {
+	return "embedding_cache"
+}
+
+// ConnectorFile 目录同步连接器的同步记录，大小和修改时间未变化的文件不再读取，服务重启后不会重新处理全部文件
+type ConnectorFile struct {
+	ID              uint      `gorm:"primarykey" json:"id"`
+	Connector       string    `gorm:"size:100;not null;uniqueIndex:idx_connector_files_path" json:"connector"`
+	Path            string    `gorm:"size:500;not null;uniqueIndex:idx_connector_files_path" json:"path"` // 相对同步目录的路径
+	KnowledgeBaseID uint      `gorm:"not null" json:"knowledge_base_id"`
+	Size            int64     `gorm:"not null" json:"size"`
+	ModTime         int64     `gorm:"not null" json:"mod_time"` // 修改时间（纳秒）
+	ContentHash     string    `gorm:"size:64;not null" json:"content_hash"` // 目录连接器为内容的SHA-256，git连接器为blob的对象ID
+	DocumentID      uint      `gorm:"not null" json:"document_id"`
+	SyncedAt        time.Time `json:"synced_at"`
+}
+
+// TableName 表名
+func (ConnectorFile) TableName() string {
+	return "connector_files"
+}
+
+// ConnectorState 连接器的同步状态，git连接器记录已索引的提交
+type ConnectorState struct {
+	Connector       string    `gorm:"primaryKey;size:100" json:"connector"`
+	KnowledgeBaseID uint      `gorm:"not null" json:"knowledge_base_id"`
+	CommitSHA       string    `gorm:"column:commit_sha;size:64;not null" json:"commit_sha"`
+	ConfigHash      string    `gorm:"size:64;not null;default:''" json:"-"` // 同步范围（include/exclude）的哈希，变化后重新全量同步
+	SyncedAt        time.Time `json:"synced_at"`
+}
+
+// TableName 表名
+func (ConnectorState) TableName() string {
+	return "connector_states"
+}
+
+// 网页来源抓取状态
+const (
+	WebSourcePending   = "pending"
+	WebSourceCrawling  = "crawling"
+	WebSourceCompleted = "completed"
+	WebSourceFailed    = "failed"
+)
+
+// WebSource 网页来源：从网址（或站点地图）抓取页面存为知识库文档，可定期重新抓取
+// 抓取到的页面记录在 connector_files 中（连接器为 web:<ID>，路径为页面地址）
+type WebSource struct {
+	ID              uint              `gorm:"primarykey" json:"id"`
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"` // 抓取中定期更新，作为心跳
+	UserID          uint              `gorm:"not null;index" json:"user_id"`
+	KnowledgeBaseID uint              `gorm:"not null;index" json:"knowledge_base_id"`
+	URL             string            `gorm:"size:500;not null" json:"url"`
+	Sitemap         bool              `gorm:"not null;default:false" json:"sitemap"` // URL为站点地图
+	MaxDepth        int               `gorm:"not null;default:0" json:"max_depth"`
+	MaxPages        int               `gorm:"not null;default:0" json:"max_pages"`
+	SameHost        bool              `gorm:"not null;default:true" json:"same_host"`
+	RespectRobots   bool              `gorm:"not null;default:true" json:"respect_robots"`
+	RefreshInterval int64             `gorm:"not null;default:0" json:"refresh_interval"` // 重新抓取间隔（秒），0表示不定期抓取
+	Tags            []string          `gorm:"serializer:json;type:text;not null;default:'[]'" json:"tags"`
+	Metadata        map[string]string `gorm:"serializer:json;type:text;not null;default:'{}'" json:"metadata"`
+	Status          string            `gorm:"size:20;not null;default:pending" json:"status"`
+	Error           string            `gorm:"type:text;not null;default:''" json:"error,omitempty"`
+	PageCount       int               `gorm:"not null;default:0" json:"page_count"` // 上次抓取到的页面数
+	LastCrawledAt   *time.Time        `json:"last_crawled_at"`
+	NextCrawlAt     *time.Time        `gorm:"index" json:"next_crawl_at"` // 为空表示不定期抓取
+}
+
+// TableName 表名
+func (WebSource) TableName() string {
+	return "web_sources"
+}
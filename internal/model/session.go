@@ -8,13 +8,41 @@ import (
 
 // Session 会话模型
 type Session struct {
+	ID          uint           `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	UserID      uint           `gorm:"index;not null" json:"user_id"`
+	Title       string         `gorm:"size:255;not null" json:"title"`
+	Mode        string         `gorm:"size:50;default:chat" json:"mode"` // 由助手决定（Assistant.SessionMode），用于列表过滤和助手被删除后的回退
+	AssistantID *uint          `gorm:"index" json:"assistant_id"`        // 会话使用的助手
+	// 会话级配置，优先于助手的默认配置
+	Model            string   `gorm:"size:100" json:"model"`
+	Temperature      *float64 `json:"temperature"`
+	KnowledgeBaseIDs []uint   `gorm:"serializer:json;type:text" json:"knowledge_base_ids"`
+	// 当前分支的最后一条消息，上下文沿该消息的父链回溯
+	ActiveMessageID *uint `json:"active_message_id"`
+	// 整理
+	Tags      []string `gorm:"serializer:json;type:text" json:"tags"`
+	Pinned    bool     `gorm:"not null;default:false;index" json:"pinned"`
+	FolderID  *uint    `gorm:"index" json:"folder_id"`
+	Archived  bool     `gorm:"not null;default:false;index" json:"archived"`
+	AutoTitle bool     `gorm:"not null;default:false" json:"auto_title"` // 标题待首轮对话后自动生成
+}
+
+// Folder 会话文件夹
+type Folder struct {
 	ID        uint           `gorm:"primarykey" json:"id"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 	UserID    uint           `gorm:"index;not null" json:"user_id"`
-	Title     string         `gorm:"size:255;not null" json:"title"`
-	Mode      string         `gorm:"size:20;default:chat" json:"mode"` // chat, code_generate, code_explain, code_optimize, code_vuln, code_test, rag
+	Name      string         `gorm:"size:100;not null" json:"name"`
+}
+
+// TableName 表名
+func (Folder) TableName() string {
+	return "session_folders"
 }
 
 // TableName 表名
@@ -22,6 +50,23 @@ func (Session) TableName() string {
 	return "sessions"
 }
 
+// SessionModeChange 会话模式变更记录
+type SessionModeChange struct {
+	ID              uint      `gorm:"primarykey" json:"id"`
+	CreatedAt       time.Time `json:"created_at"`
+	SessionID       uint      `gorm:"index;not null" json:"session_id"`
+	UserID          uint      `gorm:"not null" json:"user_id"`
+	FromMode        string    `gorm:"size:50" json:"from_mode"`
+	ToMode          string    `gorm:"size:50" json:"to_mode"`
+	FromAssistantID *uint     `json:"from_assistant_id"`
+	ToAssistantID   *uint     `json:"to_assistant_id"`
+}
+
+// TableName 表名
+func (SessionModeChange) TableName() string {
+	return "session_mode_changes"
+}
+
 // Message 消息模型
 type Message struct {
 	ID        uint           `gorm:"primarykey" json:"id"`
@@ -30,11 +75,31 @@ type Message struct {
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 	SessionID uint           `gorm:"index;not null" json:"session_id"`
 	UserID    uint           `gorm:"index;not null" json:"user_id"`
+	ParentID  *uint          `gorm:"index" json:"parent_id"`       // 父消息，为空表示会话的第一条消息；同一父消息下的多条消息互为分支
 	Role      string         `gorm:"size:20;not null" json:"role"` // user / assistant
 	Content   string         `gorm:"type:text;not null" json:"content"`
+	// 生成该回复所用的提示词模板及版本（仅assistant消息）
+	PromptTemplateID *uint `gorm:"index" json:"prompt_template_id,omitempty"`
+	PromptVersion    int   `json:"prompt_version,omitempty"`
 }
 
 // TableName 表名
 func (Message) TableName() string {
 	return "chat_messages"
 }
+
+// MessageEmbedding 消息向量（用于聊天记录的语义搜索）
+type MessageEmbedding struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	MessageID uint      `gorm:"uniqueIndex:idx_message_embeddings_space_message;not null" json:"message_id"`
+	SpaceID   uint      `gorm:"uniqueIndex:idx_message_embeddings_space_message;not null;default:0" json:"space_id"`
+	SessionID uint      `gorm:"index;not null" json:"session_id"`
+	UserID    uint      `gorm:"index;not null" json:"user_id"`
+	Embedding Vector    `gorm:"type:vector" json:"-"`
+}
+
+// TableName 表名
+func (MessageEmbedding) TableName() string {
+	return "message_embeddings"
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SessionShare 会话分享链接
+// 创建时冻结当前分支的消息，之后对会话的修改不会影响分享内容
+type SessionShare struct {
+	ID           uint            `gorm:"primarykey" json:"id"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt  `gorm:"index" json:"-"`
+	SessionID    uint            `gorm:"index;not null" json:"session_id"`
+	UserID       uint            `gorm:"index;not null" json:"user_id"`
+	Token        string          `gorm:"size:64;uniqueIndex;not null" json:"token"`
+	Title        string          `gorm:"size:255;not null" json:"title"`
+	Mode         string          `gorm:"size:50" json:"mode"`
+	Messages     []SharedMessage `gorm:"serializer:json;type:text" json:"-"`
+	PasswordHash string          `gorm:"size:255" json:"-"`
+	ExpiresAt    *time.Time      `json:"expires_at"`
+	RevokedAt    *time.Time      `json:"revoked_at"`
+	ViewCount    int             `gorm:"not null;default:0" json:"view_count"`
+	// 连续输错密码的次数，达到上限后锁定到LockedUntil
+	PasswordFailures int        `gorm:"not null;default:0" json:"-"`
+	LockedUntil      *time.Time `json:"-"`
+}
+
+// SharedMessage 分享快照中的消息
+type SharedMessage struct {
+	Role      string    `json:"role"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 表名
+func (SessionShare) TableName() string {
+	return "session_shares"
+}
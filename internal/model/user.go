@@ -16,8 +16,15 @@ type User struct {
 	Password  string         `gorm:"size:255;not null" json:"-"`
 	Nickname  string         `gorm:"size:100" json:"nickname"`
 	Email     string         `gorm:"size:100" json:"email"`
+	Role      string         `gorm:"size:20;not null;default:user" json:"role"` // user / admin
 }
 
+// 用户角色
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 // TableName 表名
 func (User) TableName() string {
 	return "users"
@@ -0,0 +1,221 @@
+package pagination
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/gob"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 每页条数
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// ErrInvalidCursor 游标无法解析或与当前排序不匹配
+var ErrInvalidCursor = errors.New("cursor 参数错误")
+
+func init() {
+	// 游标中保存排序列的值，gob需要注册接口中出现的具体类型
+	gob.Register(time.Time{})
+}
+
+// Page 分页结果
+// NextCursor 为空表示没有更多数据；请求下一页时原样传回 cursor 参数，其他参数保持不变
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// Order 排序列
+type Order struct {
+	Column string
+	Desc   bool
+}
+
+// Request 分页请求
+// Orders 的最后一列必须唯一（通常是主键），保证翻页时不重复、不遗漏
+type Request struct {
+	Limit  int
+	Field  string // 排序字段（参数值）
+	Orders []Order
+	after  []interface{} // 上一页最后一条记录的排序列的值
+}
+
+// cursor 游标内容，Key 记录生成游标时的排序，防止换了排序后继续使用旧游标
+type cursor struct {
+	Key    string
+	Values []interface{}
+}
+
+// Sort 可选的排序字段
+type Sort struct {
+	Options  map[string]string // 参数值 -> 列名
+	Default  string            // 默认排序字段（参数值）
+	Prefix   []Order           // 固定排在前面的排序列（如置顶）
+	Tiebreak string            // 唯一列，排序值相同时按该列排序
+}
+
+// FromQuery 从查询参数解析分页请求
+// limit: 每页条数；cursor: 上一页返回的 next_cursor；sort: 排序字段；order: asc / desc（默认 desc）
+func FromQuery(c *gin.Context, sort Sort) (*Request, error) {
+	limit := DefaultLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, errors.New("limit 参数错误")
+		}
+		limit = n
+	}
+
+	name := c.DefaultQuery("sort", sort.Default)
+	column, ok := sort.Options[name]
+	if !ok {
+		return nil, errors.New("不支持的排序字段: " + name)
+	}
+	desc := true
+	switch strings.ToLower(c.DefaultQuery("order", "desc")) {
+	case "asc":
+		desc = false
+	case "desc":
+	default:
+		return nil, errors.New("order 只能是 asc 或 desc")
+	}
+
+	var orders []Order
+	orders = append(orders, sort.Prefix...)
+	orders = append(orders, Order{Column: column, Desc: desc})
+	if sort.Tiebreak != "" && sort.Tiebreak != column {
+		orders = append(orders, Order{Column: sort.Tiebreak, Desc: desc})
+	}
+
+	r, err := NewRequest(limit, c.Query("cursor"), orders...)
+	if err != nil {
+		return nil, err
+	}
+	r.Field = name
+	return r, nil
+}
+
+// NewRequest 创建固定排序的分页请求（如按相关度排序的搜索结果）
+// limit<=0 时取默认值，超过上限时取上限；cursor 为上一页返回的 next_cursor
+func NewRequest(limit int, cursor string, orders ...Order) (*Request, error) {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+
+	r := &Request{Limit: limit, Orders: orders}
+	if cursor != "" {
+		values, err := Decode(cursor, r.key())
+		if err != nil || len(values) != len(r.Orders) {
+			return nil, ErrInvalidCursor
+		}
+		r.after = values
+	}
+	return r, nil
+}
+
+// After 上一页最后一条记录的排序值，第一页为nil
+func (r *Request) After() []interface{} {
+	return r.after
+}
+
+// Apply 为查询加上游标条件、排序和条数限制（多取一条用于判断是否还有下一页）
+func (r *Request) Apply(query *gorm.DB) *gorm.DB {
+	if r.after != nil {
+		// (a, b, c) 之后的记录：a在后 OR (a相等 AND b在后) OR (a、b相等 AND c在后)
+		var conds []string
+		var args []interface{}
+		for i, o := range r.Orders {
+			var parts []string
+			for j := 0; j < i; j++ {
+				parts = append(parts, r.Orders[j].Column+" = ?")
+				args = append(args, r.after[j])
+			}
+			op := " > ?"
+			if o.Desc {
+				op = " < ?"
+			}
+			parts = append(parts, o.Column+op)
+			args = append(args, r.after[i])
+			conds = append(conds, "("+strings.Join(parts, " AND ")+")")
+		}
+		query = query.Where("("+strings.Join(conds, " OR ")+")", args...)
+	}
+
+	for _, o := range r.Orders {
+		if o.Desc {
+			query = query.Order(o.Column + " DESC")
+		} else {
+			query = query.Order(o.Column + " ASC")
+		}
+	}
+	return query.Limit(r.Limit + 1)
+}
+
+// Build 由查询结果（按 Apply 多取了一条）构建分页结果
+// values 返回记录在各排序列上的值，顺序与 Orders 一致
+func Build[T any](r *Request, items []T, values func(T) []interface{}) (*Page[T], error) {
+	page := &Page[T]{Items: items}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+	if len(items) <= r.Limit {
+		return page, nil
+	}
+
+	page.Items = items[:r.Limit]
+	next, err := Encode(r.key(), values(page.Items[r.Limit-1])...)
+	if err != nil {
+		return nil, err
+	}
+	page.NextCursor = next
+	page.HasMore = true
+	return page, nil
+}
+
+// key 排序的标识
+func (r *Request) key() string {
+	parts := make([]string, 0, len(r.Orders))
+	for _, o := range r.Orders {
+		if o.Desc {
+			parts = append(parts, o.Column+":desc")
+		} else {
+			parts = append(parts, o.Column+":asc")
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// Encode 生成游标
+func Encode(key string, values ...interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(cursor{Key: key, Values: values}); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// Decode 解析游标，key与生成时不一致时返回 ErrInvalidCursor
+func Decode(s, key string) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cur cursor
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&cur); err != nil || cur.Key != key {
+		return nil, ErrInvalidCursor
+	}
+	return cur.Values, nil
+}
@@ -0,0 +1,170 @@
+package prompt
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"text/template"
+
+	"go-ai-copilot/internal/model"
+	"go-ai-copilot/internal/store"
+)
+
+// Vars 模板变量
+type Vars struct {
+	Language string // 编程语言，如 Go / Python
+	Nickname string // 当前用户昵称
+	Context  string // 检索到的参考资料（RAG）
+}
+
+// Resolved 选中的提示词模板
+type Resolved struct {
+	TemplateID *uint  // 内置默认模板为nil
+	Version    int    // 内置默认模板为0
+	Mode       string // 实际使用的模式
+	Content    string // 模板原文
+}
+
+// Defaults 内置默认模板
+// 数据库中没有对应模式的模板时使用，启动时写入为各模式的版本1
+var Defaults = map[string]string{
+	"chat":          "你是一个专业的AI助手，请用简洁清晰的语言回答用户的问题。",
+	"code_generate": `你是一个专业的{{or .Language "Go"}}后端开发工程师。请根据用户需求生成符合{{or .Language "Go"}}规范的代码，包含错误处理、注释、单元测试。`,
+	"code_explain":  `你是一个专业的{{or .Language "Go"}}后端开发工程师。请逐行解释用户提供的{{or .Language "Go"}}代码的逻辑、用途、设计思路。`,
+	"code_optimize": `你是一个专业的{{or .Language "Go"}}后端开发工程师。请优化用户提供的{{or .Language "Go"}}代码的性能、可读性、规范度，指出优化点。`,
+	"code_vuln":     `你是一个专业的{{or .Language "Go"}}安全工程师。请检测用户提供的{{or .Language "Go"}}代码中的安全漏洞、内存泄漏、并发问题、错误处理缺陷。`,
+	"code_test":     `你是一个专业的{{or .Language "Go"}}测试工程师。请为用户提供的{{or .Language "Go"}}代码生成单元测试用例，提升测试覆盖率。`,
+	"rag": `你是一个专业的AI助手。请根据以下参考资料回答用户的问题。
+
+参考资料：
+{{.Context}}
+
+请根据参考资料回答，引用时注明出处（如 path:line），如果参考资料中没有相关信息，请如实说明。`,
+}
+
+// DefaultMode 未知模式回退到的模式
+const DefaultMode = "chat"
+
+// SeedDefaults 为尚无模板的模式写入内置默认模板（版本1）
+func SeedDefaults(ctx context.Context, prompts store.PromptRepository) error {
+	for mode, content := range Defaults {
+		latest, err := prompts.LatestVersion(ctx, mode)
+		if err != nil {
+			return err
+		}
+		if latest > 0 {
+			continue
+		}
+
+		tpl := model.PromptTemplate{
+			Mode:        mode,
+			Content:     content,
+			Description: "内置默认模板",
+		}
+		if err := prompts.Create(ctx, &tpl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Resolve 选择模式对应的模板
+// 优先级：固定版本 > 按权重A/B分流（同一stickyKey始终落在同一版本）> 最新版本 > 内置默认
+func Resolve(ctx context.Context, prompts store.PromptRepository, mode string, stickyKey uint) (*Resolved, error) {
+	if mode == "" {
+		mode = DefaultMode
+	}
+
+	templates, err := prompts.List(ctx, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(templates) == 0 {
+		content, ok := Defaults[mode]
+		if !ok {
+			mode = DefaultMode
+			content = Defaults[DefaultMode]
+		}
+		return &Resolved{Mode: mode, Content: content}, nil
+	}
+
+	tpl := choose(templates, stickyKey)
+	id := tpl.ID
+	return &Resolved{
+		TemplateID: &id,
+		Version:    tpl.Version,
+		Mode:       tpl.Mode,
+		Content:    tpl.Content,
+	}, nil
+}
+
+// choose 从同一模式的模板中选出本次使用的版本（templates按版本倒序）
+func choose(templates []model.PromptTemplate, stickyKey uint) model.PromptTemplate {
+	for _, t := range templates {
+		if t.Pinned {
+			return t
+		}
+	}
+
+	total := 0
+	for _, t := range templates {
+		if t.Weight > 0 {
+			total += t.Weight
+		}
+	}
+	if total == 0 {
+		return templates[0]
+	}
+
+	// 按stickyKey哈希分桶，保证同一会话内版本稳定
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%d", stickyKey)
+	bucket := int(h.Sum32() % uint32(total))
+	for _, t := range templates {
+		if t.Weight <= 0 {
+			continue
+		}
+		if bucket < t.Weight {
+			return t
+		}
+		bucket -= t.Weight
+	}
+	return templates[0]
+}
+
+// Render 渲染模板
+func Render(content string, vars Vars) (string, error) {
+	tpl, err := Parse(content)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	if err := tpl.Execute(&b, vars); err != nil {
+		return "", fmt.Errorf("提示词模板渲染失败: %v", err)
+	}
+	return b.String(), nil
+}
+
+// Parse 解析模板并校验语法
+func Parse(content string) (*template.Template, error) {
+	tpl, err := template.New("prompt").Option("missingkey=zero").Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("提示词模板格式错误: %v", err)
+	}
+	return tpl, nil
+}
+
+// Exists 判断模式是否存在（内置默认模板或数据库中已发布的模板）
+func Exists(ctx context.Context, prompts store.PromptRepository, mode string) (bool, error) {
+	if _, ok := Defaults[mode]; ok {
+		return true, nil
+	}
+	templates, err := prompts.List(ctx, mode)
+	if err != nil {
+		return false, err
+	}
+	return len(templates) > 0, nil
+}
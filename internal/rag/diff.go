@@ -0,0 +1,46 @@
+package rag
+
+import (
+	"go-ai-copilot/internal/model"
+	"go-ai-copilot/pkg/ai"
+)
+
+// ChunkDiff 新版本文档与当前分块的差异
+type ChunkDiff struct {
+	Added   []int        // 需要新建（向量化）的分块在新分块中的序号
+	Kept    map[uint]int // 内容未变、直接复用的分块ID -> 新序号
+	Retired []uint       // 新版本中不再存在的分块ID
+}
+
+// DiffChunks 按内容哈希比较当前分块与新版本的分块文本
+// 相同内容的分块复用原有向量（内容重复出现时按出现次数一一对应），只有新内容需要向量化
+func DiffChunks(current []model.RAGChunk, texts []string) ChunkDiff {
+	diff := ChunkDiff{Kept: make(map[uint]int)}
+
+	byHash := make(map[string][]uint, len(current))
+	for _, chunk := range current {
+		hash := chunk.ContentHash
+		if hash == "" {
+			// 升级前的分块没有记录哈希
+			hash = ai.ContentHash(chunk.Content)
+		}
+		byHash[hash] = append(byHash[hash], chunk.ID)
+	}
+
+	for i, text := range texts {
+		hash := ai.ContentHash(text)
+		if ids := byHash[hash]; len(ids) > 0 {
+			diff.Kept[ids[0]] = i
+			byHash[hash] = ids[1:]
+			continue
+		}
+		diff.Added = append(diff.Added, i)
+	}
+
+	for _, chunk := range current {
+		if _, ok := diff.Kept[chunk.ID]; !ok {
+			diff.Retired = append(diff.Retired, chunk.ID)
+		}
+	}
+	return diff
+}
@@ -0,0 +1,23 @@
+package rag
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// MaxFileSize 导入知识库的单个文件大小上限（10MB）
+const MaxFileSize = 10 * 1024 * 1024
+
+// SupportedFileTypes 支持导入知识库的文件扩展名
+var SupportedFileTypes = []string{".txt", ".md", ".go", ".json", ".yaml", ".yml"}
+
+// IsSupportedFile 文件扩展名是否支持导入
+func IsSupportedFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range SupportedFileTypes {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go-ai-copilot/internal/model"
+	"go-ai-copilot/internal/store"
+)
+
+// Scope 检索范围
+type Scope = store.ChunkScope
+
+// Result 检索结果
+type Result = store.ChunkMatch
+
+// Search 在向量空间内按余弦相似度检索分块，返回相似度不低于threshold的前topK个结果
+func Search(ctx context.Context, chunks store.ChunkRepository, space *model.EmbeddingSpace, scope Scope, embedding []float32, topK int, threshold float64) ([]Result, error) {
+	if topK <= 0 {
+		topK = 3
+	}
+
+	results, err := chunks.Search(ctx, space, scope, embedding, topK)
+	if err != nil {
+		return nil, fmt.Errorf("向量检索失败: %v", err)
+	}
+
+	filtered := results[:0]
+	for _, r := range results {
+		if r.Score >= threshold {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered, nil
+}
+
+// FormatContext 将检索结果拼接为Prompt中的参考资料，标注出处便于回答引用
+func FormatContext(results []Result) string {
+	var b strings.Builder
+	for i, r := range results {
+		if citation := Citation(r); citation != "" {
+			b.WriteString(fmt.Sprintf("[相关文档 %d] %s:\n%s\n\n", i+1, citation, r.Content))
+			continue
+		}
+		b.WriteString(fmt.Sprintf("[相关文档 %d]:\n%s\n\n", i+1, r.Content))
+	}
+	return b.String()
+}
+
+// Citation 检索结果的出处，如 docs/guide.md:10-24，没有行号时只有路径，网页只有地址
+func Citation(r Result) string {
+	switch {
+	case r.Path == "":
+		return ""
+	case r.StartLine <= 0 || strings.Contains(r.Path, "://"):
+		return r.Path
+	case r.EndLine <= r.StartLine:
+		return fmt.Sprintf("%s:%d", r.Path, r.StartLine)
+	default:
+		return fmt.Sprintf("%s:%d-%d", r.Path, r.StartLine, r.EndLine)
+	}
+}
+
+// DocumentIDs 检索结果引用的文档ID（去重）
+func DocumentIDs(results []Result) []uint {
+	seen := make(map[uint]bool, len(results))
+	var ids []uint
+	for _, r := range results {
+		if !seen[r.DocumentID] {
+			seen[r.DocumentID] = true
+			ids = append(ids, r.DocumentID)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,139 @@
+package rag
+
+import (
+	"fmt"
+	"unicode"
+)
+
+// 分块策略
+const (
+	StrategyParagraph = "paragraph" // 按段落合并到块大小，超长段落按字符切分（默认）
+	StrategyFixed     = "fixed"     // 按固定字符数切分，不考虑段落
+)
+
+// 分块设置的默认值和范围
+const (
+	DefaultChunkSize    = 1024
+	DefaultChunkOverlap = 256
+	MinChunkSize        = 100
+	MaxChunkSize        = 8192
+)
+
+// Splitter 文本分块器
+type Splitter interface {
+	// Split 将文本分割成块，并记录每块在原文中的行范围
+	Split(text string) []Chunk
+}
+
+// SplitterConfig 分块设置，未指定的字段使用默认值
+type SplitterConfig struct {
+	Strategy     string `json:"strategy"`      // paragraph（默认）/ fixed
+	ChunkSize    int    `json:"chunk_size"`    // 块大小（字符数），默认1024
+	ChunkOverlap *int   `json:"chunk_overlap"` // 块重叠字符数，默认256（块大小不超过512时为块大小的1/4）
+}
+
+// Normalize 校验分块设置并补全默认值
+func (c SplitterConfig) Normalize() (SplitterConfig, error) {
+	switch c.Strategy {
+	case "":
+		c.Strategy = StrategyParagraph
+	case StrategyParagraph, StrategyFixed:
+	default:
+		return c, fmt.Errorf("不支持的分块策略: %s，仅支持: %s, %s", c.Strategy, StrategyParagraph, StrategyFixed)
+	}
+	if c.ChunkSize == 0 {
+		c.ChunkSize = DefaultChunkSize
+	}
+	if c.ChunkSize < MinChunkSize || c.ChunkSize > MaxChunkSize {
+		return c, fmt.Errorf("块大小需在%d~%d之间", MinChunkSize, MaxChunkSize)
+	}
+	if c.ChunkOverlap == nil {
+		overlap := DefaultChunkOverlap
+		if overlap >= c.ChunkSize/2 {
+			overlap = c.ChunkSize / 4
+		}
+		c.ChunkOverlap = &overlap
+	}
+	if *c.ChunkOverlap < 0 || *c.ChunkOverlap >= c.ChunkSize/2 {
+		return c, fmt.Errorf("块重叠需在0~%d之间", c.ChunkSize/2-1)
+	}
+	return c, nil
+}
+
+// NewSplitter 按分块设置创建分块器
+func NewSplitter(config SplitterConfig) (Splitter, error) {
+	config, err := config.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	if config.Strategy == StrategyFixed {
+		return &FixedSplitter{ChunkSize: config.ChunkSize, ChunkOverlap: *config.ChunkOverlap}, nil
+	}
+	// 不经过 NewTextSplitter，允许重叠为0
+	return &TextSplitter{ChunkSize: config.ChunkSize, ChunkOverlap: *config.ChunkOverlap}, nil
+}
+
+// FixedSplitter 按固定字符数分块，相邻块重叠ChunkOverlap个字符
+type FixedSplitter struct {
+	ChunkSize    int
+	ChunkOverlap int
+}
+
+// Split 将文本分割成块，并记录每块在原文中的行范围，块首尾的空白被去除
+func (s *FixedSplitter) Split(text string) []Chunk {
+	runes := []rune(text)
+	step := s.ChunkSize - s.ChunkOverlap
+	var chunks []Chunk
+	line, pos := 1, 0 // pos 之前的换行数已计入line
+	for start := 0; start < len(runes); start += step {
+		end := min(start+s.ChunkSize, len(runes))
+		first, last := start, end
+		for first < last && unicode.IsSpace(runes[first]) {
+			first++
+		}
+		for last > first && unicode.IsSpace(runes[last-1]) {
+			last--
+		}
+		if first < last {
+			if first > pos {
+				line += countNewlines(runes[pos:first])
+				pos = first
+			}
+			chunks = append(chunks, Chunk{
+				Text:      string(runes[first:last]),
+				StartLine: line,
+				EndLine:   line + countNewlines(runes[first:last]),
+			})
+		}
+		if end == len(runes) {
+			break
+		}
+	}
+	return chunks
+}
+
+func countNewlines(runes []rune) int {
+	n := 0
+	for _, r := range runes {
+		if r == '\n' {
+			n++
+		}
+	}
+	return n
+}
+
+// EstimateTokens 估算文本的token数：没有分词器，中日韩文字按1字1token，其他按约4个字符1token
+func EstimateTokens(text string) int {
+	cjk, other := 0, 0
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			cjk++
+		case unicode.IsSpace(r):
+			// 空白通常并入相邻的token
+		default:
+			other++
+		}
+	}
+	return cjk + (other+3)/4
+}
@@ -0,0 +1,155 @@
+package rag
+
+import (
+	"reflect"
+	"testing"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestSplitterConfigNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  SplitterConfig
+		want    SplitterConfig
+		wantErr bool
+	}{
+		{
+			name:   "默认值",
+			config: SplitterConfig{},
+			want:   SplitterConfig{Strategy: StrategyParagraph, ChunkSize: DefaultChunkSize, ChunkOverlap: intPtr(DefaultChunkOverlap)},
+		},
+		{
+			name:   "小块的默认重叠为块大小的1/4",
+			config: SplitterConfig{Strategy: StrategyFixed, ChunkSize: 400},
+			want:   SplitterConfig{Strategy: StrategyFixed, ChunkSize: 400, ChunkOverlap: intPtr(100)},
+		},
+		{
+			name:   "允许重叠为0",
+			config: SplitterConfig{ChunkSize: MinChunkSize, ChunkOverlap: intPtr(0)},
+			want:   SplitterConfig{Strategy: StrategyParagraph, ChunkSize: MinChunkSize, ChunkOverlap: intPtr(0)},
+		},
+		{
+			name:   "重叠上限",
+			config: SplitterConfig{ChunkSize: MaxChunkSize, ChunkOverlap: intPtr(MaxChunkSize/2 - 1)},
+			want:   SplitterConfig{Strategy: StrategyParagraph, ChunkSize: MaxChunkSize, ChunkOverlap: intPtr(MaxChunkSize/2 - 1)},
+		},
+		{name: "不支持的策略", config: SplitterConfig{Strategy: "sentence"}, wantErr: true},
+		{name: "块过小", config: SplitterConfig{ChunkSize: MinChunkSize - 1}, wantErr: true},
+		{name: "块过大", config: SplitterConfig{ChunkSize: MaxChunkSize + 1}, wantErr: true},
+		{name: "负的块大小", config: SplitterConfig{ChunkSize: -1}, wantErr: true},
+		{name: "重叠为块大小的一半", config: SplitterConfig{ChunkSize: 200, ChunkOverlap: intPtr(100)}, wantErr: true},
+		{name: "负的重叠", config: SplitterConfig{ChunkSize: 200, ChunkOverlap: intPtr(-1)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.config.Normalize()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Normalize(%+v) 没有返回错误", tt.config)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Normalize = %+v (overlap %d)，期望 %+v (overlap %d)", got, *got.ChunkOverlap, tt.want, *tt.want.ChunkOverlap)
+			}
+		})
+	}
+}
+
+func TestFixedSplitter(t *testing.T) {
+	tests := []struct {
+		name     string
+		splitter FixedSplitter
+		text     string
+		want     []Chunk
+	}{
+		{
+			name:     "重叠",
+			splitter: FixedSplitter{ChunkSize: 4, ChunkOverlap: 1},
+			text:     "abcdefghij",
+			want: []Chunk{
+				{Text: "abcd", StartLine: 1, EndLine: 1},
+				{Text: "defg", StartLine: 1, EndLine: 1},
+				{Text: "ghij", StartLine: 1, EndLine: 1},
+			},
+		},
+		{
+			name:     "中文按字符切分并计算行范围",
+			splitter: FixedSplitter{ChunkSize: 6, ChunkOverlap: 2},
+			text:     "第一行\n第二行\n\n第四行内容",
+			want: []Chunk{
+				{Text: "第一行\n第二", StartLine: 1, EndLine: 2},
+				{Text: "第二行\n\n第", StartLine: 2, EndLine: 4},
+				{Text: "第四行内容", StartLine: 4, EndLine: 4},
+			},
+		},
+		{
+			name:     "跳过只有空白的块，去除块首尾空白",
+			splitter: FixedSplitter{ChunkSize: 4, ChunkOverlap: 0},
+			text:     "ab\n\n\n\n\n\ncd",
+			want: []Chunk{
+				{Text: "ab", StartLine: 1, EndLine: 1},
+				{Text: "cd", StartLine: 7, EndLine: 7},
+			},
+		},
+		{
+			name:     "空文本",
+			splitter: FixedSplitter{ChunkSize: 4, ChunkOverlap: 1},
+			text:     "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.splitter.Split(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Split = %+v，期望 %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSplitter(t *testing.T) {
+	s, err := NewSplitter(SplitterConfig{Strategy: StrategyFixed, ChunkSize: 200, ChunkOverlap: intPtr(0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fixed, ok := s.(*FixedSplitter); !ok || fixed.ChunkSize != 200 || fixed.ChunkOverlap != 0 {
+		t.Fatalf("fixed 策略的分块器 = %#v", s)
+	}
+
+	s, err = NewSplitter(SplitterConfig{ChunkSize: 200, ChunkOverlap: intPtr(0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, ok := s.(*TextSplitter); !ok || text.ChunkOverlap != 0 {
+		t.Fatalf("paragraph 策略的分块器 = %#v", s)
+	}
+
+	if _, err := NewSplitter(SplitterConfig{ChunkSize: 10}); err == nil {
+		t.Fatal("块大小不合法时没有返回错误")
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"abcd", 1},
+		{"abcde", 2},
+		{"你好世界", 4},
+		{"你好 abcd", 3},
+		{"  \n\t", 0},
+	}
+	for _, tt := range tests {
+		if got := EstimateTokens(tt.text); got != tt.want {
+			t.Errorf("EstimateTokens(%q) = %d，期望 %d", tt.text, got, tt.want)
+		}
+	}
+}
@@ -2,12 +2,13 @@ package rag
 
 import (
 	"unicode"
+	"unicode/utf8"
 )
 
-// TextSplitter 文本分块器
+// TextSplitter 按段落分块的分块器（paragraph策略）
 type TextSplitter struct {
-	ChunkSize    int // 块大小（字符数）
-	ChunkOverlap int // 块重叠字符数
+	ChunkSize    int // 块大小（字符数，按rune计算）
+	ChunkOverlap int // 块重叠字符数（按rune计算）
 }
 
 // NewTextSplitter 创建文本分块器
@@ -27,8 +28,40 @@ func NewTextSplitter(chunkSize, chunkOverlap int) *TextSplitter {
 	}
 }
 
+// Chunk 文本块及其在原文中的行范围（行号从1开始，含首尾）
+type Chunk struct {
+	Text      string
+	StartLine int
+	EndLine   int
+}
+
+// paragraph 段落及其所在行
+type paragraph struct {
+	text string
+	line int
+}
+
+// paragraphSpan 段落在当前块中的位置（rune下标）
+type paragraphSpan struct {
+	start, end int
+	line       int
+}
+
 // SplitText 将文本分割成块
 func (s *TextSplitter) SplitText(text string) []string {
+	chunks := s.Split(text)
+	if len(chunks) == 0 {
+		return nil
+	}
+	texts := make([]string, len(chunks))
+	for i, c := range chunks {
+		texts[i] = c.Text
+	}
+	return texts
+}
+
+// Split 将文本分割成块，并记录每块在原文中的行范围
+func (s *TextSplitter) Split(text string) []Chunk {
 	if text == "" {
 		return nil
 	}
@@ -40,57 +73,95 @@ func (s *TextSplitter) SplitText(text string) []string {
 	}
 
 	// 如果单个段落就超过chunkSize，需要进一步分割
-	var chunks []string
-	currentChunk := ""
+	// 块大小和重叠都按字符（rune）计算，截取重叠部分时不会切断多字节字符
+	var chunks []Chunk
+	var currentChunk []rune
+	var spans []paragraphSpan // 当前块中各段落的位置，用于计算行范围
+
+	flush := func() {
+		chunks = append(chunks, Chunk{
+			Text:      string(currentChunk),
+			StartLine: spans[0].line,
+			EndLine:   spans[len(spans)-1].line,
+		})
+	}
 
 	for _, para := range paragraphs {
-		paraLen := len(para)
+		paraLen := utf8.RuneCountInString(para.text)
 
 		// 如果单个段落就超过chunkSize
 		if paraLen > s.ChunkSize {
 			// 先保存当前的chunk
-			if currentChunk != "" {
-				chunks = append(chunks, currentChunk)
-				currentChunk = ""
+			if len(currentChunk) > 0 {
+				flush()
+				currentChunk = nil
+				spans = nil
 			}
 			// 对这个段落进行分割
-			chunks = append(chunks, s.splitLargeChunk(para)...)
+			for _, text := range s.splitLargeChunk(para.text) {
+				chunks = append(chunks, Chunk{Text: text, StartLine: para.line, EndLine: para.line})
+			}
 			continue
 		}
 
 		// 如果加上当前段落超过chunkSize，保存当前chunk，开始新的
 		if len(currentChunk)+paraLen+1 > s.ChunkSize {
-			if currentChunk != "" {
-				chunks = append(chunks, currentChunk)
+			if len(currentChunk) > 0 {
+				flush()
 			}
 			// 新chunk从overlap部分开始
 			if len(currentChunk) > s.ChunkOverlap {
-				currentChunk = currentChunk[len(currentChunk)-s.ChunkOverlap:]
+				cut := len(currentChunk) - s.ChunkOverlap
+				currentChunk = append([]rune(nil), currentChunk[cut:]...)
+				spans = overlapSpans(spans, cut)
 			} else {
-				currentChunk = ""
+				currentChunk = nil
+				spans = nil
 			}
 		}
 
 		// 添加段落
-		if currentChunk != "" {
-			currentChunk += "\n" + para
+		if len(currentChunk) > 0 {
+			start := len(currentChunk) + 1
+			currentChunk = append(currentChunk, '\n')
+			currentChunk = append(currentChunk, []rune(para.text)...)
+			spans = append(spans, paragraphSpan{start: start, end: len(currentChunk), line: para.line})
 		} else {
-			currentChunk = para
+			currentChunk = []rune(para.text)
+			spans = []paragraphSpan{{start: 0, end: len(currentChunk), line: para.line}}
 		}
 	}
 
 	// 保存最后一个chunk
-	if currentChunk != "" {
-		chunks = append(chunks, currentChunk)
+	if len(currentChunk) > 0 {
+		flush()
 	}
 
 	return chunks
 }
 
-// splitByParagraph 按段落分割
-func (s *TextSplitter) splitByParagraph(text string) []string {
-	var paragraphs []string
+// overlapSpans 截取块末尾作为重叠部分后，仍在其中的段落（位置相对截取点）
+func overlapSpans(spans []paragraphSpan, cut int) []paragraphSpan {
+	var kept []paragraphSpan
+	for _, span := range spans {
+		if span.end <= cut {
+			continue
+		}
+		span.start -= cut
+		if span.start < 0 {
+			span.start = 0
+		}
+		span.end -= cut
+		kept = append(kept, span)
+	}
+	return kept
+}
+
+// splitByParagraph 按段落分割，记录每个段落所在的行
+func (s *TextSplitter) splitByParagraph(text string) []paragraph {
+	var paragraphs []paragraph
 	var current []rune
+	line := 1
 
 	for _, r := range text {
 		if r == '\n' {
@@ -99,10 +170,11 @@ func (s *TextSplitter) splitByParagraph(text string) []string {
 				para := string(current)
 				para = s.trimWhitespace(para)
 				if para != "" {
-					paragraphs = append(paragraphs, para)
+					paragraphs = append(paragraphs, paragraph{text: para, line: line})
 				}
 				current = nil
 			}
+			line++
 		} else {
 			current = append(current, r)
 		}
@@ -112,7 +184,7 @@ func (s *TextSplitter) splitByParagraph(text string) []string {
 	if len(current) > 0 {
 		para := s.trimWhitespace(string(current))
 		if para != "" {
-			paragraphs = append(paragraphs, para)
+			paragraphs = append(paragraphs, paragraph{text: para, line: line})
 		}
 	}
 
@@ -134,6 +206,10 @@ func (s *TextSplitter) splitLargeChunk(text string) []string {
 		if chunk != "" {
 			chunks = append(chunks, chunk)
 		}
+		// 已到段落末尾，之后的块只会是上一块的重叠部分
+		if end == len(runes) {
+			break
+		}
 	}
 
 	return chunks
@@ -0,0 +1,120 @@
+package rag
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTextSplitterSplit(t *testing.T) {
+	tests := []struct {
+		name     string
+		splitter TextSplitter
+		text     string
+		want     []Chunk
+	}{
+		{
+			name:     "合并段落",
+			splitter: TextSplitter{ChunkSize: 20, ChunkOverlap: 0},
+			text:     "first\n\n  second  \nthird",
+			want: []Chunk{
+				{Text: "first\nsecond\nthird", StartLine: 1, EndLine: 4},
+			},
+		},
+		{
+			// 块大小和重叠按字符计算：按字节计算时每个汉字占3字节，块会提前切分，重叠会切断汉字
+			name:     "中文段落按字符重叠",
+			splitter: TextSplitter{ChunkSize: 10, ChunkOverlap: 3},
+			text:     "一二三四五\n六七八九十\n甲乙丙丁戊",
+			want: []Chunk{
+				{Text: "一二三四五", StartLine: 1, EndLine: 1},
+				{Text: "三四五\n六七八九十", StartLine: 1, EndLine: 2},
+				{Text: "八九十\n甲乙丙丁戊", StartLine: 2, EndLine: 3},
+			},
+		},
+		{
+			name:     "重叠为0",
+			splitter: TextSplitter{ChunkSize: 10, ChunkOverlap: 0},
+			text:     "一二三四五\n六七八九十\n甲乙丙丁戊",
+			want: []Chunk{
+				{Text: "一二三四五", StartLine: 1, EndLine: 1},
+				{Text: "六七八九十", StartLine: 2, EndLine: 2},
+				{Text: "甲乙丙丁戊", StartLine: 3, EndLine: 3},
+			},
+		},
+		{
+			name:     "超长段落按字符切分",
+			splitter: TextSplitter{ChunkSize: 10, ChunkOverlap: 3},
+			text:     "开头\n" + strings.Repeat("长", 24) + "\n结尾",
+			want: []Chunk{
+				{Text: "开头", StartLine: 1, EndLine: 1},
+				{Text: strings.Repeat("长", 10), StartLine: 2, EndLine: 2},
+				{Text: strings.Repeat("长", 10), StartLine: 2, EndLine: 2},
+				{Text: strings.Repeat("长", 10), StartLine: 2, EndLine: 2},
+				{Text: "结尾", StartLine: 3, EndLine: 3},
+			},
+		},
+		{
+			name:     "只有空白",
+			splitter: TextSplitter{ChunkSize: 10, ChunkOverlap: 3},
+			text:     " \n\t\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.splitter.Split(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Split = %+v，期望 %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextSplitterChunkSizeInRunes(t *testing.T) {
+	var paragraphs []string
+	for i := 0; i < 50; i++ {
+		paragraphs = append(paragraphs, strings.Repeat("中文段落", 1+i%7))
+	}
+	text := strings.Join(paragraphs, "\n")
+
+	s := TextSplitter{ChunkSize: 100, ChunkOverlap: 30}
+	chunks := s.Split(text)
+	if len(chunks) < 2 {
+		t.Fatalf("分块数 = %d", len(chunks))
+	}
+	lines := strings.Split(text, "\n")
+	for i, c := range chunks {
+		if !utf8.ValidString(c.Text) {
+			t.Fatalf("第%d块不是合法的UTF-8: %q", i, c.Text)
+		}
+		if n := utf8.RuneCountInString(c.Text); n > s.ChunkSize {
+			t.Fatalf("第%d块 %d 个字符，超过块大小", i, n)
+		}
+		// 块的最后一段就是结束行的内容
+		got := c.Text[strings.LastIndex(c.Text, "\n")+1:]
+		if got != lines[c.EndLine-1] {
+			t.Fatalf("第%d块结束于第%d行 %q，实际为 %q", i, c.EndLine, lines[c.EndLine-1], got)
+		}
+		if c.StartLine > c.EndLine || (i > 0 && c.StartLine < chunks[i-1].StartLine) {
+			t.Fatalf("第%d块的行范围 %d-%d 不正确", i, c.StartLine, c.EndLine)
+		}
+	}
+}
+
+func TestNewTextSplitter(t *testing.T) {
+	tests := []struct {
+		size, overlap         int
+		wantSize, wantOverlap int
+	}{
+		{0, 0, 1024, 256},
+		{500, 100, 500, 100},
+		{200, 300, 200, 50},
+	}
+	for _, tt := range tests {
+		s := NewTextSplitter(tt.size, tt.overlap)
+		if s.ChunkSize != tt.wantSize || s.ChunkOverlap != tt.wantOverlap {
+			t.Errorf("NewTextSplitter(%d, %d) = %d, %d，期望 %d, %d",
+				tt.size, tt.overlap, s.ChunkSize, s.ChunkOverlap, tt.wantSize, tt.wantOverlap)
+		}
+	}
+}
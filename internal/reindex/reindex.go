@@ -0,0 +1,258 @@
+package reindex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go-ai-copilot/internal/model"
+	"go-ai-copilot/internal/store"
+)
+
+const (
+	defaultBatchSize = 64
+	// staleAfter 执行中的任务超过这个时间没有心跳，视为执行的实例已退出，可由其他实例接管
+	staleAfter = 5 * time.Minute
+	// watchInterval 检查待执行和心跳超时任务的间隔
+	watchInterval = time.Minute
+)
+
+// Embedder 批量向量化
+type Embedder interface {
+	GetEmbeddings(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// Config 重建向量配置
+type Config struct {
+	BatchSize int           // 每批向量化的分块数
+	Interval  time.Duration // 每批之间的间隔，限制对向量化接口的请求速率
+}
+
+// Runner 重建向量任务执行器
+// 任务使用当前配置的向量化模型，目标为当前模型对应的向量空间
+type Runner struct {
+	jobs     store.ReindexRepository
+	embedder Embedder
+	space    *model.EmbeddingSpace
+	config   Config
+
+	mu      sync.Mutex
+	running map[uint]context.CancelFunc // 本实例正在执行的任务
+}
+
+// NewRunner 创建重建向量任务执行器
+func NewRunner(jobs store.ReindexRepository, embedder Embedder, space *model.EmbeddingSpace, config Config) *Runner {
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultBatchSize
+	}
+	return &Runner{
+		jobs:     jobs,
+		embedder: embedder,
+		space:    space,
+		config:   config,
+		running:  make(map[uint]context.CancelFunc),
+	}
+}
+
+// Create 创建任务，kbID为空时重建全部分块
+func (r *Runner) Create(ctx context.Context, kbID *uint) (*model.ReindexJob, error) {
+	job := &model.ReindexJob{SpaceID: r.space.ID, KnowledgeBaseID: kbID}
+	if err := r.jobs.Create(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Start 在后台执行任务，任务已在执行时返回false
+func (r *Runner) Start(id uint) (bool, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	job, err := r.claim(ctx, id, cancel)
+	if err != nil || job == nil {
+		cancel()
+		return false, err
+	}
+	go func() {
+		defer r.release(id)
+		if err := r.run(ctx, job, nil); err != nil {
+			log.Printf("重建向量任务 %d 失败: %v", id, err)
+		}
+	}()
+	return true, nil
+}
+
+// Run 在当前goroutine中执行任务，progress在每批完成后调用
+func (r *Runner) Run(ctx context.Context, id uint, progress func(job *model.ReindexJob)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	job, err := r.claim(ctx, id, cancel)
+	if err != nil {
+		cancel()
+		return err
+	}
+	if job == nil {
+		cancel()
+		return fmt.Errorf("任务 %d 正在执行或已结束", id)
+	}
+	defer r.release(id)
+	return r.run(ctx, job, progress)
+}
+
+// Cancel 取消任务，本实例正在执行时立即停止
+func (r *Runner) Cancel(ctx context.Context, id uint) (bool, error) {
+	canceled, err := r.jobs.Cancel(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	r.mu.Lock()
+	if cancel, ok := r.running[id]; ok {
+		cancel()
+	}
+	r.mu.Unlock()
+	return canceled, nil
+}
+
+// Watch 定期接管待执行和心跳超时的任务（服务重启或其他实例退出后继续执行），ctx结束时返回
+func (r *Runner) Watch(ctx context.Context) {
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for {
+		jobs, err := r.jobs.Resumable(ctx, r.space.ID, time.Now().Add(-staleAfter))
+		if err != nil {
+			log.Printf("查询待执行的重建向量任务失败: %v", err)
+		}
+		for _, job := range jobs {
+			if started, err := r.Start(job.ID); err != nil {
+				log.Printf("重建向量任务 %d 启动失败: %v", job.ID, err)
+			} else if started {
+				log.Printf("继续执行重建向量任务 %d（%d/%d）", job.ID, job.Processed, job.Total)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// claim 领取任务并记录到本实例正在执行的任务中，任务不可领取时返回nil
+func (r *Runner) claim(ctx context.Context, id uint, cancel context.CancelFunc) (*model.ReindexJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.running[id]; ok {
+		return nil, nil
+	}
+
+	job, err := r.jobs.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// 配置的模型已更换，任务生成的向量不属于当前空间
+	if job.SpaceID != r.space.ID {
+		return nil, fmt.Errorf("任务 %d 的目标向量空间为 %d，与当前模型的向量空间 %d 不一致", id, job.SpaceID, r.space.ID)
+	}
+
+	claimed, err := r.jobs.Claim(ctx, id, time.Now().Add(-staleAfter))
+	if err != nil || !claimed {
+		return nil, err
+	}
+	job.Status = model.ReindexRunning
+	r.running[id] = cancel
+	return job, nil
+}
+
+// release 任务执行结束
+func (r *Runner) release(id uint) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cancel, ok := r.running[id]; ok {
+		cancel()
+		delete(r.running, id)
+	}
+}
+
+// run 分批生成影子向量，全部完成后替换分块的向量
+func (r *Runner) run(ctx context.Context, job *model.ReindexJob, progress func(job *model.ReindexJob)) error {
+	for {
+		chunks, err := r.jobs.NextChunks(ctx, job, r.config.BatchSize)
+		if err != nil {
+			return r.stop(ctx, job, err)
+		}
+		if len(chunks) == 0 {
+			break
+		}
+
+		texts := make([]string, len(chunks))
+		for i, chunk := range chunks {
+			texts[i] = chunk.Content
+		}
+		embeddings, err := r.embed(ctx, texts)
+		if err != nil {
+			return r.stop(ctx, job, err)
+		}
+
+		if err := r.jobs.SaveBatch(ctx, job, chunks, embeddings); err != nil {
+			if errors.Is(err, store.ErrJobStopped) {
+				return nil
+			}
+			return r.stop(ctx, job, err)
+		}
+		if progress != nil {
+			progress(job)
+		}
+
+		if r.config.Interval > 0 {
+			select {
+			case <-ctx.Done():
+				return r.stop(ctx, job, ctx.Err())
+			case <-time.After(r.config.Interval):
+			}
+		}
+	}
+
+	switched, err := r.jobs.Switch(ctx, job)
+	if err != nil {
+		if errors.Is(err, store.ErrJobStopped) {
+			return nil
+		}
+		return r.stop(ctx, job, err)
+	}
+	log.Printf("重建向量任务 %d 完成，替换了 %d 个分块的向量", job.ID, switched)
+	if progress != nil {
+		progress(job)
+	}
+	return nil
+}
+
+// embed 向量化一批文本（客户端按批重试可重试的错误）
+func (r *Runner) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings, err := r.embedder.GetEmbeddings(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings) != len(texts) {
+		return nil, fmt.Errorf("向量化返回 %d 个结果，请求了 %d 个", len(embeddings), len(texts))
+	}
+	return embeddings, nil
+}
+
+// stop 执行出错时停止任务：ctx结束（进程退出或任务被取消）记为已中断，其他错误记为失败
+// 两种状态都保留已生成的影子向量，继续执行时从游标处开始
+func (r *Runner) stop(ctx context.Context, job *model.ReindexJob, cause error) error {
+	status, message := model.ReindexFailed, cause.Error()
+	if ctx.Err() != nil {
+		status, message = model.ReindexPaused, "任务被中断"
+	}
+	if err := r.jobs.Stop(context.Background(), job.ID, status, message); err != nil {
+		log.Printf("更新重建向量任务 %d 状态失败: %v", job.ID, err)
+	}
+	job.Status = status
+	if status == model.ReindexPaused {
+		return nil
+	}
+	job.Error = message
+	return cause
+}
@@ -4,19 +4,24 @@ import (
 	"github.com/gin-gonic/gin"
 	"go-ai-copilot/internal/handler"
 	"go-ai-copilot/internal/middleware"
+	"go-ai-copilot/internal/store"
 	"go-ai-copilot/pkg/jwt"
 )
 
 // Setup 设置路由
-func Setup(jwtTool *jwt.JWT, chatHandler *handler.ChatHandler, userHandler *handler.UserHandler, sessionHandler *handler.SessionHandler, ragHandler *handler.RAGHandler) *gin.Engine {
+func Setup(jwtTool *jwt.JWT, adminUsernames []string, users store.UserRepository, chatHandler *handler.ChatHandler, userHandler *handler.UserHandler, sessionHandler *handler.SessionHandler, ragHandler *handler.RAGHandler, promptHandler *handler.PromptHandler, assistantHandler *handler.AssistantHandler, shareHandler *handler.ShareHandler, eventsHandler *handler.EventsHandler) *gin.Engine {
 	// 初始化Gin
 	r := gin.Default()
 
 	// 健康检查
 	r.GET("/health", chatHandler.Health)
 
+	// 上游调用监控指标
+	r.GET("/metrics", chatHandler.Metrics)
+
 	// 初始化中间件
 	authMiddleware := middleware.NewAuthMiddleware(jwtTool)
+	adminMiddleware := middleware.NewAdminMiddleware(adminUsernames, users)
 
 	// v1 API 路由组
 	v1 := r.Group("/api/v1")
@@ -28,6 +33,10 @@ func Setup(jwtTool *jwt.JWT, chatHandler *handler.ChatHandler, userHandler *hand
 			user.POST("/login", userHandler.Login)
 		}
 
+		// 会话分享（无需登录，只读快照）
+		v1.GET("/share/:token", shareHandler.GetShare)
+		v1.POST("/share/:token", shareHandler.GetShare)
+
 		// 需要登录的接口
 		authorized := v1.Group("")
 		authorized.Use(authMiddleware.Handler())
@@ -39,26 +48,90 @@ func Setup(jwtTool *jwt.JWT, chatHandler *handler.ChatHandler, userHandler *hand
 
 		// 会话管理
 		authorized.GET("/session/list", sessionHandler.GetSessions)
+		authorized.GET("/session/search", sessionHandler.SearchMessages)
 		authorized.POST("/session", sessionHandler.CreateSession)
+		authorized.POST("/session/import", sessionHandler.ImportSessions)
 		authorized.GET("/session/:id", sessionHandler.GetSession)
 		authorized.PUT("/session/:id", sessionHandler.UpdateSession)
 		authorized.DELETE("/session/:id", sessionHandler.DeleteSession)
 		authorized.GET("/session/:id/history", sessionHandler.GetHistory)
+		authorized.GET("/session/:id/mode-changes", sessionHandler.GetModeChanges)
+		authorized.GET("/session/:id/tree", sessionHandler.GetTree)
+		authorized.GET("/session/:id/export", sessionHandler.ExportSession)
+		authorized.POST("/session/:id/share", shareHandler.CreateShare)
+		authorized.GET("/session/:id/shares", shareHandler.ListShares)
+		authorized.DELETE("/session/:id/share/:shareId", shareHandler.RevokeShare)
+		authorized.PUT("/session/:id/active", sessionHandler.SwitchBranch)
+
+		// 会话文件夹
+		authorized.GET("/folder/list", sessionHandler.GetFolders)
+		authorized.POST("/folder", sessionHandler.CreateFolder)
+		authorized.PUT("/folder/:id", sessionHandler.UpdateFolder)
+		authorized.DELETE("/folder/:id", sessionHandler.DeleteFolder)
+
+		// 事件推送（SSE）
+		authorized.GET("/events", eventsHandler.Stream)
 
 		// 对话接口
 		authorized.POST("/chat", chatHandler.Chat)
 		authorized.POST("/chat/stream", chatHandler.StreamChat)
 		authorized.POST("/chat/mode", chatHandler.HandleChatWithMode)
+		authorized.POST("/chat/edit", chatHandler.EditMessage)
+		authorized.POST("/chat/regenerate", chatHandler.RegenerateMessage)
 
 		// RAG知识库接口
 		ragGroup := authorized.Group("/rag")
 		{
 			ragGroup.POST("/upload", ragHandler.UploadDocument)
+			ragGroup.POST("/url", ragHandler.CreateWebSource)
+			ragGroup.GET("/url/list", ragHandler.ListWebSources)
+			ragGroup.POST("/url/:id/refresh", ragHandler.RefreshWebSource)
+			ragGroup.DELETE("/url/:id", ragHandler.DeleteWebSource)
 			ragGroup.GET("/list", ragHandler.GetDocuments)
 			ragGroup.GET("/:id", ragHandler.GetDocument)
+			ragGroup.PUT("/:id", ragHandler.UpdateDocument)
+			ragGroup.GET("/:id/versions", ragHandler.ListDocumentVersions)
+			ragGroup.GET("/:id/versions/:version", ragHandler.GetDocumentVersion)
+			ragGroup.GET("/:id/download", ragHandler.DownloadDocument)
 			ragGroup.DELETE("/:id", ragHandler.DeleteDocument)
 			ragGroup.POST("/search", ragHandler.Search)
+			ragGroup.POST("/preview", ragHandler.PreviewChunks)
 			ragGroup.POST("/chat", ragHandler.RAGChat)
+
+			// 知识库
+			ragGroup.GET("/kb/list", ragHandler.ListKnowledgeBases)
+			ragGroup.POST("/kb", ragHandler.CreateKnowledgeBase)
+			ragGroup.PUT("/kb/:id", ragHandler.UpdateKnowledgeBase)
+			ragGroup.DELETE("/kb/:id", ragHandler.DeleteKnowledgeBase)
+		}
+
+		// 助手接口
+		authorized.GET("/assistant/list", assistantHandler.ListAssistants)
+		authorized.POST("/assistant", assistantHandler.CreateAssistant)
+		authorized.GET("/assistant/:id", assistantHandler.GetAssistant)
+		authorized.PUT("/assistant/:id", assistantHandler.UpdateAssistant)
+		authorized.DELETE("/assistant/:id", assistantHandler.DeleteAssistant)
+
+		// 管理员接口
+		admin := authorized.Group("/admin")
+		admin.Use(adminMiddleware.Handler())
+		{
+			// 提示词模板
+			admin.GET("/prompts", promptHandler.ListPrompts)
+			admin.POST("/prompts", promptHandler.CreatePrompt)
+			admin.POST("/prompts/preview", promptHandler.PreviewPrompt)
+			admin.GET("/prompts/:id", promptHandler.GetPrompt)
+			admin.PUT("/prompts/:id", promptHandler.UpdatePrompt)
+			admin.DELETE("/prompts/:id", promptHandler.DeletePrompt)
+			admin.POST("/prompts/:id/pin", promptHandler.PinPrompt)
+			admin.DELETE("/prompts/:id/pin", promptHandler.UnpinPrompt)
+
+			// 重建向量
+			admin.GET("/reindex", ragHandler.ListReindexJobs)
+			admin.POST("/reindex", ragHandler.CreateReindexJob)
+			admin.GET("/reindex/:id", ragHandler.GetReindexJob)
+			admin.POST("/reindex/:id/resume", ragHandler.ResumeReindexJob)
+			admin.POST("/reindex/:id/cancel", ragHandler.CancelReindexJob)
 		}
 	}
 
@@ -0,0 +1,274 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go-ai-copilot/internal/model"
+	"go-ai-copilot/internal/pagination"
+	"go-ai-copilot/internal/store"
+	"go-ai-copilot/pkg/ai"
+)
+
+// 高亮标记，渲染摘要时转义内容后替换为 <mark>
+const (
+	markStart = store.HighlightStart
+	markStop  = store.HighlightStop
+)
+
+// snippetRunes 摘要中关键词前后保留的字符数
+const snippetRunes = 60
+
+// maxEmbedRunes 生成消息向量时截取的最大字符数
+const maxEmbedRunes = 4000
+
+// indexBatchSize 批量生成消息向量时每批的消息数
+const indexBatchSize = 100
+
+// configPattern 关键词搜索配置名（全文检索配置会拼接到SQL和索引定义中，必须是合法标识符）
+var configPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// defaultTSConfig 默认使用三元组索引做子串匹配，中文无需分词插件，索引由数据库迁移创建
+const defaultTSConfig = store.TrigramSearch
+
+// Searcher 聊天记录搜索
+type Searcher struct {
+	messages store.MessageSearchRepository
+	tsConfig string
+	client   *ai.EmbeddingClient
+	space    *model.EmbeddingSpace // 消息向量所在的向量空间，只比较同一空间的向量
+}
+
+// Query 搜索条件
+type Query struct {
+	UserID    uint
+	Keyword   string
+	Mode      string     // 会话模式
+	Role      string     // user / assistant
+	From      *time.Time // 消息创建时间下限（含）
+	To        *time.Time // 消息创建时间上限（不含）
+	Limit     int
+	Cursor    string  // 上一页返回的 next_cursor
+	Threshold float64 // 语义搜索的最小相似度
+}
+
+// Hit 搜索结果
+type Hit struct {
+	MessageID    uint      `json:"message_id"`
+	SessionID    uint      `json:"session_id"`
+	SessionTitle string    `json:"session_title"`
+	SessionMode  string    `json:"session_mode"`
+	Role         string    `json:"role"`
+	Snippet      string    `json:"snippet"` // 已转义的HTML，命中的关键词用 <mark> 包裹
+	Score        float64   `json:"score"`
+	CreatedAt    time.Time `json:"created_at"`
+	Link         string    `json:"link"` // 定位到该消息所在分支的历史接口
+}
+
+// New 创建聊天记录搜索；client不为空时启用消息向量（语义搜索），向量保存在space中
+// 启动时检查关键词搜索配置对应的索引，缺失时返回包含建索引语句的错误（不在运行中的库上自动建索引）
+func New(st *store.Store, config string, client *ai.EmbeddingClient, space *model.EmbeddingSpace) (*Searcher, error) {
+	s := &Searcher{messages: st.MessageSearch, tsConfig: defaultTSConfig, client: client, space: space}
+	if config != "" {
+		if !configPattern.MatchString(config) {
+			return nil, fmt.Errorf("关键词搜索配置名不合法: %s", config)
+		}
+		s.tsConfig = config
+	}
+
+	if err := s.messages.CheckTextIndex(context.Background(), s.tsConfig); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SemanticEnabled 是否启用了语义搜索
+func (s *Searcher) SemanticEnabled() bool {
+	return s != nil && s.client != nil
+}
+
+// IndexMessage 异步为消息生成向量，未启用语义搜索时忽略
+func (s *Searcher) IndexMessage(msg model.Message) {
+	s.IndexMessages([]model.Message{msg})
+}
+
+// IndexMessages 异步为一批消息（如导入的会话）生成向量，按批调用向量化接口，未启用语义搜索时忽略
+func (s *Searcher) IndexMessages(msgs []model.Message) {
+	if !s.SemanticEnabled() {
+		return
+	}
+	var todo []model.Message
+	for _, m := range msgs {
+		if strings.TrimSpace(m.Content) != "" {
+			todo = append(todo, m)
+		}
+	}
+	if len(todo) == 0 {
+		return
+	}
+
+	go func() {
+		for start := 0; start < len(todo); start += indexBatchSize {
+			s.indexBatch(todo[start:min(start+indexBatchSize, len(todo))])
+		}
+	}()
+}
+
+// indexBatch 为一批消息生成并保存向量
+func (s *Searcher) indexBatch(msgs []model.Message) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	texts := make([]string, len(msgs))
+	for i, m := range msgs {
+		texts[i] = m.Content
+		if utf8.RuneCountInString(texts[i]) > maxEmbedRunes {
+			texts[i] = string([]rune(texts[i])[:maxEmbedRunes])
+		}
+	}
+	embeddings, err := s.client.GetEmbeddings(ctx, texts)
+	if err != nil {
+		log.Printf("消息向量化失败: %v", err)
+		return
+	}
+
+	rows := make([]model.MessageEmbedding, len(msgs))
+	for i, m := range msgs {
+		rows[i] = model.MessageEmbedding{
+			MessageID: m.ID,
+			SpaceID:   s.space.ID,
+			SessionID: m.SessionID,
+			UserID:    m.UserID,
+			Embedding: model.Vector(embeddings[i]),
+		}
+	}
+	if err := s.messages.SaveEmbeddings(ctx, rows); err != nil {
+		log.Printf("消息向量保存失败: %v", err)
+	}
+}
+
+// Keyword 按关键词搜索消息
+// PostgreSQL默认通过三元组索引做子串匹配，其他配置使用全文检索，均按相关度排序
+// q.Cursor 无法解析时返回 pagination.ErrInvalidCursor
+func (s *Searcher) Keyword(ctx context.Context, q Query) (*pagination.Page[Hit], error) {
+	page, err := s.messages.Keyword(ctx, s.query(q), q.Limit, q.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	return hits(page, q.Keyword), nil
+}
+
+// Semantic 按语义相似度搜索消息（需启用消息向量）
+// q.Cursor 无法解析时返回 pagination.ErrInvalidCursor
+func (s *Searcher) Semantic(ctx context.Context, q Query) (*pagination.Page[Hit], error) {
+	if !s.SemanticEnabled() {
+		return nil, fmt.Errorf("未启用语义搜索")
+	}
+	embedding, err := s.client.GetEmbedding(ctx, q.Keyword)
+	if err != nil {
+		return nil, err
+	}
+	page, err := s.messages.Semantic(ctx, s.space, s.query(q), embedding, q.Threshold, q.Limit, q.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	return hits(page, q.Keyword), nil
+}
+
+// query 存储层的搜索条件
+func (s *Searcher) query(q Query) store.MessageSearchQuery {
+	return store.MessageSearchQuery{
+		UserID:     q.UserID,
+		Keyword:    q.Keyword,
+		Mode:       q.Mode,
+		Role:       q.Role,
+		From:       q.From,
+		To:         q.To,
+		TextConfig: s.tsConfig,
+	}
+}
+
+// hits 生成摘要和链接
+func hits(page *pagination.Page[store.MessageHit], keyword string) *pagination.Page[Hit] {
+	result := &pagination.Page[Hit]{
+		Items:      make([]Hit, 0, len(page.Items)),
+		NextCursor: page.NextCursor,
+		HasMore:    page.HasMore,
+	}
+	for _, m := range page.Items {
+		snippet := m.Snippet
+		if !strings.Contains(snippet, markStart) {
+			snippet = highlight(m.Content, keyword)
+		}
+		result.Items = append(result.Items, Hit{
+			MessageID:    m.MessageID,
+			SessionID:    m.SessionID,
+			SessionTitle: m.SessionTitle,
+			SessionMode:  m.SessionMode,
+			Role:         m.Role,
+			Snippet:      render(snippet),
+			Score:        m.Score,
+			CreatedAt:    m.CreatedAt,
+			Link:         fmt.Sprintf("/api/v1/session/%d/history?leaf_id=%d", m.SessionID, m.MessageID),
+		})
+	}
+	return result
+}
+
+// highlight 在内容中查找关键词（不区分大小写），截取前后若干字符并加上高亮标记
+// 找不到关键词时返回内容开头
+func highlight(content, keyword string) string {
+	runes := []rune(content)
+	lower := []rune(strings.ToLower(content))
+	key := []rune(strings.ToLower(strings.TrimSpace(keyword)))
+
+	pos := -1
+	if len(key) > 0 && len(lower) == len(runes) {
+		for i := 0; i+len(key) <= len(lower); i++ {
+			if string(lower[i:i+len(key)]) == string(key) {
+				pos = i
+				break
+			}
+		}
+	}
+	if pos < 0 {
+		if len(runes) > 2*snippetRunes {
+			return string(runes[:2*snippetRunes]) + "…"
+		}
+		return content
+	}
+
+	start := pos - snippetRunes
+	if start < 0 {
+		start = 0
+	}
+	end := pos + len(key) + snippetRunes
+	if end > len(runes) {
+		end = len(runes)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	b.WriteString(string(runes[start:pos]))
+	b.WriteString(markStart + string(runes[pos:pos+len(key)]) + markStop)
+	b.WriteString(string(runes[pos+len(key) : end]))
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// render 转义摘要并把高亮标记替换为 <mark>
+func render(snippet string) string {
+	escaped := html.EscapeString(snippet)
+	escaped = strings.ReplaceAll(escaped, markStart, "<mark>")
+	return strings.ReplaceAll(escaped, markStop, "</mark>")
+}
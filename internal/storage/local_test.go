@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCleanKey(t *testing.T) {
+	tests := []struct {
+		key   string
+		valid bool
+	}{
+		{"1/abc.md", true},
+		{"a/b/c.txt", true},
+		{"..x", true},
+		{"", false},
+		{"../x", false},
+		{"..", false},
+		{"/abs", false},
+		{"/etc/passwd", false},
+		{"a/../../b", false},
+		{"a/../b", false},
+		{"a//b", false},
+		{"./a", false},
+		{"a/", false},
+		{`a\..\b`, false},
+	}
+	for _, tt := range tests {
+		_, err := cleanKey(tt.key)
+		if (err == nil) != tt.valid {
+			t.Errorf("cleanKey(%q) 错误 = %v，期望合法 = %v", tt.key, err, tt.valid)
+		}
+	}
+}
+
+func TestLocalRejectsTraversal(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "uploads")
+	l, err := NewLocal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	for _, key := range []string{"../x", "/abs", "a/../../b"} {
+		if err := l.Put(ctx, key, strings.NewReader("x"), 1, ""); err == nil {
+			t.Errorf("Put(%q) 应返回错误", key)
+		}
+		if _, _, err := l.Get(ctx, key); err == nil || errors.Is(err, ErrNotFound) {
+			t.Errorf("Get(%q) 错误 = %v，期望Key不合法", key, err)
+		}
+		if err := l.Delete(ctx, key); err == nil {
+			t.Errorf("Delete(%q) 应返回错误", key)
+		}
+	}
+
+	// 存储目录之外没有写入任何文件
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "uploads" {
+		t.Fatalf("存储目录之外出现了文件: %v", entries)
+	}
+}
+
+func TestLocalPutGetDelete(t *testing.T) {
+	l, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if err := l.Put(ctx, "1/a.md", strings.NewReader("hello"), 5, "text/markdown"); err != nil {
+		t.Fatal(err)
+	}
+	// 覆盖已有的Key
+	if err := l.Put(ctx, "1/a.md", strings.NewReader("hello world"), 11, "text/markdown"); err != nil {
+		t.Fatal(err)
+	}
+
+	rc, size, err := l.Get(ctx, "1/a.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(data) != "hello world" || size != 11 {
+		t.Fatalf("Get = %q (%d)", data, size)
+	}
+
+	if err := l.Delete(ctx, "1/a.md"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := l.Get(ctx, "1/a.md"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("删除后 Get 错误 = %v，期望 ErrNotFound", err)
+	}
+	if err := l.Delete(ctx, "1/a.md"); err != nil {
+		t.Fatalf("删除不存在的Key: %v", err)
+	}
+}
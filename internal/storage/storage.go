@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// ErrNotFound 文件不存在
+var ErrNotFound = errors.New("storage: blob not found")
+
+// BlobStore 上传文件存储
+// Key 为以/分隔的相对路径，如 "1/<sha256>.md"
+type BlobStore interface {
+	// Put 写入文件，Key已存在时覆盖
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get 读取文件及其大小，Key不存在时返回 ErrNotFound，调用方负责关闭
+	Get(ctx context.Context, key string) (io.ReadCloser, int64, error)
+	// Delete 删除文件，Key不存在时不报错
+	Delete(ctx context.Context, key string) error
+	// Name 实现名称，用于日志
+	Name() string
+}
+
+// 存储实现
+const (
+	DriverLocal = "local" // 本地目录
+	DriverS3    = "s3"    // S3兼容的对象存储（AWS S3、MinIO等）
+)
+
+// Config 文件存储配置
+type Config struct {
+	Driver string
+	Dir    string // 本地存储目录
+	S3     S3Config
+}
+
+// New 根据配置创建文件存储
+func New(cfg Config) (BlobStore, error) {
+	switch cfg.Driver {
+	case DriverLocal, "":
+		return NewLocal(cfg.Dir)
+	case DriverS3:
+		return NewS3(cfg.S3)
+	}
+	return nil, fmt.Errorf("不支持的文件存储驱动: %s", cfg.Driver)
+}
+
+// cleanKey 校验Key，不允许绝对路径和跳出存储根目录
+func cleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", fmt.Errorf("storage: invalid key %q", key)
+	}
+	cleaned := path.Clean(key)
+	if cleaned != key || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("storage: invalid key %q", key)
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,90 @@
+package store
+
+import (
+	"context"
+
+	"go-ai-copilot/internal/model"
+	"gorm.io/gorm"
+)
+
+// AssistantRepository 助手存储
+type AssistantRepository interface {
+	Create(ctx context.Context, a *model.Assistant) error
+	// List 用户可用的助手（内置、团队共享和自己创建的），内置助手在前
+	List(ctx context.Context, userID uint) ([]model.Assistant, error)
+	// GetVisible 获取用户可用的助手
+	GetVisible(ctx context.Context, userID, id uint) (*model.Assistant, error)
+	// GetOwned 获取用户创建的助手，内置助手除外
+	GetOwned(ctx context.Context, userID, id uint) (*model.Assistant, error)
+	// GetBuiltin 获取模式对应的内置助手
+	GetBuiltin(ctx context.Context, mode string) (*model.Assistant, error)
+	Save(ctx context.Context, a *model.Assistant) error
+	// Delete 删除用户创建的助手，不存在时返回 ErrNotFound
+	Delete(ctx context.Context, userID, id uint) error
+}
+
+// assistantRepo 助手存储
+type assistantRepo struct {
+	db *gorm.DB
+}
+
+func (r *assistantRepo) Create(ctx context.Context, a *model.Assistant) error {
+	return r.db.WithContext(ctx).Create(a).Error
+}
+
+func (r *assistantRepo) List(ctx context.Context, userID uint) ([]model.Assistant, error) {
+	var assistants []model.Assistant
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? OR visibility = ? OR builtin = ?", userID, model.VisibilityTeam, true).
+		Order("builtin DESC, id ASC").
+		Find(&assistants).Error; err != nil {
+		return nil, err
+	}
+	return assistants, nil
+}
+
+func (r *assistantRepo) GetVisible(ctx context.Context, userID, id uint) (*model.Assistant, error) {
+	var a model.Assistant
+	if err := r.db.WithContext(ctx).
+		Where("id = ?", id).
+		Where("user_id = ? OR visibility = ? OR builtin = ?", userID, model.VisibilityTeam, true).
+		First(&a).Error; err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *assistantRepo) GetOwned(ctx context.Context, userID, id uint) (*model.Assistant, error) {
+	var a model.Assistant
+	if err := r.db.WithContext(ctx).
+		Where("id = ? AND user_id = ? AND builtin = ?", id, userID, false).
+		First(&a).Error; err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *assistantRepo) GetBuiltin(ctx context.Context, mode string) (*model.Assistant, error) {
+	var a model.Assistant
+	if err := r.db.WithContext(ctx).Where("builtin = ? AND mode = ?", true, mode).First(&a).Error; err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *assistantRepo) Save(ctx context.Context, a *model.Assistant) error {
+	return r.db.WithContext(ctx).Save(a).Error
+}
+
+func (r *assistantRepo) Delete(ctx context.Context, userID, id uint) error {
+	result := r.db.WithContext(ctx).
+		Where("id = ? AND user_id = ? AND builtin = ?", id, userID, false).
+		Delete(&model.Assistant{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package store
+
+import (
+	"context"
+
+	"go-ai-copilot/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ConnectorFileRepository 连接器的同步记录和同步状态存储
+type ConnectorFileRepository interface {
+	// List 连接器的全部同步记录
+	List(ctx context.Context, connector string) ([]model.ConnectorFile, error)
+	// Save 按连接器和路径写入同步记录，已存在时更新
+	Save(ctx context.Context, file *model.ConnectorFile) error
+	Delete(ctx context.Context, id uint) error
+	// State 连接器的同步状态，还没有同步过时返回ErrNotFound
+	State(ctx context.Context, connector string) (*model.ConnectorState, error)
+	SaveState(ctx context.Context, state *model.ConnectorState) error
+}
+
+// connectorFileRepo 连接器的同步记录和同步状态存储
+type connectorFileRepo struct {
+	db *gorm.DB
+}
+
+func (r *connectorFileRepo) List(ctx context.Context, connector string) ([]model.ConnectorFile, error) {
+	var files []model.ConnectorFile
+	err := r.db.WithContext(ctx).Where("connector = ?", connector).Order("path").Find(&files).Error
+	return files, err
+}
+
+func (r *connectorFileRepo) Save(ctx context.Context, file *model.ConnectorFile) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "connector"}, {Name: "path"}},
+		DoUpdates: clause.AssignmentColumns([]string{"knowledge_base_id", "size", "mod_time", "content_hash", "document_id", "synced_at"}),
+	}).Create(file).Error
+}
+
+func (r *connectorFileRepo) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.ConnectorFile{}, id).Error
+}
+
+func (r *connectorFileRepo) State(ctx context.Context, connector string) (*model.ConnectorState, error) {
+	var state model.ConnectorState
+	if err := r.db.WithContext(ctx).Where("connector = ?", connector).First(&state).Error; err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func (r *connectorFileRepo) SaveState(ctx context.Context, state *model.ConnectorState) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "connector"}},
+		DoUpdates: clause.AssignmentColumns([]string{"knowledge_base_id", "commit_sha", "config_hash", "synced_at"}),
+	}).Create(state).Error
+}
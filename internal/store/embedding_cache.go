@@ -0,0 +1,76 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"go-ai-copilot/internal/model"
+	"go-ai-copilot/pkg/ai"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// cacheLookupBatch 单次查询的最大哈希数，避免超出SQL参数个数限制
+const cacheLookupBatch = 500
+
+// EmbeddingCacheRepository 向量缓存存储
+type EmbeddingCacheRepository interface {
+	// ForSpace 向量空间对应的向量缓存
+	ForSpace(spaceID uint) ai.EmbeddingCache
+}
+
+// embeddingCacheRepo 向量缓存存储
+type embeddingCacheRepo struct {
+	db *gorm.DB
+}
+
+func (r *embeddingCacheRepo) ForSpace(spaceID uint) ai.EmbeddingCache {
+	return &spaceEmbeddingCache{db: r.db, spaceID: spaceID}
+}
+
+// spaceEmbeddingCache 单个向量空间的向量缓存
+type spaceEmbeddingCache struct {
+	db      *gorm.DB
+	spaceID uint
+}
+
+func (c *spaceEmbeddingCache) GetMany(ctx context.Context, hashes []string) (map[string][]float32, error) {
+	found := make(map[string][]float32, len(hashes))
+	for start := 0; start < len(hashes); start += cacheLookupBatch {
+		end := start + cacheLookupBatch
+		if end > len(hashes) {
+			end = len(hashes)
+		}
+
+		var entries []model.EmbeddingCacheEntry
+		if err := c.db.WithContext(ctx).
+			Where("space_id = ? AND content_hash IN ?", c.spaceID, hashes[start:end]).
+			Find(&entries).Error; err != nil {
+			return found, err
+		}
+		for _, entry := range entries {
+			found[entry.ContentHash] = entry.Embedding
+		}
+	}
+	return found, nil
+}
+
+func (c *spaceEmbeddingCache) SetMany(ctx context.Context, embeddings map[string][]float32) error {
+	if len(embeddings) == 0 {
+		return nil
+	}
+	now := time.Now()
+	entries := make([]model.EmbeddingCacheEntry, 0, len(embeddings))
+	for hash, embedding := range embeddings {
+		entries = append(entries, model.EmbeddingCacheEntry{
+			SpaceID:     c.spaceID,
+			ContentHash: hash,
+			Embedding:   embedding,
+			CreatedAt:   now,
+		})
+	}
+	// 并发上传相同内容时可能已被其他请求写入
+	return c.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		CreateInBatches(entries, 100).Error
+}
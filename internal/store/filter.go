@@ -0,0 +1,82 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"go-ai-copilot/internal/model"
+	"gorm.io/gorm"
+)
+
+// applyFilter 按所属文档的属性过滤分块
+// 标签和元数据使用分块上冗余的列，文件类型和上传时间通过文档子查询过滤
+func (r *chunkRepo) applyFilter(ctx context.Context, query *gorm.DB, f ChunkFilter) *gorm.DB {
+	postgres := isPostgres(r.db)
+	query = whereTags(query, postgres, "rag_chunks.tags", f.Tags)
+	query = whereMetadata(query, postgres, "rag_chunks.metadata", f.Metadata)
+
+	if len(f.FileTypes) == 0 && f.From == nil && f.To == nil {
+		return query
+	}
+	documents := r.db.WithContext(ctx).Model(&model.RAGDocument{}).Select("id")
+	if len(f.FileTypes) > 0 {
+		documents = documents.Where("file_type IN ?", f.FileTypes)
+	}
+	if f.From != nil {
+		documents = documents.Where("created_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		documents = documents.Where("created_at < ?", *f.To)
+	}
+	return query.Where("document_id IN (?)", documents)
+}
+
+// whereTags 以JSON数组保存的标签列同时包含全部标签
+func whereTags(query *gorm.DB, postgres bool, column string, tags []string) *gorm.DB {
+	if len(tags) == 0 {
+		return query
+	}
+	if postgres {
+		return query.Where(column+"::jsonb @> ?::jsonb", jsonText(tags))
+	}
+	for _, tag := range tags {
+		query = query.Where("EXISTS (SELECT 1 FROM json_each("+column+") WHERE json_each.value = ?)", tag)
+	}
+	return query
+}
+
+// whereMetadata 以JSON对象保存的元数据列包含全部键值
+func whereMetadata(query *gorm.DB, postgres bool, column string, metadata map[string]string) *gorm.DB {
+	if len(metadata) == 0 {
+		return query
+	}
+	if postgres {
+		return query.Where(column+"::jsonb @> ?::jsonb", jsonText(metadata))
+	}
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		query = query.Where("EXISTS (SELECT 1 FROM json_each("+column+") WHERE json_each.key = ? AND json_each.value = ?)", key, metadata[key])
+	}
+	return query
+}
+
+// jsonText 序列化为JSON文本，空值保存为空数组或空对象
+func jsonText(v interface{}) string {
+	switch v := v.(type) {
+	case []string:
+		if v == nil {
+			return "[]"
+		}
+	case map[string]string:
+		if v == nil {
+			return "{}"
+		}
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
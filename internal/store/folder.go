@@ -0,0 +1,62 @@
+package store
+
+import (
+	"context"
+
+	"go-ai-copilot/internal/model"
+	"gorm.io/gorm"
+)
+
+// FolderRepository 会话文件夹存储
+type FolderRepository interface {
+	// List 用户的文件夹，按名称排序
+	List(ctx context.Context, userID uint) ([]model.Folder, error)
+	// Get 获取用户的文件夹
+	Get(ctx context.Context, userID, id uint) (*model.Folder, error)
+	Create(ctx context.Context, folder *model.Folder) error
+	Save(ctx context.Context, folder *model.Folder) error
+	// Delete 删除文件夹，其中的会话移出文件夹（不删除会话）
+	Delete(ctx context.Context, folder *model.Folder) error
+}
+
+// folderRepo 会话文件夹存储
+type folderRepo struct {
+	db *gorm.DB
+}
+
+func (r *folderRepo) List(ctx context.Context, userID uint) ([]model.Folder, error) {
+	var folders []model.Folder
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).
+		Order("name ASC").
+		Find(&folders).Error; err != nil {
+		return nil, err
+	}
+	return folders, nil
+}
+
+func (r *folderRepo) Get(ctx context.Context, userID, id uint) (*model.Folder, error) {
+	var folder model.Folder
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&folder).Error; err != nil {
+		return nil, err
+	}
+	return &folder, nil
+}
+
+func (r *folderRepo) Create(ctx context.Context, folder *model.Folder) error {
+	return r.db.WithContext(ctx).Create(folder).Error
+}
+
+func (r *folderRepo) Save(ctx context.Context, folder *model.Folder) error {
+	return r.db.WithContext(ctx).Save(folder).Error
+}
+
+func (r *folderRepo) Delete(ctx context.Context, folder *model.Folder) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Session{}).
+			Where("folder_id = ? AND user_id = ?", folder.ID, folder.UserID).
+			UpdateColumn("folder_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(folder).Error
+	})
+}
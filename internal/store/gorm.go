@@ -0,0 +1,631 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go-ai-copilot/internal/model"
+	"go-ai-copilot/internal/pagination"
+	"gorm.io/gorm"
+)
+
+// isPostgres 是否为PostgreSQL
+func isPostgres(db *gorm.DB) bool {
+	return db.Dialector.Name() == "postgres"
+}
+
+// userRepo 用户存储
+type userRepo struct {
+	db *gorm.DB
+}
+
+func (r *userRepo) Create(ctx context.Context, user *model.User) error {
+	return r.db.WithContext(ctx).Create(user).Error
+}
+
+func (r *userRepo) Get(ctx context.Context, id uint) (*model.User, error) {
+	var user model.User
+	if err := r.db.WithContext(ctx).First(&user, id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *userRepo) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+	var user model.User
+	if err := r.db.WithContext(ctx).Where("username = ?", username).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *userRepo) Update(ctx context.Context, id uint, fields map[string]interface{}) error {
+	return r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", id).Updates(fields).Error
+}
+
+// sessionRepo 会话存储
+type sessionRepo struct {
+	db *gorm.DB
+}
+
+func (r *sessionRepo) Create(ctx context.Context, session *model.Session) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+func (r *sessionRepo) Get(ctx context.Context, userID, id uint) (*model.Session, error) {
+	var session model.Session
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *sessionRepo) List(ctx context.Context, q SessionQuery, page *pagination.Request) ([]model.Session, error) {
+	query := r.db.WithContext(ctx).Where("user_id = ?", q.UserID)
+	if q.Mode != "" {
+		query = query.Where("mode = ?", q.Mode)
+	}
+	if q.From != nil {
+		query = query.Where("created_at >= ?", *q.From)
+	}
+	if q.To != nil {
+		query = query.Where("created_at < ?", *q.To)
+	}
+	if q.FolderID != nil {
+		if *q.FolderID == 0 {
+			query = query.Where("folder_id IS NULL")
+		} else {
+			query = query.Where("folder_id = ?", *q.FolderID)
+		}
+	}
+	if q.Pinned != nil {
+		query = query.Where("pinned = ?", *q.Pinned)
+	}
+	query = query.Where("archived = ?", q.Archived)
+	if q.Tag != "" {
+		// 标签以JSON数组保存在文本列中
+		if isPostgres(r.db) {
+			tagJSON, _ := json.Marshal([]string{q.Tag})
+			query = query.Where("tags::jsonb @> ?::jsonb", string(tagJSON))
+		} else {
+			query = query.Where("EXISTS (SELECT 1 FROM json_each(sessions.tags) WHERE json_each.value = ?)", q.Tag)
+		}
+	}
+
+	var sessions []model.Session
+	if err := page.Apply(query).Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (r *sessionRepo) Save(ctx context.Context, session *model.Session, change *model.SessionModeChange) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(session).Error; err != nil {
+			return err
+		}
+		if change != nil {
+			return tx.Create(change).Error
+		}
+		return nil
+	})
+}
+
+func (r *sessionRepo) Delete(ctx context.Context, session *model.Session) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(session).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("session_id = ?", session.ID).Delete(&model.Message{}).Error; err != nil {
+			return err
+		}
+		// 撤销会话的分享链接，分享内容是快照，不撤销会在会话删除后仍可访问
+		return tx.Model(&model.SessionShare{}).
+			Where("session_id = ? AND revoked_at IS NULL", session.ID).
+			Update("revoked_at", time.Now()).Error
+	})
+}
+
+func (r *sessionRepo) ActiveMessageID(ctx context.Context, id uint) (*uint, error) {
+	var session model.Session
+	if err := r.db.WithContext(ctx).Select("id", "active_message_id").First(&session, id).Error; err != nil {
+		return nil, err
+	}
+	return session.ActiveMessageID, nil
+}
+
+func (r *sessionRepo) SetActive(ctx context.Context, id uint, leafID *uint) error {
+	return r.db.WithContext(ctx).Model(&model.Session{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"active_message_id": leafID,
+		"updated_at":        time.Now(),
+	}).Error
+}
+
+func (r *sessionRepo) ModeChanges(ctx context.Context, id uint) ([]model.SessionModeChange, error) {
+	var changes []model.SessionModeChange
+	if err := r.db.WithContext(ctx).Where("session_id = ?", id).
+		Order("created_at ASC, id ASC").
+		Find(&changes).Error; err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+func (r *sessionRepo) ClaimAutoTitle(ctx context.Context, userID, id uint) (string, error) {
+	var session model.Session
+	if err := r.db.WithContext(ctx).Select("id", "title").
+		Where("id = ? AND user_id = ? AND auto_title = ?", id, userID, true).
+		First(&session).Error; err != nil {
+		return "", err
+	}
+	// 条件更新保证同一会话只被认领一次
+	result := r.db.WithContext(ctx).Model(&model.Session{}).
+		Where("id = ? AND auto_title = ?", id, true).
+		Update("auto_title", false)
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return "", ErrNotFound
+	}
+	return session.Title, nil
+}
+
+func (r *sessionRepo) ReleaseAutoTitle(ctx context.Context, id uint, placeholder string) error {
+	return r.db.WithContext(ctx).Model(&model.Session{}).
+		Where("id = ? AND title = ?", id, placeholder).
+		Update("auto_title", true).Error
+}
+
+func (r *sessionRepo) SetGeneratedTitle(ctx context.Context, userID, id uint, placeholder, title string, tags []string) (*model.Session, error) {
+	var session model.Session
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ? AND user_id = ?", id, userID).First(&session).Error; err != nil {
+			return err
+		}
+		if session.Title != placeholder {
+			return ErrTitleChanged
+		}
+		session.Title = title
+		if len(session.Tags) == 0 {
+			session.Tags = tags
+		}
+		return tx.Model(&session).Select("title", "tags").Updates(&session).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// messageRepo 消息存储
+type messageRepo struct {
+	db *gorm.DB
+}
+
+func (r *messageRepo) Create(ctx context.Context, msg *model.Message) error {
+	return r.db.WithContext(ctx).Create(msg).Error
+}
+
+func (r *messageRepo) Get(ctx context.Context, sessionID, id uint) (*model.Message, error) {
+	var msg model.Message
+	if err := r.db.WithContext(ctx).Where("id = ? AND session_id = ?", id, sessionID).First(&msg).Error; err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+func (r *messageRepo) List(ctx context.Context, sessionID uint) ([]model.Message, error) {
+	var messages []model.Message
+	if err := r.db.WithContext(ctx).Where("session_id = ?", sessionID).
+		Order("created_at ASC, id ASC").
+		Find(&messages).Error; err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// branchSQL 从叶子消息沿 parent_id 向上递归查询分支，hops 为到叶子消息的步数，depth 为从第一条消息开始的位置
+const branchSQL = `WITH RECURSIVE branch(id, hops) AS (
+	SELECT id, 0 FROM chat_messages WHERE id = ? AND session_id = ? AND deleted_at IS NULL
+	UNION ALL
+	SELECT m.parent_id, b.hops + 1 FROM chat_messages m JOIN branch b ON m.id = b.id
+	WHERE m.parent_id IS NOT NULL AND m.session_id = ? AND m.deleted_at IS NULL
+)
+SELECT m.*, (SELECT MAX(hops) FROM branch) - b.hops AS depth
+FROM branch b JOIN chat_messages m ON m.id = b.id AND m.deleted_at IS NULL`
+
+func (r *messageRepo) History(ctx context.Context, q HistoryQuery, page *pagination.Request) ([]BranchMessage, error) {
+	if _, err := r.Get(ctx, q.SessionID, q.LeafID); err != nil {
+		return nil, err
+	}
+
+	// 递归查询已排除软删除的消息，外层查询不再追加 deleted_at 条件
+	query := r.db.WithContext(ctx).Unscoped().
+		Table("(?) AS history", gorm.Expr(branchSQL, q.LeafID, q.SessionID, q.SessionID))
+	if q.Role != "" {
+		query = query.Where("role = ?", q.Role)
+	}
+	if q.From != nil {
+		query = query.Where("created_at >= ?", *q.From)
+	}
+	if q.To != nil {
+		query = query.Where("created_at < ?", *q.To)
+	}
+
+	var messages []BranchMessage
+	if err := page.Apply(query).Find(&messages).Error; err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func (r *messageRepo) LatestChild(ctx context.Context, sessionID, parentID uint) (*model.Message, error) {
+	var child model.Message
+	if err := r.db.WithContext(ctx).Where("session_id = ? AND parent_id = ?", sessionID, parentID).
+		Order("id DESC").
+		First(&child).Error; err != nil {
+		return nil, err
+	}
+	return &child, nil
+}
+
+func (r *messageRepo) Chain(ctx context.Context, sessionID uint) error {
+	var messages []model.Message
+	if err := r.db.WithContext(ctx).Select("id", "parent_id").Where("session_id = ?", sessionID).
+		Order("created_at ASC, id ASC").
+		Find(&messages).Error; err != nil {
+		return err
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := 1; i < len(messages); i++ {
+			if messages[i].ParentID != nil {
+				continue
+			}
+			if err := tx.Model(&model.Message{}).Where("id = ?", messages[i].ID).
+				Update("parent_id", messages[i-1].ID).Error; err != nil {
+				return err
+			}
+		}
+		last := messages[len(messages)-1].ID
+		return tx.Model(&model.Session{}).Where("id = ?", sessionID).Update("active_message_id", last).Error
+	})
+}
+
+// documentRepo 文档存储
+type documentRepo struct {
+	db *gorm.DB
+}
+
+func (r *documentRepo) Create(ctx context.Context, doc *model.RAGDocument, version *model.RAGDocumentVersion) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(doc).Error; err != nil {
+			return err
+		}
+		version.DocumentID = doc.ID
+		version.Version = 1
+		return tx.Create(version).Error
+	})
+}
+
+func (r *documentRepo) Get(ctx context.Context, userID, id uint) (*model.RAGDocument, error) {
+	var doc model.RAGDocument
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&doc).Error; err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (r *documentRepo) GetByID(ctx context.Context, id uint) (*model.RAGDocument, error) {
+	var doc model.RAGDocument
+	if err := r.db.WithContext(ctx).First(&doc, id).Error; err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (r *documentRepo) FindBySource(ctx context.Context, userID, kbID uint, sourceKey string) (*model.RAGDocument, error) {
+	var doc model.RAGDocument
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? AND knowledge_base_id = ? AND source_key = ?", userID, kbID, sourceKey).
+		First(&doc).Error; err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (r *documentRepo) CreateVersion(ctx context.Context, version *model.RAGDocumentVersion) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var latest int
+		if err := tx.Model(&model.RAGDocumentVersion{}).
+			Where("document_id = ?", version.DocumentID).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&latest).Error; err != nil {
+			return err
+		}
+		version.Version = latest + 1
+		if err := tx.Create(version).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.RAGDocument{}).Where("id = ?", version.DocumentID).Update("status", version.Status).Error
+	})
+}
+
+func (r *documentRepo) Versions(ctx context.Context, documentID uint) ([]model.RAGDocumentVersion, error) {
+	var versions []model.RAGDocumentVersion
+	if err := r.db.WithContext(ctx).Where("document_id = ?", documentID).
+		Order("version DESC").
+		Find(&versions).Error; err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+func (r *documentRepo) GetVersion(ctx context.Context, documentID uint, version int) (*model.RAGDocumentVersion, error) {
+	var v model.RAGDocumentVersion
+	if err := r.db.WithContext(ctx).Where("document_id = ? AND version = ?", documentID, version).First(&v).Error; err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (r *documentRepo) ApplyVersion(ctx context.Context, doc *model.RAGDocument, version *model.RAGDocumentVersion, chunks VersionChunks) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.RAGDocument{}).
+			Where("id = ? AND version = ?", doc.ID, doc.Version).
+			Updates(map[string]interface{}{
+				"version":      version.Version,
+				"content_hash": version.ContentHash,
+				"file_name":    version.FileName,
+				"file_size":    version.FileSize,
+				"file_type":    doc.FileType,
+				"status":       "completed",
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrDocumentChanged
+		}
+
+		if len(chunks.Added) > 0 {
+			// 新分块继承文档当前的标签和元数据（更新文档后读取，与 SetMetadata 不会交错）
+			var current model.RAGDocument
+			if err := tx.Select("tags", "metadata").First(&current, doc.ID).Error; err != nil {
+				return err
+			}
+			for i := range chunks.Added {
+				chunks.Added[i].Tags = current.Tags
+				chunks.Added[i].Metadata = current.Metadata
+			}
+			if err := tx.CreateInBatches(chunks.Added, 100).Error; err != nil {
+				return err
+			}
+		}
+		for id, pos := range chunks.Moved {
+			if err := tx.Model(&model.RAGChunk{}).Where("id = ?", id).Updates(map[string]interface{}{
+				"chunk_index": pos.Index,
+				"start_line":  pos.StartLine,
+				"end_line":    pos.EndLine,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		if len(chunks.Retired) > 0 {
+			if err := tx.Model(&model.RAGChunk{}).Where("id IN ?", chunks.Retired).
+				Update("retired_version", version.Version).Error; err != nil {
+				return err
+			}
+		}
+
+		version.Status = "completed"
+		version.AddedChunks = len(chunks.Added)
+		version.RemovedChunks = len(chunks.Retired)
+		return tx.Model(version).Updates(map[string]interface{}{
+			"status":         version.Status,
+			"chunk_count":    version.ChunkCount,
+			"added_chunks":   version.AddedChunks,
+			"removed_chunks": version.RemovedChunks,
+		}).Error
+	})
+}
+
+func (r *documentRepo) FailVersion(ctx context.Context, doc *model.RAGDocument, version *model.RAGDocumentVersion) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(version).Update("status", "failed").Error; err != nil {
+			return err
+		}
+		status := "failed"
+		if doc.Version > 0 {
+			status = "completed"
+		}
+		return tx.Model(&model.RAGDocument{}).Where("id = ?", doc.ID).Update("status", status).Error
+	})
+}
+
+func (r *documentRepo) VersionHeartbeat(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Model(&model.RAGDocumentVersion{}).
+		Where("id = ? AND status = ?", id, "processing").
+		Update("updated_at", time.Now()).Error
+}
+
+func (r *documentRepo) StaleVersions(ctx context.Context, staleBefore time.Time) ([]model.RAGDocumentVersion, error) {
+	var versions []model.RAGDocumentVersion
+	if err := r.db.WithContext(ctx).
+		Where("status = ? AND (updated_at IS NULL OR updated_at < ?)", "processing", staleBefore).
+		Order("id ASC").
+		Find(&versions).Error; err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+func (r *documentRepo) ClaimVersion(ctx context.Context, id uint, staleBefore time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.RAGDocumentVersion{}).
+		Where("id = ? AND status = ? AND (updated_at IS NULL OR updated_at < ?)", id, "processing", staleBefore).
+		Update("updated_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *documentRepo) List(ctx context.Context, q DocumentQuery, page *pagination.Request) ([]model.RAGDocument, error) {
+	query := r.db.WithContext(ctx).Where("user_id = ?", q.UserID)
+	if q.KnowledgeBaseID != nil {
+		query = query.Where("knowledge_base_id = ?", *q.KnowledgeBaseID)
+	}
+	if q.Status != "" {
+		query = query.Where("status = ?", q.Status)
+	}
+	if q.FileType != "" {
+		query = query.Where("file_type = ?", q.FileType)
+	}
+	if q.From != nil {
+		query = query.Where("created_at >= ?", *q.From)
+	}
+	if q.To != nil {
+		query = query.Where("created_at < ?", *q.To)
+	}
+	query = whereTags(query, isPostgres(r.db), "rag_documents.tags", q.Tags)
+
+	var documents []model.RAGDocument
+	if err := page.Apply(query).Find(&documents).Error; err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
+
+func (r *documentRepo) SetStatus(ctx context.Context, id uint, status string) error {
+	return r.db.WithContext(ctx).Model(&model.RAGDocument{}).Where("id = ?", id).Update("status", status).Error
+}
+
+func (r *documentRepo) SetMetadata(ctx context.Context, id uint, tags []string, metadata map[string]string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		fields := map[string]interface{}{
+			"tags":     jsonText(tags),
+			"metadata": jsonText(metadata),
+		}
+		// 先更新文档（PostgreSQL中同时锁住文档行），与写入新版本的分块不会交错
+		if err := tx.Model(&model.RAGDocument{}).Where("id = ?", id).Updates(fields).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.RAGChunk{}).Where("document_id = ?", id).Updates(fields).Error
+	})
+}
+
+func (r *documentRepo) Delete(ctx context.Context, doc *model.RAGDocument) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("document_id = ?", doc.ID).Delete(&model.RAGChunk{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(doc).Error
+	})
+}
+
+func (r *documentRepo) UnreferencedBlobs(ctx context.Context, documentIDs []uint) ([]string, error) {
+	if len(documentIDs) == 0 {
+		return nil, nil
+	}
+	db := r.db.WithContext(ctx)
+	var keys []string
+	if err := db.Model(&model.RAGDocumentVersion{}).
+		Where("document_id IN ? AND blob_key <> ''", documentIDs).
+		Distinct().Pluck("blob_key", &keys).Error; err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	// 相同内容的上传共用一个文件，仍被未删除的文档引用时保留
+	var used []string
+	if err := db.Model(&model.RAGDocumentVersion{}).
+		Joins("JOIN rag_documents ON rag_documents.id = rag_document_versions.document_id AND rag_documents.deleted_at IS NULL").
+		Where("rag_document_versions.blob_key IN ?", keys).
+		Distinct().Pluck("rag_document_versions.blob_key", &used).Error; err != nil {
+		return nil, err
+	}
+	inUse := make(map[string]bool, len(used))
+	for _, key := range used {
+		inUse[key] = true
+	}
+	unreferenced := keys[:0]
+	for _, key := range keys {
+		if !inUse[key] {
+			unreferenced = append(unreferenced, key)
+		}
+	}
+	return unreferenced, nil
+}
+
+// chunkRepo 文档分块存储
+type chunkRepo struct {
+	db *gorm.DB
+}
+
+func (r *chunkRepo) Create(ctx context.Context, chunks []model.RAGChunk) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).CreateInBatches(chunks, 100).Error
+}
+
+func (r *chunkRepo) List(ctx context.Context, documentID uint) ([]model.RAGChunk, error) {
+	var chunks []model.RAGChunk
+	if err := r.db.WithContext(ctx).Where("document_id = ? AND retired_version IS NULL", documentID).
+		Order("chunk_index").
+		Find(&chunks).Error; err != nil {
+		return nil, err
+	}
+	return chunks, nil
+}
+
+func (r *chunkRepo) ListVersion(ctx context.Context, documentID uint, version int) ([]model.RAGChunk, error) {
+	var chunks []model.RAGChunk
+	if err := r.db.WithContext(ctx).
+		Where("document_id = ? AND version <= ?", documentID, version).
+		Where("retired_version IS NULL OR retired_version > ?", version).
+		Order("chunk_index").
+		Find(&chunks).Error; err != nil {
+		return nil, err
+	}
+	return chunks, nil
+}
+
+func (r *chunkRepo) Search(ctx context.Context, space *model.EmbeddingSpace, scope ChunkScope, embedding []float32, topK int) ([]ChunkMatch, error) {
+	if len(embedding) != space.Dimensions {
+		return nil, fmt.Errorf("查询向量维度为 %d，与向量空间的 %d 不一致", len(embedding), space.Dimensions)
+	}
+
+	// 空间ID直接写入SQL，使PostgreSQL在预编译语句中也能匹配按空间创建的部分索引
+	scoped := func(db *gorm.DB) *gorm.DB {
+		query := db.Model(&model.RAGChunk{}).
+			Where(fmt.Sprintf("space_id = %d", space.ID)).
+			Where("embedding IS NOT NULL AND retired_version IS NULL")
+		if len(scope.KnowledgeBaseIDs) > 0 {
+			query = query.Where("knowledge_base_id IN ?", scope.KnowledgeBaseIDs)
+		} else {
+			query = query.Where("user_id = ?", scope.UserID)
+		}
+		return r.applyFilter(ctx, query, scope.Filter)
+	}
+
+	if !isPostgres(r.db) {
+		return searchBruteForce(scoped(r.db.WithContext(ctx)), embedding, topK)
+	}
+	// 知识库、用户和标签等过滤在索引扫描之后执行，只匹配少量分块时需要扩大或迭代索引扫描
+	var matches []ChunkMatch
+	err := withHNSWScan(r.db.WithContext(ctx), topK, "relaxed_order", func(tx *gorm.DB) error {
+		var err error
+		matches, err = searchPgvector(scoped(tx), space.Dimensions, embedding, topK)
+		return err
+	})
+	return matches, err
+}
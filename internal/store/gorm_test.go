@@ -0,0 +1,409 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"go-ai-copilot/internal/database"
+	"go-ai-copilot/internal/model"
+	"go-ai-copilot/internal/pagination"
+	"gorm.io/gorm/logger"
+)
+
+// newTestStore 基于临时SQLite数据库（已执行迁移）创建存储
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	if err := database.Open(database.Config{Driver: database.DriverSQLite, Path: t.TempDir() + "/test.db"}); err != nil {
+		t.Fatal(err)
+	}
+	db := database.DB
+	db.Logger = logger.Discard
+	if _, err := database.MigrateUp(db); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return New(db)
+}
+
+// createDocument 创建文档及其第一个版本
+func createDocument(t *testing.T, st *Store, doc *model.RAGDocument, blobKey string) *model.RAGDocumentVersion {
+	t.Helper()
+	version := &model.RAGDocumentVersion{FileName: doc.FileName, BlobKey: blobKey, Status: "processing"}
+	if err := st.Documents.Create(context.Background(), doc, version); err != nil {
+		t.Fatal(err)
+	}
+	return version
+}
+
+func TestDocumentApplyVersion(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.Background()
+
+	doc := &model.RAGDocument{UserID: 1, FileName: "a.md", FileType: "md", SourceKey: "a.md", Status: "processing"}
+	v1 := createDocument(t, st, doc, "")
+	if err := st.Documents.ApplyVersion(ctx, doc, v1, VersionChunks{Added: []model.RAGChunk{
+		{DocumentID: doc.ID, UserID: 1, Content: "one", ChunkIndex: 0, Version: 1},
+		{DocumentID: doc.ID, UserID: 1, Content: "two", ChunkIndex: 1, Version: 1},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	doc.Version = 1
+	old, err := st.Chunks.List(ctx, doc.ID)
+	if err != nil || len(old) != 2 {
+		t.Fatalf("第一个版本的分块 = %v, %v", old, err)
+	}
+	if err := st.Documents.SetMetadata(ctx, doc.ID, []string{"go"}, map[string]string{"lang": "zh"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 第二个版本：移除 one，two 移到开头，新增 three
+	v2 := &model.RAGDocumentVersion{DocumentID: doc.ID, FileName: "a.md", Status: "processing", ChunkCount: 2}
+	if err := st.Documents.CreateVersion(ctx, v2); err != nil {
+		t.Fatal(err)
+	}
+	if v2.Version != 2 {
+		t.Fatalf("新版本号 = %d，期望 2", v2.Version)
+	}
+	changes := VersionChunks{
+		Added:   []model.RAGChunk{{DocumentID: doc.ID, UserID: 1, Content: "three", ChunkIndex: 1, Version: 2}},
+		Moved:   map[uint]ChunkPosition{old[1].ID: {Index: 0, StartLine: 1, EndLine: 1}},
+		Retired: []uint{old[0].ID},
+	}
+	if err := st.Documents.ApplyVersion(ctx, doc, v2, changes); err != nil {
+		t.Fatal(err)
+	}
+
+	current, err := st.Chunks.List(ctx, doc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(current) != 2 || current[0].Content != "two" || current[1].Content != "three" {
+		t.Fatalf("当前版本的分块 = %+v", current)
+	}
+	if current[0].StartLine != 1 || current[0].EndLine != 1 {
+		t.Fatalf("移动的分块位置未更新: %+v", current[0])
+	}
+	// 新分块继承文档的标签和元数据
+	if len(current[1].Tags) != 1 || current[1].Tags[0] != "go" || current[1].Metadata["lang"] != "zh" {
+		t.Fatalf("新分块的标签和元数据 = %v %v", current[1].Tags, current[1].Metadata)
+	}
+
+	// 旧版本的分块保留用于审计
+	previous, err := st.Chunks.ListVersion(ctx, doc.ID, 1)
+	if err != nil || len(previous) != 2 {
+		t.Fatalf("第一个版本的分块 = %v, %v", previous, err)
+	}
+
+	got, err := st.Documents.Get(ctx, 1, doc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Version != 2 || got.Status != "completed" {
+		t.Fatalf("文档版本 = %d，状态 = %s", got.Version, got.Status)
+	}
+	saved, err := st.Documents.GetVersion(ctx, doc.ID, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Status != "completed" || saved.AddedChunks != 1 || saved.RemovedChunks != 1 || saved.ChunkCount != 2 {
+		t.Fatalf("版本记录 = %+v", saved)
+	}
+
+	// 基于过期的当前版本写入时返回错误，不修改分块
+	v3 := &model.RAGDocumentVersion{DocumentID: doc.ID, FileName: "a.md", Status: "processing"}
+	if err := st.Documents.CreateVersion(ctx, v3); err != nil {
+		t.Fatal(err)
+	}
+	stale := VersionChunks{
+		Added:   []model.RAGChunk{{DocumentID: doc.ID, UserID: 1, Content: "four", ChunkIndex: 2, Version: 3}},
+		Retired: []uint{current[0].ID},
+	}
+	if err := st.Documents.ApplyVersion(ctx, doc, v3, stale); !errors.Is(err, ErrDocumentChanged) {
+		t.Fatalf("过期版本的错误 = %v，期望 ErrDocumentChanged", err)
+	}
+	after, err := st.Chunks.List(ctx, doc.ID)
+	if err != nil || len(after) != 2 || after[0].ID != current[0].ID {
+		t.Fatalf("写入失败后分块发生了变化: %+v", after)
+	}
+}
+
+func TestDocumentUnreferencedBlobs(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.Background()
+
+	doc1 := &model.RAGDocument{UserID: 1, FileName: "a.md", FileType: "md", SourceKey: "a.md"}
+	createDocument(t, st, doc1, "1/own")
+	v2 := &model.RAGDocumentVersion{DocumentID: doc1.ID, FileName: "a.md", BlobKey: "1/shared"}
+	if err := st.Documents.CreateVersion(ctx, v2); err != nil {
+		t.Fatal(err)
+	}
+	v3 := &model.RAGDocumentVersion{DocumentID: doc1.ID, FileName: "a.md"} // 升级前的上传没有文件Key
+	if err := st.Documents.CreateVersion(ctx, v3); err != nil {
+		t.Fatal(err)
+	}
+	// 内容相同的上传共用文件
+	doc2 := &model.RAGDocument{UserID: 1, FileName: "b.md", FileType: "md", SourceKey: "b.md"}
+	createDocument(t, st, doc2, "1/shared")
+
+	if err := st.Documents.Delete(ctx, doc1); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := st.Documents.UnreferencedBlobs(ctx, []uint{doc1.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0] != "1/own" {
+		t.Fatalf("未引用的文件 = %v，期望 [1/own]", keys)
+	}
+
+	if err := st.Documents.Delete(ctx, doc2); err != nil {
+		t.Fatal(err)
+	}
+	keys, err = st.Documents.UnreferencedBlobs(ctx, []uint{doc1.ID, doc2.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(keys)
+	if len(keys) != 2 || keys[0] != "1/own" || keys[1] != "1/shared" {
+		t.Fatalf("未引用的文件 = %v，期望 [1/own 1/shared]", keys)
+	}
+
+	if keys, err := st.Documents.UnreferencedBlobs(ctx, nil); err != nil || len(keys) != 0 {
+		t.Fatalf("空的文档列表 = %v, %v", keys, err)
+	}
+}
+
+func TestPromptCreateVersion(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.Background()
+
+	create := func(mode string) *model.PromptTemplate {
+		t.Helper()
+		tpl := &model.PromptTemplate{Mode: mode, Content: "你好", CreatedBy: 1}
+		if err := st.Prompts.Create(ctx, tpl); err != nil {
+			t.Fatal(err)
+		}
+		return tpl
+	}
+
+	v1 := create("review")
+	v2 := create("review")
+	if v1.Version != 1 || v2.Version != 2 {
+		t.Fatalf("版本号 = %d, %d，期望 1, 2", v1.Version, v2.Version)
+	}
+	if other := create("other"); other.Version != 1 {
+		t.Fatalf("其他模式的版本号 = %d，期望 1", other.Version)
+	}
+
+	// 删除的版本号不复用
+	if err := st.Prompts.Delete(ctx, v2.ID); err != nil {
+		t.Fatal(err)
+	}
+	if v3 := create("review"); v3.Version != 3 {
+		t.Fatalf("删除后的版本号 = %d，期望 3", v3.Version)
+	}
+	if err := st.Prompts.Delete(ctx, v2.ID); !IsNotFound(err) {
+		t.Fatalf("重复删除 = %v，期望 ErrNotFound", err)
+	}
+}
+
+func TestStoreTransaction(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.Background()
+
+	errRollback := errors.New("rollback")
+	var sessionID uint
+	err := st.Transaction(ctx, func(tx *Store) error {
+		session := &model.Session{UserID: 1, Title: "导入"}
+		if err := tx.Sessions.Create(ctx, session); err != nil {
+			return err
+		}
+		sessionID = session.ID
+		if err := tx.Messages.Create(ctx, &model.Message{SessionID: session.ID, UserID: 1, Role: "user", Content: "hi"}); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("Transaction = %v，期望 %v", err, errRollback)
+	}
+	if _, err := st.Sessions.Get(ctx, 1, sessionID); !IsNotFound(err) {
+		t.Fatalf("回滚后的会话 = %v，期望 ErrNotFound", err)
+	}
+	if messages, err := st.Messages.List(ctx, sessionID); err != nil || len(messages) != 0 {
+		t.Fatalf("回滚后的消息 = %v, %v", messages, err)
+	}
+}
+
+func TestMessageHistory(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.Background()
+
+	session := &model.Session{UserID: 1, Title: "分支"}
+	if err := st.Sessions.Create(ctx, session); err != nil {
+		t.Fatal(err)
+	}
+	add := func(parent *model.Message, role, content string) *model.Message {
+		t.Helper()
+		msg := &model.Message{SessionID: session.ID, UserID: 1, Role: role, Content: content}
+		if parent != nil {
+			msg.ParentID = &parent.ID
+		}
+		if err := st.Messages.Create(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	}
+	// q1 -> a1 -> q2 -> a2，a1 另有一个分支 a1'
+	q1 := add(nil, "user", "q1")
+	a1 := add(q1, "assistant", "a1")
+	add(q1, "assistant", "a1'")
+	q2 := add(a1, "user", "q2")
+	a2 := add(q2, "assistant", "a2")
+
+	history := func(q HistoryQuery, limit int, cursor string, desc bool) ([]string, string) {
+		t.Helper()
+		page, err := pagination.NewRequest(limit, cursor, pagination.Order{Column: "depth", Desc: desc}, pagination.Order{Column: "id", Desc: desc})
+		if err != nil {
+			t.Fatal(err)
+		}
+		messages, err := st.Messages.History(ctx, q, page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := pagination.Build(page, messages, func(m BranchMessage) []interface{} {
+			return []interface{}{m.Depth, m.ID}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var contents []string
+		for _, m := range result.Items {
+			contents = append(contents, m.Content)
+		}
+		return contents, result.NextCursor
+	}
+
+	q := HistoryQuery{SessionID: session.ID, LeafID: a2.ID}
+	first, cursor := history(q, 3, "", true)
+	rest, next := history(q, 3, cursor, true)
+	if !reflect.DeepEqual(first, []string{"a2", "q2", "a1"}) || !reflect.DeepEqual(rest, []string{"q1"}) || next != "" {
+		t.Fatalf("分支历史 = %v, %v", first, rest)
+	}
+	if got, _ := history(HistoryQuery{SessionID: session.ID, LeafID: a2.ID, Role: "user"}, 10, "", false); !reflect.DeepEqual(got, []string{"q1", "q2"}) {
+		t.Fatalf("用户消息 = %v", got)
+	}
+	if got, _ := history(HistoryQuery{SessionID: session.ID, LeafID: a1.ID}, 10, "", false); !reflect.DeepEqual(got, []string{"q1", "a1"}) {
+		t.Fatalf("到 a1 的分支 = %v", got)
+	}
+
+	page, _ := pagination.NewRequest(10, "", pagination.Order{Column: "depth"}, pagination.Order{Column: "id"})
+	if _, err := st.Messages.History(ctx, HistoryQuery{SessionID: session.ID + 1, LeafID: a2.ID}, page); !IsNotFound(err) {
+		t.Fatalf("其他会话的消息 = %v，期望 ErrNotFound", err)
+	}
+}
+
+func TestDocumentClaimStaleVersion(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.Background()
+
+	doc := &model.RAGDocument{UserID: 1, FileName: "a.md", FileType: "md", SourceKey: "a.md", Status: "processing"}
+	version := createDocument(t, st, doc, "1/a.md")
+
+	// 刚创建的版本有心跳，不会被接管
+	if versions, err := st.Documents.StaleVersions(ctx, time.Now().Add(-time.Minute)); err != nil || len(versions) != 0 {
+		t.Fatalf("心跳未超时的版本 = %v, %v", versions, err)
+	}
+
+	staleBefore := time.Now().Add(time.Minute)
+	versions, err := st.Documents.StaleVersions(ctx, staleBefore)
+	if err != nil || len(versions) != 1 || versions[0].ID != version.ID || versions[0].BlobKey != "1/a.md" {
+		t.Fatalf("心跳超时的版本 = %v, %v", versions, err)
+	}
+	if claimed, err := st.Documents.ClaimVersion(ctx, version.ID, time.Now().Add(-time.Minute)); err != nil || claimed {
+		t.Fatalf("领取心跳未超时的版本 = %v, %v", claimed, err)
+	}
+	if claimed, err := st.Documents.ClaimVersion(ctx, version.ID, staleBefore); err != nil || !claimed {
+		t.Fatalf("领取心跳超时的版本 = %v, %v", claimed, err)
+	}
+
+	// 处理失败的版本不再接管
+	if err := st.Documents.FailVersion(ctx, doc, version); err != nil {
+		t.Fatal(err)
+	}
+	if versions, err := st.Documents.StaleVersions(ctx, time.Now().Add(time.Hour)); err != nil || len(versions) != 0 {
+		t.Fatalf("失败后的版本 = %v, %v", versions, err)
+	}
+	if claimed, _ := st.Documents.ClaimVersion(ctx, version.ID, time.Now().Add(time.Hour)); claimed {
+		t.Fatal("领取了处理失败的版本")
+	}
+}
+
+func TestDocumentSourceUnique(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.Background()
+
+	doc := &model.RAGDocument{UserID: 1, FileName: "a.md", FileType: "md", SourceKey: "docs/a.md", Status: "processing"}
+	createDocument(t, st, doc, "")
+	dup := &model.RAGDocument{UserID: 1, FileName: "a.md", FileType: "md", SourceKey: "docs/a.md", Status: "processing"}
+	if err := st.Documents.Create(ctx, dup, &model.RAGDocumentVersion{FileName: "a.md", Status: "processing"}); err == nil {
+		t.Fatal("相同的文档标识创建成功，期望唯一索引冲突")
+	}
+	// 其他知识库、其他用户可以使用相同的标识
+	createDocument(t, st, &model.RAGDocument{UserID: 1, KnowledgeBaseID: 2, FileName: "a.md", FileType: "md", SourceKey: "docs/a.md"}, "")
+	createDocument(t, st, &model.RAGDocument{UserID: 2, FileName: "a.md", FileType: "md", SourceKey: "docs/a.md"}, "")
+
+	// 删除后可以重新上传
+	if err := st.Documents.Delete(ctx, doc); err != nil {
+		t.Fatal(err)
+	}
+	createDocument(t, st, &model.RAGDocument{UserID: 1, FileName: "a.md", FileType: "md", SourceKey: "docs/a.md"}, "")
+}
+
+func TestMessageKeywordTrigram(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.Background()
+
+	// SQLite没有需要检查的索引
+	if err := st.MessageSearch.CheckTextIndex(ctx, TrigramSearch); err != nil {
+		t.Fatal(err)
+	}
+
+	session := &model.Session{UserID: 1, Title: "搜索"}
+	if err := st.Sessions.Create(ctx, session); err != nil {
+		t.Fatal(err)
+	}
+	for _, content := range []string{"如何配置向量索引", "100%_完成", "无关内容"} {
+		if err := st.Messages.Create(ctx, &model.Message{SessionID: session.ID, UserID: 1, Role: "user", Content: content}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	search := func(keyword string) []string {
+		t.Helper()
+		page, err := st.MessageSearch.Keyword(ctx, MessageSearchQuery{UserID: 1, Keyword: keyword, TextConfig: TrigramSearch}, 10, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var contents []string
+		for _, h := range page.Items {
+			contents = append(contents, h.Content)
+		}
+		return contents
+	}
+	if got := search("向量"); !reflect.DeepEqual(got, []string{"如何配置向量索引"}) {
+		t.Fatalf("中文子串 = %v", got)
+	}
+	// LIKE通配符按字面匹配
+	if got := search("%_"); !reflect.DeepEqual(got, []string{"100%_完成"}) {
+		t.Fatalf("通配符 = %v", got)
+	}
+}
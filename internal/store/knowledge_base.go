@@ -0,0 +1,116 @@
+package store
+
+import (
+	"context"
+
+	"go-ai-copilot/internal/model"
+	"gorm.io/gorm"
+)
+
+// KnowledgeBaseRepository 知识库存储
+type KnowledgeBaseRepository interface {
+	Create(ctx context.Context, kb *model.KnowledgeBase) error
+	// Get 获取知识库，不校验访问权限（如连接器和管理员任务）
+	Get(ctx context.Context, id uint) (*model.KnowledgeBase, error)
+	// GetOwned 获取用户创建的知识库
+	GetOwned(ctx context.Context, userID, id uint) (*model.KnowledgeBase, error)
+	// List 用户可访问的知识库（自己创建的和团队共享的），按创建时间倒序
+	List(ctx context.Context, userID uint) ([]model.KnowledgeBase, error)
+	// Accessible 过滤出用户可访问的知识库ID
+	Accessible(ctx context.Context, userID uint, ids []uint) ([]uint, error)
+	// Update 更新用户创建的知识库，不存在时返回 ErrNotFound
+	Update(ctx context.Context, userID, id uint, updates map[string]interface{}) error
+	// Delete 在一个事务中删除知识库及其中的文档、分块和网页来源，返回删除的文档ID
+	Delete(ctx context.Context, kb *model.KnowledgeBase) ([]uint, error)
+}
+
+// knowledgeBaseRepo 知识库存储
+type knowledgeBaseRepo struct {
+	db *gorm.DB
+}
+
+func (r *knowledgeBaseRepo) Create(ctx context.Context, kb *model.KnowledgeBase) error {
+	return r.db.WithContext(ctx).Create(kb).Error
+}
+
+func (r *knowledgeBaseRepo) Get(ctx context.Context, id uint) (*model.KnowledgeBase, error) {
+	var kb model.KnowledgeBase
+	if err := r.db.WithContext(ctx).First(&kb, id).Error; err != nil {
+		return nil, err
+	}
+	return &kb, nil
+}
+
+func (r *knowledgeBaseRepo) GetOwned(ctx context.Context, userID, id uint) (*model.KnowledgeBase, error) {
+	var kb model.KnowledgeBase
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&kb).Error; err != nil {
+		return nil, err
+	}
+	return &kb, nil
+}
+
+func (r *knowledgeBaseRepo) List(ctx context.Context, userID uint) ([]model.KnowledgeBase, error) {
+	var kbs []model.KnowledgeBase
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? OR visibility = ?", userID, model.VisibilityTeam).
+		Order("created_at DESC").
+		Find(&kbs).Error; err != nil {
+		return nil, err
+	}
+	return kbs, nil
+}
+
+func (r *knowledgeBaseRepo) Accessible(ctx context.Context, userID uint, ids []uint) ([]uint, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var accessible []uint
+	if err := r.db.WithContext(ctx).Model(&model.KnowledgeBase{}).
+		Where("id IN ?", ids).
+		Where("user_id = ? OR visibility = ?", userID, model.VisibilityTeam).
+		Pluck("id", &accessible).Error; err != nil {
+		return nil, err
+	}
+	return accessible, nil
+}
+
+func (r *knowledgeBaseRepo) Update(ctx context.Context, userID, id uint, updates map[string]interface{}) error {
+	result := r.db.WithContext(ctx).Model(&model.KnowledgeBase{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *knowledgeBaseRepo) Delete(ctx context.Context, kb *model.KnowledgeBase) ([]uint, error) {
+	var docIDs []uint
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.RAGDocument{}).Where("knowledge_base_id = ?", kb.ID).Pluck("id", &docIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("knowledge_base_id = ?", kb.ID).Delete(&model.RAGChunk{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("knowledge_base_id = ?", kb.ID).Delete(&model.RAGDocument{}).Error; err != nil {
+			return err
+		}
+		// 知识库的网页来源及其页面的同步记录，正在进行的抓取在下一个页面时停止
+		if err := tx.Where("knowledge_base_id = ? AND connector LIKE ?", kb.ID, "web:%").Delete(&model.ConnectorFile{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("knowledge_base_id = ?", kb.ID).Delete(&model.WebSource{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(kb).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return docIDs, nil
+}
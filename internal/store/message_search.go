@@ -0,0 +1,316 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go-ai-copilot/internal/model"
+	"go-ai-copilot/internal/pagination"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 全文检索摘要的高亮标记，由调用方转义内容后替换为实际的标签
+const (
+	HighlightStart = "\x01"
+	HighlightStop  = "\x02"
+)
+
+// TrigramSearch 关键词搜索使用 pg_trgm 三元组索引做子串匹配（默认），其他配置名为PostgreSQL全文检索配置
+const TrigramSearch = "trigram"
+
+// trigramIndex 三元组索引，由迁移创建
+const trigramIndex = "idx_chat_messages_content_trgm"
+
+// MessageSearchQuery 聊天记录搜索条件
+type MessageSearchQuery struct {
+	UserID     uint
+	Keyword    string
+	Mode       string     // 会话模式
+	Role       string     // user / assistant
+	From       *time.Time // 消息创建时间下限（含）
+	To         *time.Time // 消息创建时间上限（不含）
+	TextConfig string     // TrigramSearch 或PostgreSQL全文检索配置（合法标识符，直接写入SQL）
+}
+
+// MessageHit 聊天记录搜索结果
+type MessageHit struct {
+	MessageID    uint
+	SessionID    uint
+	SessionTitle string
+	SessionMode  string
+	Role         string
+	Content      string
+	Snippet      string // 全文检索生成的摘要（关键词以 HighlightStart/HighlightStop 标记），子串匹配时为空
+	Score        float64
+	CreatedAt    time.Time
+}
+
+// MessageSearchRepository 聊天记录搜索
+type MessageSearchRepository interface {
+	// SaveEmbeddings 保存消息向量，同一向量空间中已有向量的消息跳过
+	SaveEmbeddings(ctx context.Context, rows []model.MessageEmbedding) error
+	// CheckTextIndex 检查关键词搜索配置对应的索引是否存在且有效（仅PostgreSQL），缺失时返回包含建索引语句的错误
+	CheckTextIndex(ctx context.Context, config string) error
+	// Keyword 按关键词搜索：PostgreSQL使用三元组子串匹配或全文检索并按相关度排序，SQLite使用子串匹配并按时间倒序
+	// cursor 无法解析时返回 pagination.ErrInvalidCursor
+	Keyword(ctx context.Context, q MessageSearchQuery, limit int, cursor string) (*pagination.Page[MessageHit], error)
+	// Semantic 在向量空间内按余弦相似度搜索，threshold>0 时只返回相似度不低于该值的消息
+	// cursor 无法解析时返回 pagination.ErrInvalidCursor
+	Semantic(ctx context.Context, space *model.EmbeddingSpace, q MessageSearchQuery, embedding []float32, threshold float64, limit int, cursor string) (*pagination.Page[MessageHit], error)
+}
+
+// messageSearchRepo 聊天记录搜索
+type messageSearchRepo struct {
+	db *gorm.DB
+}
+
+func (r *messageSearchRepo) SaveEmbeddings(ctx context.Context, rows []model.MessageEmbedding) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error
+}
+
+// CheckTextIndex 索引定义必须与 Keyword 查询中的表达式一致
+// 在运行中的库上建索引耗时且需要 CONCURRENTLY（不能在事务中执行），由迁移或运维创建，这里只检查
+func (r *messageSearchRepo) CheckTextIndex(ctx context.Context, config string) error {
+	if !isPostgres(r.db) {
+		return nil
+	}
+	name := trigramIndex
+	create := fmt.Sprintf(
+		"CREATE EXTENSION IF NOT EXISTS pg_trgm; CREATE INDEX CONCURRENTLY %s ON chat_messages USING gin (content gin_trgm_ops);", name,
+	)
+	if config != TrigramSearch {
+		name = "idx_chat_messages_fts_" + config
+		create = fmt.Sprintf(
+			"CREATE INDEX CONCURRENTLY %s ON chat_messages USING gin (to_tsvector('%s', content));", name, config,
+		)
+	}
+
+	var valid []bool
+	if err := r.db.WithContext(ctx).Raw(
+		"SELECT i.indisvalid FROM pg_class c JOIN pg_index i ON i.indexrelid = c.oid WHERE c.relname = ?", name,
+	).Scan(&valid).Error; err != nil {
+		return fmt.Errorf("检查关键词搜索索引 %s 失败: %w", name, err)
+	}
+	if len(valid) == 0 {
+		return fmt.Errorf("关键词搜索索引 %s 不存在，请在数据库中执行: %s", name, create)
+	}
+	if !valid[0] {
+		// 中断的并发创建会留下无效索引
+		return fmt.Errorf("关键词搜索索引 %s 无效，请在数据库中执行: DROP INDEX CONCURRENTLY %s; %s", name, name, create)
+	}
+	return nil
+}
+
+func (r *messageSearchRepo) Keyword(ctx context.Context, q MessageSearchQuery, limit int, cursor string) (*pagination.Page[MessageHit], error) {
+	if !isPostgres(r.db) {
+		return r.keywordLike(ctx, q, limit, cursor)
+	}
+
+	// 相关度在子查询中计算，外层按相关度、时间和消息ID翻页
+	page, err := pagination.NewRequest(limit, cursor,
+		pagination.Order{Column: "r.score", Desc: true},
+		pagination.Order{Column: "r.created_at", Desc: true},
+		pagination.Order{Column: "r.message_id", Desc: true},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	query := base(r.db.WithContext(ctx), q, "chat_messages m")
+	if q.TextConfig == TrigramSearch {
+		// 子串匹配使用三元组索引，相关度为关键词与内容中最相近片段的相似度，摘要由调用方生成
+		query = query.
+			Select(
+				"m.id AS message_id, m.session_id, s.title AS session_title, s.mode AS session_mode, m.role, m.created_at, m.content, "+
+					"word_similarity(?, m.content) AS score",
+				q.Keyword,
+			).
+			Where(`m.content ILIKE ? ESCAPE '\'`, "%"+escapeLike(q.Keyword)+"%")
+	} else {
+		tsvector := fmt.Sprintf("to_tsvector('%s', m.content)", q.TextConfig)
+		tsquery := fmt.Sprintf("plainto_tsquery('%s', ?)", q.TextConfig)
+		options := fmt.Sprintf("StartSel=%s,StopSel=%s,MaxFragments=2,MaxWords=30,MinWords=10", HighlightStart, HighlightStop)
+		query = query.
+			Select(
+				"m.id AS message_id, m.session_id, s.title AS session_title, s.mode AS session_mode, m.role, m.created_at, m.content, "+
+					"ts_rank("+tsvector+", "+tsquery+") AS score, "+
+					"ts_headline('"+q.TextConfig+"', m.content, "+tsquery+", ?) AS snippet",
+				q.Keyword, q.Keyword, options,
+			).
+			Where(tsvector+" @@ "+tsquery, q.Keyword)
+	}
+
+	var hits []MessageHit
+	if err := page.Apply(r.db.WithContext(ctx).Table("(?) AS r", query)).Scan(&hits).Error; err != nil {
+		return nil, err
+	}
+	return pagination.Build(page, hits, func(h MessageHit) []interface{} {
+		return []interface{}{h.Score, h.CreatedAt, h.MessageID}
+	})
+}
+
+// keywordLike 子串匹配（SQLite的LIKE对ASCII字符不区分大小写），按时间倒序
+func (r *messageSearchRepo) keywordLike(ctx context.Context, q MessageSearchQuery, limit int, cursor string) (*pagination.Page[MessageHit], error) {
+	page, err := pagination.NewRequest(limit, cursor,
+		pagination.Order{Column: "m.created_at", Desc: true},
+		pagination.Order{Column: "m.id", Desc: true},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	query := base(r.db.WithContext(ctx), q, "chat_messages m").
+		Select("m.id AS message_id, m.session_id, s.title AS session_title, s.mode AS session_mode, m.role, m.created_at, m.content").
+		Where(`m.content LIKE ? ESCAPE '\'`, "%"+escapeLike(q.Keyword)+"%")
+
+	var hits []MessageHit
+	if err := page.Apply(query).Scan(&hits).Error; err != nil {
+		return nil, err
+	}
+	return pagination.Build(page, hits, func(h MessageHit) []interface{} {
+		return []interface{}{h.CreatedAt, h.MessageID}
+	})
+}
+
+// semanticOrders 语义搜索的排序：相似度从高到低，相同时按消息ID
+var semanticOrders = []pagination.Order{
+	{Column: "score", Desc: true},
+	{Column: "message_id"},
+}
+
+// semanticValues 语义搜索结果在排序列上的值
+func semanticValues(h MessageHit) []interface{} {
+	return []interface{}{h.Score, h.MessageID}
+}
+
+func (r *messageSearchRepo) Semantic(ctx context.Context, space *model.EmbeddingSpace, q MessageSearchQuery, embedding []float32, threshold float64, limit int, cursor string) (*pagination.Page[MessageHit], error) {
+	page, err := pagination.NewRequest(limit, cursor, semanticOrders...)
+	if err != nil {
+		return nil, err
+	}
+	if !isPostgres(r.db) {
+		return r.semanticBruteForce(ctx, space, q, embedding, threshold, page)
+	}
+
+	var hits []MessageHit
+	// 带过滤条件的向量索引扫描：游标按相似度比较，需要严格按距离排序的迭代扫描
+	err = withHNSWScan(r.db.WithContext(ctx), page.Limit+1, "strict_order", func(tx *gorm.DB) error {
+		return r.semanticQuery(tx, space, q, embedding, threshold, page).Scan(&hits).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pagination.Build(page, hits, semanticValues)
+}
+
+// semanticQuery 语义搜索的查询（PostgreSQL），按距离排序，使用向量空间的向量索引
+func (r *messageSearchRepo) semanticQuery(db *gorm.DB, space *model.EmbeddingSpace, q MessageSearchQuery, embedding []float32, threshold float64, page *pagination.Request) *gorm.DB {
+	vec := model.Vector(embedding)
+	distance := VectorExpr("e.embedding", space.Dimensions) + " <=> ?"
+
+	query := base(db, q, embeddingsFrom(space)).
+		Select(
+			"m.id AS message_id, m.session_id, s.title AS session_title, s.mode AS session_mode, m.role, m.created_at, m.content, "+
+				"1 - ("+distance+") AS score",
+			vec,
+		).
+		Order(clause.Expr{SQL: distance, Vars: []interface{}{vec}}).
+		Order("m.id ASC").
+		Limit(page.Limit + 1)
+	if threshold > 0 {
+		query = query.Where("1 - ("+distance+") >= ?", vec, threshold)
+	}
+	// 游标条件直接写在距离表达式上（不包子查询），排序仍可使用向量索引
+	if after := page.After(); after != nil {
+		score := "1 - (" + distance + ")"
+		query = query.Where("("+score+" < ? OR ("+score+" = ? AND m.id > ?))", vec, after[0], vec, after[0], after[1])
+	}
+	return query
+}
+
+// semanticBruteForce 在进程内计算相似度（没有向量索引的数据库，如SQLite）
+func (r *messageSearchRepo) semanticBruteForce(ctx context.Context, space *model.EmbeddingSpace, q MessageSearchQuery, embedding []float32, threshold float64, page *pagination.Request) (*pagination.Page[MessageHit], error) {
+	var candidates []struct {
+		MessageHit
+		Embedding model.Vector `gorm:"type:vector"`
+	}
+	if err := base(r.db.WithContext(ctx), q, embeddingsFrom(space)).
+		Select("m.id AS message_id, m.session_id, s.title AS session_title, s.mode AS session_mode, m.role, m.created_at, m.content, e.embedding").
+		Scan(&candidates).Error; err != nil {
+		return nil, err
+	}
+
+	var afterScore float64
+	var afterID uint
+	after := page.After()
+	if after != nil {
+		var ok1, ok2 bool
+		afterScore, ok1 = after[0].(float64)
+		afterID, ok2 = after[1].(uint)
+		if !ok1 || !ok2 {
+			return nil, pagination.ErrInvalidCursor
+		}
+	}
+
+	hits := make([]MessageHit, 0, len(candidates))
+	for _, c := range candidates {
+		c.Score = Cosine(embedding, c.Embedding)
+		if threshold > 0 && c.Score < threshold {
+			continue
+		}
+		// 跳过上一页及之前的结果
+		if after != nil && (c.Score > afterScore || (c.Score == afterScore && c.MessageID <= afterID)) {
+			continue
+		}
+		hits = append(hits, c.MessageHit)
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].MessageID < hits[j].MessageID
+	})
+	if len(hits) > page.Limit+1 {
+		hits = hits[:page.Limit+1]
+	}
+	return pagination.Build(page, hits, semanticValues)
+}
+
+// embeddingsFrom 向量空间内的消息向量关联消息
+// 空间ID直接写入SQL，使PostgreSQL在预编译语句中也能匹配按空间创建的部分索引
+func embeddingsFrom(space *model.EmbeddingSpace) string {
+	return fmt.Sprintf("message_embeddings e JOIN chat_messages m ON m.id = e.message_id AND m.deleted_at IS NULL AND e.space_id = %d", space.ID)
+}
+
+// base 构建公共的过滤条件（用户、会话模式、角色和时间范围）
+func base(db *gorm.DB, q MessageSearchQuery, from string) *gorm.DB {
+	query := db.
+		Table(from).
+		Joins("JOIN sessions s ON s.id = m.session_id AND s.deleted_at IS NULL").
+		Where("m.deleted_at IS NULL AND m.user_id = ?", q.UserID)
+	if q.Mode != "" {
+		query = query.Where("s.mode = ?", q.Mode)
+	}
+	if q.Role != "" {
+		query = query.Where("m.role = ?", q.Role)
+	}
+	if q.From != nil {
+		query = query.Where("m.created_at >= ?", *q.From)
+	}
+	if q.To != nil {
+		query = query.Where("m.created_at < ?", *q.To)
+	}
+	return query
+}
+
+// escapeLike 转义LIKE模式中的特殊字符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
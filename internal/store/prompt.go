@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"go-ai-copilot/internal/model"
+	"gorm.io/gorm"
+)
+
+// PromptRepository 提示词模板存储
+type PromptRepository interface {
+	// Create 发布模式的新版本，版本号在事务中分配（包含已删除的版本，避免版本号复用）
+	Create(ctx context.Context, tpl *model.PromptTemplate) error
+	Get(ctx context.Context, id uint) (*model.PromptTemplate, error)
+	// LatestVersion 模式已发布的最大版本号（包含已删除的版本），没有发布过时为0
+	LatestVersion(ctx context.Context, mode string) (int, error)
+	// List 按模式、版本倒序列出模板，mode为空时列出全部
+	List(ctx context.Context, mode string) ([]model.PromptTemplate, error)
+	// Update 更新指定字段，不存在时返回 ErrNotFound
+	Update(ctx context.Context, id uint, updates map[string]interface{}) error
+	// Delete 软删除模板版本，不存在时返回 ErrNotFound
+	Delete(ctx context.Context, id uint) error
+	// Pin 固定模式使用该版本，同时取消同一模式下其他版本的固定
+	Pin(ctx context.Context, tpl *model.PromptTemplate) error
+}
+
+// promptRepo 提示词模板存储
+type promptRepo struct {
+	db *gorm.DB
+}
+
+func (r *promptRepo) Create(ctx context.Context, tpl *model.PromptTemplate) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		latest, err := latestVersion(tx, tpl.Mode)
+		if err != nil {
+			return err
+		}
+		tpl.Version = latest + 1
+		return tx.Create(tpl).Error
+	})
+}
+
+func (r *promptRepo) LatestVersion(ctx context.Context, mode string) (int, error) {
+	return latestVersion(r.db.WithContext(ctx), mode)
+}
+
+// latestVersion 模式的最大版本号（包含已删除的版本）
+func latestVersion(db *gorm.DB, mode string) (int, error) {
+	var latest model.PromptTemplate
+	err := db.Unscoped().Where("mode = ?", mode).Order("version DESC").First(&latest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return latest.Version, nil
+}
+
+func (r *promptRepo) Get(ctx context.Context, id uint) (*model.PromptTemplate, error) {
+	var tpl model.PromptTemplate
+	if err := r.db.WithContext(ctx).First(&tpl, id).Error; err != nil {
+		return nil, err
+	}
+	return &tpl, nil
+}
+
+func (r *promptRepo) List(ctx context.Context, mode string) ([]model.PromptTemplate, error) {
+	query := r.db.WithContext(ctx).Order("mode ASC, version DESC")
+	if mode != "" {
+		query = query.Where("mode = ?", mode)
+	}
+
+	var templates []model.PromptTemplate
+	if err := query.Find(&templates).Error; err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+func (r *promptRepo) Update(ctx context.Context, id uint, updates map[string]interface{}) error {
+	result := r.db.WithContext(ctx).Model(&model.PromptTemplate{}).Where("id = ?", id).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *promptRepo) Delete(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&model.PromptTemplate{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *promptRepo) Pin(ctx context.Context, tpl *model.PromptTemplate) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.PromptTemplate{}).
+			Where("mode = ? AND pinned = ?", tpl.Mode, true).
+			Update("pinned", false).Error; err != nil {
+			return err
+		}
+		return tx.Model(tpl).Update("pinned", true).Error
+	})
+}
@@ -0,0 +1,218 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go-ai-copilot/internal/model"
+	"gorm.io/gorm"
+)
+
+// ErrJobStopped 任务已不在执行中（被取消或被其他实例接管）
+var ErrJobStopped = errors.New("任务已停止")
+
+// ReindexRepository 重建向量任务存储
+type ReindexRepository interface {
+	// Create 创建任务，并统计需要重建的分块数
+	Create(ctx context.Context, job *model.ReindexJob) error
+	Get(ctx context.Context, id uint) (*model.ReindexJob, error)
+	// List 最近的任务，按创建时间倒序
+	List(ctx context.Context, limit int) ([]model.ReindexJob, error)
+	// Resumable 目标空间中待执行、或执行中但心跳早于staleBefore（执行的实例已退出）的任务
+	Resumable(ctx context.Context, spaceID uint, staleBefore time.Time) ([]model.ReindexJob, error)
+	// Claim 将任务置为执行中，只能领取待执行、已中断、失败或心跳超时的任务，成功领取时返回true
+	Claim(ctx context.Context, id uint, staleBefore time.Time) (bool, error)
+	// NextChunks 游标之后、不在目标空间的分块，按ID排序
+	NextChunks(ctx context.Context, job *model.ReindexJob, limit int) ([]model.RAGChunk, error)
+	// SaveBatch 写入一批影子向量并推进游标（同时更新心跳），任务已不在执行中时返回ErrJobStopped
+	SaveBatch(ctx context.Context, job *model.ReindexJob, chunks []model.RAGChunk, embeddings [][]float32) error
+	// Switch 在一个事务中用影子向量替换分块的向量并完成任务，返回替换的分块数
+	Switch(ctx context.Context, job *model.ReindexJob) (int64, error)
+	// Stop 将执行中的任务置为已中断或失败，保留已生成的影子向量以便继续执行
+	Stop(ctx context.Context, id uint, status, message string) error
+	// Cancel 取消未结束的任务并删除影子向量，任务已结束时返回false
+	Cancel(ctx context.Context, id uint) (bool, error)
+}
+
+// reindexRepo 重建向量任务存储
+type reindexRepo struct {
+	db *gorm.DB
+}
+
+// pendingChunks 需要重建的分块：不在目标空间、属于文档当前版本、属于任务范围
+func (r *reindexRepo) pendingChunks(tx *gorm.DB, job *model.ReindexJob) *gorm.DB {
+	query := tx.Model(&model.RAGChunk{}).Where("space_id <> ? AND retired_version IS NULL", job.SpaceID)
+	if job.KnowledgeBaseID != nil {
+		query = query.Where("knowledge_base_id = ?", *job.KnowledgeBaseID)
+	}
+	return query
+}
+
+func (r *reindexRepo) Create(ctx context.Context, job *model.ReindexJob) error {
+	db := r.db.WithContext(ctx)
+	if err := r.pendingChunks(db, job).Count(&job.Total).Error; err != nil {
+		return err
+	}
+	job.Status = model.ReindexPending
+	return db.Create(job).Error
+}
+
+func (r *reindexRepo) Get(ctx context.Context, id uint) (*model.ReindexJob, error) {
+	var job model.ReindexJob
+	if err := r.db.WithContext(ctx).First(&job, id).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *reindexRepo) List(ctx context.Context, limit int) ([]model.ReindexJob, error) {
+	var jobs []model.ReindexJob
+	if err := r.db.WithContext(ctx).Order("id DESC").Limit(limit).Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (r *reindexRepo) Resumable(ctx context.Context, spaceID uint, staleBefore time.Time) ([]model.ReindexJob, error) {
+	var jobs []model.ReindexJob
+	if err := r.db.WithContext(ctx).
+		Where("space_id = ?", spaceID).
+		Where("status = ? OR (status = ? AND updated_at < ?)", model.ReindexPending, model.ReindexRunning, staleBefore).
+		Order("id").
+		Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (r *reindexRepo) Claim(ctx context.Context, id uint, staleBefore time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.ReindexJob{}).
+		Where("id = ?", id).
+		Where("status IN ? OR (status = ? AND updated_at < ?)",
+			[]string{model.ReindexPending, model.ReindexPaused, model.ReindexFailed}, model.ReindexRunning, staleBefore).
+		Updates(map[string]interface{}{
+			"status":     model.ReindexRunning,
+			"error":      "",
+			"updated_at": time.Now(),
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *reindexRepo) NextChunks(ctx context.Context, job *model.ReindexJob, limit int) ([]model.RAGChunk, error) {
+	var chunks []model.RAGChunk
+	if err := r.pendingChunks(r.db.WithContext(ctx), job).
+		Select("id", "content").
+		Where("id > ?", job.LastChunkID).
+		Order("id").
+		Limit(limit).
+		Find(&chunks).Error; err != nil {
+		return nil, err
+	}
+	return chunks, nil
+}
+
+func (r *reindexRepo) SaveBatch(ctx context.Context, job *model.ReindexJob, chunks []model.RAGChunk, embeddings [][]float32) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+	lastID := chunks[len(chunks)-1].ID
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 先更新任务（PostgreSQL中同时锁住任务行），取消任务与写入影子向量不会交错
+		result := tx.Model(&model.ReindexJob{}).
+			Where("id = ? AND status = ?", job.ID, model.ReindexRunning).
+			Updates(map[string]interface{}{
+				"last_chunk_id": lastID,
+				"processed":     gorm.Expr("processed + ?", len(chunks)),
+				"updated_at":    time.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrJobStopped
+		}
+
+		rows := make([]model.ReindexEmbedding, len(chunks))
+		for i, chunk := range chunks {
+			rows[i] = model.ReindexEmbedding{JobID: job.ID, ChunkID: chunk.ID, Embedding: embeddings[i]}
+		}
+		if err := tx.CreateInBatches(rows, 100).Error; err != nil {
+			return err
+		}
+
+		job.LastChunkID = lastID
+		job.Processed += int64(len(chunks))
+		return nil
+	})
+}
+
+func (r *reindexRepo) Switch(ctx context.Context, job *model.ReindexJob) (int64, error) {
+	var switched int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&model.ReindexJob{}).
+			Where("id = ? AND status = ?", job.ID, model.ReindexRunning).
+			Updates(map[string]interface{}{
+				"status":      model.ReindexCompleted,
+				"updated_at":  now,
+				"finished_at": now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrJobStopped
+		}
+
+		// 相关子查询在PostgreSQL和SQLite中都可用
+		result = tx.Exec(`UPDATE rag_chunks SET
+				embedding = (SELECT e.embedding FROM reindex_embeddings e WHERE e.job_id = ? AND e.chunk_id = rag_chunks.id),
+				space_id = ?,
+				updated_at = ?
+			WHERE id IN (SELECT chunk_id FROM reindex_embeddings WHERE job_id = ?)`,
+			job.ID, job.SpaceID, now, job.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		switched = result.RowsAffected
+
+		if err := tx.Where("job_id = ?", job.ID).Delete(&model.ReindexEmbedding{}).Error; err != nil {
+			return err
+		}
+		job.Status = model.ReindexCompleted
+		job.FinishedAt = &now
+		return nil
+	})
+	return switched, err
+}
+
+func (r *reindexRepo) Stop(ctx context.Context, id uint, status, message string) error {
+	return r.db.WithContext(ctx).Model(&model.ReindexJob{}).
+		Where("id = ? AND status = ?", id, model.ReindexRunning).
+		Updates(map[string]interface{}{
+			"status":     status,
+			"error":      message,
+			"updated_at": time.Now(),
+		}).Error
+}
+
+func (r *reindexRepo) Cancel(ctx context.Context, id uint) (bool, error) {
+	canceled := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&model.ReindexJob{}).
+			Where("id = ? AND status NOT IN ?", id, []string{model.ReindexCompleted, model.ReindexCanceled}).
+			Updates(map[string]interface{}{
+				"status":      model.ReindexCanceled,
+				"updated_at":  now,
+				"finished_at": now,
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		canceled = true
+		return tx.Where("job_id = ?", id).Delete(&model.ReindexEmbedding{}).Error
+	})
+	return canceled, err
+}
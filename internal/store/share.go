@@ -0,0 +1,97 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"go-ai-copilot/internal/model"
+	"gorm.io/gorm"
+)
+
+// ShareRepository 会话分享存储
+type ShareRepository interface {
+	Create(ctx context.Context, share *model.SessionShare) error
+	// List 会话的分享链接，不含消息快照，按创建时间倒序
+	List(ctx context.Context, userID, sessionID uint) ([]model.SessionShare, error)
+	// Revoke 撤销未撤销的分享链接，不存在时返回 ErrNotFound
+	Revoke(ctx context.Context, userID, sessionID, id uint) error
+	GetByToken(ctx context.Context, token string) (*model.SessionShare, error)
+	// AddView 访问次数加一
+	AddView(ctx context.Context, id uint) error
+	// ReservePasswordAttempt 未锁定且失败次数未达到 maxFailures 时占用一次尝试次数，返回是否占用成功
+	ReservePasswordAttempt(ctx context.Context, id uint, maxFailures int, now time.Time) (bool, error)
+	// LockPassword 失败次数达到 maxFailures 时锁定到 until 并清零计数
+	LockPassword(ctx context.Context, id uint, maxFailures int, until time.Time) error
+	// ResetPasswordFailures 密码正确后清零失败次数
+	ResetPasswordFailures(ctx context.Context, id uint) error
+}
+
+// shareRepo 会话分享存储
+type shareRepo struct {
+	db *gorm.DB
+}
+
+func (r *shareRepo) Create(ctx context.Context, share *model.SessionShare) error {
+	return r.db.WithContext(ctx).Create(share).Error
+}
+
+func (r *shareRepo) List(ctx context.Context, userID, sessionID uint) ([]model.SessionShare, error) {
+	var shares []model.SessionShare
+	if err := r.db.WithContext(ctx).Omit("messages").
+		Where("session_id = ? AND user_id = ?", sessionID, userID).
+		Order("created_at DESC").
+		Find(&shares).Error; err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
+func (r *shareRepo) Revoke(ctx context.Context, userID, sessionID, id uint) error {
+	result := r.db.WithContext(ctx).Model(&model.SessionShare{}).
+		Where("id = ? AND session_id = ? AND user_id = ? AND revoked_at IS NULL", id, sessionID, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *shareRepo) GetByToken(ctx context.Context, token string) (*model.SessionShare, error) {
+	var share model.SessionShare
+	if err := r.db.WithContext(ctx).Where("token = ?", token).First(&share).Error; err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+func (r *shareRepo) AddView(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Model(&model.SessionShare{}).Where("id = ?", id).
+		UpdateColumn("view_count", gorm.Expr("view_count + 1")).Error
+}
+
+func (r *shareRepo) ReservePasswordAttempt(ctx context.Context, id uint, maxFailures int, now time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.SessionShare{}).
+		Where("id = ? AND password_failures < ? AND (locked_until IS NULL OR locked_until <= ?)", id, maxFailures, now).
+		UpdateColumn("password_failures", gorm.Expr("password_failures + 1"))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *shareRepo) LockPassword(ctx context.Context, id uint, maxFailures int, until time.Time) error {
+	return r.db.WithContext(ctx).Model(&model.SessionShare{}).
+		Where("id = ? AND password_failures >= ?", id, maxFailures).
+		UpdateColumns(map[string]interface{}{
+			"password_failures": 0,
+			"locked_until":      until,
+		}).Error
+}
+
+func (r *shareRepo) ResetPasswordFailures(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Model(&model.SessionShare{}).Where("id = ?", id).
+		UpdateColumn("password_failures", 0).Error
+}
@@ -0,0 +1,96 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"go-ai-copilot/internal/model"
+	"gorm.io/gorm"
+)
+
+// hnswMaxDimensions pgvector 的 HNSW 索引支持的最大维度
+const hnswMaxDimensions = 2000
+
+// spaceRepo 向量空间存储
+type spaceRepo struct {
+	db *gorm.DB
+}
+
+func (r *spaceRepo) Ensure(ctx context.Context, name, version string, dimensions int) (*model.EmbeddingSpace, error) {
+	var space model.EmbeddingSpace
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("model = ? AND version = ?", name, version).First(&space).Error
+		if err == nil {
+			if space.Dimensions != dimensions {
+				return fmt.Errorf("模型 %s（版本 %q）的向量维度已记录为 %d，与配置的 %d 不一致，请修改 embedding_version",
+					name, version, space.Dimensions, dimensions)
+			}
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		// 升级前生成的向量由迁移归入模型未知的空间，归属到当前配置的模型
+		err = tx.Where("model = ''").First(&space).Error
+		if err == nil && space.Dimensions == dimensions {
+			space.Model = name
+			space.Version = version
+			return tx.Save(&space).Error
+		}
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		space = model.EmbeddingSpace{Model: name, Version: version, Dimensions: dimensions}
+		return tx.Create(&space).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 索引创建失败时返回错误，避免检索在没有索引的情况下静默退化为顺序扫描；下次启动时重试
+	if isPostgres(r.db) {
+		if err := r.createIndexes(ctx, &space); err != nil {
+			return nil, err
+		}
+	}
+	return &space, nil
+}
+
+// createIndexes 为向量空间创建部分HNSW索引（只包含该空间的向量）
+// 超过HNSW支持的维度时不创建索引，检索退化为顺序扫描
+func (r *spaceRepo) createIndexes(ctx context.Context, space *model.EmbeddingSpace) error {
+	if space.Dimensions > hnswMaxDimensions {
+		log.Printf("警告: 向量维度 %d 超过HNSW索引上限 %d，向量空间 %d 不创建索引", space.Dimensions, hnswMaxDimensions, space.ID)
+		return nil
+	}
+	for _, table := range []string{"rag_chunks", "message_embeddings"} {
+		sql := fmt.Sprintf(
+			"CREATE INDEX IF NOT EXISTS idx_%s_embedding_space_%d ON %s USING hnsw (%s vector_cosine_ops) WHERE space_id = %d",
+			table, space.ID, table, VectorExpr("embedding", space.Dimensions), space.ID,
+		)
+		if err := r.db.WithContext(ctx).Exec(sql).Error; err != nil {
+			return fmt.Errorf("创建向量空间 %d 的 %s 向量索引失败: %w", space.ID, table, err)
+		}
+	}
+	return nil
+}
+
+func (r *spaceRepo) Get(ctx context.Context, id uint) (*model.EmbeddingSpace, error) {
+	var space model.EmbeddingSpace
+	if err := r.db.WithContext(ctx).First(&space, id).Error; err != nil {
+		return nil, err
+	}
+	return &space, nil
+}
+
+func (r *spaceRepo) List(ctx context.Context) ([]model.EmbeddingSpace, error) {
+	var spaces []model.EmbeddingSpace
+	if err := r.db.WithContext(ctx).Order("id").Find(&spaces).Error; err != nil {
+		return nil, err
+	}
+	return spaces, nil
+}
@@ -0,0 +1,273 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go-ai-copilot/internal/model"
+	"go-ai-copilot/internal/pagination"
+	"gorm.io/gorm"
+)
+
+// ErrNotFound 记录不存在
+var ErrNotFound = gorm.ErrRecordNotFound
+
+// ErrDocumentChanged 写入新版本时文档已被其他上传更新
+var ErrDocumentChanged = errors.New("文档已被其他上传更新")
+
+// ErrTitleChanged 生成标题期间会话标题已被用户修改
+var ErrTitleChanged = errors.New("会话标题已修改")
+
+// UserRepository 用户存储
+type UserRepository interface {
+	Create(ctx context.Context, user *model.User) error
+	Get(ctx context.Context, id uint) (*model.User, error)
+	GetByUsername(ctx context.Context, username string) (*model.User, error)
+	// Update 更新指定字段
+	Update(ctx context.Context, id uint, fields map[string]interface{}) error
+}
+
+// SessionQuery 会话列表过滤条件
+type SessionQuery struct {
+	UserID   uint
+	Mode     string
+	From     *time.Time // 创建时间下限（含）
+	To       *time.Time // 创建时间上限（不含）
+	FolderID *uint      // 0 表示不在文件夹中的会话
+	Pinned   *bool
+	Archived bool
+	Tag      string
+}
+
+// HistoryQuery 分支历史过滤条件
+type HistoryQuery struct {
+	SessionID uint
+	LeafID    uint       // 分支的最后一条消息
+	Role      string     // user / assistant
+	From      *time.Time // 创建时间下限（含）
+	To        *time.Time // 创建时间上限（不含）
+}
+
+// BranchMessage 分支上的消息，Depth 为消息在分支上的位置（第一条消息为0）
+type BranchMessage struct {
+	model.Message
+	Depth int `json:"-"`
+}
+
+// SessionRepository 会话存储
+type SessionRepository interface {
+	Create(ctx context.Context, session *model.Session) error
+	// Get 获取用户的会话
+	Get(ctx context.Context, userID, id uint) (*model.Session, error)
+	List(ctx context.Context, q SessionQuery, page *pagination.Request) ([]model.Session, error)
+	// Save 保存会话，change不为空时在同一事务中记录模式变更
+	Save(ctx context.Context, session *model.Session, change *model.SessionModeChange) error
+	// Delete 删除会话及其消息，并撤销会话的分享链接
+	Delete(ctx context.Context, session *model.Session) error
+	// ActiveMessageID 当前分支的最后一条消息
+	ActiveMessageID(ctx context.Context, id uint) (*uint, error)
+	// SetActive 设置当前分支并更新会话时间
+	SetActive(ctx context.Context, id uint, leafID *uint) error
+	// ModeChanges 会话的模式变更记录，按时间排序
+	ModeChanges(ctx context.Context, id uint) ([]model.SessionModeChange, error)
+	// ClaimAutoTitle 认领自动生成标题的任务：会话待生成标题时清除标记并返回当前（占位）标题，否则返回 ErrNotFound
+	ClaimAutoTitle(ctx context.Context, userID, id uint) (string, error)
+	// ReleaseAutoTitle 生成失败时恢复标记（标题仍为占位标题时），下一轮对话后重试
+	ReleaseAutoTitle(ctx context.Context, id uint, placeholder string) error
+	// SetGeneratedTitle 标题仍为占位标题时写入生成的标题，用户未设置标签时一并写入标签
+	// 生成期间标题已被用户修改时返回 ErrTitleChanged
+	SetGeneratedTitle(ctx context.Context, userID, id uint, placeholder, title string, tags []string) (*model.Session, error)
+}
+
+// MessageRepository 消息存储
+type MessageRepository interface {
+	Create(ctx context.Context, msg *model.Message) error
+	// Get 获取会话中的消息
+	Get(ctx context.Context, sessionID, id uint) (*model.Message, error)
+	// List 会话的全部消息（含各分支），按创建时间排序
+	List(ctx context.Context, sessionID uint) ([]model.Message, error)
+	// History 按分页请求（排序列为 depth、id）获取从第一条消息到 q.LeafID 的分支上的消息，
+	// 分支在数据库中沿 parent_id 递归查询，叶子消息不在会话中时返回 ErrNotFound
+	History(ctx context.Context, q HistoryQuery, page *pagination.Request) ([]BranchMessage, error)
+	// LatestChild 最新的子消息
+	LatestChild(ctx context.Context, sessionID, parentID uint) (*model.Message, error)
+	// Chain 将支持分支之前的平铺消息按时间顺序串成一条分支，并设为会话的当前分支
+	Chain(ctx context.Context, sessionID uint) error
+}
+
+// DocumentQuery 文档列表过滤条件
+type DocumentQuery struct {
+	UserID          uint
+	KnowledgeBaseID *uint
+	Status          string
+	FileType        string
+	From            *time.Time
+	To              *time.Time
+	Tags            []string // 同时包含全部标签
+}
+
+// ChunkPosition 分块在文档中的位置
+type ChunkPosition struct {
+	Index     int
+	StartLine int
+	EndLine   int
+}
+
+// VersionChunks 文档新版本的分块变化
+type VersionChunks struct {
+	Added   []model.RAGChunk       // 新内容的分块（已向量化）
+	Moved   map[uint]ChunkPosition // 复用但位置（序号或行号）变化的分块ID -> 新位置
+	Retired []uint                 // 不再属于新版本的分块ID
+}
+
+// DocumentRepository 文档存储
+type DocumentRepository interface {
+	// Create 创建文档及其第一个版本
+	Create(ctx context.Context, doc *model.RAGDocument, version *model.RAGDocumentVersion) error
+	// Get 获取用户的文档
+	Get(ctx context.Context, userID, id uint) (*model.RAGDocument, error)
+	// GetByID 按ID获取文档，不校验所属用户（用于后台任务）
+	GetByID(ctx context.Context, id uint) (*model.RAGDocument, error)
+	// FindBySource 按文档标识查找用户在知识库中的文档
+	FindBySource(ctx context.Context, userID, kbID uint, sourceKey string) (*model.RAGDocument, error)
+	// CreateVersion 创建新版本（版本号为已有最大版本号+1），并将文档置为处理中
+	CreateVersion(ctx context.Context, version *model.RAGDocumentVersion) error
+	// Versions 文档的全部版本，按版本号倒序
+	Versions(ctx context.Context, documentID uint) ([]model.RAGDocumentVersion, error)
+	GetVersion(ctx context.Context, documentID uint, version int) (*model.RAGDocumentVersion, error)
+	// ApplyVersion 在一个事务中写入新版本的分块变化并切换为当前版本，检索立即看到新版本
+	// doc.Version 为计算差异时的当前版本，期间文档已被其他上传更新时返回错误
+	ApplyVersion(ctx context.Context, doc *model.RAGDocument, version *model.RAGDocumentVersion, chunks VersionChunks) error
+	// FailVersion 版本处理失败，文档继续使用当前版本（还没有可用版本时置为失败）
+	FailVersion(ctx context.Context, doc *model.RAGDocument, version *model.RAGDocumentVersion) error
+	// VersionHeartbeat 更新处理中版本的心跳
+	VersionHeartbeat(ctx context.Context, id uint) error
+	// StaleVersions 处理中但心跳早于staleBefore（处理的实例已退出）的版本
+	StaleVersions(ctx context.Context, staleBefore time.Time) ([]model.RAGDocumentVersion, error)
+	// ClaimVersion 领取心跳超时的处理中版本（更新心跳），已被其他实例领取或已处理完成时返回false
+	ClaimVersion(ctx context.Context, id uint, staleBefore time.Time) (bool, error)
+	List(ctx context.Context, q DocumentQuery, page *pagination.Request) ([]model.RAGDocument, error)
+	SetStatus(ctx context.Context, id uint, status string) error
+	// SetMetadata 在一个事务中更新文档及其全部分块的标签和元数据
+	SetMetadata(ctx context.Context, id uint, tags []string, metadata map[string]string) error
+	// Delete 删除文档及其分块
+	Delete(ctx context.Context, doc *model.RAGDocument) error
+	// UnreferencedBlobs 已删除文档各版本的上传文件中，不再被其他文档引用的文件Key
+	UnreferencedBlobs(ctx context.Context, documentIDs []uint) ([]string, error)
+}
+
+// ChunkScope 分块检索范围
+type ChunkScope struct {
+	UserID           uint   // 未指定知识库时检索该用户的全部文档
+	KnowledgeBaseIDs []uint // 指定的知识库（调用方需先校验访问权限）
+	Filter           ChunkFilter
+}
+
+// ChunkFilter 按所属文档的属性过滤分块，与向量检索在同一条SQL中执行
+type ChunkFilter struct {
+	Tags      []string          `json:"tags,omitempty"`       // 同时包含全部标签
+	Metadata  map[string]string `json:"metadata,omitempty"`   // 元数据全部匹配
+	FileTypes []string          `json:"file_types,omitempty"` // 文件类型之一
+	From      *time.Time        `json:"from,omitempty"`       // 文档上传时间下限（含）
+	To        *time.Time        `json:"to,omitempty"`         // 文档上传时间上限（不含）
+}
+
+// IsEmpty 是否没有任何过滤条件
+func (f ChunkFilter) IsEmpty() bool {
+	return len(f.Tags) == 0 && len(f.Metadata) == 0 && len(f.FileTypes) == 0 && f.From == nil && f.To == nil
+}
+
+// ChunkMatch 分块检索结果
+type ChunkMatch struct {
+	ChunkID         uint    `json:"chunk_id"`
+	DocumentID      uint    `json:"document_id"`
+	KnowledgeBaseID uint    `json:"knowledge_base_id"`
+	Content         string  `json:"content"`
+	ChunkIndex      int     `json:"chunk_index"`
+	Path            string  `json:"path"`
+	StartLine       int     `json:"start_line"`
+	EndLine         int     `json:"end_line"`
+	Score           float64 `json:"score"`
+}
+
+// ChunkRepository 文档分块存储
+type ChunkRepository interface {
+	Create(ctx context.Context, chunks []model.RAGChunk) error
+	// List 文档当前版本的分块，按序号排序
+	List(ctx context.Context, documentID uint) ([]model.RAGChunk, error)
+	// ListVersion 文档指定版本的分块（含之后版本中移除的），按序号排序
+	ListVersion(ctx context.Context, documentID uint, version int) ([]model.RAGChunk, error)
+	// Search 在向量空间内按余弦相似度检索最相近的topK个分块
+	Search(ctx context.Context, space *model.EmbeddingSpace, scope ChunkScope, embedding []float32, topK int) ([]ChunkMatch, error)
+}
+
+// SpaceRepository 向量空间存储
+type SpaceRepository interface {
+	// Ensure 获取模型（版本）对应的向量空间，不存在时创建
+	// 同一模型版本的维度与已记录的不一致时返回错误；PostgreSQL 上创建该空间的向量索引失败时也返回错误
+	Ensure(ctx context.Context, model, version string, dimensions int) (*model.EmbeddingSpace, error)
+	Get(ctx context.Context, id uint) (*model.EmbeddingSpace, error)
+	List(ctx context.Context) ([]model.EmbeddingSpace, error)
+}
+
+// Store 数据存储
+type Store struct {
+	Users     UserRepository
+	Sessions  SessionRepository
+	Folders   FolderRepository
+	Messages  MessageRepository
+	Documents DocumentRepository
+	Chunks    ChunkRepository
+	Spaces    SpaceRepository
+	Reindex   ReindexRepository
+
+	KnowledgeBases KnowledgeBaseRepository
+	Assistants     AssistantRepository
+	Prompts        PromptRepository
+	Shares         ShareRepository
+	MessageSearch  MessageSearchRepository
+	EmbeddingCache EmbeddingCacheRepository
+	ConnectorFiles ConnectorFileRepository
+	WebSources     WebSourceRepository
+
+	db *gorm.DB
+}
+
+// New 基于GORM创建存储
+// PostgreSQL通过pgvector在数据库中检索向量，SQLite在进程内计算相似度
+func New(db *gorm.DB) *Store {
+	return &Store{
+		Users:     &userRepo{db: db},
+		Sessions:  &sessionRepo{db: db},
+		Folders:   &folderRepo{db: db},
+		Messages:  &messageRepo{db: db},
+		Documents: &documentRepo{db: db},
+		Chunks:    &chunkRepo{db: db},
+		Spaces:    &spaceRepo{db: db},
+		Reindex:   &reindexRepo{db: db},
+
+		KnowledgeBases: &knowledgeBaseRepo{db: db},
+		Assistants:     &assistantRepo{db: db},
+		Prompts:        &promptRepo{db: db},
+		Shares:         &shareRepo{db: db},
+		MessageSearch:  &messageSearchRepo{db: db},
+		EmbeddingCache: &embeddingCacheRepo{db: db},
+		ConnectorFiles: &connectorFileRepo{db: db},
+		WebSources:     &webSourceRepo{db: db},
+
+		db: db,
+	}
+}
+
+// Transaction 在一个事务中执行fn，fn中通过tx访问的存储都在该事务内，fn返回错误时回滚
+func (s *Store) Transaction(ctx context.Context, fn func(tx *Store) error) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(New(tx))
+	})
+}
+
+// IsNotFound 判断是否为记录不存在
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
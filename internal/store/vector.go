@@ -0,0 +1,153 @@
+package store
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go-ai-copilot/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// hnswEfSearch HNSW索引扫描的候选数（pgvector默认40）
+	// 过滤条件在索引扫描之后执行，候选太少时只匹配少量分块的过滤会返回不足topK甚至空的结果
+	hnswEfSearch = 200
+	// hnswMaxEfSearch pgvector 允许的最大 ef_search
+	hnswMaxEfSearch = 1000
+)
+
+// iterativeScans 各数据库的pgvector是否支持迭代索引扫描（0.8起），按 *gorm.Config 区分数据库
+var iterativeScans sync.Map
+
+// withHNSWScan 在事务中设置本次HNSW索引扫描的参数（SET LOCAL 只在该事务内生效）后执行fn
+// 增大 ef_search；pgvector支持时开启迭代扫描（order 为 strict_order 或 relaxed_order），
+// 过滤后结果不足时继续扫描索引，直到找到足够的结果或达到 hnsw.max_scan_tuples
+func withHNSWScan(db *gorm.DB, limit int, order string, fn func(tx *gorm.DB) error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		ef := min(max(hnswEfSearch, limit), hnswMaxEfSearch)
+		if err := tx.Exec(fmt.Sprintf("SET LOCAL hnsw.ef_search = %d", ef)).Error; err != nil {
+			return err
+		}
+		if iterativeScan(tx) {
+			if err := tx.Exec("SET LOCAL hnsw.iterative_scan = " + order).Error; err != nil {
+				return err
+			}
+		}
+		return fn(tx)
+	})
+}
+
+// iterativeScan 数据库的pgvector版本是否支持迭代索引扫描，查询失败时按不支持处理（不缓存）
+func iterativeScan(db *gorm.DB) bool {
+	if v, ok := iterativeScans.Load(db.Config); ok {
+		return v.(bool)
+	}
+	var version string
+	if err := db.Raw("SELECT extversion FROM pg_extension WHERE extname = 'vector'").Scan(&version).Error; err != nil {
+		return false
+	}
+	supported := versionAtLeast(version, 0, 8)
+	iterativeScans.Store(db.Config, supported)
+	return supported
+}
+
+// versionAtLeast 版本号（如 0.8.0）是否不低于 major.minor
+func versionAtLeast(version string, major, minor int) bool {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	x, err1 := strconv.Atoi(parts[0])
+	y, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	return x > major || (x == major && y >= minor)
+}
+
+// searchPgvector 通过pgvector在数据库中排序和截断
+// 向量列不限定维度，按空间的维度转换后比较，与部分索引的表达式一致
+// relaxed_order 的迭代扫描返回的结果可能没有严格按距离排序，取回后重新排序
+func searchPgvector(query *gorm.DB, dimensions int, embedding []float32, topK int) ([]ChunkMatch, error) {
+	vec := model.Vector(embedding)
+	distance := fmt.Sprintf("%s <=> ?", VectorExpr("embedding", dimensions))
+
+	var matches []ChunkMatch
+	if err := query.
+		Select("id AS chunk_id, document_id, knowledge_base_id, content, chunk_index, path, start_line, end_line, 1 - ("+distance+") AS score", vec).
+		Order(clause.Expr{SQL: distance, Vars: []interface{}{vec}}).
+		Limit(topK).
+		Scan(&matches).Error; err != nil {
+		return nil, err
+	}
+	return topMatches(matches, topK), nil
+}
+
+// searchBruteForce 逐个计算相似度（没有向量索引的数据库，如SQLite）
+// 分批读取，只在内存中保留前topK个结果
+func searchBruteForce(query *gorm.DB, embedding []float32, topK int) ([]ChunkMatch, error) {
+	var rows []model.RAGChunk
+	var matches []ChunkMatch
+	err := query.
+		Select("id", "document_id", "knowledge_base_id", "content", "chunk_index", "path", "start_line", "end_line", "embedding").
+		FindInBatches(&rows, 500, func(tx *gorm.DB, batch int) error {
+			for _, c := range rows {
+				matches = append(matches, ChunkMatch{
+					ChunkID:         c.ID,
+					DocumentID:      c.DocumentID,
+					KnowledgeBaseID: c.KnowledgeBaseID,
+					Content:         c.Content,
+					ChunkIndex:      c.ChunkIndex,
+					Path:            c.Path,
+					StartLine:       c.StartLine,
+					EndLine:         c.EndLine,
+					Score:           Cosine(embedding, c.Embedding),
+				})
+			}
+			matches = topMatches(matches, topK)
+			return nil
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+	return topMatches(matches, topK), nil
+}
+
+// VectorExpr 将不限定维度的向量列转换为指定维度，供pgvector索引和距离计算使用
+func VectorExpr(column string, dimensions int) string {
+	return fmt.Sprintf("(%s::vector(%d))", column, dimensions)
+}
+
+// topMatches 按相似度从高到低取前k个
+func topMatches(matches []ChunkMatch, k int) []ChunkMatch {
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	if len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches
+}
+
+// Cosine 计算余弦相似度，维度不同时返回0
+func Cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,182 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"go-ai-copilot/internal/model"
+)
+
+func TestCosine(t *testing.T) {
+	tests := []struct {
+		a, b []float32
+		want float64
+	}{
+		{[]float32{1, 0}, []float32{1, 0}, 1},
+		{[]float32{1, 0}, []float32{0, 1}, 0},
+		{[]float32{1, 0}, []float32{-1, 0}, -1},
+		{[]float32{1, 1}, []float32{1, 0}, 1 / math.Sqrt2},
+		{[]float32{1, 0}, []float32{1, 0, 0}, 0}, // 维度不同
+		{[]float32{0, 0}, []float32{1, 0}, 0},    // 零向量
+	}
+	for _, tt := range tests {
+		if got := Cosine(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Cosine(%v, %v) = %v，期望 %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestChunkSearchBruteForce(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.Background()
+
+	space, err := st.Spaces.Ensure(ctx, "test-model", "", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := st.Spaces.Ensure(ctx, "other-model", "", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc1 := &model.RAGDocument{UserID: 1, KnowledgeBaseID: 1, FileName: "a.md", FileType: "md", SourceKey: "a.md",
+		Tags: []string{"go"}, Metadata: map[string]string{"lang": "zh"}}
+	createDocument(t, st, doc1, "")
+	doc2 := &model.RAGDocument{UserID: 1, KnowledgeBaseID: 2, FileName: "b.pdf", FileType: "pdf", SourceKey: "b.pdf",
+		Tags: []string{"go", "db"}, CreatedAt: time.Now().Add(-48 * time.Hour)}
+	createDocument(t, st, doc2, "")
+	doc3 := &model.RAGDocument{UserID: 2, FileName: "c.md", FileType: "md", SourceKey: "c.md"}
+	createDocument(t, st, doc3, "")
+
+	retired := 1
+	chunk := func(doc *model.RAGDocument, content string, space *model.EmbeddingSpace, embedding ...float32) model.RAGChunk {
+		return model.RAGChunk{
+			DocumentID: doc.ID, UserID: doc.UserID, KnowledgeBaseID: doc.KnowledgeBaseID, Content: content,
+			SpaceID: space.ID, Embedding: model.Vector(embedding), Tags: doc.Tags, Metadata: doc.Metadata,
+		}
+	}
+	retiredChunk := chunk(doc1, "retired", space, 1, 0, 0)
+	retiredChunk.RetiredVersion = &retired
+	chunks := []model.RAGChunk{
+		chunk(doc1, "exact", space, 1, 0, 0),
+		chunk(doc1, "orthogonal", space, 0, 1, 0),
+		chunk(doc2, "close", space, 1, 1, 0),
+		chunk(doc1, "other space", other, 1, 0, 0),
+		chunk(doc3, "other user", space, 1, 0, 0),
+		retiredChunk,
+	}
+	if err := st.Chunks.Create(ctx, chunks); err != nil {
+		t.Fatal(err)
+	}
+
+	query := []float32{1, 0, 0}
+	from := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name  string
+		scope ChunkScope
+		topK  int
+		want  []string
+	}{
+		{"按相似度排序", ChunkScope{UserID: 1}, 10, []string{"exact", "close", "orthogonal"}},
+		{"topK", ChunkScope{UserID: 1}, 2, []string{"exact", "close"}},
+		{"知识库", ChunkScope{UserID: 1, KnowledgeBaseIDs: []uint{2}}, 10, []string{"close"}},
+		{"标签", ChunkScope{UserID: 1, Filter: ChunkFilter{Tags: []string{"go", "db"}}}, 10, []string{"close"}},
+		{"元数据", ChunkScope{UserID: 1, Filter: ChunkFilter{Metadata: map[string]string{"lang": "zh"}}}, 10, []string{"exact", "orthogonal"}},
+		{"文件类型", ChunkScope{UserID: 1, Filter: ChunkFilter{FileTypes: []string{"pdf"}}}, 10, []string{"close"}},
+		{"上传时间", ChunkScope{UserID: 1, Filter: ChunkFilter{From: &from}}, 10, []string{"exact", "orthogonal"}},
+		{"其他用户", ChunkScope{UserID: 2}, 10, []string{"other user"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, err := st.Chunks.Search(ctx, space, tt.scope, query, tt.topK)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, m := range matches {
+				got = append(got, m.Content)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("检索结果 = %v，期望 %v", got, tt.want)
+			}
+			for i := 1; i < len(matches); i++ {
+				if matches[i].Score > matches[i-1].Score {
+					t.Fatalf("结果未按相似度排序: %+v", matches)
+				}
+			}
+		})
+	}
+
+	if _, err := st.Chunks.Search(ctx, space, ChunkScope{UserID: 1}, []float32{1, 0}, 10); err == nil {
+		t.Fatal("查询向量维度不一致时应返回错误")
+	}
+}
+
+// 过滤条件只匹配很少的分块，且这些分块都不是整体上最相似的，仍应返回topK个结果
+// （PostgreSQL 上过滤在HNSW索引扫描之后执行，需要扩大或迭代扫描）
+func TestChunkSearchSelectiveFilter(t *testing.T) {
+	st := newTestStore(t)
+	ctx := context.Background()
+
+	space, err := st.Spaces.Ensure(ctx, "test-model", "", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	common := &model.RAGDocument{UserID: 1, FileName: "common.md", FileType: "md", SourceKey: "common.md", Tags: []string{}}
+	createDocument(t, st, common, "")
+	rare := &model.RAGDocument{UserID: 1, FileName: "rare.md", FileType: "md", SourceKey: "rare.md", Tags: []string{"rare"}}
+	createDocument(t, st, rare, "")
+
+	var chunks []model.RAGChunk
+	for i := 0; i < 1000; i++ {
+		chunks = append(chunks, model.RAGChunk{
+			DocumentID: common.ID, UserID: 1, Content: fmt.Sprintf("common %d", i), ChunkIndex: i,
+			SpaceID: space.ID, Embedding: model.Vector{1, float32(i%10) / 100, 0}, Tags: common.Tags,
+		})
+	}
+	for i := 0; i < 5; i++ {
+		chunks = append(chunks, model.RAGChunk{
+			DocumentID: rare.ID, UserID: 1, Content: fmt.Sprintf("rare %d", i), ChunkIndex: i,
+			SpaceID: space.ID, Embedding: model.Vector{1, 1, float32(i)}, Tags: rare.Tags,
+		})
+	}
+	if err := st.Chunks.Create(ctx, chunks); err != nil {
+		t.Fatal(err)
+	}
+
+	matches, err := st.Chunks.Search(ctx, space, ChunkScope{UserID: 1, Filter: ChunkFilter{Tags: []string{"rare"}}}, []float32{1, 0, 0}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, m := range matches {
+		got = append(got, m.Content)
+	}
+	if want := []string{"rare 0", "rare 1", "rare 2"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("检索结果 = %v，期望 %v", got, want)
+	}
+}
+
+func TestVersionAtLeast(t *testing.T) {
+	tests := []struct {
+		version string
+		want    bool
+	}{
+		{"0.8.0", true},
+		{"0.8", true},
+		{"0.10.1", true},
+		{"1.0.0", true},
+		{"0.7.4", false},
+		{"0.5.1", false},
+		{"", false},
+		{"dev", false},
+	}
+	for _, tt := range tests {
+		if got := versionAtLeast(tt.version, 0, 8); got != tt.want {
+			t.Errorf("versionAtLeast(%q, 0, 8) = %v，期望 %v", tt.version, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go-ai-copilot/internal/model"
+	"gorm.io/gorm"
+)
+
+// ErrSourceDeleted 网页来源已被删除
+var ErrSourceDeleted = errors.New("网页来源已删除")
+
+// WebSourceRepository 网页来源存储
+type WebSourceRepository interface {
+	Create(ctx context.Context, source *model.WebSource) error
+	// Get 获取用户的网页来源
+	Get(ctx context.Context, userID, id uint) (*model.WebSource, error)
+	// List 用户的网页来源，按创建时间倒序
+	List(ctx context.Context, userID uint) ([]model.WebSource, error)
+	// Due 到达重新抓取时间、或抓取中但心跳早于staleBefore（执行的实例已退出）的网页来源
+	Due(ctx context.Context, now, staleBefore time.Time) ([]model.WebSource, error)
+	// Claim 将网页来源置为抓取中，已在抓取中（且心跳未超时）时返回false
+	Claim(ctx context.Context, id uint, staleBefore time.Time) (bool, error)
+	// Heartbeat 更新抓取心跳和已抓取的页面数，网页来源已被删除时返回ErrSourceDeleted
+	Heartbeat(ctx context.Context, id uint, pages int) error
+	// Finish 结束抓取，记录结果并计算下次抓取时间
+	Finish(ctx context.Context, source *model.WebSource, status, message string, pages int) error
+	Delete(ctx context.Context, id uint) error
+}
+
+// webSourceRepo 网页来源存储
+type webSourceRepo struct {
+	db *gorm.DB
+}
+
+func (r *webSourceRepo) Create(ctx context.Context, source *model.WebSource) error {
+	source.Status = model.WebSourcePending
+	return r.db.WithContext(ctx).Create(source).Error
+}
+
+func (r *webSourceRepo) Get(ctx context.Context, userID, id uint) (*model.WebSource, error) {
+	var source model.WebSource
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&source).Error; err != nil {
+		return nil, err
+	}
+	return &source, nil
+}
+
+func (r *webSourceRepo) List(ctx context.Context, userID uint) ([]model.WebSource, error) {
+	var sources []model.WebSource
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("id DESC").Find(&sources).Error; err != nil {
+		return nil, err
+	}
+	return sources, nil
+}
+
+func (r *webSourceRepo) Due(ctx context.Context, now, staleBefore time.Time) ([]model.WebSource, error) {
+	var sources []model.WebSource
+	if err := r.db.WithContext(ctx).
+		Where("(status <> ? AND next_crawl_at <= ?) OR (status = ? AND updated_at < ?)",
+			model.WebSourceCrawling, now, model.WebSourceCrawling, staleBefore).
+		Order("id").
+		Find(&sources).Error; err != nil {
+		return nil, err
+	}
+	return sources, nil
+}
+
+func (r *webSourceRepo) Claim(ctx context.Context, id uint, staleBefore time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.WebSource{}).
+		Where("id = ?", id).
+		Where("status <> ? OR updated_at < ?", model.WebSourceCrawling, staleBefore).
+		Updates(map[string]interface{}{
+			"status":     model.WebSourceCrawling,
+			"error":      "",
+			"updated_at": time.Now(),
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *webSourceRepo) Heartbeat(ctx context.Context, id uint, pages int) error {
+	result := r.db.WithContext(ctx).Model(&model.WebSource{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"page_count": pages,
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSourceDeleted
+	}
+	return nil
+}
+
+func (r *webSourceRepo) Finish(ctx context.Context, source *model.WebSource, status, message string, pages int) error {
+	now := time.Now()
+	var next *time.Time
+	if source.RefreshInterval > 0 {
+		t := now.Add(time.Duration(source.RefreshInterval) * time.Second)
+		next = &t
+	}
+	source.Status = status
+	source.Error = message
+	source.PageCount = pages
+	source.LastCrawledAt = &now
+	source.NextCrawlAt = next
+	return r.db.WithContext(ctx).Model(&model.WebSource{}).
+		Where("id = ?", source.ID).
+		Updates(map[string]interface{}{
+			"status":          status,
+			"error":           message,
+			"page_count":      pages,
+			"last_crawled_at": now,
+			"next_crawl_at":   next,
+			"updated_at":      now,
+		}).Error
+}
+
+func (r *webSourceRepo) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.WebSource{}, id).Error
+}
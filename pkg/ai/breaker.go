@@ -0,0 +1,121 @@
+package ai
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen 熔断器处于打开状态
+var ErrCircuitOpen = errors.New("熔断器已打开")
+
+// breakerState 熔断器状态
+type breakerState int
+
+const (
+	stateClosed   breakerState = iota // 正常放行
+	stateOpen                         // 熔断中，拒绝请求
+	stateHalfOpen                     // 冷却结束，放行一个探测请求
+)
+
+// CircuitBreaker 提供方级别的熔断器
+// 连续失败达到阈值后打开，冷却期结束后进入半开状态放行一个探测请求
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	state     breakerState
+	failures  int
+	openedAt  time.Time
+	probing   bool
+	probeAt   time.Time
+}
+
+// NewCircuitBreaker 创建熔断器
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold <= 0 {
+		threshold = 5
+	}
+	if cooldown <= 0 {
+		cooldown = 30 * time.Second
+	}
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// Allow 判断当前是否允许请求通过
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case stateOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = stateHalfOpen
+		b.probing = true
+		b.probeAt = time.Now()
+		return true
+	case stateHalfOpen:
+		// 半开状态只放行一个探测请求；探测请求被调用方中途放弃时，冷却期后允许重新探测
+		if b.probing && time.Since(b.probeAt) < b.cooldown {
+			return false
+		}
+		b.probing = true
+		b.probeAt = time.Now()
+		return true
+	default:
+		return true
+	}
+}
+
+// Success 记录一次成功调用
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = stateClosed
+	b.failures = 0
+	b.probing = false
+}
+
+// Failure 记录一次失败调用
+func (b *CircuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if b.state == stateHalfOpen {
+		b.trip()
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.trip()
+	}
+}
+
+// State 返回当前状态（closed / open / half_open），用于监控
+func (b *CircuitBreaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case stateOpen:
+		return "open"
+	case stateHalfOpen:
+		return "half_open"
+	default:
+		return "closed"
+	}
+}
+
+// trip 打开熔断器（调用方需持有锁）
+func (b *CircuitBreaker) trip() {
+	b.state = stateOpen
+	b.openedAt = time.Now()
+	b.failures = 0
+}
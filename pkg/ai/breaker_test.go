@@ -0,0 +1,77 @@
+package ai
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	cooldown := 20 * time.Millisecond
+	b := NewCircuitBreaker(3, cooldown)
+
+	// 成功调用清零连续失败次数
+	b.Failure()
+	b.Failure()
+	b.Success()
+	b.Failure()
+	b.Failure()
+	if b.State() != "closed" || !b.Allow() {
+		t.Fatalf("未连续失败3次时状态 = %s", b.State())
+	}
+
+	b.Failure()
+	if b.State() != "open" || b.Allow() {
+		t.Fatalf("连续失败3次后状态 = %s", b.State())
+	}
+
+	// 冷却结束后半开，只放行一个探测请求
+	time.Sleep(cooldown + 5*time.Millisecond)
+	if !b.Allow() || b.State() != "half_open" {
+		t.Fatalf("冷却结束后状态 = %s", b.State())
+	}
+	if b.Allow() {
+		t.Fatal("半开状态放行了第二个请求")
+	}
+
+	// 探测失败重新打开
+	b.Failure()
+	if b.State() != "open" || b.Allow() {
+		t.Fatalf("探测失败后状态 = %s", b.State())
+	}
+
+	// 探测成功关闭
+	time.Sleep(cooldown + 5*time.Millisecond)
+	if !b.Allow() {
+		t.Fatal("冷却结束后没有放行探测请求")
+	}
+	b.Success()
+	if b.State() != "closed" || !b.Allow() || !b.Allow() {
+		t.Fatalf("探测成功后状态 = %s", b.State())
+	}
+}
+
+func TestCircuitBreakerAbandonedProbe(t *testing.T) {
+	cooldown := 20 * time.Millisecond
+	b := NewCircuitBreaker(1, cooldown)
+	b.Failure()
+
+	time.Sleep(cooldown + 5*time.Millisecond)
+	if !b.Allow() {
+		t.Fatal("冷却结束后没有放行探测请求")
+	}
+	// 探测请求没有报告结果（调用方中途放弃），冷却期后允许重新探测
+	time.Sleep(cooldown + 5*time.Millisecond)
+	if !b.Allow() {
+		t.Fatal("探测请求被放弃后没有重新放行")
+	}
+	if b.Allow() {
+		t.Fatal("重新探测时放行了第二个请求")
+	}
+}
+
+func TestNewCircuitBreakerDefaults(t *testing.T) {
+	b := NewCircuitBreaker(0, 0)
+	if b.threshold != 5 || b.cooldown != 30*time.Second {
+		t.Fatalf("默认阈值 %d，冷却 %v", b.threshold, b.cooldown)
+	}
+}
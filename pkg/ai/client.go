@@ -5,24 +5,123 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
+	"time"
 
 	"github.com/sashabaranov/go-openai"
 )
 
+// ErrUnavailable 所有提供方均不可用（重试耗尽或熔断）
+var ErrUnavailable = errors.New("AI服务暂时不可用")
+
 // Client AI客户端
+// 按顺序尝试主提供方和备用提供方，每个提供方内部按重试策略退避重试
 type Client struct {
-	apiKey   string
-	baseURL  string
-	model    string
-	temp     float64
+	apiKey    string
+	baseURL   string
+	model     string
+	temp      float64
 	maxTokens int
-	timeout  int
-	client   *openai.Client
+	timeout   int
+	client    *openai.Client
+	retry     RetryPolicy
+	providers []*provider
+}
+
+// Provider 备用提供方配置
+type Provider struct {
+	Name    string // 提供方名称，同名提供方共享熔断器；为空时取BaseURL的主机名
+	APIKey  string
+	BaseURL string
+	Model   string
+}
+
+// provider 运行时的提供方
+type provider struct {
+	name    string
+	model   string
+	client  *openai.Client
+	breaker *CircuitBreaker
+}
+
+// options 客户端可选配置
+type options struct {
+	retry            RetryPolicy
+	breakerThreshold int
+	breakerCooldown  time.Duration
+	fallbacks        []Provider
+}
+
+// Option 客户端配置项
+type Option func(*options)
+
+// WithRetryPolicy 设置重试策略
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(o *options) {
+		o.retry = p
+	}
+}
+
+// WithCircuitBreaker 设置熔断阈值（连续失败次数）和冷却时间
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(o *options) {
+		o.breakerThreshold = threshold
+		o.breakerCooldown = cooldown
+	}
+}
+
+// WithFallbacks 设置按顺序尝试的备用提供方和模型
+func WithFallbacks(providers ...Provider) Option {
+	return func(o *options) {
+		o.fallbacks = append(o.fallbacks, providers...)
+	}
+}
+
+// callOptions 单次调用的可选参数
+type callOptions struct {
+	model       string
+	temperature *float64
+}
+
+// CallOption 单次调用配置项
+type CallOption func(*callOptions)
+
+// WithModel 指定本次调用的模型（仅作用于主提供方，备用提供方使用各自配置的模型）
+func WithModel(model string) CallOption {
+	return func(o *callOptions) {
+		o.model = model
+	}
+}
+
+// WithTemperature 指定本次调用的温度
+func WithTemperature(temperature float64) CallOption {
+	return func(o *callOptions) {
+		o.temperature = &temperature
+	}
+}
+
+// resolve 计算提供方p上本次调用实际使用的模型和温度
+func (c *Client) resolve(p *provider, opts []CallOption) (string, float32) {
+	var o callOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	model := p.model
+	if o.model != "" && p == c.providers[0] {
+		model = o.model
+	}
+	temp := c.temp
+	if o.temperature != nil {
+		temp = *o.temperature
+	}
+	return model, float32(temp)
 }
 
 // NewClient 创建AI客户端
-func NewClient(apiKey, baseURL, model string, temperature float64, maxTokens, timeout int) (*Client, error) {
+// timeout: 非流式调用的超时时间（秒），流式调用为等待下一个数据块（含首个）的最长时间，<=0表示不限制
+func NewClient(apiKey, baseURL, model string, temperature float64, maxTokens, timeout int, opts ...Option) (*Client, error) {
 	if apiKey == "" {
 		apiKey = os.Getenv("AI_API_KEY")
 	}
@@ -30,85 +129,305 @@ func NewClient(apiKey, baseURL, model string, temperature float64, maxTokens, ti
 		return nil, errors.New("API_KEY未设置")
 	}
 
-	cfg := openai.DefaultConfig(apiKey)
-	cfg.BaseURL = baseURL
-
-	client := openai.NewClientWithConfig(cfg)
+	o := options{retry: DefaultRetryPolicy()}
+	for _, opt := range opts {
+		opt(&o)
+	}
 
-	return &Client{
-		apiKey:   apiKey,
-		baseURL:  baseURL,
-		model:    model,
-		temp:     temperature,
+	c := &Client{
+		apiKey:    apiKey,
+		baseURL:   baseURL,
+		model:     model,
+		temp:      temperature,
 		maxTokens: maxTokens,
-		timeout:  timeout,
-		client:   client,
-	}, nil
+		timeout:   timeout,
+		retry:     o.retry.normalize(),
+	}
+
+	// 同名提供方共享一个熔断器
+	breakers := make(map[string]*CircuitBreaker)
+	addProvider := func(p Provider) {
+		name := p.Name
+		if name == "" {
+			name = hostOf(p.BaseURL)
+		}
+		breaker, ok := breakers[name]
+		if !ok {
+			breaker = NewCircuitBreaker(o.breakerThreshold, o.breakerCooldown)
+			breakers[name] = breaker
+		}
+
+		cfg := openai.DefaultConfig(p.APIKey)
+		cfg.BaseURL = p.BaseURL
+		cfg.HTTPClient = newHTTPClient()
+
+		c.providers = append(c.providers, &provider{
+			name:    name,
+			model:   p.Model,
+			client:  openai.NewClientWithConfig(cfg),
+			breaker: breaker,
+		})
+	}
+
+	addProvider(Provider{APIKey: apiKey, BaseURL: baseURL, Model: model})
+	for _, fb := range o.fallbacks {
+		if fb.APIKey == "" {
+			fb.APIKey = apiKey
+		}
+		if fb.BaseURL == "" {
+			fb.BaseURL = baseURL
+		}
+		if fb.Model == "" {
+			fb.Model = model
+		}
+		addProvider(fb)
+	}
+	c.client = c.providers[0].client
+
+	return c, nil
+}
+
+// hostOf 提取URL主机名作为默认提供方名称
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	return u.Host
+}
+
+// callTimeout 单次调用超时时间
+func (c *Client) callTimeout() time.Duration {
+	if c.timeout <= 0 {
+		return 0
+	}
+	return time.Duration(c.timeout) * time.Second
+}
+
+// errStreamIdle 流式响应超过超时时间没有新数据，视为上游超时（可重试）
+var errStreamIdle = fmt.Errorf("流式响应超时: %w", context.DeadlineExceeded)
+
+// stopError 标记无需重试、也无需切换提供方的错误
+type stopError struct {
+	err      error
+	upstream bool // 上游中途失败（计入提供方健康度），否则为调用方中止
+}
+
+func (e *stopError) Error() string { return e.err.Error() }
+func (e *stopError) Unwrap() error { return e.err }
+
+// invoke 依次尝试各提供方，每个提供方内部按重试策略重试
+// call的ctx已带Retry-After记录器，超时由call按调用方式自行控制
+func (c *Client) invoke(ctx context.Context, call func(ctx context.Context, p *provider) error) error {
+	var lastErr error
+
+	for _, p := range c.providers {
+		if !p.breaker.Allow() {
+			lastErr = fmt.Errorf("%s: %w", p.name, ErrCircuitOpen)
+			continue
+		}
+
+		err := c.invokeProvider(ctx, p, call)
+		if err == nil {
+			return nil
+		}
+
+		var stop *stopError
+		if errors.As(err, &stop) {
+			return stop.err
+		}
+		// 调用方取消或非可重试错误（如参数错误）不切换提供方
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !IsRetryable(err) && !errors.Is(err, ErrCircuitOpen) {
+			return err
+		}
+		lastErr = fmt.Errorf("%s: %w", p.name, err)
+	}
+
+	return fmt.Errorf("%w: %v", ErrUnavailable, lastErr)
+}
+
+// invokeProvider 在单个提供方上执行带退避的重试
+func (c *Client) invokeProvider(ctx context.Context, p *provider, call func(ctx context.Context, p *provider) error) error {
+	var err error
+
+	for attempt := 0; attempt < c.retry.MaxAttempts; attempt++ {
+		if attempt > 0 && !p.breaker.Allow() {
+			return fmt.Errorf("%w: %v", ErrCircuitOpen, err)
+		}
+
+		attemptCtx, hint := withRetryHint(ctx)
+		err = call(attemptCtx, p)
+
+		if err == nil {
+			p.breaker.Success()
+			return nil
+		}
+
+		// 调用方已取消，与提供方无关，不计入健康度
+		if ctx.Err() != nil {
+			return err
+		}
+		var stop *stopError
+		if errors.As(err, &stop) {
+			// 已向调用方输出内容，不再重试；上游中途失败计为一次失败
+			if stop.upstream {
+				p.breaker.Failure()
+			}
+			return err
+		}
+		if !IsRetryable(err) {
+			p.breaker.Success()
+			return err
+		}
+		p.breaker.Failure()
+
+		if attempt == c.retry.MaxAttempts-1 {
+			break
+		}
+
+		delay := c.retry.backoff(attempt)
+		if hint.after > 0 {
+			// 上游要求等待的时间超过上限，直接切换到下一个提供方
+			if hint.after > c.retry.MaxDelay {
+				return err
+			}
+			delay = hint.after
+		}
+		if sleepErr := sleep(ctx, delay); sleepErr != nil {
+			return sleepErr
+		}
+	}
+
+	return err
 }
 
 // StreamChat 流式对话
 // ctx: 用于控制请求生命周期，支持用户断开时自动终止
 // messages: 对话历史
 // onChunk: 每个token的回调函数
-func (c *Client) StreamChat(ctx context.Context, messages []openai.ChatCompletionMessage, onChunk func(string) error) error {
-	req := openai.ChatCompletionRequest{
-		Model:       c.model,
-		Messages:    messages,
-		Temperature: float32(c.temp),
-		MaxTokens:   c.maxTokens,
-		Stream:      true,
-	}
-
-	stream, err := c.client.CreateChatCompletionStream(ctx, req)
-	if err != nil {
-		return fmt.Errorf("创建流式请求失败: %v", err)
-	}
-	defer stream.Close()
-
-	// 持续读取直到上下文取消或流结束
-	for {
-		select {
-		case <-ctx.Done():
-			// 用户断开连接，主动终止请求
-			return ctx.Err()
-		default:
-			resp, err := stream.Recv()
-			if errors.Is(err, io.EOF) {
-				return nil
+// 只有在尚未向调用方输出任何token时才会重试或切换提供方
+// 超时时间作用于每个数据块之间的等待（含首个数据块），不限制整个回复的时长
+func (c *Client) StreamChat(ctx context.Context, messages []openai.ChatCompletionMessage, onChunk func(string) error, opts ...CallOption) error {
+	sent := false
+
+	return c.invoke(ctx, func(ctx context.Context, p *provider) error {
+		ctx, cancel := context.WithCancelCause(ctx)
+		defer cancel(nil)
+		// 空闲计时器，每收到一个数据块重新计时
+		resetIdle := func() {}
+		if d := c.callTimeout(); d > 0 {
+			timer := time.AfterFunc(d, func() { cancel(errStreamIdle) })
+			defer timer.Stop()
+			resetIdle = func() { timer.Reset(d) }
+		}
+		// fail 区分空闲超时（上游问题）和调用方中止，已输出内容时不再重试
+		fail := func(err error) error {
+			upstream := true
+			if ctx.Err() != nil {
+				if cause := context.Cause(ctx); errors.Is(cause, errStreamIdle) {
+					err = errStreamIdle
+				} else {
+					err, upstream = cause, false
+				}
 			}
-			if err != nil {
-				return fmt.Errorf("读取流失败: %v", err)
+			if sent {
+				return &stopError{err: err, upstream: upstream}
 			}
+			return err
+		}
 
-			if len(resp.Choices) > 0 {
-				content := resp.Choices[0].Delta.Content
-				if content != "" {
-					if err := onChunk(content); err != nil {
-						return err
+		model, temp := c.resolve(p, opts)
+		req := openai.ChatCompletionRequest{
+			Model:       model,
+			Messages:    messages,
+			Temperature: temp,
+			MaxTokens:   c.maxTokens,
+			Stream:      true,
+		}
+
+		stream, err := p.client.CreateChatCompletionStream(ctx, req)
+		if err != nil {
+			return fail(fmt.Errorf("创建流式请求失败: %w", err))
+		}
+		defer stream.Close()
+
+		// 持续读取直到上下文取消或流结束
+		for {
+			select {
+			case <-ctx.Done():
+				// 用户断开连接或等待超时，主动终止请求
+				return fail(ctx.Err())
+			default:
+				resp, err := stream.Recv()
+				if errors.Is(err, io.EOF) {
+					return nil
+				}
+				if err != nil {
+					return fail(fmt.Errorf("读取流失败: %w", err))
+				}
+				resetIdle()
+
+				if len(resp.Choices) > 0 {
+					content := resp.Choices[0].Delta.Content
+					if content != "" {
+						sent = true
+						if err := onChunk(content); err != nil {
+							return &stopError{err: err}
+						}
 					}
 				}
 			}
 		}
-	}
+	})
 }
 
 // Chat 普通对话（非流式）
-func (c *Client) Chat(ctx context.Context, messages []openai.ChatCompletionMessage) (string, error) {
-	req := openai.ChatCompletionRequest{
-		Model:       c.model,
-		Messages:    messages,
-		Temperature: float32(c.temp),
-		MaxTokens:   c.maxTokens,
-	}
+func (c *Client) Chat(ctx context.Context, messages []openai.ChatCompletionMessage, opts ...CallOption) (string, error) {
+	var reply string
 
-	resp, err := c.client.CreateChatCompletion(ctx, req)
-	if err != nil {
-		return "", fmt.Errorf("AI调用失败: %v", err)
-	}
+	err := c.invoke(ctx, func(ctx context.Context, p *provider) error {
+		if d := c.callTimeout(); d > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, d)
+			defer cancel()
+		}
 
-	if len(resp.Choices) > 0 {
-		return resp.Choices[0].Message.Content, nil
-	}
+		model, temp := c.resolve(p, opts)
+		req := openai.ChatCompletionRequest{
+			Model:       model,
+			Messages:    messages,
+			Temperature: temp,
+			MaxTokens:   c.maxTokens,
+		}
+
+		resp, err := p.client.CreateChatCompletion(ctx, req)
+		if err != nil {
+			return fmt.Errorf("AI调用失败: %w", err)
+		}
 
-	return "", errors.New("AI返回为空")
+		if len(resp.Choices) == 0 {
+			return errors.New("AI返回为空")
+		}
+		reply = resp.Choices[0].Message.Content
+		return nil
+	})
+
+	return reply, err
+}
+
+// Model 返回主提供方的模型名称
+func (c *Client) Model() string {
+	return c.model
+}
+
+// ProviderStates 返回各提供方的熔断器状态，用于监控
+func (c *Client) ProviderStates() map[string]string {
+	states := make(map[string]string, len(c.providers))
+	for _, p := range c.providers {
+		states[p.name] = p.breaker.State()
+	}
+	return states
 }
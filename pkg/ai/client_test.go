@@ -0,0 +1,300 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// upstream 模拟的OpenAI兼容上游，按请求顺序依次使用responses中的处理函数
+type upstream struct {
+	*httptest.Server
+	mu        sync.Mutex
+	responses []http.HandlerFunc
+	models    []string // 各请求的模型
+}
+
+func newUpstream(t *testing.T, responses ...http.HandlerFunc) *upstream {
+	t.Helper()
+	u := &upstream{responses: responses}
+	u.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openai.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		u.mu.Lock()
+		u.models = append(u.models, req.Model)
+		n := len(u.models)
+		u.mu.Unlock()
+		if n > len(u.responses) {
+			t.Errorf("第%d个请求超出预期", n)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		u.responses[n-1](w, r)
+	}))
+	t.Cleanup(u.Close)
+	return u
+}
+
+// requests 已收到的请求所用的模型
+func (u *upstream) requests() []string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return append([]string(nil), u.models...)
+}
+
+func (u *upstream) baseURL() string {
+	return u.URL + "/v1"
+}
+
+// status 返回错误状态码，retryAfter不为空时设置Retry-After
+func status(code int, retryAfter string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if retryAfter != "" {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		fmt.Fprintf(w, `{"error":{"message":"status %d","type":"server_error"}}`, code)
+	}
+}
+
+// reply 返回非流式回复
+func reply(content string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":%q}}]}`, content)
+	}
+}
+
+// stream 返回流式回复；broken为true时输出完数据块后直接断开连接，不发送结束标记
+func stream(broken bool, chunks ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range chunks {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":%q}}]}\n\n", chunk)
+		}
+		w.(http.Flusher).Flush()
+		if broken {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+			return
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}
+}
+
+// hang 直到请求被取消都不返回
+func hang(w http.ResponseWriter, r *http.Request) {
+	<-r.Context().Done()
+}
+
+// fastRetry 测试用的重试策略，退避时间很短
+var fastRetry = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+func newTestClient(t *testing.T, baseURL string, timeout int, opts ...Option) *Client {
+	t.Helper()
+	c, err := NewClient("test-key", baseURL, "primary-model", 0.7, 100, timeout, append([]Option{WithRetryPolicy(fastRetry)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func equalStrings(a, b []string) bool {
+	return strings.Join(a, ",") == strings.Join(b, ",")
+}
+
+func TestChatRetry(t *testing.T) {
+	u := newUpstream(t, status(500, ""), status(502, ""), reply("你好"))
+	c := newTestClient(t, u.baseURL(), 0)
+
+	got, err := c.Chat(context.Background(), nil)
+	if err != nil || got != "你好" {
+		t.Fatalf("Chat = %q, %v", got, err)
+	}
+	if models := u.requests(); len(models) != 3 {
+		t.Fatalf("请求次数 = %d，期望 3", len(models))
+	}
+	if c.ProviderStates()[hostOf(u.URL)] != "closed" {
+		t.Fatalf("成功后熔断器状态 = %v", c.ProviderStates())
+	}
+}
+
+func TestChatNotRetryable(t *testing.T) {
+	primary := newUpstream(t, status(400, ""))
+	fallback := newUpstream(t)
+	c := newTestClient(t, primary.baseURL(), 0, WithFallbacks(Provider{BaseURL: fallback.baseURL()}))
+
+	_, err := c.Chat(context.Background(), nil)
+	var apiErr *openai.APIError
+	if !errors.As(err, &apiErr) || apiErr.HTTPStatusCode != 400 {
+		t.Fatalf("Chat 错误 = %v，期望上游的400错误", err)
+	}
+	if len(primary.requests()) != 1 || len(fallback.requests()) != 0 {
+		t.Fatalf("参数错误被重试或切换了提供方: %v, %v", primary.requests(), fallback.requests())
+	}
+}
+
+func TestChatRetryAfter(t *testing.T) {
+	u := newUpstream(t, status(429, "1"), reply("ok"))
+	c := newTestClient(t, u.baseURL(), 0, WithRetryPolicy(RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Second}))
+
+	start := time.Now()
+	if _, err := c.Chat(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	// 按 Retry-After 等待，而不是很短的退避时间
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Fatalf("重试前只等待了 %v，期望按 Retry-After 等待1秒", elapsed)
+	}
+}
+
+func TestChatFallbackOrder(t *testing.T) {
+	// Retry-After 超过退避上限时不在主提供方等待，直接切换
+	primary := newUpstream(t, status(429, "60"))
+	second := newUpstream(t, status(503, ""), status(503, ""), status(503, ""))
+	third := newUpstream(t, reply("来自第三个提供方"))
+	c := newTestClient(t, primary.baseURL(), 0, WithFallbacks(
+		Provider{Name: "second", BaseURL: second.baseURL(), Model: "second-model"},
+		Provider{Name: "third", BaseURL: third.baseURL(), Model: "third-model"},
+	))
+
+	got, err := c.Chat(context.Background(), nil, WithModel("override-model"))
+	if err != nil || got != "来自第三个提供方" {
+		t.Fatalf("Chat = %q, %v", got, err)
+	}
+	// 指定的模型只作用于主提供方，备用提供方使用各自的模型
+	if models := primary.requests(); !equalStrings(models, []string{"override-model"}) {
+		t.Fatalf("主提供方的请求 = %v", models)
+	}
+	if models := second.requests(); !equalStrings(models, []string{"second-model", "second-model", "second-model"}) {
+		t.Fatalf("第二个提供方的请求 = %v", models)
+	}
+	if models := third.requests(); !equalStrings(models, []string{"third-model"}) {
+		t.Fatalf("第三个提供方的请求 = %v", models)
+	}
+}
+
+func TestChatCircuitBreaker(t *testing.T) {
+	primary := newUpstream(t, status(500, ""), status(500, ""))
+	fallback := newUpstream(t, reply("a"), reply("b"))
+	c := newTestClient(t, primary.baseURL(), 0,
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}),
+		WithCircuitBreaker(2, time.Minute),
+		WithFallbacks(Provider{Name: "fallback", BaseURL: fallback.baseURL()}),
+	)
+
+	if got, err := c.Chat(context.Background(), nil); err != nil || got != "a" {
+		t.Fatalf("第一次 Chat = %q, %v", got, err)
+	}
+	if state := c.ProviderStates()[hostOf(primary.URL)]; state != "open" {
+		t.Fatalf("主提供方连续失败后熔断器状态 = %s", state)
+	}
+	// 熔断期间不再请求主提供方
+	if got, err := c.Chat(context.Background(), nil); err != nil || got != "b" {
+		t.Fatalf("第二次 Chat = %q, %v", got, err)
+	}
+	if n := len(primary.requests()); n != 2 {
+		t.Fatalf("主提供方收到 %d 个请求，期望 2", n)
+	}
+}
+
+func TestChatUnavailable(t *testing.T) {
+	u := newUpstream(t, status(500, ""), status(500, ""), status(500, ""))
+	c := newTestClient(t, u.baseURL(), 0)
+
+	if _, err := c.Chat(context.Background(), nil); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("重试耗尽后错误 = %v，期望 ErrUnavailable", err)
+	}
+}
+
+// collect 收集流式回复
+func collect(c *Client) (string, error) {
+	var sb strings.Builder
+	err := c.StreamChat(context.Background(), nil, func(s string) error {
+		sb.WriteString(s)
+		return nil
+	})
+	return sb.String(), err
+}
+
+func TestStreamChatRetryBeforeFirstToken(t *testing.T) {
+	u := newUpstream(t, status(500, ""), stream(false, "你", "好"))
+	c := newTestClient(t, u.baseURL(), 0)
+
+	got, err := collect(c)
+	if err != nil || got != "你好" {
+		t.Fatalf("StreamChat = %q, %v", got, err)
+	}
+	if n := len(u.requests()); n != 2 {
+		t.Fatalf("请求次数 = %d，期望 2", n)
+	}
+}
+
+func TestStreamChatNoRetryAfterToken(t *testing.T) {
+	primary := newUpstream(t, stream(true, "部分"))
+	fallback := newUpstream(t)
+	c := newTestClient(t, primary.baseURL(), 0, WithFallbacks(Provider{BaseURL: fallback.baseURL()}))
+
+	got, err := collect(c)
+	if err == nil {
+		t.Fatal("中途断开时没有返回错误")
+	}
+	if errors.Is(err, ErrUnavailable) {
+		t.Fatalf("已输出内容后切换了提供方: %v", err)
+	}
+	// 已输出的内容不会重复
+	if got != "部分" {
+		t.Fatalf("输出 = %q", got)
+	}
+	if len(primary.requests()) != 1 || len(fallback.requests()) != 0 {
+		t.Fatalf("已输出内容后仍重试: %v, %v", primary.requests(), fallback.requests())
+	}
+}
+
+func TestStreamChatIdleTimeout(t *testing.T) {
+	u := newUpstream(t, hang, stream(false, "ok"))
+	c := newTestClient(t, u.baseURL(), 1)
+
+	start := time.Now()
+	got, err := collect(c)
+	if err != nil || got != "ok" {
+		t.Fatalf("StreamChat = %q, %v", got, err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Fatalf("空闲 %v 就超时了", elapsed)
+	}
+	if n := len(u.requests()); n != 2 {
+		t.Fatalf("请求次数 = %d，期望首个数据块超时后重试一次", n)
+	}
+}
+
+func TestStreamChatCallerAbort(t *testing.T) {
+	primary := newUpstream(t, stream(false, "a", "b"))
+	fallback := newUpstream(t)
+	c := newTestClient(t, primary.baseURL(), 0, WithFallbacks(Provider{BaseURL: fallback.baseURL()}))
+
+	errStop := errors.New("客户端断开")
+	err := c.StreamChat(context.Background(), nil, func(string) error { return errStop })
+	if !errors.Is(err, errStop) {
+		t.Fatalf("StreamChat 错误 = %v，期望 %v", err, errStop)
+	}
+	if len(fallback.requests()) != 0 {
+		t.Fatal("调用方中止后切换了提供方")
+	}
+	if state := c.ProviderStates()[hostOf(primary.URL)]; state != "closed" {
+		t.Fatalf("调用方中止计入了提供方失败: %s", state)
+	}
+}
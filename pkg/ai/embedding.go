@@ -2,19 +2,47 @@ package ai
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"log"
 	"os"
+	"sync"
+	"unicode/utf8"
 
 	"github.com/sashabaranov/go-openai"
 )
 
+// 批量向量化的默认限制
+const (
+	defaultEmbeddingBatchSize   = 32
+	defaultEmbeddingBatchTokens = 8000
+	defaultEmbeddingConcurrency = 4
+)
+
+// EmbeddingCache 向量缓存，以文本内容的哈希为键
+// 缓存的向量与模型和维度绑定，调用方需按模型（向量空间）区分
+type EmbeddingCache interface {
+	// GetMany 查询已缓存的向量，返回命中的部分
+	GetMany(ctx context.Context, hashes []string) (map[string][]float32, error)
+	// SetMany 写入向量，已存在的忽略
+	SetMany(ctx context.Context, embeddings map[string][]float32) error
+}
+
 // EmbeddingClient 向量化客户端
 type EmbeddingClient struct {
-	apiKey  string
-	baseURL string
-	model   string
-	client  *openai.Client
+	apiKey     string
+	baseURL    string
+	model      string
+	dimensions int // 期望的向量维度，0表示不校验
+	client     *openai.Client
+
+	batchSize   int // 单次请求的最大文本数
+	batchTokens int // 单次请求的最大token数（估算）
+	concurrency int // 同时发出的批量请求数
+	retry       RetryPolicy
+	cache       EmbeddingCache
 }
 
 // NewEmbeddingClient 创建向量化客户端
@@ -40,17 +68,73 @@ func NewEmbeddingClient(apiKey, baseURL, model string) (*EmbeddingClient, error)
 		// DeepSeek的embedding API需要使用v1路径
 		cfg.BaseURL = baseURL + "/v1"
 	}
+	cfg.HTTPClient = newHTTPClient()
 
 	client := openai.NewClientWithConfig(cfg)
 
 	return &EmbeddingClient{
-		apiKey:  apiKey,
-		baseURL: baseURL,
-		model:   model,
-		client:  client,
+		apiKey:      apiKey,
+		baseURL:     baseURL,
+		model:       model,
+		client:      client,
+		batchSize:   defaultEmbeddingBatchSize,
+		batchTokens: defaultEmbeddingBatchTokens,
+		concurrency: defaultEmbeddingConcurrency,
+		retry:       DefaultRetryPolicy(),
 	}, nil
 }
 
+// SetDimensions 设置期望的向量维度，返回的向量维度不一致时报错
+func (c *EmbeddingClient) SetDimensions(dimensions int) {
+	c.dimensions = dimensions
+}
+
+// SetBatchLimits 设置单次请求的最大文本数和最大token数（按字符数估算），<=0时使用默认值
+func (c *EmbeddingClient) SetBatchLimits(size, tokens int) {
+	if size > 0 {
+		c.batchSize = size
+	}
+	if tokens > 0 {
+		c.batchTokens = tokens
+	}
+}
+
+// SetConcurrency 设置同时发出的批量请求数，<=0时使用默认值
+func (c *EmbeddingClient) SetConcurrency(n int) {
+	if n > 0 {
+		c.concurrency = n
+	}
+}
+
+// SetRetryPolicy 设置每批请求的重试策略
+func (c *EmbeddingClient) SetRetryPolicy(p RetryPolicy) {
+	c.retry = p.normalize()
+}
+
+// SetCache 设置向量缓存，相同内容的文本只向量化一次
+func (c *EmbeddingClient) SetCache(cache EmbeddingCache) {
+	c.cache = cache
+}
+
+// Model 向量化模型名称
+func (c *EmbeddingClient) Model() string {
+	return c.model
+}
+
+// ContentHash 文本内容的哈希，作为向量缓存的键
+func ContentHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// checkDimensions 校验向量维度
+func (c *EmbeddingClient) checkDimensions(embedding []float32) error {
+	if c.dimensions > 0 && len(embedding) != c.dimensions {
+		return fmt.Errorf("模型 %s 返回的向量维度为 %d，与配置的 %d 不一致", c.model, len(embedding), c.dimensions)
+	}
+	return nil
+}
+
 // GetEmbedding 获取文本的向量表示
 func (c *EmbeddingClient) GetEmbedding(ctx context.Context, text string) ([]float32, error) {
 	req := openai.EmbeddingRequest{
@@ -66,26 +150,193 @@ func (c *EmbeddingClient) GetEmbedding(ctx context.Context, text string) ([]floa
 	if len(resp.Data) == 0 {
 		return nil, errors.New("向量化结果为空")
 	}
+	if err := c.checkDimensions(resp.Data[0].Embedding); err != nil {
+		return nil, err
+	}
 
 	return resp.Data[0].Embedding, nil
 }
 
-// GetEmbeddings 批量获取文本的向量表示
+// GetEmbeddings 批量获取文本的向量表示，结果与texts一一对应
+// 相同内容只向量化一次（含缓存中已有的），其余按提供方限制拆分成多批并发请求，每批独立重试
 func (c *EmbeddingClient) GetEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	hashes := make([]string, len(texts))
+	unique := make(map[string]string, len(texts)) // 哈希 -> 文本
+	for i, text := range texts {
+		hashes[i] = ContentHash(text)
+		unique[hashes[i]] = text
+	}
+
+	found := make(map[string][]float32, len(unique))
+	if c.cache != nil {
+		keys := make([]string, 0, len(unique))
+		for hash := range unique {
+			keys = append(keys, hash)
+		}
+		cached, err := c.cache.GetMany(ctx, keys)
+		if err != nil {
+			log.Printf("读取向量缓存失败: %v", err)
+		}
+		for hash, embedding := range cached {
+			if c.checkDimensions(embedding) == nil {
+				found[hash] = embedding
+			}
+		}
+	}
+
+	// 按原始顺序收集未命中的文本
+	var missing []string
+	var missingHashes []string
+	for _, hash := range hashes {
+		if _, ok := found[hash]; ok {
+			continue
+		}
+		if text, ok := unique[hash]; ok {
+			missing = append(missing, text)
+			missingHashes = append(missingHashes, hash)
+			delete(unique, hash)
+		}
+	}
+
+	if len(missing) > 0 {
+		embeddings, err := c.embedBatches(ctx, missing)
+		if err != nil {
+			return nil, err
+		}
+		created := make(map[string][]float32, len(missing))
+		for i, hash := range missingHashes {
+			found[hash] = embeddings[i]
+			created[hash] = embeddings[i]
+		}
+		if c.cache != nil {
+			if err := c.cache.SetMany(ctx, created); err != nil {
+				log.Printf("写入向量缓存失败: %v", err)
+			}
+		}
+	}
+
+	result := make([][]float32, len(texts))
+	for i, hash := range hashes {
+		result[i] = found[hash]
+	}
+	return result, nil
+}
+
+// splitBatches 按文本数和估算的token数拆分批次，返回每批在texts中的起止下标
+// 单个文本超过token限制时单独成批，由提供方决定是否接受
+func (c *EmbeddingClient) splitBatches(texts []string) [][2]int {
+	var batches [][2]int
+	start, tokens := 0, 0
+	for i, text := range texts {
+		// 没有分词器，按字符数估算（中文接近1字符1token，英文会高估）
+		n := utf8.RuneCountInString(text)
+		if i > start && (i-start >= c.batchSize || tokens+n > c.batchTokens) {
+			batches = append(batches, [2]int{start, i})
+			start, tokens = i, 0
+		}
+		tokens += n
+	}
+	if start < len(texts) {
+		batches = append(batches, [2]int{start, len(texts)})
+	}
+	return batches
+}
+
+// embedBatches 分批并发向量化，任一批最终失败时取消其余批次
+func (c *EmbeddingClient) embedBatches(ctx context.Context, texts []string) ([][]float32, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	result := make([][]float32, len(texts))
+	sem := make(chan struct{}, c.concurrency)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+
+	for _, batch := range c.splitBatches(texts) {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			embeddings, err := c.embedBatch(ctx, texts[start:end])
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			copy(result[start:end], embeddings)
+		}(batch[0], batch[1])
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// embedBatch 向量化一批文本，可重试的错误按退避策略重试（遵循Retry-After）
+func (c *EmbeddingClient) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
 	req := openai.EmbeddingRequest{
 		Model: openai.EmbeddingModel(c.model),
 		Input: texts,
 	}
 
-	resp, err := c.client.CreateEmbeddings(ctx, req)
-	if err != nil {
-		return nil, fmt.Errorf("embedding请求失败: %v", err)
-	}
+	var err error
+	for attempt := 0; attempt < c.retry.MaxAttempts; attempt++ {
+		attemptCtx, hint := withRetryHint(ctx)
+		var resp openai.EmbeddingResponse
+		resp, err = c.client.CreateEmbeddings(attemptCtx, req)
+		if err == nil {
+			return c.batchResult(resp, len(texts))
+		}
+		if ctx.Err() != nil || !IsRetryable(err) || attempt == c.retry.MaxAttempts-1 {
+			break
+		}
 
-	embeddings := make([][]float32, len(resp.Data))
-	for i, data := range resp.Data {
-		embeddings[i] = data.Embedding
+		delay := c.retry.backoff(attempt)
+		if hint.after > 0 {
+			// 上游要求等待的时间超过上限，不再重试
+			if hint.after > c.retry.MaxDelay {
+				break
+			}
+			delay = hint.after
+		}
+		if sleepErr := sleep(ctx, delay); sleepErr != nil {
+			return nil, sleepErr
+		}
 	}
+	return nil, fmt.Errorf("embedding请求失败: %v", err)
+}
 
+// batchResult 按Index整理批量请求的结果
+func (c *EmbeddingClient) batchResult(resp openai.EmbeddingResponse, n int) ([][]float32, error) {
+	if len(resp.Data) != n {
+		return nil, fmt.Errorf("向量化返回 %d 个结果，请求了 %d 个", len(resp.Data), n)
+	}
+	embeddings := make([][]float32, n)
+	for _, data := range resp.Data {
+		if data.Index < 0 || data.Index >= n || embeddings[data.Index] != nil {
+			return nil, fmt.Errorf("向量化结果的序号 %d 不合法", data.Index)
+		}
+		if err := c.checkDimensions(data.Embedding); err != nil {
+			return nil, err
+		}
+		embeddings[data.Index] = data.Embedding
+	}
 	return embeddings, nil
 }
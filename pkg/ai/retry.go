@@ -0,0 +1,164 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// RetryPolicy 重试策略
+// 采用带抖动的指数退避，遇到429/5xx等可重试错误时自动重试
+type RetryPolicy struct {
+	MaxAttempts int           // 单个提供方的最大尝试次数（含首次）
+	BaseDelay   time.Duration // 初始退避时间
+	MaxDelay    time.Duration // 单次退避上限，Retry-After超过该值时直接切换提供方
+}
+
+// DefaultRetryPolicy 默认重试策略
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    10 * time.Second,
+	}
+}
+
+// normalize 补齐未配置的字段
+func (p RetryPolicy) normalize() RetryPolicy {
+	def := DefaultRetryPolicy()
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = def.MaxAttempts
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = def.BaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = def.MaxDelay
+	}
+	return p
+}
+
+// backoff 计算第attempt次失败后的等待时间（full jitter）
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay << uint(attempt)
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+// sleep 等待指定时间，上下文取消时提前返回
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// IsRetryable 判断错误是否值得重试
+// 429、408、5xx以及网络错误视为可重试；4xx参数/鉴权错误直接返回
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return retryableStatus(apiErr.HTTPStatusCode)
+	}
+
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		if reqErr.HTTPStatusCode == 0 {
+			return true
+		}
+		return retryableStatus(reqErr.HTTPStatusCode)
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// retryableStatus 可重试的HTTP状态码
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests ||
+		code == http.StatusRequestTimeout ||
+		code >= http.StatusInternalServerError
+}
+
+// retryHint 记录上游返回的Retry-After
+type retryHint struct {
+	after time.Duration
+}
+
+type retryHintKey struct{}
+
+// withRetryHint 在上下文中挂载Retry-After记录器
+func withRetryHint(ctx context.Context) (context.Context, *retryHint) {
+	hint := &retryHint{}
+	return context.WithValue(ctx, retryHintKey{}, hint), hint
+}
+
+// retryAfterTransport 读取429/503响应的Retry-After头
+// go-openai的错误类型不携带响应头，因此在Transport层截获后写回请求上下文
+type retryAfterTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip 实现http.RoundTripper
+func (t *retryAfterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		if hint, ok := req.Context().Value(retryHintKey{}).(*retryHint); ok {
+			hint.after = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		}
+	}
+	return resp, nil
+}
+
+// parseRetryAfter 解析Retry-After（秒数或HTTP日期）
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// newHTTPClient 创建带Retry-After解析能力的HTTP客户端
+func newHTTPClient() *http.Client {
+	return &http.Client{
+		Transport: &retryAfterTransport{base: http.DefaultTransport},
+	}
+}
@@ -0,0 +1,100 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"3", 3 * time.Second},
+		{"0", 0},
+		{"-1", 0},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"soon", 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v，期望 %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt, limit := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second} {
+		seen := make(map[time.Duration]bool)
+		for i := 0; i < 200; i++ {
+			d := p.backoff(attempt)
+			if d < 0 || d > limit {
+				t.Fatalf("第%d次失败后等待 %v，超出 [0, %v]", attempt, d, limit)
+			}
+			seen[d] = true
+		}
+		// full jitter：等待时间随机分布
+		if len(seen) < 10 {
+			t.Fatalf("第%d次失败后的等待时间没有抖动: %v", attempt, seen)
+		}
+	}
+
+	// 位移溢出时使用上限
+	if d := p.backoff(80); d < 0 || d > p.MaxDelay {
+		t.Fatalf("溢出时等待 %v", d)
+	}
+}
+
+func TestRetryPolicyNormalize(t *testing.T) {
+	got := RetryPolicy{MaxAttempts: 1}.normalize()
+	def := DefaultRetryPolicy()
+	if got.MaxAttempts != 1 || got.BaseDelay != def.BaseDelay || got.MaxDelay != def.MaxDelay {
+		t.Fatalf("normalize = %+v", got)
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+var _ net.Error = timeoutError{}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"429", &openai.APIError{HTTPStatusCode: 429}, true},
+		{"408", &openai.APIError{HTTPStatusCode: 408}, true},
+		{"500", fmt.Errorf("AI调用失败: %w", &openai.APIError{HTTPStatusCode: 500}), true},
+		{"400", &openai.APIError{HTTPStatusCode: 400}, false},
+		{"401", &openai.RequestError{HTTPStatusCode: 401, Err: errors.New("unauthorized")}, false},
+		{"503", &openai.RequestError{HTTPStatusCode: 503, Err: errors.New("unavailable")}, true},
+		{"没有响应", &openai.RequestError{Err: errors.New("connection reset")}, true},
+		{"超时", context.DeadlineExceeded, true},
+		{"流式空闲超时", errStreamIdle, true},
+		{"网络错误", &net.OpError{Op: "dial", Err: timeoutError{}}, true},
+		{"调用方取消", context.Canceled, false},
+		{"其他错误", errors.New("AI返回为空"), false},
+	}
+	for _, tt := range tests {
+		if got := IsRetryable(tt.err); got != tt.want {
+			t.Errorf("%s: IsRetryable(%v) = %v，期望 %v", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrQueueFull 排队人数已达上限
+var ErrQueueFull = errors.New("请求排队已满，请稍后重试")
+
+// Priority 上游请求优先级
+type Priority int
+
+const (
+	PriorityBatch       Priority = iota // 后台任务（标题生成、重建索引等）
+	PriorityStandard                    // 同步对话
+	PriorityInteractive                 // 流式对话，用户正在等待首个token
+
+	numPriorities = 3
+)
+
+// String 优先级名称，用于监控标签
+func (p Priority) String() string {
+	switch p {
+	case PriorityInteractive:
+		return "interactive"
+	case PriorityStandard:
+		return "standard"
+	default:
+		return "batch"
+	}
+}
+
+// Scheduler 上游并发调度器
+// 限制同时发往提供方的请求数；超出部分按优先级排队，同一优先级内按用户轮转，
+// 避免单个用户的大量请求占满共享的API Key额度
+type Scheduler struct {
+	mu       sync.Mutex
+	limit    int
+	maxQueue int
+	inFlight int
+	queued   int
+	queues   [numPriorities]*fairQueue
+
+	// 监控指标
+	waitCount int64
+	waitSum   time.Duration
+	waitMax   time.Duration
+}
+
+// SchedulerStats 调度器监控指标
+type SchedulerStats struct {
+	Limit     int
+	InFlight  int
+	Queued    map[string]int // 按优先级统计的排队数
+	WaitCount int64          // 经过排队的请求数
+	WaitSum   time.Duration  // 累计排队时间
+	WaitMax   time.Duration  // 最长排队时间
+}
+
+// waiter 排队中的请求
+type waiter struct {
+	userID     uint
+	enqueuedAt time.Time
+	ready      chan struct{}
+	positions  chan int // 最新排队位置，容量为1，只保留最新值
+	position   int
+	granted    bool
+}
+
+// fairQueue 单个优先级的公平队列，按用户轮转出队
+type fairQueue struct {
+	users   []uint
+	waiters map[uint][]*waiter
+}
+
+// NewScheduler 创建调度器
+// limit: 最大并发数；maxQueue: 最大排队数，<=0表示不限制
+func NewScheduler(limit, maxQueue int) *Scheduler {
+	if limit <= 0 {
+		limit = 1
+	}
+	s := &Scheduler{limit: limit, maxQueue: maxQueue}
+	for i := range s.queues {
+		s.queues[i] = &fairQueue{waiters: make(map[uint][]*waiter)}
+	}
+	return s
+}
+
+// Acquire 获取一个上游并发额度
+// 排队期间排队位置变化时调用onPosition（在调用方goroutine中执行，位置从1开始）
+// 返回的release必须在上游调用结束后调用
+func (s *Scheduler) Acquire(ctx context.Context, userID uint, prio Priority, onPosition func(position int)) (release func(), err error) {
+	if prio < 0 || prio >= numPriorities {
+		prio = PriorityStandard
+	}
+
+	s.mu.Lock()
+	if s.inFlight < s.limit && s.queued == 0 {
+		s.inFlight++
+		s.mu.Unlock()
+		return s.releaseFunc(), nil
+	}
+	if s.maxQueue > 0 && s.queued >= s.maxQueue {
+		s.mu.Unlock()
+		return nil, ErrQueueFull
+	}
+
+	w := &waiter{
+		userID:     userID,
+		enqueuedAt: time.Now(),
+		ready:      make(chan struct{}),
+		positions:  make(chan int, 1),
+	}
+	s.queues[prio].push(w)
+	s.queued++
+	s.updatePositions()
+	s.mu.Unlock()
+
+	for {
+		select {
+		case <-w.ready:
+			return s.releaseFunc(), nil
+		case pos := <-w.positions:
+			if onPosition != nil {
+				onPosition(pos)
+			}
+		case <-ctx.Done():
+			s.mu.Lock()
+			if w.granted {
+				// 出队与取消同时发生：额度已分配，直接归还
+				s.mu.Unlock()
+				s.releaseFunc()()
+				return nil, ctx.Err()
+			}
+			s.queues[prio].remove(w)
+			s.queued--
+			s.updatePositions()
+			s.mu.Unlock()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// releaseFunc 返回只生效一次的额度释放函数
+func (s *Scheduler) releaseFunc() func() {
+	var once sync.Once
+	return func() {
+		once.Do(s.release)
+	}
+}
+
+// release 归还额度并唤醒下一个排队请求
+func (s *Scheduler) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.inFlight--
+	for s.inFlight < s.limit && s.queued > 0 {
+		w := s.next()
+		if w == nil {
+			break
+		}
+		s.queued--
+		s.inFlight++
+
+		wait := time.Since(w.enqueuedAt)
+		s.waitCount++
+		s.waitSum += wait
+		if wait > s.waitMax {
+			s.waitMax = wait
+		}
+
+		w.granted = true
+		close(w.ready)
+	}
+	s.updatePositions()
+}
+
+// next 从最高优先级的非空队列中按用户轮转取出一个请求（调用方需持有锁）
+func (s *Scheduler) next() *waiter {
+	for p := numPriorities - 1; p >= 0; p-- {
+		if w := s.queues[p].pop(); w != nil {
+			return w
+		}
+	}
+	return nil
+}
+
+// updatePositions 按出队顺序重新计算排队位置，并通知位置发生变化的请求（调用方需持有锁）
+func (s *Scheduler) updatePositions() {
+	pos := 0
+	for p := numPriorities - 1; p >= 0; p-- {
+		q := s.queues[p]
+		for round := 0; ; round++ {
+			found := false
+			for _, uid := range q.users {
+				ws := q.waiters[uid]
+				if round >= len(ws) {
+					continue
+				}
+				found = true
+				pos++
+				w := ws[round]
+				if w.position != pos {
+					w.position = pos
+					select {
+					case <-w.positions:
+					default:
+					}
+					w.positions <- pos
+				}
+			}
+			if !found {
+				break
+			}
+		}
+	}
+}
+
+// Stats 返回调度器监控指标
+func (s *Scheduler) Stats() SchedulerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queued := make(map[string]int, numPriorities)
+	for p := 0; p < numPriorities; p++ {
+		n := 0
+		for _, ws := range s.queues[p].waiters {
+			n += len(ws)
+		}
+		queued[Priority(p).String()] = n
+	}
+
+	return SchedulerStats{
+		Limit:     s.limit,
+		InFlight:  s.inFlight,
+		Queued:    queued,
+		WaitCount: s.waitCount,
+		WaitSum:   s.waitSum,
+		WaitMax:   s.waitMax,
+	}
+}
+
+// push 入队
+func (q *fairQueue) push(w *waiter) {
+	if len(q.waiters[w.userID]) == 0 {
+		q.users = append(q.users, w.userID)
+	}
+	q.waiters[w.userID] = append(q.waiters[w.userID], w)
+}
+
+// pop 取出轮转到的用户的最早请求，并把该用户移到队尾
+func (q *fairQueue) pop() *waiter {
+	if len(q.users) == 0 {
+		return nil
+	}
+	uid := q.users[0]
+	ws := q.waiters[uid]
+	w := ws[0]
+
+	q.users = q.users[1:]
+	if len(ws) > 1 {
+		q.waiters[uid] = ws[1:]
+		q.users = append(q.users, uid)
+	} else {
+		delete(q.waiters, uid)
+	}
+	return w
+}
+
+// remove 移除指定请求（请求方取消时）
+func (q *fairQueue) remove(w *waiter) {
+	ws := q.waiters[w.userID]
+	for i, x := range ws {
+		if x == w {
+			ws = append(ws[:i], ws[i+1:]...)
+			break
+		}
+	}
+	if len(ws) > 0 {
+		q.waiters[w.userID] = ws
+		return
+	}
+
+	delete(q.waiters, w.userID)
+	for i, uid := range q.users {
+		if uid == w.userID {
+			q.users = append(q.users[:i], q.users[i+1:]...)
+			break
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// grant 获得额度的请求
+type grant struct {
+	name    string
+	release func()
+}
+
+// queuedRequest 排队中的请求
+type queuedRequest struct {
+	cancel context.CancelFunc
+	err    chan error
+
+	mu        sync.Mutex
+	positions []int
+}
+
+// lastPosition 最近一次通知的排队位置
+func (r *queuedRequest) lastPosition() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.positions) == 0 {
+		return 0
+	}
+	return r.positions[len(r.positions)-1]
+}
+
+// schedulerTest 按顺序向调度器提交请求，获得额度的请求发送到granted
+type schedulerTest struct {
+	t       *testing.T
+	s       *Scheduler
+	granted chan grant
+}
+
+func newSchedulerTest(t *testing.T, limit, maxQueue int) *schedulerTest {
+	return &schedulerTest{t: t, s: NewScheduler(limit, maxQueue), granted: make(chan grant, 16)}
+}
+
+// totalQueued 排队中的请求数
+func (st *schedulerTest) totalQueued() int {
+	n := 0
+	for _, v := range st.s.Stats().Queued {
+		n += v
+	}
+	return n
+}
+
+// waitFor 等待条件成立
+func (st *schedulerTest) waitFor(what string, cond func() bool) {
+	st.t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			st.t.Fatalf("等待%s超时", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// acquire 立即获得额度
+func (st *schedulerTest) acquire(userID uint) func() {
+	st.t.Helper()
+	release, err := st.s.Acquire(context.Background(), userID, PriorityStandard, nil)
+	if err != nil {
+		st.t.Fatal(err)
+	}
+	return release
+}
+
+// enqueue 提交一个会排队的请求，等到它进入队列后返回
+func (st *schedulerTest) enqueue(name string, userID uint, prio Priority) *queuedRequest {
+	st.t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &queuedRequest{cancel: cancel, err: make(chan error, 1)}
+	before := st.totalQueued()
+	go func() {
+		release, err := st.s.Acquire(ctx, userID, prio, func(pos int) {
+			r.mu.Lock()
+			r.positions = append(r.positions, pos)
+			r.mu.Unlock()
+		})
+		r.err <- err
+		if err == nil {
+			st.granted <- grant{name: name, release: release}
+		}
+	}()
+	st.waitFor(name+"入队", func() bool { return st.totalQueued() == before+1 })
+	return r
+}
+
+// order 依次释放当前额度，返回之后获得额度的请求顺序
+func (st *schedulerTest) order(release func(), n int) []string {
+	st.t.Helper()
+	var names []string
+	for i := 0; i < n; i++ {
+		release()
+		select {
+		case g := <-st.granted:
+			names = append(names, g.name)
+			release = g.release
+		case <-time.After(2 * time.Second):
+			st.t.Fatalf("等待第%d个请求获得额度超时，已获得: %v", i+1, names)
+		}
+	}
+	release()
+	return names
+}
+
+func TestSchedulerImmediate(t *testing.T) {
+	st := newSchedulerTest(t, 2, 0)
+	r1 := st.acquire(1)
+	r2 := st.acquire(1)
+	if stats := st.s.Stats(); stats.InFlight != 2 {
+		t.Fatalf("InFlight = %d", stats.InFlight)
+	}
+
+	// 重复释放只生效一次
+	r1()
+	r1()
+	if stats := st.s.Stats(); stats.InFlight != 1 {
+		t.Fatalf("重复释放后 InFlight = %d", stats.InFlight)
+	}
+	r2()
+	if stats := st.s.Stats(); stats.InFlight != 0 || stats.WaitCount != 0 {
+		t.Fatalf("Stats = %+v", stats)
+	}
+}
+
+func TestSchedulerUserFairness(t *testing.T) {
+	st := newSchedulerTest(t, 1, 0)
+	release := st.acquire(9)
+
+	// 用户1先提交了3个请求，之后用户2、3各提交1个
+	st.enqueue("u1-a", 1, PriorityStandard)
+	st.enqueue("u1-b", 1, PriorityStandard)
+	st.enqueue("u1-c", 1, PriorityStandard)
+	st.enqueue("u2-a", 2, PriorityStandard)
+	st.enqueue("u3-a", 3, PriorityStandard)
+
+	got := st.order(release, 5)
+	want := []string{"u1-a", "u2-a", "u3-a", "u1-b", "u1-c"}
+	if !equalStrings(got, want) {
+		t.Fatalf("出队顺序 = %v，期望 %v", got, want)
+	}
+	if stats := st.s.Stats(); stats.InFlight != 0 || stats.WaitCount != 5 || stats.WaitMax <= 0 {
+		t.Fatalf("Stats = %+v", stats)
+	}
+}
+
+func TestSchedulerPriority(t *testing.T) {
+	st := newSchedulerTest(t, 1, 0)
+	release := st.acquire(9)
+
+	st.enqueue("batch", 1, PriorityBatch)
+	st.enqueue("standard-1", 1, PriorityStandard)
+	st.enqueue("interactive-1", 1, PriorityInteractive)
+	st.enqueue("standard-2", 2, PriorityStandard)
+	st.enqueue("interactive-2", 2, PriorityInteractive)
+	st.enqueue("unknown", 3, Priority(7)) // 未知优先级按同步对话处理
+
+	stats := st.s.Stats()
+	if stats.Queued["interactive"] != 2 || stats.Queued["standard"] != 3 || stats.Queued["batch"] != 1 {
+		t.Fatalf("按优先级的排队数 = %v", stats.Queued)
+	}
+
+	got := st.order(release, 6)
+	want := []string{"interactive-1", "interactive-2", "standard-1", "standard-2", "unknown", "batch"}
+	if !equalStrings(got, want) {
+		t.Fatalf("出队顺序 = %v，期望 %v", got, want)
+	}
+}
+
+func TestSchedulerQueuePositions(t *testing.T) {
+	st := newSchedulerTest(t, 1, 0)
+	release := st.acquire(9)
+
+	a := st.enqueue("a", 1, PriorityStandard)
+	b := st.enqueue("b", 1, PriorityStandard)
+	c := st.enqueue("c", 2, PriorityBatch)
+	st.waitFor("初始排队位置", func() bool {
+		return a.lastPosition() == 1 && b.lastPosition() == 2 && c.lastPosition() == 3
+	})
+
+	// 高优先级请求插到前面，排在后面的请求位置后移
+	d := st.enqueue("d", 3, PriorityInteractive)
+	st.waitFor("高优先级入队后的排队位置", func() bool {
+		return d.lastPosition() == 1 && a.lastPosition() == 2 && b.lastPosition() == 3 && c.lastPosition() == 4
+	})
+
+	// 前面的请求取消，后面的请求位置前移
+	a.cancel()
+	if err := <-a.err; !errors.Is(err, context.Canceled) {
+		t.Fatalf("取消排队的请求返回 %v", err)
+	}
+	st.waitFor("取消后的排队位置", func() bool {
+		return d.lastPosition() == 1 && b.lastPosition() == 2 && c.lastPosition() == 3
+	})
+
+	if got, want := st.order(release, 3), []string{"d", "b", "c"}; !equalStrings(got, want) {
+		t.Fatalf("出队顺序 = %v，期望 %v", got, want)
+	}
+}
+
+func TestSchedulerCancelWhileQueued(t *testing.T) {
+	st := newSchedulerTest(t, 1, 0)
+	release := st.acquire(9)
+
+	a := st.enqueue("a", 1, PriorityStandard)
+	st.enqueue("b", 2, PriorityStandard)
+	a.cancel()
+	if err := <-a.err; !errors.Is(err, context.Canceled) {
+		t.Fatalf("取消排队的请求返回 %v", err)
+	}
+	if n := st.totalQueued(); n != 1 {
+		t.Fatalf("取消后排队数 = %d", n)
+	}
+
+	// 释放后额度分给仍在排队的请求，不会分给已取消的请求
+	if got := st.order(release, 1); !equalStrings(got, []string{"b"}) {
+		t.Fatalf("出队顺序 = %v", got)
+	}
+	if stats := st.s.Stats(); stats.InFlight != 0 {
+		t.Fatalf("InFlight = %d", stats.InFlight)
+	}
+}
+
+func TestSchedulerQueueFull(t *testing.T) {
+	st := newSchedulerTest(t, 1, 1)
+	release := st.acquire(9)
+	st.enqueue("a", 1, PriorityStandard)
+
+	if _, err := st.s.Acquire(context.Background(), 2, PriorityInteractive, nil); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("排队已满时返回 %v", err)
+	}
+	if got := st.order(release, 1); !equalStrings(got, []string{"a"}) {
+		t.Fatalf("出队顺序 = %v", got)
+	}
+}
@@ -22,10 +22,11 @@ export const uploadDocument = (formData: FormData) => {
 }
 
 // 获取文档列表
-export const getDocuments = () => {
+export const getDocuments = (params: Record<string, string | number | undefined> = { limit: 100 }) => {
   return request({
     url: '/api/v1/rag/list',
-    method: 'get'
+    method: 'get',
+    params
   })
 }
 
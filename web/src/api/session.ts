@@ -1,7 +1,10 @@
 import request from './request'
 
-export const getSessions = () => {
-  return request.get<any, any>('/api/v1/session/list')
+// 分页参数：limit、cursor（上一页的 next_cursor）、sort、order 以及各列表的过滤条件
+export type PageParams = Record<string, string | number | boolean | undefined>
+
+export const getSessions = (params: PageParams = { limit: 100 }) => {
+  return request.get<any, any>('/api/v1/session/list', { params })
 }
 
 export const createSession = (title: string, mode: string = 'chat') => {
@@ -20,6 +23,6 @@ export const deleteSession = (id: number) => {
   return request.delete<any, any>(`/api/v1/session/${id}`)
 }
 
-export const getHistory = (id: number) => {
-  return request.get<any, any>(`/api/v1/session/${id}/history`)
+export const getHistory = (id: number, params: PageParams = {}) => {
+  return request.get<any, any>(`/api/v1/session/${id}/history`, { params })
 }
@@ -26,8 +26,8 @@ export const useChatStore = defineStore('chat', () => {
   // 获取会话列表
   const fetchSessions = async () => {
     const res = await getSessions()
-    sessions.value = res.data
-    return res.data
+    sessions.value = res.data.items
+    return res.data.items
   }
 
   // 创建会话
@@ -57,8 +57,15 @@ export const useChatStore = defineStore('chat', () => {
     currentSessionId.value = id
     loading.value = true
     try {
-      const res = await getHistory(id)
-      messages.value = res.data
+      // 从第一条开始逐页加载当前分支
+      const all: Message[] = []
+      let cursor: string | undefined
+      do {
+        const res = await getHistory(id, { order: 'asc', limit: 100, cursor })
+        all.push(...res.data.items)
+        cursor = res.data.next_cursor || undefined
+      } while (cursor)
+      messages.value = all
     } finally {
       loading.value = false
     }